@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// CylinderContents describes the gas in a cylinder
+type CylinderContents struct {
+	Cylinder   Cylinder
+	Moles      MoleCount
+	GasVolume  GasVolume
+	GasWeight  GasWeight
+	GasVolumes map[Gas]GasVolume
+}
+
+// AnalyzeCylinder returns the contents of the cylinder
+func AnalyzeCylinder(cylinder Cylinder, gasSystem GasSystem, temperature Temperature) CylinderContents {
+	return CylinderContents{
+		Cylinder:   cylinder,
+		Moles:      gasSystem.Moles(cylinder.CylinderVolume, cylinder.Pressure, temperature, cylinder.GasComposition),
+		GasVolume:  cylinder.GasVolume(gasSystem, temperature),
+		GasWeight:  cylinder.GasWeight(temperature),
+		GasVolumes: cylinder.GasVolumes(gasSystem, temperature),
+	}
+}
+
+func analyzeMain(args []string) error {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	gasFlags := registerGasCompositionFlags(fs)
+	var cylinderFlags stringListFlag
+	fs.Var(&cylinderFlags, "cylinder", "Cylinder as [name=]volume@pressure[:mix], e.g. 12l@232bar:32; repeat for multiple cylinders")
+	var bestNitroxFlag = fs.Bool("best-nitrox", false, "Find the nitrox with the most oxygen for -depth within the -ppo2 limit instead of analyzing cylinders")
+	var bestTrimixFlag = fs.Bool("best-trimix", false, "Find the trimix with the least helium for -depth within the -ppo2 and -end limits instead of analyzing cylinders")
+	var depthFlag = fs.String("depth", "", "Planned depth for -best-nitrox or -best-trimix (m or ft)")
+	var ppO2Flag = fs.Float64("ppo2", 1.4, "Oxygen partial pressure limit in bar for -best-nitrox or -best-trimix")
+	var endFlag = fs.String("end", "30m", "Equivalent narcotic depth limit for -best-trimix")
+	var oxygenNarcoticFlag = fs.Bool("oxygen-narcotic", true, "Count oxygen as narcotic for the equivalent narcotic depth")
+	var targetPressureFlag = fs.String("target-pressure", "", "With -best-nitrox or -best-trimix: plan a partial pressure blend of the mix to this pressure in the -cylinder")
+	var topUpFlag = fs.String("top-up", "air", "Top-up gas mix for blending with -best-nitrox or -best-trimix")
+	var fillTemperatureFlag = fs.String("fill-temperature", "", "Temperature of the gas when the -cylinder pressures were read right after filling, e.g. 45C; reports the contents after cooling down to -temperature")
+	analyzerFlags := registerAnalyzerFlags(fs)
+	fs.Parse(args)
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	w, err := flags.output(os.Stdout, units)
+	if err != nil {
+		return err
+	}
+	gasSystem, temperature, err := flags.gasSettings(units)
+	if err != nil {
+		return err
+	}
+	gasComposition, err := gasFlags.gasComposition()
+	if err != nil {
+		return err
+	}
+	if *bestNitroxFlag || *bestTrimixFlag {
+		depth, err := units.ParseDepth(*depthFlag)
+		if err != nil || depth <= 0 {
+			return errors.New("invalid depth; -best-nitrox and -best-trimix need -depth")
+		}
+		if *ppO2Flag <= 0 {
+			return errors.New("invalid oxygen partial pressure limit; must be >0")
+		}
+		settings := DiveGasSettings{OxygenPartialPressureLimits: []PressureBar{PressureBar(*ppO2Flag)}, PlannedDepth: depth, OxygenNarcotic: *oxygenNarcoticFlag, HypoxicThreshold: defaultHypoxicThreshold}
+		var bestMix GasComposition
+		if *bestTrimixFlag {
+			narcoticDepthLimit, err := units.ParseDepth(*endFlag)
+			if err != nil || narcoticDepthLimit < 0 {
+				return fmt.Errorf("invalid END limit: %s", *endFlag)
+			}
+			if bestMix, err = BestTrimix(depth, PressureBar(*ppO2Flag), narcoticDepthLimit, *oxygenNarcoticFlag); err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "Best trimix for %.1f%s at pO2 %.2g and END %.1f%s: %s\n", units.Depth(depth), units.DepthUnit(), *ppO2Flag, units.Depth(narcoticDepthLimit), units.DepthUnit(), bestMix)
+		} else {
+			if bestMix, err = BestNitrox(depth, PressureBar(*ppO2Flag)); err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "Best nitrox for %.1f%s at pO2 %.2g: %s\n", units.Depth(depth), units.DepthUnit(), *ppO2Flag, bestMix)
+		}
+		printDiveGas(w, bestMix, settings, gasSystem, temperature, units)
+		if *targetPressureFlag != "" {
+			return blendBestMix(w, cylinderFlags, bestMix, *targetPressureFlag, *topUpFlag, gasComposition, gasSystem, temperature, units, *flags.verbose)
+		}
+		return nil
+	}
+	if len(cylinderFlags) == 0 {
+		return errors.New("at least one -cylinder is required")
+	}
+	cylinders, err := units.ParseCylinderSpecs(cylinderFlags, "cylinder")
+	if err != nil {
+		return fmt.Errorf("invalid cylinder: %w", err)
+	}
+	if err := checkCylinders(cylinders, "cylinder", true, units); err != nil {
+		return err
+	}
+	cylinders.SetDefaultGasComposition(gasComposition)
+	analyzerModel, analysisTemperature, err := analyzerFlags.model(temperature, units)
+	if err != nil {
+		return err
+	}
+	var fillTemperature Temperature
+	if *fillTemperatureFlag != "" {
+		if fillTemperature, err = units.ParseTemperature(*fillTemperatureFlag); err != nil || fillTemperature <= 0 {
+			return fmt.Errorf("invalid fill temperature: %s", *fillTemperatureFlag)
+		}
+	}
+	for _, cylinder := range cylinders {
+		if fillTemperature > 0 {
+			settledPressure := SettledPressure(cylinder, fillTemperature, gasSystem, temperature)
+			fmt.Fprintf(w, "%s: %.0f%s at %.0f°%s settles to %.0f%s at %.0f°%s\n", cylinder.Description, units.Pressure(cylinder.Pressure), units.PressureUnit(), units.Temperature(fillTemperature), units.TemperatureUnit(), units.Pressure(settledPressure), units.PressureUnit(), units.Temperature(temperature), units.TemperatureUnit())
+			if analyzerModel != nil {
+				printAnalyzerReading(w, cylinder.GasComposition, analyzerModel.Reading(cylinder.GasComposition, fillTemperature), fillTemperature, units)
+			}
+			cylinder.Pressure = settledPressure
+		}
+		printCylinderContents(w, AnalyzeCylinder(cylinder, gasSystem, temperature), units)
+		if analyzerModel != nil {
+			readingTemperature := analysisTemperature
+			if fillTemperature > 0 {
+				readingTemperature = temperature
+			}
+			printAnalyzerReading(w, cylinder.GasComposition, analyzerModel.Reading(cylinder.GasComposition, readingTemperature), readingTemperature, units)
+		}
+	}
+	return nil
+}
+
+func printCylinderContents(w io.Writer, contents CylinderContents, units UnitSystem) {
+	cylinder := contents.Cylinder
+	fmt.Fprintf(w, "%s: %.1fl at %.0f%s, %s\n", cylinder.Description, cylinder.CylinderVolume, units.Pressure(cylinder.Pressure), units.PressureUnit(), cylinder.GasComposition)
+	fmt.Fprintf(w, "  %.0f%s of gas, %.2f mol, %.0f%s\n", units.Volume(contents.GasVolume), units.VolumeUnit(), contents.Moles, units.Weight(contents.GasWeight), units.WeightUnit())
+	gases := make([]Gas, 0, len(contents.GasVolumes))
+	for gas := range contents.GasVolumes {
+		gases = append(gases, gas)
+	}
+	sort.Slice(gases, func(i, j int) bool { return gases[i] < gases[j] })
+	for _, gas := range gases {
+		fmt.Fprintf(w, "  %-8s %6.2f%% %8.1f%s\n", SpeciesLookup[gas].Symbol, 100*cylinder.GasComposition[gas], units.Volume(contents.GasVolumes[gas]), units.VolumeUnit())
+	}
+}
+
+// blendBestMix plans a partial pressure blend of the mix to the target pressure, starting from the gas in the single
+// cylinder
+func blendBestMix(w io.Writer, cylinderFlags stringListFlag, targetComposition GasComposition, targetPressureFlag string, topUpFlag string, gasComposition GasComposition, gasSystem GasSystem, temperature Temperature, units UnitSystem, verbose bool) error {
+	if len(cylinderFlags) != 1 {
+		return errors.New("blending needs a single -cylinder to fill")
+	}
+	cylinders, err := units.ParseCylinderSpecs(cylinderFlags, "cylinder")
+	if err != nil {
+		return fmt.Errorf("invalid cylinder: %w", err)
+	}
+	if err := checkCylinders(cylinders, "cylinder", true, units); err != nil {
+		return err
+	}
+	cylinders.SetDefaultGasComposition(gasComposition)
+	targetPressure, err := units.ParsePressure(targetPressureFlag)
+	if err != nil {
+		return fmt.Errorf("invalid target pressure: %w", err)
+	}
+	if err := checkCylinders(CylinderList{{CylinderVolume: cylinders[0].CylinderVolume, Pressure: targetPressure}}, "target", false, units); err != nil {
+		return err
+	}
+	topUpComposition, err := ParseGasComposition(topUpFlag)
+	if err != nil {
+		return fmt.Errorf("invalid top-up mix: %w", err)
+	}
+	if cylinders[0].Pressure > targetPressure {
+		return errors.New("cylinder pressure must not exceed target pressure")
+	}
+	plan, err := PlanBlend(cylinders[0], targetComposition, targetPressure, topUpComposition, units.AmbientPressure, gasSystem, temperature)
+	if err != nil {
+		return err
+	}
+	printBlendPlan(w, plan, units, verbose)
+	return nil
+}
@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestAnalyzeCylinder(t *testing.T) {
+	cylinder := Cylinder{CylinderVolume: 12, Pressure: 200, GasComposition: GasComposition{Oxygen: 0.32, Nitrogen: 0.68}}
+	contents := AnalyzeCylinder(cylinder, IdealGas, 293.15)
+	if !compareFloats(float64(contents.GasVolume), 2400) {
+		t.Errorf("Invalid gas volume %f", contents.GasVolume)
+	}
+	if !compareFloats(float64(contents.GasVolumes[Oxygen]), 768) {
+		t.Errorf("Invalid oxygen volume %f", contents.GasVolumes[Oxygen])
+	}
+	if contents.GasWeight <= 0 || contents.Moles <= 0 {
+		t.Errorf("Invalid contents %+v", contents)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+// AnalyzerModel describes how oxygen and helium analyzers read a mix. Oxygen cells are calibrated with air at the
+// calibration temperature; their temperature compensation lags behind when analyzing a warm cylinder, and helium
+// diffusing faster through the membrane makes some cells read high.
+type AnalyzerModel struct {
+	CalibrationTemperature Temperature
+	// OxygenTemperatureCoefficient is the relative change of the oxygen reading per kelvin the gas is warmer than
+	// the calibration temperature
+	OxygenTemperatureCoefficient float64
+	// OxygenHeliumCoefficient is the relative change of the oxygen reading in pure helium, scaled by the helium
+	// fraction
+	OxygenHeliumCoefficient float64
+}
+
+// AnalyzerReading holds expected oxygen and helium analyzer readings as fractions
+type AnalyzerReading struct {
+	Oxygen float64
+	Helium float64
+}
+
+// Reading returns the expected analyzer readings of the mix analyzed at the gas temperature
+func (m AnalyzerModel) Reading(gasComposition GasComposition, gasTemperature Temperature) AnalyzerReading {
+	oxygen := gasComposition[Oxygen] * (1 + m.OxygenTemperatureCoefficient*float64(gasTemperature-m.CalibrationTemperature)) * (1 + m.OxygenHeliumCoefficient*gasComposition[Helium])
+	return AnalyzerReading{Oxygen: oxygen, Helium: gasComposition[Helium]}
+}
+
+func printAnalyzerReading(w io.Writer, gasComposition GasComposition, reading AnalyzerReading, gasTemperature Temperature, units UnitSystem) {
+	fmt.Fprintf(w, "Expected analyzer readings at %.0f°%s: O2 %.1f%%, He %.1f%%", units.Temperature(gasTemperature), units.TemperatureUnit(), reading.Oxygen*100, reading.Helium*100)
+	if fmt.Sprintf("%.1f", reading.Oxygen*100) != fmt.Sprintf("%.1f", gasComposition[Oxygen]*100) {
+		fmt.Fprintf(w, " (actual O2 %.1f%%)", gasComposition[Oxygen]*100)
+	}
+	fmt.Fprintln(w)
+}
+
+// analyzerFlags holds flags for expected analyzer readings
+type analyzerFlags struct {
+	analyzerReadings             *bool
+	analysisTemperature          *string
+	oxygenTemperatureCoefficient *float64
+	oxygenHeliumCoefficient      *float64
+}
+
+func registerAnalyzerFlags(fs *flag.FlagSet) *analyzerFlags {
+	return &analyzerFlags{
+		analyzerReadings:             fs.Bool("analyzer-readings", false, "Print the expected oxygen and helium analyzer readings of the resulting mix"),
+		analysisTemperature:          fs.String("analysis-temperature", "", "Temperature of the gas when analyzed, e.g. 35C for a cylinder still warm from filling; defaults to -temperature, which the oxygen cell is calibrated at"),
+		oxygenTemperatureCoefficient: fs.Float64("o2-cell-temperature-coefficient", 0.002, "Relative change of the oxygen cell reading per degree the gas is warmer than at calibration"),
+		oxygenHeliumCoefficient:      fs.Float64("o2-cell-helium-coefficient", 0.01, "Relative change of the oxygen cell reading in pure helium; 0 for cells unaffected by helium"),
+	}
+}
+
+// model returns the analyzer model and the temperature the gas is analyzed at, or a nil model when readings are not
+// requested
+func (f *analyzerFlags) model(temperature Temperature, units UnitSystem) (*AnalyzerModel, Temperature, error) {
+	if !*f.analyzerReadings {
+		return nil, temperature, nil
+	}
+	analysisTemperature := temperature
+	if *f.analysisTemperature != "" {
+		var err error
+		if analysisTemperature, err = units.ParseTemperature(*f.analysisTemperature); err != nil || analysisTemperature <= 0 {
+			return nil, 0, fmt.Errorf("%w: analysis temperature %s", ErrInvalidTemperature, *f.analysisTemperature)
+		}
+	}
+	return &AnalyzerModel{
+		CalibrationTemperature:       temperature,
+		OxygenTemperatureCoefficient: *f.oxygenTemperatureCoefficient,
+		OxygenHeliumCoefficient:      *f.oxygenHeliumCoefficient,
+	}, analysisTemperature, nil
+}
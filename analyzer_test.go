@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalyzerReading(t *testing.T) {
+	model := AnalyzerModel{CalibrationTemperature: 293.15, OxygenTemperatureCoefficient: 0.002, OxygenHeliumCoefficient: 0.01}
+	ean32 := GasComposition{Oxygen: 0.32, Nitrogen: 0.68}
+	if reading := model.Reading(ean32, 293.15); !compareFloats(reading.Oxygen, 0.32) || reading.Helium != 0 {
+		t.Errorf("Expected EAN32 at the calibration temperature to read as is, got %+v", reading)
+	}
+	if reading := model.Reading(ean32, 308.15); !compareFloats(reading.Oxygen, 0.32*1.03) {
+		t.Errorf("Invalid reading of a warm cylinder: %+v", reading)
+	}
+	trimix := GasComposition{Oxygen: 0.18, Helium: 0.45, Nitrogen: 0.37}
+	if reading := model.Reading(trimix, 293.15); !compareFloats(reading.Oxygen, 0.18*(1+0.01*0.45)) || !compareFloats(reading.Helium, 0.45) {
+		t.Errorf("Invalid reading of trimix: %+v", reading)
+	}
+}
+
+func TestPrintAnalyzerReading(t *testing.T) {
+	var output strings.Builder
+	ean32 := GasComposition{Oxygen: 0.32, Nitrogen: 0.68}
+	printAnalyzerReading(&output, ean32, AnalyzerReading{Oxygen: 0.3296}, 308.15, Metric)
+	if expected := "Expected analyzer readings at 35°C: O2 33.0%, He 0.0% (actual O2 32.0%)\n"; output.String() != expected {
+		t.Errorf("Invalid output %q, expected %q", output.String(), expected)
+	}
+}
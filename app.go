@@ -1,10 +1,12 @@
 package main
 
 import (
-	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"math"
-	"os"
+	"slices"
+	"strings"
 )
 
 // R is an ideal gas constant
@@ -25,8 +27,8 @@ type GasWeight float64
 // PressureBar represents pressure (in bar)
 type PressureBar float64
 
-// AtomicWeight is an atomic weight for an element
-type AtomicWeight float64
+// MolarMass is the weight of a single mole of a gas in grams
+type MolarMass float64
 
 // PressureFromVolumes returns a new PressureBar instance from gas volume and cylinder volume.
 func PressureFromVolumes(gasVolume GasVolume, totalVolume CylinderVolume) PressureBar {
@@ -38,23 +40,21 @@ func (p PressureBar) PartialPressure(pp float64) PressureBar {
 	return PressureBar(float64(p) * pp)
 }
 
-// GasWeightFromMole calculates gas weight based on the mole count and atomic weight.
-func GasWeightFromMole(moleCount MoleCount, atomicWeight AtomicWeight) GasWeight {
-	return GasWeight(float64(moleCount) * float64(atomicWeight))
+// GasWeightFromMole calculates gas weight based on the mole count and molar mass.
+func GasWeightFromMole(moleCount MoleCount, molarMass MolarMass) GasWeight {
+	return GasWeight(float64(moleCount) * float64(molarMass))
 }
 
 // MoleCount represents number of atoms
 type MoleCount float64
 
 // GasSystem is the system used to calculate amount of the gas.
-type GasSystem int
-
-const (
-	// IdealGas uses ideal gas equations which do not compensate for pressure and temperature
-	IdealGas GasSystem = iota
-	// VanDerWaals uses Van Der Waals equations to compensate for temperature and pressure.
-	VanDerWaals
-)
+type GasSystem interface {
+	// Moles returns number of moles of the gas mix in a cylinder at the given pressure
+	Moles(cylinderVolume CylinderVolume, pressure PressureBar, temperature Temperature, gasComposition GasComposition) MoleCount
+	// Pressure returns pressure of the given number of moles of the gas mix in a cylinder
+	Pressure(cylinderVolume CylinderVolume, moleCount MoleCount, temperature Temperature, gasComposition GasComposition) PressureBar
+}
 
 // Gas represents various gases cylinders may contain.
 type Gas int
@@ -67,6 +67,8 @@ const (
 	Argon
 	Neon
 	Hydrogen
+	CarbonDioxide
+	CarbonMonoxide
 )
 
 // VanDerWaalsConstant represents Van der Waals equation constants
@@ -75,71 +77,128 @@ type VanDerWaalsConstant struct {
 	B float64
 }
 
-// AtomicWeightLookup is the weight of a single mole in grams.
-var AtomicWeightLookup = map[Gas]AtomicWeight{
-	Argon:    14.0067,
-	Helium:   4.002602,
-	Hydrogen: 1.00784,
-	Neon:     20.1797,
-	Nitrogen: 14.0067,
-	Oxygen:   15.999,
-}
-
-// VanDerWaalsConstants holds Van der Waals constants for gases.
-var VanDerWaalsConstants = map[Gas]VanDerWaalsConstant{
-	Argon:    {A: 1.355, B: 0.03201},
-	Helium:   {A: 0.0346, B: 0.0238},
-	Hydrogen: {A: 0.2476, B: 0.02661},
-	Neon:     {A: 0.2135, B: 0.01709},
-	Nitrogen: {A: 1.370, B: 0.0387},
-	Oxygen:   {A: 1.382, B: 0.03186},
-}
-
 // GasComposition stores information about gases currently being processed
 type GasComposition map[Gas]float64
 
-// Equalize equalizes all input cylinders
-func Equalize(cylinders []*Cylinder, gasSystem GasSystem, gasComposition GasComposition, temperature Temperature, verbose bool, debug bool) {
+// Equalize equalizes all input cylinders. Resulting gas composition is weighted by the amount of each gas in the cylinders.
+// Debug records are written to logger unless it is nil.
+func Equalize(cylinders []*Cylinder, gasSystem GasSystem, temperature Temperature, logger *slog.Logger) {
 	var totalVolume CylinderVolume
-	var pressureAfterEqualize PressureBar
-	if gasSystem == IdealGas {
-		var totalGasVolume GasVolume
-		for i := range cylinders {
-			totalGasVolume += cylinders[i].GasVolume(gasSystem, gasComposition, temperature)
-			totalVolume += cylinders[i].CylinderVolume
-		}
-		pressureAfterEqualize = PressureFromVolumes(totalGasVolume, totalVolume)
-	} else {
-		var totalMoles MoleCount
-		for i := range cylinders {
-			moles := cylinders[i].Moles(temperature, gasComposition)
-			if debug {
-				fmt.Println("Cylinder", cylinders[i], "moles", moles)
-			}
-			totalMoles += moles
-			totalVolume += cylinders[i].CylinderVolume
+	var totalGasVolume GasVolume
+	gasVolumes := make(map[Gas]GasVolume)
+	for i := range cylinders {
+		cylinderGasVolumes := cylinders[i].GasVolumes(gasSystem, temperature)
+		if logger != nil {
+			logger.Debug("cylinder gas volumes", "cylinder", cylinders[i].Description, "pressure", cylinders[i].Pressure, "mix", cylinders[i].GasComposition.String(), "gasVolume", cylinders[i].GasVolume(gasSystem, temperature))
 		}
-
-		pressureAfterEqualize = cylinderMolesToPressure(totalVolume, totalMoles, temperature, gasComposition)
-
-		if debug {
-			fmt.Println("Moles:", totalMoles, "Pressure after equalize:", pressureAfterEqualize)
+		for gasType, gasVolume := range cylinderGasVolumes {
+			gasVolumes[gasType] += gasVolume
+			totalGasVolume += gasVolume
 		}
+		totalVolume += cylinders[i].CylinderVolume
+	}
+	gasComposition := GasCompositionFromGasVolumes(gasVolumes)
+	pressureAfterEqualize := PressureFromGasVolume(totalVolume, totalGasVolume, gasSystem, gasComposition, temperature)
+	if logger != nil {
+		logger.Debug("equalized", "gasVolume", totalGasVolume, "pressure", pressureAfterEqualize, "mix", gasComposition.String())
 	}
 
 	for i := range cylinders {
 		cylinders[i].Pressure = pressureAfterEqualize
+		cylinders[i].GasComposition = gasComposition.Clone()
 	}
 }
 
 // CylinderConfiguration holds information about available cylinders and cylinder configuration, such as manifolds
 type CylinderConfiguration struct {
-	DestinationCylinderIsTwinset bool
-	DestinationCylinderPressure  PressureBar
-	DestinationCylinderVolume    CylinderVolume
-	SourceCylinderIsTwinset      bool
-	SourceCylinderPressure       PressureBar
-	SourceCylinderVolume         CylinderVolume
+	DestinationCylinders      CylinderList
+	DestinationManifoldClosed bool
+	SourceCylinders           CylinderList
+	SourceManifoldClosed      bool
+	// IndependentDestinations are never joined by a manifold, such as sidemount cylinders
+	IndependentDestinations bool
+	// SourceIsolators and DestinationIsolators, if set, open or close the isolator between each pair of neighbouring
+	// cylinders of a side, such as a quad, instead of its whole manifold; isolator i joins cylinders i and i+1
+	SourceIsolators      []bool
+	DestinationIsolators []bool
+	// CompareIsolators adds each combination of open and closed isolators of sides with more than two cylinders to the
+	// configurations compared
+	CompareIsolators bool
+	// Booster, if set, pushes the destination towards BoostTargetPressure after equalizing
+	Booster             *Booster
+	BoostTargetPressure PressureBar
+	// Compressor, if set, tops off the destination to CompressorTargetPressure after all transfers
+	Compressor               *Compressor
+	CompressorTargetPressure PressureBar
+	// FlowCoefficient is the Cv of the whip and valves; transfer times are estimated when it is set
+	FlowCoefficient float64
+	// Whip, if set, vents the gas left in the transfer whip after each transfer
+	Whip *Whip
+	// Prices, if set, price the gas taken from the sources and added by the compressor
+	Prices *GasPrices
+	// FillProcess selects temperature behavior during transfers; results are reported after cooling to ambient
+	// temperature
+	FillProcess FillProcess
+	// Cycles is how many times the cylinders are connected, equalized and disconnected, once when 0; with a
+	// non-isothermal fill process the cylinders cool down to ambient temperature in between
+	Cycles int
+	// OnTransferStep, if set, is called after each transfer between a source and a destination cylinder
+	OnTransferStep func(TransferStep)
+	// Explain writes the equations and intermediate results of each isothermal transfer to the report
+	Explain bool
+	// OnExplanation, if set, is called with the explanation of each isothermal transfer before it is made
+	OnExplanation func(equalizeExplanation)
+	// Observer, if set, is notified at the start and the end of each transfer
+	Observer TransferObserver
+	// StopPressure, if set, stops each isothermal transfer when the destination reaches it instead of equalizing
+	StopPressure PressureBar
+	// ManifoldStrategies are compared with the fully open and closed destination manifold
+	ManifoldStrategies []ManifoldStrategy
+	// DestinationManifoldStrategy, if set, opens the destination isolator during the isothermal transfers as the
+	// strategy says instead of keeping it closed
+	DestinationManifoldStrategy *ManifoldStrategy
+}
+
+// TransferState is the source and the destination cylinder of a transfer at its start or end. Cylinders are copies
+// that observers may keep.
+type TransferState struct {
+	// Configuration is the description of the manifold configuration
+	Configuration string
+	Step          int
+	Cycle         int
+	Source        Cylinder
+	Destination   Cylinder
+}
+
+// TransferObserver is notified of each transfer between a source and a destination cylinder, so that applications
+// embedding the calculator can render progress or record audits without parsing printed text
+type TransferObserver interface {
+	// OnTransferStart is called before the gas flows
+	OnTransferStart(before TransferState)
+	// OnTransferComplete is called after the transfer, and venting the whip, with the cylinders before and after it
+	OnTransferComplete(before TransferState, after TransferState)
+}
+
+func newTransferState(configuration string, step int, cycle int, source Cylinder, destination Cylinder) TransferState {
+	source.GasComposition = source.GasComposition.Clone()
+	destination.GasComposition = destination.GasComposition.Clone()
+	return TransferState{Configuration: configuration, Step: step, Cycle: cycle, Source: source, Destination: destination}
+}
+
+// TransferStep describes a single transfer between a source and a destination cylinder. Pressures are settled
+// pressures at ambient temperature.
+type TransferStep struct {
+	// Configuration is the description of the manifold configuration
+	Configuration             string
+	Step                      int
+	Cycle                     int
+	Source                    string
+	Destination               string
+	SourcePressureBefore      PressureBar
+	SourcePressureAfter       PressureBar
+	DestinationPressureBefore PressureBar
+	DestinationPressureAfter  PressureBar
+	TransferredGasVolume      GasVolume
 }
 
 // Cylinder represents a single cylinder and gas it contains
@@ -147,33 +206,94 @@ type Cylinder struct {
 	Description    string
 	CylinderVolume CylinderVolume
 	Pressure       PressureBar
+	GasComposition GasComposition
+	// WorkingPressure and TestPressure are the rated pressures of the cylinder; zero when not known
+	WorkingPressure PressureBar
+	TestPressure    PressureBar
+	// O2Clean is set when the cylinder is cleaned for oxygen service
+	O2Clean bool
 }
 
-// GasVolume returns amount of gas in the cylinder
-func (c1 Cylinder) GasVolume(gasSystem GasSystem, gasComposition GasComposition, temperature Temperature) GasVolume {
+// GasVolume returns amount of gas in the cylinder. Ideal gas reports free gas volume at 1 bar (cylinder volume
+// multiplied by pressure); other gas systems report moles multiplied by 22.4 l/mol.
+func (c1 Cylinder) GasVolume(gasSystem GasSystem, temperature Temperature) GasVolume {
 	if gasSystem == IdealGas {
 		return GasVolume(float64(c1.CylinderVolume) * float64(c1.Pressure))
 	}
-	return GasVolume(gasCompositionToMoles(c1.CylinderVolume, c1.Pressure, temperature, gasComposition) * 22.4)
+	return GasVolume(gasSystem.Moles(c1.CylinderVolume, c1.Pressure, temperature, c1.GasComposition) * 22.4)
+}
+
+// GasVolumes returns amount of each gas in the cylinder
+func (c1 Cylinder) GasVolumes(gasSystem GasSystem, temperature Temperature) map[Gas]GasVolume {
+	gasVolumes := make(map[Gas]GasVolume)
+	totalGasVolume := c1.GasVolume(gasSystem, temperature)
+	for gasType, gasInfo := range c1.GasComposition {
+		if gasInfo == 0 {
+			continue
+		}
+		gasVolumes[gasType] = totalGasVolume * GasVolume(gasInfo)
+	}
+	return gasVolumes
+}
+
+// PressureFromGasVolume returns pressure of a cylinder holding the given amount of gas
+func PressureFromGasVolume(cylinderVolume CylinderVolume, gasVolume GasVolume, gasSystem GasSystem, gasComposition GasComposition, temperature Temperature) PressureBar {
+	if gasSystem == IdealGas {
+		return PressureFromVolumes(gasVolume, cylinderVolume)
+	}
+	return gasSystem.Pressure(cylinderVolume, MoleCount(gasVolume/22.4), temperature, gasComposition)
 }
 
 // Equalize equalizes two cylinders
-func (c1 *Cylinder) Equalize(c2 *Cylinder, gasSystem GasSystem, gasComposition GasComposition, temperature Temperature, verbose bool, debug bool) {
+func (c1 *Cylinder) Equalize(c2 *Cylinder, gasSystem GasSystem, temperature Temperature, logger *slog.Logger) {
 	listOfCylinders := []*Cylinder{c1, c2}
-	Equalize(listOfCylinders, gasSystem, gasComposition, temperature, verbose, debug)
+	Equalize(listOfCylinders, gasSystem, temperature, logger)
 }
 
-// Moles returns number of atoms (in mole) inside a cylinder
-func (c1 *Cylinder) Moles(temperature Temperature, gasComposition GasComposition) MoleCount {
-	return gasCompositionToMoles(c1.CylinderVolume, c1.Pressure, temperature, gasComposition)
+// EqualizeUntil equalizes the cylinders like Equalize, but stops the transfer once c1 reaches stopPressure. It
+// reports whether c1 is at the stop pressure; nothing is transferred if it already was.
+func (c1 *Cylinder) EqualizeUntil(c2 *Cylinder, stopPressure PressureBar, gasSystem GasSystem, temperature Temperature, logger *slog.Logger) bool {
+	if c1.Pressure >= stopPressure {
+		return true
+	}
+	destinationBefore, sourceBefore := *c1, *c2
+	destinationGasBefore := c1.GasVolume(gasSystem, temperature)
+	c1.Equalize(c2, gasSystem, temperature, logger)
+	if c1.Pressure < stopPressure {
+		return false
+	}
+	// Only transfer the amount of gas required to reach the stop pressure
+	stopGas := Cylinder{CylinderVolume: c1.CylinderVolume, Pressure: stopPressure, GasComposition: c1.GasComposition}.GasVolume(gasSystem, temperature)
+	*c1, *c2 = destinationBefore, sourceBefore
+	c1.TransferGas(c2, stopGas-destinationGasBefore, gasSystem, temperature)
+	c1.Pressure = stopPressure
+	return true
 }
 
-func gasCompositionToMoles(cylinderVolume CylinderVolume, cylinderPressure PressureBar, temperature Temperature, gasComposition GasComposition) MoleCount {
-	var moles MoleCount
-	for gasType, gasInfo := range gasComposition {
-		moles += GasToMoles(cylinderVolume, cylinderPressure.PartialPressure(gasInfo), VanDerWaalsConstants[gasType], temperature)
+// AddGas adds the given amount of gas with the given composition to the cylinder
+func (c1 *Cylinder) AddGas(gasComposition GasComposition, gasVolume GasVolume, gasSystem GasSystem, temperature Temperature) {
+	cylinderGasVolumes := c1.GasVolumes(gasSystem, temperature)
+	for gasType, fraction := range gasComposition {
+		cylinderGasVolumes[gasType] += gasVolume * GasVolume(fraction)
+	}
+	var cylinderGasVolume GasVolume
+	for _, gas := range cylinderGasVolumes {
+		cylinderGasVolume += gas
 	}
-	return moles
+	c1.GasComposition = GasCompositionFromGasVolumes(cylinderGasVolumes)
+	c1.Pressure = PressureFromGasVolume(c1.CylinderVolume, cylinderGasVolume, gasSystem, c1.GasComposition, temperature)
+}
+
+// TransferGas moves the given amount of gas from c2 to c1
+func (c1 *Cylinder) TransferGas(c2 *Cylinder, gasVolume GasVolume, gasSystem GasSystem, temperature Temperature) {
+	sourceGasVolume := c2.GasVolume(gasSystem, temperature)
+	c1.AddGas(c2.GasComposition, gasVolume, gasSystem, temperature)
+	c2.Pressure = PressureFromGasVolume(c2.CylinderVolume, sourceGasVolume-gasVolume, gasSystem, c2.GasComposition, temperature)
+}
+
+// Moles returns number of atoms (in mole) inside a cylinder
+func (c1 *Cylinder) Moles(temperature Temperature) MoleCount {
+	return VanDerWaals.Moles(c1.CylinderVolume, c1.Pressure, temperature, c1.GasComposition)
 }
 
 // GasToMoles calculates number of atoms in given cylinder
@@ -203,24 +323,15 @@ func GasToMoles(cylinderVolume CylinderVolume, cylinderPressure PressureBar, vdw
 }
 
 // GasWeight returns weight of the gas stored inside the cylinder
-func (c1 Cylinder) GasWeight(gasComposition GasComposition, temperature Temperature) GasWeight {
+func (c1 Cylinder) GasWeight(temperature Temperature) GasWeight {
+	moleCount := c1.Moles(temperature)
 	var weightSum GasWeight
-	for gasType, gasInfo := range gasComposition {
-		moleCount := GasToMoles(c1.CylinderVolume, c1.Pressure.PartialPressure(gasInfo), VanDerWaalsConstants[gasType], temperature)
-		gasWeight := GasWeightFromMole(moleCount, AtomicWeightLookup[gasType])
-		weightSum += gasWeight
+	for gasType, gasInfo := range c1.GasComposition {
+		weightSum += GasWeightFromMole(moleCount*MoleCount(gasInfo), SpeciesLookup[gasType].MolarMass)
 	}
 	return weightSum
 }
 
-func cylinderMolesToPressure(cylinderVolume CylinderVolume, n MoleCount, temperature Temperature, gasComposition GasComposition) PressureBar {
-	var pressureSum PressureBar
-	for gasType, gasInfo := range gasComposition {
-		pressureSum += MolesToPressure(cylinderVolume, MoleCount(float64(n)*gasInfo), temperature, VanDerWaalsConstants[gasType])
-	}
-	return pressureSum
-}
-
 // MolesToPressure returns pressure based on the volume, atomic count and gas composition.
 func MolesToPressure(cylinderVolume CylinderVolume, moleCount MoleCount, T Temperature, vdwConstants VanDerWaalsConstant) PressureBar {
 	V := float64(cylinderVolume)
@@ -243,68 +354,106 @@ func (cl CylinderList) TotalVolume() CylinderVolume {
 	return totalVolume
 }
 
+// MaxPressure returns the highest pressure of listed cylinders
+func (cl CylinderList) MaxPressure() PressureBar {
+	var maxPressure PressureBar
+	for _, cylinder := range cl {
+		if cylinder.Pressure > maxPressure {
+			maxPressure = cylinder.Pressure
+		}
+	}
+	return maxPressure
+}
+
+// CombinedPressure returns the pressure all listed cylinders would have when connected together
+func (cl CylinderList) CombinedPressure(gasSystem GasSystem, temperature Temperature) PressureBar {
+	return openManifold(append(CylinderList(nil), cl...), "", gasSystem, temperature)[0].Pressure
+}
+
+// HasUniformGasComposition returns true when all listed cylinders contain the same gas
+func (cl CylinderList) HasUniformGasComposition() bool {
+	for _, cylinder := range cl {
+		if !cylinder.GasComposition.Equal(cl[0].GasComposition) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetDefaultGasComposition sets gas composition for cylinders without one
+func (cl CylinderList) SetDefaultGasComposition(gasComposition GasComposition) {
+	for i := range cl {
+		if cl[i].GasComposition == nil {
+			cl[i].GasComposition = gasComposition.Clone()
+		}
+	}
+}
+
+// MinPressure returns the lowest pressure of listed cylinders
+func (cl CylinderList) MinPressure() PressureBar {
+	var minPressure PressureBar
+	for i, cylinder := range cl {
+		if i == 0 || cylinder.Pressure < minPressure {
+			minPressure = cylinder.Pressure
+		}
+	}
+	return minPressure
+}
+
 // TotalGasWeight calculates the weight of the gas for all cylinders in cylinder list.
-func (cl CylinderList) TotalGasWeight(gasComposition GasComposition, temperature Temperature) GasWeight {
+func (cl CylinderList) TotalGasWeight(temperature Temperature) GasWeight {
 	var weightSum GasWeight
 	for _, cylinder := range cl {
-		weightSum += cylinder.GasWeight(gasComposition, temperature)
+		weightSum += cylinder.GasWeight(temperature)
 	}
 	return weightSum
 }
 
 // TotalGasVolume returns total gas volume for all listed cylinders
-func (cl CylinderList) TotalGasVolume(gasSystem GasSystem, gasComposition GasComposition, temperature Temperature) GasVolume {
+func (cl CylinderList) TotalGasVolume(gasSystem GasSystem, temperature Temperature) GasVolume {
 	var totalGasVolume GasVolume
 	for _, cylinder := range cl {
-		totalGasVolume += cylinder.GasVolume(gasSystem, gasComposition, temperature)
+		totalGasVolume += cylinder.GasVolume(gasSystem, temperature)
 	}
 	return totalGasVolume
 }
 
-func initializeCylinders(cylinderConfiguration CylinderConfiguration, sourceCylinders *CylinderList, destinationCylinders *CylinderList) {
-	if cylinderConfiguration.SourceCylinderIsTwinset {
-		*sourceCylinders = []Cylinder{
-			{
-				Description:    "left",
-				CylinderVolume: CylinderVolume(cylinderConfiguration.SourceCylinderVolume / 2),
-				Pressure:       cylinderConfiguration.SourceCylinderPressure,
-			},
-			{
-				Description:    "right",
-				CylinderVolume: CylinderVolume(cylinderConfiguration.SourceCylinderVolume / 2),
-				Pressure:       cylinderConfiguration.SourceCylinderPressure,
-			},
-		}
-	} else {
-		*sourceCylinders = []Cylinder{
-			{
-				Description:    "source",
-				CylinderVolume: CylinderVolume(cylinderConfiguration.SourceCylinderVolume),
-				Pressure:       cylinderConfiguration.SourceCylinderPressure,
-			},
+// NewTwinset returns a twinset as two cylinders of equal size, connected with a closeable manifold
+func NewTwinset(cylinderVolume CylinderVolume, pressure PressureBar) CylinderList {
+	return NewCylinderSet(cylinderVolume, pressure, 2)
+}
+
+// openManifold equalizes all cylinders and combines them to a single cylinder
+func openManifold(cylinders CylinderList, description string, gasSystem GasSystem, temperature Temperature) CylinderList {
+	if len(cylinders) > 1 && (cylinders.MaxPressure() != cylinders.MinPressure() || !cylinders.HasUniformGasComposition()) {
+		cylinderPointers := make([]*Cylinder, len(cylinders))
+		for i := range cylinders {
+			cylinderPointers[i] = &cylinders[i]
 		}
+		Equalize(cylinderPointers, gasSystem, temperature, nil)
 	}
-	if cylinderConfiguration.DestinationCylinderIsTwinset {
-		*destinationCylinders = []Cylinder{
-			{
-				Description:    "left",
-				CylinderVolume: CylinderVolume(cylinderConfiguration.DestinationCylinderVolume / 2),
-				Pressure:       cylinderConfiguration.DestinationCylinderPressure,
-			},
-			{
-				Description:    "right",
-				CylinderVolume: CylinderVolume(cylinderConfiguration.DestinationCylinderVolume / 2),
-				Pressure:       cylinderConfiguration.DestinationCylinderPressure,
-			},
-		}
-	} else {
-		*destinationCylinders = []Cylinder{
-			{
-				Description:    "destination",
-				CylinderVolume: CylinderVolume(cylinderConfiguration.DestinationCylinderVolume),
-				Pressure:       cylinderConfiguration.DestinationCylinderPressure,
-			},
-		}
+	return CylinderList{
+		{
+			Description:    description,
+			CylinderVolume: cylinders.TotalVolume(),
+			Pressure:       cylinders[0].Pressure,
+			GasComposition: cylinders[0].GasComposition.Clone(),
+		},
+	}
+}
+
+func initializeCylinders(cylinderConfiguration CylinderConfiguration, gasSystem GasSystem, temperature Temperature, sourceCylinders *CylinderList, destinationCylinders *CylinderList) {
+	*sourceCylinders = append(CylinderList(nil), cylinderConfiguration.SourceCylinders...)
+	if cylinderConfiguration.SourceIsolators != nil {
+		*sourceCylinders = joinIsolated(*sourceCylinders, cylinderConfiguration.SourceIsolators, gasSystem, temperature)
+	} else if !cylinderConfiguration.SourceManifoldClosed {
+		*sourceCylinders = openManifold(*sourceCylinders, "source", gasSystem, temperature)
+	}
+	*destinationCylinders = append(CylinderList(nil), cylinderConfiguration.DestinationCylinders...)
+	if cylinderConfiguration.DestinationIsolators != nil {
+		*destinationCylinders = joinIsolated(*destinationCylinders, cylinderConfiguration.DestinationIsolators, gasSystem, temperature)
+	} else if !cylinderConfiguration.DestinationManifoldClosed && !cylinderConfiguration.IndependentDestinations {
+		*destinationCylinders = openManifold(*destinationCylinders, "destination", gasSystem, temperature)
 	}
 }
 
@@ -317,188 +466,436 @@ type CylinderSummary struct {
 	SourceCylinderGasVolume      GasVolume
 	SourceCylinderPressure       PressureBar
 	SourceCylinderGasWeight      GasWeight
+	DestinationGasComposition    GasComposition
+	SourceGasComposition         GasComposition
+	UniformGasComposition        bool
+	BoostedGasVolume             GasVolume
+	DriveGasVolume               GasVolume
+	CompressorGasVolume          GasVolume
+	CompressorMinutes            float64
+	WhipGasVolume                GasVolume
+	TransferTime                 TransferTime
+	// GasCost is set when the configuration has prices
+	GasCost *GasCost
 }
 
-func equalizeAndReport(cylinderConfiguration CylinderConfiguration, gasSystem GasSystem, gasComposition GasComposition, temperature Temperature, verbose bool, debug bool, printSourceSummary bool) CylinderSummary {
+// equalizeAndReport runs the transfers of the configuration, writing the report to w and debug records to logger
+// unless it is nil
+func equalizeAndReport(w io.Writer, cylinderConfiguration CylinderConfiguration, gasSystem GasSystem, temperature Temperature, units UnitSystem, verbose bool, logger *slog.Logger, printSourceSummary bool) CylinderSummary {
 	var sourceCylinders CylinderList
 	var destinationCylinders CylinderList
-	initializeCylinders(cylinderConfiguration, &sourceCylinders, &destinationCylinders)
+	initializeCylinders(cylinderConfiguration, gasSystem, temperature, &sourceCylinders, &destinationCylinders)
 	if printSourceSummary {
-		sourceCylinderGasVolume := sourceCylinders.TotalGasVolume(gasSystem, gasComposition, temperature)
-		destinationCylinderGasVolume := destinationCylinders.TotalGasVolume(gasSystem, gasComposition, temperature)
+		sourceCylinderGasVolume := sourceCylinders.TotalGasVolume(gasSystem, temperature)
+		destinationCylinderGasVolume := destinationCylinders.TotalGasVolume(gasSystem, temperature)
 		if verbose {
-			fmt.Println("Before any transfers:")
-			fmt.Println("Source cylinders:", sourceCylinderGasVolume, "l of gas, pressure", cylinderConfiguration.SourceCylinderPressure, "bar")
-			fmt.Println("Destination cylinders:", destinationCylinderGasVolume, "l of gas, pressure", cylinderConfiguration.DestinationCylinderPressure, "bar")
-			fmt.Println()
+			fmt.Fprintln(w, tr(w, "Before any transfers:"))
+			fmt.Fprintln(w, tr(w, "Source cylinders:"), units.Volume(sourceCylinderGasVolume), units.VolumeUnit(), tr(w, "of gas, pressure"), units.Pressure(sourceCylinders.CombinedPressure(gasSystem, temperature)), units.PressureUnit())
+			fmt.Fprintln(w, tr(w, "Destination cylinders:"), units.Volume(destinationCylinderGasVolume), units.VolumeUnit(), tr(w, "of gas, pressure"), units.Pressure(destinationCylinders.CombinedPressure(gasSystem, temperature)), units.PressureUnit())
+			fmt.Fprintln(w)
 		}
 	}
 
+	uniformGasComposition := append(append(CylinderList(nil), sourceCylinders...), destinationCylinders...).HasUniformGasComposition()
 	var description string
-	if cylinderConfiguration.DestinationCylinderIsTwinset && cylinderConfiguration.SourceCylinderIsTwinset {
+	if cylinderConfiguration.DestinationManifoldClosed && cylinderConfiguration.SourceManifoldClosed {
 		description = "both manifolds closed"
-	} else if cylinderConfiguration.DestinationCylinderIsTwinset {
+	} else if cylinderConfiguration.DestinationManifoldClosed {
 		description = "destination manifold closed"
-	} else if cylinderConfiguration.SourceCylinderIsTwinset {
+	} else if cylinderConfiguration.SourceManifoldClosed {
 		description = "source manifold closed"
 	} else {
 		description = "all manifolds open"
 	}
-	fmt.Println("Equalizing with", description)
+	if cylinderConfiguration.SourceIsolators != nil || cylinderConfiguration.DestinationIsolators != nil {
+		description = isolatorDescription(cylinderConfiguration)
+	}
+	if cylinderConfiguration.DestinationManifoldStrategy != nil {
+		description = cylinderConfiguration.DestinationManifoldStrategy.Description()
+	}
+	independentDestinations := cylinderConfiguration.IndependentDestinations && len(destinationCylinders) > 1
+	if independentDestinations && cylinderConfiguration.SourceManifoldClosed {
+		description = "source manifold closed, independent destinations"
+	} else if independentDestinations {
+		description = "independent destinations"
+	}
+	fmt.Fprintf(w, tr(w, "Equalizing with %s\n"), tr(w, description))
 	stepI := 0
-	for sourceI := range sourceCylinders {
-		for destinationI := range destinationCylinders {
-			stepI++
-			destinationCylinderGasVolumeBefore := destinationCylinders[destinationI].GasVolume(gasSystem, gasComposition, temperature)
-			destinationCylinders[destinationI].Equalize(&sourceCylinders[sourceI], gasSystem, gasComposition, temperature, verbose, debug)
-			if verbose {
-				fmt.Printf("Step %d: from %s to %s; transferred %.0fl of gas\n", stepI, sourceCylinders[sourceI].Description, destinationCylinders[destinationI].Description, destinationCylinders[destinationI].GasVolume(gasSystem, gasComposition, temperature)-destinationCylinderGasVolumeBefore)
+	var hottestFill FillResult
+	var whipGasVolume GasVolume
+	var transferTime TransferTime
+	stopped := make([]bool, len(destinationCylinders))
+	for cycle := 0; cycle < max(cylinderConfiguration.Cycles, 1); cycle++ {
+		destinationGasVolumeBeforeCycle := destinationCylinders.TotalGasVolume(gasSystem, temperature)
+		destinationPressureBeforeCycle := destinationCylinders.CombinedPressure(gasSystem, temperature)
+		for sourceI := range sourceCylinders {
+			for destinationI := range destinationCylinders {
+				if cycle > 0 && sourceCylinders[sourceI].Pressure <= destinationCylinders[destinationI].Pressure {
+					// Reconnecting would not fill the destination, so the valve stays closed
+					continue
+				}
+				stepI++
+				// Gas flows into all destinations through an opened isolator
+				destinationGasVolume := func() GasVolume {
+					if cylinderConfiguration.DestinationManifoldStrategy != nil {
+						return destinationCylinders.TotalGasVolume(gasSystem, temperature)
+					}
+					return destinationCylinders[destinationI].GasVolume(gasSystem, temperature)
+				}
+				destinationCylinderGasVolumeBefore := destinationGasVolume()
+				sourcePressureBefore := sourceCylinders[sourceI].Pressure
+				destinationPressureBefore := destinationCylinders[destinationI].Pressure
+				var stateBefore TransferState
+				if cylinderConfiguration.Observer != nil {
+					stateBefore = newTransferState(description, stepI, cycle+1, sourceCylinders[sourceI], destinationCylinders[destinationI])
+					cylinderConfiguration.Observer.OnTransferStart(stateBefore)
+				}
+				if cylinderConfiguration.FlowCoefficient > 0 {
+					stepTime := EstimateTransferTime(sourceCylinders[sourceI], destinationCylinders[destinationI], cylinderConfiguration.FlowCoefficient, gasSystem, temperature)
+					transferTime.Minutes90 += stepTime.Minutes90
+					transferTime.Minutes99 += stepTime.Minutes99
+					if verbose {
+						fmt.Fprintf(w, tr(w, "Step %d: %.1f minutes to 90%% equalized, %.1f minutes to 99%%\n"), stepI, stepTime.Minutes90, stepTime.Minutes99)
+					}
+				}
+				if !cylinderConfiguration.FillProcess.Isothermal() {
+					fillResult := destinationCylinders[destinationI].Fill(&sourceCylinders[sourceI], cylinderConfiguration.FillProcess, gasSystem, temperature)
+					if fillResult.HotPressure > hottestFill.HotPressure {
+						hottestFill = fillResult
+					}
+					if verbose {
+						fmt.Fprintf(w, tr(w, "Step %d: %s hot %.*f%s at %.0f°%s, settles to %.*f%s\n"), stepI, destinationCylinders[destinationI].Description, units.decimals(0), units.round(units.Pressure(fillResult.HotPressure)), units.PressureUnit(), units.Temperature(fillResult.HotTemperature), units.TemperatureUnit(), units.decimals(0), units.round(units.Pressure(fillResult.SettledPressure)), units.PressureUnit())
+					}
+				} else {
+					if (cylinderConfiguration.Explain || cylinderConfiguration.OnExplanation != nil) && cylinderConfiguration.DestinationManifoldStrategy == nil {
+						explanation := newEqualizeExplanation(description, stepI, []Cylinder{destinationCylinders[destinationI], sourceCylinders[sourceI]}, gasSystem, temperature)
+						if cylinderConfiguration.Explain {
+							explanation.writeText(w)
+						}
+						if cylinderConfiguration.OnExplanation != nil {
+							cylinderConfiguration.OnExplanation(explanation)
+						}
+					}
+					if strategy := cylinderConfiguration.DestinationManifoldStrategy; strategy != nil {
+						strategy.transfer(stepI-1, &sourceCylinders[sourceI], destinationCylinders, destinationI, gasSystem, temperature, logger)
+					} else if cylinderConfiguration.StopPressure > 0 {
+						stopped[destinationI] = destinationCylinders[destinationI].EqualizeUntil(&sourceCylinders[sourceI], cylinderConfiguration.StopPressure, gasSystem, temperature, logger)
+					} else {
+						destinationCylinders[destinationI].Equalize(&sourceCylinders[sourceI], gasSystem, temperature, logger)
+					}
+				}
+				transferred := destinationGasVolume() - destinationCylinderGasVolumeBefore
+				if cylinderConfiguration.Whip != nil {
+					whipGasVolume += cylinderConfiguration.Whip.Vent(&sourceCylinders[sourceI], destinationCylinders[destinationI], units.AmbientPressure, gasSystem, temperature)
+				}
+				if verbose {
+					fmt.Fprintf(w, tr(w, "Step %d: from %s to %s; transferred %.*f%s of gas\n"), stepI, sourceCylinders[sourceI].Description, destinationCylinders[destinationI].Description, units.decimals(0), units.round(units.Volume(transferred)), units.VolumeUnit())
+				}
+				if cylinderConfiguration.OnTransferStep != nil {
+					cylinderConfiguration.OnTransferStep(TransferStep{
+						Configuration:             description,
+						Step:                      stepI,
+						Cycle:                     cycle + 1,
+						Source:                    sourceCylinders[sourceI].Description,
+						Destination:               destinationCylinders[destinationI].Description,
+						SourcePressureBefore:      sourcePressureBefore,
+						SourcePressureAfter:       sourceCylinders[sourceI].Pressure,
+						DestinationPressureBefore: destinationPressureBefore,
+						DestinationPressureAfter:  destinationCylinders[destinationI].Pressure,
+						TransferredGasVolume:      transferred,
+					})
+				}
+				if cylinderConfiguration.Observer != nil {
+					cylinderConfiguration.Observer.OnTransferComplete(stateBefore, newTransferState(description, stepI, cycle+1, sourceCylinders[sourceI], destinationCylinders[destinationI]))
+				}
 			}
 		}
+		if cylinderConfiguration.Cycles > 1 {
+			destinationPressure := destinationCylinders.CombinedPressure(gasSystem, temperature)
+			gained := destinationCylinders.TotalGasVolume(gasSystem, temperature) - destinationGasVolumeBeforeCycle
+			fmt.Fprintf(w, tr(w, "Cycle %d: destination settles to %.*f%s, up %.*f%s with %.*f%s of gas\n"), cycle+1, units.decimals(0), units.round(units.Pressure(destinationPressure)), units.PressureUnit(), units.decimals(1), units.round(units.PressureDifference(destinationPressure-destinationPressureBeforeCycle)), units.PressureUnit(), units.decimals(0), units.round(units.Volume(gained)), units.VolumeUnit())
+		}
 	}
-	destinationCylinderPointers := make([]*Cylinder, len(destinationCylinders))
-	for destinationI := range destinationCylinders {
-		destinationCylinderPointers[destinationI] = &destinationCylinders[destinationI]
-	}
-	Equalize(destinationCylinderPointers, gasSystem, gasComposition, temperature, verbose, debug)
-	if debug {
-		fmt.Println("Source cylinders gas volume:", sourceCylinders.TotalGasVolume(gasSystem, gasComposition, temperature))
-		fmt.Println("Destination cylinders gas volume:", destinationCylinders.TotalGasVolume(gasSystem, gasComposition, temperature))
-	}
-	sourceCylinderGasVolume := sourceCylinders.TotalGasVolume(gasSystem, gasComposition, temperature)
-	sourceCylinderPressure := PressureFromVolumes(sourceCylinderGasVolume, sourceCylinders.TotalVolume())
-	destinationCylinderGasVolume := destinationCylinders.TotalGasVolume(gasSystem, gasComposition, temperature)
-	destinationCylinderPressure := PressureFromVolumes(destinationCylinderGasVolume, destinationCylinders.TotalVolume())
-	fmt.Printf("Source cylinders: %.0fl, %.0fbar\n", sourceCylinderGasVolume, sourceCylinderPressure)
-	fmt.Printf("Destination cylinders: %.0fl, %.0fbar\n", destinationCylinderGasVolume, destinationCylinderPressure)
-	fmt.Println()
+	if independentDestinations {
+		destinationPressures := make([]string, len(destinationCylinders))
+		for destinationI, destinationCylinder := range destinationCylinders {
+			destinationPressures[destinationI] = fmt.Sprintf("%s %.*f%s", destinationCylinder.Description, units.decimals(0), units.round(units.Pressure(destinationCylinder.Pressure)), units.PressureUnit())
+		}
+		fmt.Fprintln(w, tr(w, "Independent destinations:"), strings.Join(destinationPressures, ", "))
+	} else {
+		destinationCylinderPointers := make([]*Cylinder, len(destinationCylinders))
+		for destinationI := range destinationCylinders {
+			destinationCylinderPointers[destinationI] = &destinationCylinders[destinationI]
+		}
+		Equalize(destinationCylinderPointers, gasSystem, temperature, logger)
+	}
+	if !cylinderConfiguration.FillProcess.Isothermal() {
+		fmt.Fprintf(w, tr(w, "Fill (%s): destination up to %.*f%s at %.0f°%s while filling\n"), cylinderConfiguration.FillProcess, units.decimals(0), units.round(units.Pressure(hottestFill.HotPressure)), units.PressureUnit(), units.Temperature(hottestFill.HotTemperature), units.TemperatureUnit())
+	}
+	if cylinderConfiguration.StopPressure > 0 {
+		reached := !slices.Contains(stopped, false)
+		sourcePressures := make([]string, len(sourceCylinders))
+		for sourceI, sourceCylinder := range sourceCylinders {
+			sourcePressures[sourceI] = fmt.Sprintf("%s %.*f%s", sourceCylinder.Description, units.decimals(0), units.round(units.Pressure(sourceCylinder.Pressure)), units.PressureUnit())
+		}
+		fmt.Fprintf(w, tr(w, "Transfers stop at %.*f%s, reached: %t; left in the sources: %s\n"), units.decimals(0), units.round(units.Pressure(cylinderConfiguration.StopPressure)), units.PressureUnit(), reached, strings.Join(sourcePressures, ", "))
+	}
+	if cylinderConfiguration.FlowCoefficient > 0 {
+		fmt.Fprintf(w, tr(w, "Transfers take %.1f minutes to 90%% equalized (%.1f minutes to 99%%)\n"), transferTime.Minutes90, transferTime.Minutes99)
+	}
+	if cylinderConfiguration.Whip != nil {
+		fmt.Fprintf(w, tr(w, "Whip vented %.*f%s of gas over %d connections\n"), units.decimals(1), units.round(units.Volume(whipGasVolume)), units.VolumeUnit(), stepI*cylinderConfiguration.Whip.Connections)
+	}
+	if logger != nil {
+		logger.Debug("transfers done", "configuration", description, "sourceGasVolume", sourceCylinders.TotalGasVolume(gasSystem, temperature), "destinationGasVolume", destinationCylinders.TotalGasVolume(gasSystem, temperature))
+	}
+	var boostResult BoostResult
+	if cylinderConfiguration.Booster != nil {
+		sourceCylinders = openManifold(sourceCylinders, "source", gasSystem, temperature)
+		destinationCylinders = openManifold(destinationCylinders, "destination", gasSystem, temperature)
+		boostResult = cylinderConfiguration.Booster.Boost(&sourceCylinders[0], &destinationCylinders[0], cylinderConfiguration.BoostTargetPressure, gasSystem, temperature)
+		if verbose || !boostResult.TargetReached {
+			fmt.Fprintf(w, tr(w, "Booster: destination %.*f%s to %.*f%s, target reached: %t\n"), units.decimals(0), units.round(units.Pressure(boostResult.DestinationPressureBefore)), units.PressureUnit(), units.decimals(0), units.round(units.Pressure(boostResult.DestinationPressureAfter)), units.PressureUnit(), boostResult.TargetReached)
+		}
+		fmt.Fprintf(w, tr(w, "Booster moved %.*f%s of gas using %.*f%s of drive gas\n"), units.decimals(0), units.round(units.Volume(boostResult.BoostedGasVolume)), units.VolumeUnit(), units.decimals(0), units.round(units.Volume(boostResult.DriveGasVolume)), units.VolumeUnit())
+	}
+	var compressorResult CompressorResult
+	if cylinderConfiguration.Compressor != nil {
+		destinationCylinders = openManifold(destinationCylinders, "destination", gasSystem, temperature)
+		compressorResult = cylinderConfiguration.Compressor.TopOff(&destinationCylinders[0], cylinderConfiguration.CompressorTargetPressure, gasSystem, temperature)
+		fmt.Fprintf(w, tr(w, "Compressor needs %.0f minutes to finish to %.*f%s (%.*f%s of air)\n"), compressorResult.Minutes, units.decimals(0), units.round(units.Pressure(compressorResult.PressureAfter)), units.PressureUnit(), units.decimals(0), units.round(units.Volume(compressorResult.GasVolume)), units.VolumeUnit())
+		if !compressorResult.TargetReached {
+			fmt.Fprintf(w, tr(w, "Compressor maximum pressure %.*f%s is below the target\n"), units.decimals(0), units.round(units.Pressure(cylinderConfiguration.Compressor.MaxPressure)), units.PressureUnit())
+		}
+	}
+	sourceCylinderGasVolume := sourceCylinders.TotalGasVolume(gasSystem, temperature)
+	sourceCylinderPressure := sourceCylinders.CombinedPressure(gasSystem, temperature)
+	destinationCylinderGasVolume := destinationCylinders.TotalGasVolume(gasSystem, temperature)
+	destinationCylinderPressure := destinationCylinders.CombinedPressure(gasSystem, temperature)
+	fmt.Fprintf(w, tr(w, "Source cylinders: %.*f%s, %.*f%s\n"), units.decimals(0), units.round(units.Volume(sourceCylinderGasVolume)), units.VolumeUnit(), units.decimals(0), units.round(units.Pressure(sourceCylinderPressure)), units.PressureUnit())
+	fmt.Fprintf(w, tr(w, "Destination cylinders: %.*f%s, %.*f%s\n"), units.decimals(0), units.round(units.Volume(destinationCylinderGasVolume)), units.VolumeUnit(), units.decimals(0), units.round(units.Pressure(destinationCylinderPressure)), units.PressureUnit())
+	if !uniformGasComposition {
+		fmt.Fprintln(w, tr(w, "Destination mix:"), destinationCylinders[0].GasComposition)
+	}
+	var gasCost *GasCost
+	if cylinderConfiguration.Prices != nil {
+		gasVolumes := takenGasVolumes(cylinderConfiguration.SourceCylinders, sourceCylinderGasVolume, gasSystem, temperature)
+		for gasType, fraction := range compressorAir {
+			gasVolumes[gasType] += compressorResult.GasVolume * GasVolume(fraction)
+		}
+		cost := cylinderConfiguration.Prices.Cost(gasVolumes)
+		gasCost = &cost
+		if verbose {
+			fmt.Fprintln(w, tr(w, "Gas cost:"), cost)
+		}
+	}
+	fmt.Fprintln(w)
 	return CylinderSummary{
 		Description:                  description,
 		DestinationCylinderGasVolume: destinationCylinderGasVolume,
-		DestinationCylinderGasWeight: destinationCylinders.TotalGasWeight(gasComposition, temperature),
+		DestinationCylinderGasWeight: destinationCylinders.TotalGasWeight(temperature),
 		DestinationCylinderPressure:  destinationCylinderPressure,
 		SourceCylinderGasVolume:      sourceCylinderGasVolume,
-		SourceCylinderGasWeight:      sourceCylinders.TotalGasWeight(gasComposition, temperature),
+		SourceCylinderGasWeight:      sourceCylinders.TotalGasWeight(temperature),
 		SourceCylinderPressure:       sourceCylinderPressure,
+		DestinationGasComposition:    destinationCylinders[0].GasComposition,
+		SourceGasComposition:         sourceCylinders[0].GasComposition,
+		UniformGasComposition:        uniformGasComposition,
+		BoostedGasVolume:             boostResult.BoostedGasVolume,
+		DriveGasVolume:               boostResult.DriveGasVolume,
+		CompressorGasVolume:          compressorResult.GasVolume,
+		CompressorMinutes:            compressorResult.Minutes,
+		WhipGasVolume:                whipGasVolume,
+		TransferTime:                 transferTime,
+		GasCost:                      gasCost,
+	}
+}
+
+// equalizeAllConfigurations equalizes the cylinders with each combination of manifolds closed and opened, reporting
+// to w
+func equalizeAllConfigurations(w io.Writer, cylinderConfiguration CylinderConfiguration, gasSystem GasSystem, temperature Temperature, units UnitSystem, verbose bool, logger *slog.Logger) []CylinderSummary {
+	sourceHasManifold := len(cylinderConfiguration.SourceCylinders) > 1
+	destinationHasManifold := len(cylinderConfiguration.DestinationCylinders) > 1 && !cylinderConfiguration.IndependentDestinations
+	cylinderConfiguration.SourceManifoldClosed = sourceHasManifold
+	cylinderConfiguration.DestinationManifoldClosed = destinationHasManifold
+	var cylinderSummaries []CylinderSummary
+	cylinderSummaries = append(cylinderSummaries, equalizeAndReport(w, cylinderConfiguration, gasSystem, temperature, units, verbose, logger, true))
+	if sourceHasManifold && destinationHasManifold {
+		cylinderConfiguration.SourceManifoldClosed = false
+		cylinderSummaries = append(cylinderSummaries, equalizeAndReport(w, cylinderConfiguration, gasSystem, temperature, units, verbose, logger, true))
+		cylinderConfiguration.SourceManifoldClosed = true
+
+		cylinderConfiguration.DestinationManifoldClosed = false
+		cylinderSummaries = append(cylinderSummaries, equalizeAndReport(w, cylinderConfiguration, gasSystem, temperature, units, verbose, logger, true))
+		cylinderConfiguration.DestinationManifoldClosed = true
+	}
+	if sourceHasManifold || destinationHasManifold {
+		cylinderConfiguration.DestinationManifoldClosed = false
+		cylinderConfiguration.SourceManifoldClosed = false
+		cylinderSummaries = append(cylinderSummaries, equalizeAndReport(w, cylinderConfiguration, gasSystem, temperature, units, verbose, logger, true))
+	}
+	if cylinderConfiguration.CompareIsolators {
+		// Isolators of one side at a time, with the manifold of the other side closed
+		cylinderConfiguration.SourceManifoldClosed = sourceHasManifold
+		cylinderConfiguration.DestinationManifoldClosed = destinationHasManifold
+		if len(cylinderConfiguration.SourceCylinders) > 2 {
+			for _, isolators := range mixedIsolatorStates(len(cylinderConfiguration.SourceCylinders)) {
+				cylinderConfiguration.SourceIsolators = isolators
+				cylinderSummaries = append(cylinderSummaries, equalizeAndReport(w, cylinderConfiguration, gasSystem, temperature, units, verbose, logger, true))
+			}
+			cylinderConfiguration.SourceIsolators = nil
+		}
+		if destinationHasManifold && len(cylinderConfiguration.DestinationCylinders) > 2 {
+			for _, isolators := range mixedIsolatorStates(len(cylinderConfiguration.DestinationCylinders)) {
+				cylinderConfiguration.DestinationIsolators = isolators
+				cylinderSummaries = append(cylinderSummaries, equalizeAndReport(w, cylinderConfiguration, gasSystem, temperature, units, verbose, logger, true))
+			}
+			cylinderConfiguration.DestinationIsolators = nil
+		}
 	}
+	if destinationHasManifold {
+		// Strategies keep the destinations apart and cascade from closed sources like the first configuration
+		cylinderConfiguration.SourceManifoldClosed = sourceHasManifold
+		cylinderConfiguration.DestinationManifoldClosed = true
+		for i := range cylinderConfiguration.ManifoldStrategies {
+			cylinderConfiguration.DestinationManifoldStrategy = &cylinderConfiguration.ManifoldStrategies[i]
+			cylinderSummaries = append(cylinderSummaries, equalizeAndReport(w, cylinderConfiguration, gasSystem, temperature, units, verbose, logger, true))
+		}
+	}
+	return cylinderSummaries
 }
-func printSummaries(cylinderSummaries []CylinderSummary, verbose bool) {
+
+// worstDestinationPressure returns the lowest destination pressure of the summaries, the default baseline for
+// improvements
+func worstDestinationPressure(cylinderSummaries []CylinderSummary) PressureBar {
 	var worstDestinationPressure PressureBar
 	for _, cylinderSummary := range cylinderSummaries {
 		if cylinderSummary.DestinationCylinderPressure < worstDestinationPressure || worstDestinationPressure == 0 {
 			worstDestinationPressure = cylinderSummary.DestinationCylinderPressure
 		}
 	}
+	return worstDestinationPressure
+}
 
-	fmt.Printf("%30s src bar  src l  dst bar  dst l improvement\n", "")
+// bestSummary returns the configuration reaching the highest destination pressure, skipping configurations that were
+// not run
+func bestSummary(cylinderSummaries []CylinderSummary) CylinderSummary {
+	var best CylinderSummary
 	for _, cylinderSummary := range cylinderSummaries {
-		if cylinderSummary.Description == "" {
-			continue
-		}
-		fmt.Printf("%30s %7.0f %6.0f %8.0f %6.0f %10.2f%%\n", cylinderSummary.Description, cylinderSummary.SourceCylinderPressure, cylinderSummary.SourceCylinderGasVolume, cylinderSummary.DestinationCylinderPressure, cylinderSummary.DestinationCylinderGasVolume, 100*(cylinderSummary.DestinationCylinderPressure-worstDestinationPressure)/worstDestinationPressure)
-		if verbose {
-			fmt.Printf("                            Gas weight %6.0fg         %6.0fg\n", cylinderSummary.SourceCylinderGasWeight, cylinderSummary.DestinationCylinderGasWeight)
+		if cylinderSummary.Description != "" && (best.Description == "" || cylinderSummary.DestinationCylinderPressure > best.DestinationCylinderPressure) {
+			best = cylinderSummary
 		}
 	}
+	return best
 }
 
-func main() {
-	var verboseFlag = flag.Bool("verbose", false, "Print detailed information")
-	var debugFlag = flag.Bool("debug", false, "Print debug information")
-	var sourceCylinderVolumeFlag = flag.Float64("source-cylinder-volume", 24, "Source cylinder volume in liters")
-	var useIdealGasFlag = flag.Bool("use-ideal-gas", false, "Use ideal gas equations instead of Van der Waals")
-	var destinationCylinderVolumeFlag = flag.Float64("destination-cylinder-volume", 24, "Destination cylinder volume in liters")
-	var sourceCylinderPressureFlag = flag.Float64("source-cylinder-pressure", 232, "Source cylinder pressure in bar")
-	var destinationCylinderPressureFlag = flag.Float64("destination-cylinder-pressure", 100, "Destination cylinder pressure")
-	var sourceCylinderIsTwinsetFlag = flag.Bool("source-cylinder-twinset", false, "Source cylinder is a twinset with a closeable manifold")
-	var destinationCylinderIsTwinsetFlag = flag.Bool("destination-cylinder-twinset", false, "Destination cylinder is a twinset with a closeable manifold")
-	var temperatureFlag = flag.Float64("temperature", 20.0, "Gas temperature for Van der Waals equation (celsius)")
-	var heliumPercentFlag = flag.Float64("helium", 0.0, "Percentage of helium")
-	var oxygenPercentFlag = flag.Float64("oxygen", 0.21, "Percentage of oxygen")
-	var neonPercentFlag = flag.Float64("neon", 0, "Percentage of neon")
-	var argonPercentFlag = flag.Float64("argon", 0, "Percentage of argon")
-	var hydrogenPercentFlag = flag.Float64("hydrogen", 0, "Percentage of hydrogen")
-	flag.Parse()
-
-	if *temperatureFlag < -30 || *temperatureFlag > 80 {
-		println("Invalid temperature. Must be >-30 and <80")
-		os.Exit(1)
-	}
-
-	gasSum := *heliumPercentFlag + *oxygenPercentFlag + *neonPercentFlag + *argonPercentFlag + *hydrogenPercentFlag
-	if gasSum > 1.0 {
-		println("Defined gases must not exceed 100% (1.0)")
-		os.Exit(11)
-	}
-	nitrogenPercent := 1.0 - gasSum
-	gasComposition := GasComposition{
-		Argon:    *argonPercentFlag,
-		Helium:   *heliumPercentFlag,
-		Hydrogen: *hydrogenPercentFlag,
-		Neon:     *neonPercentFlag,
-		Nitrogen: nitrogenPercent,
-		Oxygen:   *oxygenPercentFlag,
-	}
-
-	if *destinationCylinderPressureFlag > 350 || *destinationCylinderPressureFlag < 0 {
-		println("Invalid destination cylinder pressure; must be >= 0 and <=350")
-		os.Exit(1)
-	}
-	if *sourceCylinderPressureFlag > 350 || *sourceCylinderPressureFlag <= 0 {
-		println("Invalid source cylinder pressure; must be > 0 and <=350")
-		os.Exit(1)
-	}
-	if *sourceCylinderPressureFlag < *destinationCylinderPressureFlag {
-		println("Source pressure must be higher than destination pressure")
-		os.Exit(1)
-	}
-	if *destinationCylinderVolumeFlag <= 0 || *destinationCylinderVolumeFlag > 1000 {
-		println("Destination cylinder volume size must be greater than 0 and less than 1000")
-		os.Exit(1)
-	}
-	if *sourceCylinderVolumeFlag <= 0 || *sourceCylinderVolumeFlag > 1000 {
-		println("Source cylinder volume size must be greater than 0 and less than 1000")
-		os.Exit(1)
-	}
-	temperature := Temperature(*temperatureFlag + 273.15)
-	var gasSystem GasSystem
-	if *useIdealGasFlag {
-		gasSystem = IdealGas
-	} else {
-		gasSystem = VanDerWaals
-	}
-
-	cylinderConfiguration := CylinderConfiguration{
-		DestinationCylinderIsTwinset: *destinationCylinderIsTwinsetFlag,
-		DestinationCylinderPressure:  PressureBar(*destinationCylinderPressureFlag),
-		DestinationCylinderVolume:    CylinderVolume(*destinationCylinderVolumeFlag),
-		SourceCylinderIsTwinset:      *sourceCylinderIsTwinsetFlag,
-		SourceCylinderPressure:       PressureBar(*sourceCylinderPressureFlag),
-		SourceCylinderVolume:         CylinderVolume(*sourceCylinderVolumeFlag),
-	}
-	cylinderSummaries := make([]CylinderSummary, 4)
-	a := 0
-
-	cylinderSummaries[a] = equalizeAndReport(cylinderConfiguration, gasSystem, gasComposition, temperature, *verboseFlag, *debugFlag, true)
-	a++
-	if *sourceCylinderIsTwinsetFlag {
-		cylinderConfiguration.SourceCylinderIsTwinset = false
-		cylinderSummaries[a] = equalizeAndReport(cylinderConfiguration, gasSystem, gasComposition, temperature, *verboseFlag, *debugFlag, true)
-		a++
-		cylinderConfiguration.SourceCylinderIsTwinset = true
-	}
-	if *destinationCylinderIsTwinsetFlag {
-		cylinderConfiguration.DestinationCylinderIsTwinset = false
-		cylinderSummaries[a] = equalizeAndReport(cylinderConfiguration, gasSystem, gasComposition, temperature, *verboseFlag, *debugFlag, true)
-		a++
-		cylinderConfiguration.DestinationCylinderIsTwinset = true
-	}
-	if *destinationCylinderIsTwinsetFlag || *sourceCylinderIsTwinsetFlag {
-		cylinderConfiguration.DestinationCylinderIsTwinset = false
-		cylinderConfiguration.SourceCylinderIsTwinset = false
-		cylinderSummaries[a] = equalizeAndReport(cylinderConfiguration, gasSystem, gasComposition, temperature, *verboseFlag, *debugFlag, true)
-		a++
-	}
-	printSummaries(cylinderSummaries, *verboseFlag)
+// summaryColumn is a pressure or volume column of the summary table
+type summaryColumn struct {
+	header string
+	width  int
+	value  func(CylinderSummary) float64
+}
 
+// summaryColumns returns the source and destination pressure and volume columns, in both unit systems with DualUnits
+func summaryColumns(w io.Writer, units UnitSystem) []summaryColumn {
+	unitSystems := []UnitSystem{units}
+	if units.DualUnits {
+		unitSystems = append(unitSystems, units.otherUnits())
+	}
+	// Columns widen by the decimals of -precision
+	extra := 0
+	if decimals := units.decimals(0); decimals > 0 {
+		extra = decimals + 1
+	}
+	var columns []summaryColumn
+	for _, u := range unitSystems {
+		columns = append(columns, summaryColumn{tr(w, "src ") + u.PressureUnit(), 7 + extra, func(summary CylinderSummary) float64 { return u.round(u.Pressure(summary.SourceCylinderPressure)) }})
+	}
+	for _, u := range unitSystems {
+		columns = append(columns, summaryColumn{tr(w, "src ") + u.VolumeUnit(), 6 + extra, func(summary CylinderSummary) float64 { return u.round(u.Volume(summary.SourceCylinderGasVolume)) }})
+	}
+	for _, u := range unitSystems {
+		columns = append(columns, summaryColumn{tr(w, "dst ") + u.PressureUnit(), 8 + extra, func(summary CylinderSummary) float64 { return u.round(u.Pressure(summary.DestinationCylinderPressure)) }})
+	}
+	for _, u := range unitSystems {
+		columns = append(columns, summaryColumn{tr(w, "dst ") + u.VolumeUnit(), 6 + extra, func(summary CylinderSummary) float64 { return u.round(u.Volume(summary.DestinationCylinderGasVolume)) }})
+	}
+	return fitSummaryHeaders(columns)
+}
+
+// improvementColumns returns the destination pressure and volume improvement columns on the baseline
+func improvementColumns(w io.Writer, units UnitSystem, baseline CylinderSummary) []summaryColumn {
+	extra := 0
+	if decimals := units.decimals(0); decimals > 0 {
+		extra = decimals + 1
+	}
+	return fitSummaryHeaders([]summaryColumn{
+		{tr(w, "impr ") + units.PressureUnit(), 8 + extra, func(summary CylinderSummary) float64 {
+			return units.round(units.PressureDifference(summary.DestinationCylinderPressure - baseline.DestinationCylinderPressure))
+		}},
+		{tr(w, "impr ") + units.VolumeUnit(), 7 + extra, func(summary CylinderSummary) float64 {
+			return units.round(units.Volume(summary.DestinationCylinderGasVolume - baseline.DestinationCylinderGasVolume))
+		}},
+	})
+}
+
+// fitSummaryHeaders widens the columns narrower than their headers
+func fitSummaryHeaders(columns []summaryColumn) []summaryColumn {
+	for i := range columns {
+		if len(columns[i].header) > columns[i].width {
+			columns[i].width = len(columns[i].header)
+		}
+	}
+	return columns
+}
+
+// printSummaries prints the summary table of the configurations with the options
+func printSummaries(w io.Writer, cylinderSummaries []CylinderSummary, options summaryOptions, units UnitSystem, verbose bool) {
+	baseline := options.baselineSummary(cylinderSummaries)
+	best := bestSummary(cylinderSummaries)
+	columns := append(summaryColumns(w, units), improvementColumns(w, units, baseline)...)
+	decimals := units.decimals(0)
+
+	var summaryTable table
+	summaryTable.addColumn("", 30, false)
+	for _, column := range columns {
+		summaryTable.addColumn(column.header, column.width, false)
+	}
+	summaryTable.addColumn(tr(w, "improvement"), 11, false)
+	hasCost := len(cylinderSummaries) > 0 && cylinderSummaries[0].GasCost != nil
+	if hasCost {
+		summaryTable.addColumn(tr(w, "cost ")+cylinderSummaries[0].GasCost.Currency, 10, false)
+	}
+	// Plain tables print gas weights on a line of their own under each row, other styles in columns
+	weightColumns := verbose && options.style != "" && options.style != tablePlain
+	if weightColumns {
+		summaryTable.addColumn(tr(w, "src ")+units.WeightUnit(), 6, false)
+		summaryTable.addColumn(tr(w, "dst ")+units.WeightUnit(), 6, false)
+	}
+	for _, cylinderSummary := range options.rows(cylinderSummaries) {
+		cells := []string{tr(w, cylinderSummary.Description)}
+		for _, column := range columns {
+			cells = append(cells, fmt.Sprintf("%.*f", decimals, column.value(cylinderSummary)))
+		}
+		cells = append(cells, fmt.Sprintf("%.2f%%", improvement(cylinderSummary, baseline)))
+		if hasCost {
+			var cost string
+			if cylinderSummary.GasCost != nil {
+				cost = fmt.Sprintf("%.2f", cylinderSummary.GasCost.Total)
+			}
+			cells = append(cells, cost)
+		}
+		if weightColumns {
+			cells = append(cells, fmt.Sprintf("%.0f", units.Weight(cylinderSummary.SourceCylinderGasWeight)), fmt.Sprintf("%.0f", units.Weight(cylinderSummary.DestinationCylinderGasWeight)))
+		}
+		var color ansiColor
+		if cylinderSummary.Description == best.Description {
+			color = colorBest
+		}
+		summaryTable.addRow(color, cells...)
+		if verbose && !weightColumns {
+			summaryTable.addNote(strings.TrimSuffix(fmt.Sprintf(tr(w, "                            Gas weight %6.0f%-2s        %6.0f%s\n"), units.Weight(cylinderSummary.SourceCylinderGasWeight), units.WeightUnit(), units.Weight(cylinderSummary.DestinationCylinderGasWeight), units.WeightUnit()), "\n"))
+		}
+	}
+	summaryTable.render(w, options.style)
 }
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"flag"
+	"io"
+	"log/slog"
 	"math"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -36,11 +41,227 @@ func TestPartialPressure(t *testing.T) {
 }
 
 func TestGasWeightFromMole(t *testing.T) {
-	atomicWeight := AtomicWeightLookup[Argon]
+	molarMass := SpeciesLookup[Argon].MolarMass
 	moleCount := MoleCount(32.5)
-	weight := GasWeightFromMole(moleCount, atomicWeight)
-	expectedWeight := 455.217750
+	weight := GasWeightFromMole(moleCount, molarMass)
+	expectedWeight := 1298.31
 	if !compareFloats(float64(weight), expectedWeight) {
 		t.Errorf("Invalid gas weight %f, expected %f", weight, expectedWeight)
 	}
 }
+
+func TestEqualizeAndReportOutput(t *testing.T) {
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "source", CylinderVolume: 12, Pressure: 232, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+		DestinationCylinders: CylinderList{{Description: "destination", CylinderVolume: 12, Pressure: 80, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+	}
+	var output, logs strings.Builder
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	equalizeAndReport(&output, cylinderConfiguration, IdealGas, 293.15, Metric, false, logger, false)
+	if !strings.HasPrefix(output.String(), "Equalizing with all manifolds open\n") || strings.Contains(output.String(), "level=") {
+		t.Errorf("Unexpected output %q", output.String())
+	}
+	if !strings.Contains(logs.String(), "msg=equalized") || !strings.Contains(logs.String(), "pressure=156") {
+		t.Errorf("Unexpected debug records %q", logs.String())
+	}
+}
+
+func TestEqualizeAndReportRealPressure(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "source", CylinderVolume: 12, Pressure: 232, GasComposition: air}},
+		DestinationCylinders: CylinderList{{Description: "destination", CylinderVolume: 12, Pressure: 80, GasComposition: air}},
+	}
+	gasVolume := cylinderConfiguration.SourceCylinders.TotalGasVolume(VanDerWaals, 293.15) + cylinderConfiguration.DestinationCylinders.TotalGasVolume(VanDerWaals, 293.15)
+	expectedPressure := PressureFromGasVolume(24, gasVolume, VanDerWaals, air, 293.15)
+	cylinderSummary := equalizeAndReport(io.Discard, cylinderConfiguration, VanDerWaals, 293.15, Metric, false, nil, false)
+	if math.Abs(float64(cylinderSummary.DestinationCylinderPressure-expectedPressure)) > 0.01 || math.Abs(float64(cylinderSummary.SourceCylinderPressure-expectedPressure)) > 0.01 {
+		t.Errorf("Invalid pressures %f and %f, expected %f", cylinderSummary.DestinationCylinderPressure, cylinderSummary.SourceCylinderPressure, expectedPressure)
+	}
+}
+
+func TestPrintSummariesDualUnits(t *testing.T) {
+	cylinderSummaries := []CylinderSummary{{Description: "all manifolds open", SourceCylinderPressure: 200, SourceCylinderGasVolume: 2400, DestinationCylinderPressure: 200, DestinationCylinderGasVolume: 2400}}
+	var output strings.Builder
+	printSummaries(&output, cylinderSummaries, summaryOptions{}, UnitSystem{DualUnits: true}, false)
+	lines := strings.Split(output.String(), "\n")
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "src bar src psi src l src cuft dst bar dst psi dst l dst cuft impr bar impr l improvement" {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	if fields := strings.Fields(lines[1]); len(fields) != 14 || fields[4] != "2901" || fields[6] != "85" {
+		t.Errorf("Unexpected row %q", lines[1])
+	}
+}
+
+// recordingObserver records the transfers it is notified of
+type recordingObserver struct {
+	started   []TransferState
+	completed [][2]TransferState
+}
+
+func (o *recordingObserver) OnTransferStart(before TransferState) {
+	o.started = append(o.started, before)
+}
+
+func (o *recordingObserver) OnTransferComplete(before TransferState, after TransferState) {
+	o.completed = append(o.completed, [2]TransferState{before, after})
+}
+
+func TestTransferObserver(t *testing.T) {
+	observer := &recordingObserver{}
+	var steps []TransferStep
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:           CylinderList{{Description: "source", CylinderVolume: 12, Pressure: 233, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+		DestinationCylinders:      CylinderList{{Description: "left", CylinderVolume: 6, Pressure: 51, GasComposition: GasComposition{Oxygen: 0.32, Nitrogen: 0.68}}, {Description: "right", CylinderVolume: 6, Pressure: 51, GasComposition: GasComposition{Oxygen: 0.32, Nitrogen: 0.68}}},
+		DestinationManifoldClosed: true,
+		Observer:                  observer,
+		OnTransferStep:            func(step TransferStep) { steps = append(steps, step) },
+	}
+	equalizeAndReport(io.Discard, cylinderConfiguration, IdealGas, 293.15, Metric, false, nil, false)
+	if len(observer.started) != 2 || len(observer.completed) != 2 || len(steps) != 2 {
+		t.Fatalf("Expected two transfers, got %+v", observer)
+	}
+	for i, step := range steps {
+		before, after := observer.completed[i][0], observer.completed[i][1]
+		if before.Destination.Description != step.Destination || before.Step != step.Step || observer.started[i].Destination.Pressure != step.DestinationPressureBefore {
+			t.Errorf("Unexpected start of transfer %+v for %+v", before, step)
+		}
+		if after.Source.Pressure != step.SourcePressureAfter || after.Destination.Pressure != step.DestinationPressureAfter {
+			t.Errorf("Unexpected end of transfer %+v for %+v", after, step)
+		}
+	}
+	// States are copies, so the mix of the first destination before the transfer is the original one
+	if !compareFloats(observer.completed[0][0].Destination.GasComposition[Oxygen], 0.32) || compareFloats(observer.completed[0][1].Destination.GasComposition[Oxygen], 0.32) {
+		t.Errorf("Expected the mix to change in the transfer, got %+v", observer.completed[0])
+	}
+}
+
+func TestEqualizeUntil(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	source := Cylinder{Description: "source", CylinderVolume: 12, Pressure: 232, GasComposition: air}
+	destination := Cylinder{Description: "destination", CylinderVolume: 12, Pressure: 80, GasComposition: air}
+	if !destination.EqualizeUntil(&source, 130, IdealGas, 293.15, nil) {
+		t.Error("Expected the transfer to stop")
+	}
+	// 600l of gas moves from the source to the destination
+	if !compareFloats(float64(destination.Pressure), 130) || math.Abs(float64(source.Pressure)-182) > 1e-6 {
+		t.Errorf("Unexpected pressures %f and %f", source.Pressure, destination.Pressure)
+	}
+	if destination.EqualizeUntil(&source, 200, IdealGas, 293.15, nil) || math.Abs(float64(destination.Pressure)-156) > 1e-6 {
+		t.Errorf("Expected the cylinders to equalize below the stop pressure, got %f", destination.Pressure)
+	}
+}
+
+func TestEqualizeAndReportStopPressure(t *testing.T) {
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "source", CylinderVolume: 12, Pressure: 232, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+		DestinationCylinders: CylinderList{{Description: "destination", CylinderVolume: 12, Pressure: 80, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+		StopPressure:         130,
+	}
+	var output strings.Builder
+	summary := equalizeAndReport(&output, cylinderConfiguration, IdealGas, 293.15, Metric, false, nil, false)
+	if !strings.Contains(output.String(), "Transfers stop at 130bar, reached: true; left in the sources: source 182bar\n") {
+		t.Errorf("Unexpected output %q", output.String())
+	}
+	if math.Abs(float64(summary.SourceCylinderPressure)-182) > 1e-6 {
+		t.Errorf("Expected 182bar left in the source, got %f", summary.SourceCylinderPressure)
+	}
+
+	// With real gases the summary reports the same pressure the transfers stopped at
+	cylinderConfiguration.StopPressure = 140
+	output.Reset()
+	summary = equalizeAndReport(&output, cylinderConfiguration, VanDerWaals, 293.15, Metric, false, nil, false)
+	if !strings.Contains(output.String(), "Transfers stop at 140bar, reached: true") || !strings.Contains(output.String(), "Destination cylinders: ") || !strings.HasSuffix(strings.Split(output.String(), "Destination cylinders: ")[1], ", 140bar\n\n") {
+		t.Errorf("Unexpected output %q", output.String())
+	}
+	if math.Abs(float64(summary.DestinationCylinderPressure)-140) > 1e-6 {
+		t.Errorf("Expected the destination at 140bar, got %f", summary.DestinationCylinderPressure)
+	}
+}
+
+func TestEqualizeAndReportCycles(t *testing.T) {
+	var steps []TransferStep
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "source", CylinderVolume: 50, Pressure: 232, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+		DestinationCylinders: CylinderList{{Description: "destination", CylinderVolume: 12, Pressure: 50, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+		FillProcess:          AdiabaticFill,
+		Cycles:               3,
+		OnTransferStep:       func(step TransferStep) { steps = append(steps, step) },
+	}
+	var output strings.Builder
+	equalizeAndReport(&output, cylinderConfiguration, IdealGas, 293.15, Metric, false, nil, false)
+	if len(steps) != 3 || strings.Count(output.String(), "Cycle ") != 3 {
+		t.Fatalf("Expected three cycles, got %+v: %q", steps, output.String())
+	}
+	// Each cycle after cooling down gains less than the one before
+	for i := 1; i < len(steps); i++ {
+		gained := steps[i].DestinationPressureAfter - steps[i].DestinationPressureBefore
+		if previous := steps[i-1].DestinationPressureAfter - steps[i-1].DestinationPressureBefore; gained <= 0 || gained >= previous {
+			t.Errorf("Cycle %d gained %f after %f", i+1, gained, previous)
+		}
+	}
+	// Equalized cylinders are not connected again
+	steps = nil
+	cylinderConfiguration.FillProcess = IsothermalFill
+	equalizeAndReport(io.Discard, cylinderConfiguration, IdealGas, 293.15, Metric, false, nil, false)
+	if len(steps) != 1 {
+		t.Errorf("Expected a single transfer, got %+v", steps)
+	}
+}
+
+func TestCyclesFlag(t *testing.T) {
+	for _, args := range [][]string{{"-cycles", "3"}, {"-cool-down-cycles", "2"}} {
+		fs := flag.NewFlagSet("equalize", flag.ContinueOnError)
+		cycles := registerCyclesFlag(fs)
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		if *cycles != 3 {
+			t.Fatalf("Expected %v to give 3 cycles, got %d", args, *cycles)
+		}
+		cylinderConfiguration := CylinderConfiguration{
+			SourceCylinders:      CylinderList{{Description: "source", CylinderVolume: 50, Pressure: 232, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+			DestinationCylinders: CylinderList{{Description: "destination", CylinderVolume: 12, Pressure: 50, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+			FillProcess:          AdiabaticFill,
+			Cycles:               *cycles,
+		}
+		var output strings.Builder
+		equalizeAndReport(&output, cylinderConfiguration, VanDerWaals, 293.15, Metric, false, nil, false)
+		var gains []float64
+		for _, line := range strings.Split(output.String(), "\n") {
+			if _, gainText, ok := strings.Cut(line, ", up "); ok && strings.HasPrefix(line, "Cycle ") {
+				gain, err := strconv.ParseFloat(strings.TrimSuffix(strings.Fields(gainText)[0], "bar"), 64)
+				if err != nil {
+					t.Fatalf("Invalid cycle line %q", line)
+				}
+				gains = append(gains, gain)
+			}
+		}
+		if len(gains) != 3 {
+			t.Fatalf("Expected three cycles with %v, got %q", args, output.String())
+		}
+		for i := 1; i < len(gains); i++ {
+			if gains[i] <= 0 || gains[i] >= gains[i-1] {
+				t.Errorf("Expected cycle %d to gain less than %.1fbar, got %.1fbar", i+1, gains[i-1], gains[i])
+			}
+		}
+	}
+}
+
+func TestIndependentDestinations(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:         CylinderList{{Description: "source", CylinderVolume: 12, Pressure: 232, GasComposition: air}},
+		DestinationCylinders:    CylinderList{{Description: "left", CylinderVolume: 12, Pressure: 50, GasComposition: air}, {Description: "right", CylinderVolume: 12, Pressure: 50, GasComposition: air}},
+		IndependentDestinations: true,
+	}
+	var output strings.Builder
+	cylinderSummaries := equalizeAllConfigurations(&output, cylinderConfiguration, IdealGas, 293.15, Metric, false, nil)
+	if len(cylinderSummaries) != 1 || cylinderSummaries[0].Description != "independent destinations" {
+		t.Fatalf("Expected a single configuration, got %+v", cylinderSummaries)
+	}
+	// The source equalizes with left to 141bar and then with right to 95.5bar, and they are never joined
+	if !strings.Contains(output.String(), "Independent destinations: left 141bar, right 96bar\n") {
+		t.Errorf("Unexpected output %q", output.String())
+	}
+}
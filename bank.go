@@ -0,0 +1,383 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BankState is the state of named storage banks, kept in a JSON file between runs so that bank pressures follow the
+// fills of the day
+type BankState struct {
+	Banks []Bank `json:"banks"`
+}
+
+// Bank is a named storage bank. Volume is in liters and pressure in bar, relative to the pressure reference.
+// Retired banks are kept in the inventory but can not be used.
+type Bank struct {
+	Name        string  `json:"name"`
+	Volume      float64 `json:"volume"`
+	Pressure    float64 `json:"pressure"`
+	Mix         string  `json:"mix,omitempty"`
+	OxygenClean bool    `json:"oxygen_clean,omitempty"`
+	Retired     bool    `json:"retired,omitempty"`
+}
+
+// defaultBankStatePath returns the bank state file in the user configuration directory
+func defaultBankStatePath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "banks.json"
+	}
+	return filepath.Join(configDir, "scuba-whip-calculator", "banks.json")
+}
+
+// LoadBankState reads a bank state file. A missing file is an empty state.
+func LoadBankState(path string) (BankState, error) {
+	var state BankState
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(content, &state); err != nil {
+		return state, fmt.Errorf("invalid bank state file %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// Save writes the bank state file, replacing the previous file only once the new one is complete
+func (s BankState) Save(path string) error {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	temporaryPath := path + ".tmp"
+	if err := os.WriteFile(temporaryPath, append(content, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(temporaryPath, path)
+}
+
+// Bank returns the bank with the name
+func (s *BankState) Bank(name string) (*Bank, error) {
+	for i := range s.Banks {
+		if s.Banks[i].Name == name {
+			return &s.Banks[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown bank %q", name)
+}
+
+// Add adds a bank to the inventory. Names must be unique, including retired banks.
+func (s *BankState) Add(bank Bank) error {
+	if bank.Name == "" {
+		return errors.New("bank name is required")
+	}
+	if _, err := s.Bank(bank.Name); err == nil {
+		return fmt.Errorf("bank %q already exists", bank.Name)
+	}
+	s.Banks = append(s.Banks, bank)
+	return nil
+}
+
+// Cylinders returns the named banks as cylinders described by the bank name. Retired banks can not be used.
+func (s *BankState) Cylinders(names []string, units UnitSystem) (CylinderList, error) {
+	cylinders := make(CylinderList, len(names))
+	for i, name := range names {
+		bank, err := s.Bank(name)
+		if err != nil {
+			return nil, err
+		}
+		if bank.Retired {
+			return nil, fmt.Errorf("bank %q is retired", name)
+		}
+		cylinders[i] = Cylinder{
+			Description:    bank.Name,
+			CylinderVolume: CylinderVolume(bank.Volume),
+			Pressure:       units.AbsolutePressure(PressureBar(bank.Pressure)),
+			O2Clean:        bank.OxygenClean,
+		}
+		if bank.Mix != "" {
+			if cylinders[i].GasComposition, err = ParseGasComposition(bank.Mix); err != nil {
+				return nil, fmt.Errorf("bank %s: %w", name, err)
+			}
+		}
+	}
+	return cylinders, nil
+}
+
+// SetPressures updates pressures of the banks named in pressures
+func (s *BankState) SetPressures(pressures map[string]PressureBar, units UnitSystem) {
+	for i := range s.Banks {
+		if pressure, ok := pressures[s.Banks[i].Name]; ok {
+			s.Banks[i].Pressure = float64(pressure - units.AmbientPressure)
+		}
+	}
+}
+
+// bankPressuresAfterTransfer returns the pressure of each bank among the source cylinders after the transfers of
+// the manifold configuration of the summary. Sources behind an open manifold, or boosted, end at the combined source
+// pressure.
+func bankPressuresAfterTransfer(cylinderConfiguration CylinderConfiguration, bankNames []string, steps []TransferStep, cylinderSummary CylinderSummary) map[string]PressureBar {
+	pressures := make(map[string]PressureBar)
+	sourceManifoldOpen := len(cylinderConfiguration.SourceCylinders) == 1 || cylinderSummary.Description == "destination manifold closed" || cylinderSummary.Description == "all manifolds open" || cylinderSummary.Description == "independent destinations"
+	for _, name := range bankNames {
+		if sourceManifoldOpen || cylinderConfiguration.Booster != nil {
+			pressures[name] = cylinderSummary.SourceCylinderPressure
+			continue
+		}
+		for _, step := range steps {
+			if step.Configuration == cylinderSummary.Description && step.Source == name {
+				pressures[name] = step.SourcePressureAfter
+			}
+		}
+	}
+	return pressures
+}
+
+// printBankPressures prints bank pressures before and after a fill
+func printBankPressures(w io.Writer, banks CylinderList, pressures map[string]PressureBar, committed bool, units UnitSystem) {
+	for _, bank := range banks {
+		if pressure, ok := pressures[bank.Description]; ok {
+			fmt.Fprintf(w, "Bank %s: %.0f → %.0f%s\n", bank.Description, units.Pressure(bank.Pressure), units.Pressure(pressure), units.PressureUnit())
+		}
+	}
+	if !committed {
+		fmt.Fprintln(w, "Bank pressures not saved; use -commit to save them")
+	}
+}
+
+// commitBankPressures saves bank pressures to the bank state file at path
+func commitBankPressures(path string, pressures map[string]PressureBar, units UnitSystem) error {
+	state, err := LoadBankState(path)
+	if err != nil {
+		return err
+	}
+	state.SetPressures(pressures, units)
+	return state.Save(path)
+}
+
+// warnOxygenClean writes a warning for each of the named banks holding over 40% oxygen without being oxygen clean
+func (s *BankState) warnOxygenClean(w io.Writer, names []string) {
+	for _, name := range names {
+		bank, err := s.Bank(name)
+		if err != nil || bank.OxygenClean || bank.Mix == "" {
+			continue
+		}
+		if gasComposition, err := ParseGasComposition(bank.Mix); err == nil && gasComposition[Oxygen] > OxygenCleanFraction {
+			warnf(w, colorDanger, tr(w, "Warning: bank %s contains %s with over %.0f%% oxygen but is not oxygen clean\n"), name, gasComposition, OxygenCleanFraction*100)
+		}
+	}
+}
+
+// bankStateFlags holds flags for taking cylinders from the bank state file
+type bankStateFlags struct {
+	path     *string
+	useBanks stringListFlag
+	commit   *bool
+	state    BankState
+}
+
+func registerBankStateFlags(fs *flag.FlagSet) *bankStateFlags {
+	f := &bankStateFlags{
+		path:   fs.String("bank-state", "", "Bank state file; defaults to banks.json in the user configuration directory"),
+		commit: fs.Bool("commit", false, "Save bank pressures after the fill to the bank state file"),
+	}
+	fs.Var(&f.useBanks, "use-bank", "Use a bank from the bank state file by name, with its saved pressure; repeat for multiple banks")
+	return f
+}
+
+// statePath returns the bank state file
+func (f *bankStateFlags) statePath() string {
+	if *f.path != "" {
+		return *f.path
+	}
+	return defaultBankStatePath()
+}
+
+// banks returns the banks given with -use-bank as cylinders, writing warnings of banks that are not oxygen clean to w
+func (f *bankStateFlags) banks(w io.Writer, units UnitSystem) (CylinderList, error) {
+	if *f.commit && len(f.useBanks) == 0 {
+		return nil, errors.New("-commit needs -use-bank")
+	}
+	if len(f.useBanks) == 0 {
+		return nil, nil
+	}
+	var err error
+	if f.state, err = LoadBankState(f.statePath()); err != nil {
+		return nil, fmt.Errorf("unable to load bank state: %w", err)
+	}
+	banks, err := f.state.Cylinders(f.useBanks, units)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bank: %w", err)
+	}
+	f.state.warnOxygenClean(w, f.useBanks)
+	return banks, nil
+}
+
+// inventory returns the banks given with -use-bank, or all banks that are not retired
+func (f *bankStateFlags) inventory(w io.Writer, units UnitSystem) (CylinderList, error) {
+	if len(f.useBanks) == 0 {
+		state, err := LoadBankState(f.statePath())
+		if err != nil {
+			return nil, fmt.Errorf("unable to load bank state: %w", err)
+		}
+		for _, bank := range state.Banks {
+			if !bank.Retired {
+				f.useBanks = append(f.useBanks, bank.Name)
+			}
+		}
+		if len(f.useBanks) == 0 {
+			return nil, fmt.Errorf("no banks in the bank state file %s", f.statePath())
+		}
+	}
+	return f.banks(w, units)
+}
+
+// update prints bank pressures after the fill and saves them with -commit
+func (f *bankStateFlags) update(w io.Writer, banks CylinderList, pressures map[string]PressureBar, units UnitSystem) error {
+	if len(banks) == 0 {
+		return nil
+	}
+	if *f.commit {
+		if err := commitBankPressures(f.statePath(), pressures, units); err != nil {
+			return fmt.Errorf("unable to save bank state: %w", err)
+		}
+	}
+	printBankPressures(w, banks, pressures, *f.commit, units)
+	return nil
+}
+
+// bankActions are the actions of the bank command
+const bankActions = "add, list, set-pressure or retire"
+
+func bankMain(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("usage: bank <action> [flags] [arguments]; action is %s", bankActions)
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("bank "+action, flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	var statePathFlag = fs.String("bank-state", "", "Bank state file; defaults to banks.json in the user configuration directory")
+	var oxygenCleanFlag = fs.Bool("oxygen-clean", false, "With add: the bank is cleaned for oxygen service")
+	var allFlag = fs.Bool("all", false, "With list: include retired banks")
+	fs.Parse(args[1:])
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	w, err := flags.output(os.Stdout, units)
+	if err != nil {
+		return err
+	}
+	statePath := *statePathFlag
+	if statePath == "" {
+		statePath = defaultBankStatePath()
+	}
+	state, err := LoadBankState(statePath)
+	if err != nil {
+		return fmt.Errorf("unable to load bank state: %w", err)
+	}
+	switch action {
+	case "list":
+		printBanks(w, state, *allFlag, units)
+		return nil
+	case "add":
+		if fs.NArg() != 1 {
+			return errors.New("usage: bank add [-oxygen-clean] name=volume@pressure[:mix]")
+		}
+		cylinder, err := units.ParseCylinderSpec(fs.Arg(0), "")
+		if err != nil {
+			return fmt.Errorf("invalid bank: %w", err)
+		}
+		if err := checkCylinders(CylinderList{cylinder}, "bank", true, units); err != nil {
+			return err
+		}
+		bank := Bank{
+			Name:        cylinder.Description,
+			Volume:      float64(cylinder.CylinderVolume),
+			Pressure:    float64(cylinder.Pressure - units.AmbientPressure),
+			OxygenClean: *oxygenCleanFlag,
+		}
+		if len(cylinder.GasComposition) > 0 {
+			bank.Mix = cylinder.GasComposition.String()
+		}
+		if err := state.Add(bank); err != nil {
+			return fmt.Errorf("unable to add bank: %w", err)
+		}
+	case "set-pressure":
+		if fs.NArg() != 2 {
+			return errors.New("usage: bank set-pressure name pressure")
+		}
+		bank, err := state.Bank(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		pressure, err := units.ParsePressure(fs.Arg(1))
+		if err != nil {
+			return fmt.Errorf("invalid pressure: %w", err)
+		}
+		if err := checkCylinders(CylinderList{{CylinderVolume: CylinderVolume(bank.Volume), Pressure: pressure}}, "bank", true, units); err != nil {
+			return err
+		}
+		bank.Pressure = float64(pressure - units.AmbientPressure)
+	case "retire":
+		if fs.NArg() != 1 {
+			return errors.New("usage: bank retire name")
+		}
+		bank, err := state.Bank(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		bank.Retired = true
+	default:
+		return fmt.Errorf("unknown bank action %s; must be %s", action, bankActions)
+	}
+	if err := state.Save(statePath); err != nil {
+		return fmt.Errorf("unable to save bank state: %w", err)
+	}
+	return nil
+}
+
+// printBanks prints the bank inventory, optionally with retired banks
+func printBanks(w io.Writer, state BankState, all bool, units UnitSystem) {
+	pressureUnit := units.PressureUnit()
+	fmt.Fprintf(w, "%20s %10s %10s %10s %10s\n", "bank", "volume l", pressureUnit, "mix", "O2 clean")
+	for _, bank := range state.Banks {
+		if bank.Retired && !all {
+			continue
+		}
+		mix := bank.Mix
+		if mix == "" {
+			mix = "-"
+		}
+		oxygenClean := "no"
+		if bank.OxygenClean {
+			oxygenClean = "yes"
+		}
+		fmt.Fprintf(w, "%20s %10.1f %10.0f %10s %10s", bank.Name, bank.Volume, units.PressureDifference(PressureBar(bank.Pressure)), mix, oxygenClean)
+		if bank.Retired {
+			fmt.Fprint(w, " retired")
+		}
+		fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBankStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "banks.json")
+	state, err := LoadBankState(path)
+	if err != nil || len(state.Banks) != 0 {
+		t.Fatalf("Expected empty state for missing file, got %+v, %v", state, err)
+	}
+	state.Banks = []Bank{
+		{Name: "helium-bank-1", Volume: 50, Pressure: 200, Mix: "0/100"},
+		{Name: "air", Volume: 50, Pressure: 300},
+	}
+	if err := state.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadBankState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Banks) != 2 || loaded.Banks[0] != state.Banks[0] {
+		t.Errorf("Invalid loaded state %+v", loaded)
+	}
+}
+
+func TestBankStateCylinders(t *testing.T) {
+	state := BankState{Banks: []Bank{
+		{Name: "helium-bank-1", Volume: 50, Pressure: 200, Mix: "0/100"},
+		{Name: "air", Volume: 50, Pressure: 300},
+	}}
+	units := UnitSystem{AmbientPressure: 1}
+	cylinders, err := state.Cylinders([]string{"helium-bank-1"}, units)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cylinders) != 1 || cylinders[0].Description != "helium-bank-1" || cylinders[0].Pressure != 201 || cylinders[0].GasComposition[Helium] != 1 {
+		t.Errorf("Invalid cylinders %+v", cylinders)
+	}
+	if _, err := state.Cylinders([]string{"missing"}, units); err == nil {
+		t.Error("Expected error for unknown bank")
+	}
+
+	state.SetPressures(map[string]PressureBar{"helium-bank-1": 151}, units)
+	if state.Banks[0].Pressure != 150 || state.Banks[1].Pressure != 300 {
+		t.Errorf("Invalid pressures after update %+v", state.Banks)
+	}
+}
+
+func TestBankPressuresAfterTransfer(t *testing.T) {
+	gasComposition := GasComposition{Nitrogen: 0.79, Oxygen: 0.21}
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders: CylinderList{
+			{Description: "bank1", CylinderVolume: 50, Pressure: 200, GasComposition: gasComposition},
+			{Description: "bank2", CylinderVolume: 50, Pressure: 300, GasComposition: gasComposition},
+		},
+		DestinationCylinders: CylinderList{{Description: "destination", CylinderVolume: 10, Pressure: 50, GasComposition: gasComposition}},
+	}
+	var steps []TransferStep
+	cylinderConfiguration.OnTransferStep = func(step TransferStep) {
+		steps = append(steps, step)
+	}
+	cylinderSummaries := equalizeAllConfigurations(io.Discard, cylinderConfiguration, IdealGas, 293.15, Metric, false, nil)
+	if len(cylinderSummaries) != 2 {
+		t.Fatalf("Expected 2 configurations, got %d", len(cylinderSummaries))
+	}
+
+	// Source manifold closed: bank1 equalizes to 50l@200 + 10l@50 = 175, then bank2 to 50l@300 + 10l@175
+	pressures := bankPressuresAfterTransfer(cylinderConfiguration, []string{"bank1", "bank2"}, steps, cylinderSummaries[0])
+	if !compareFloats(float64(pressures["bank1"]), 175) || !compareFloats(float64(pressures["bank2"]), (50*300+10*175.0)/60) {
+		t.Errorf("Invalid bank pressures with closed manifold %v", pressures)
+	}
+	pressures = bankPressuresAfterTransfer(cylinderConfiguration, []string{"bank1", "bank2"}, steps, cylinderSummaries[1])
+	if pressures["bank1"] != cylinderSummaries[1].SourceCylinderPressure || pressures["bank2"] != cylinderSummaries[1].SourceCylinderPressure {
+		t.Errorf("Expected combined source pressure with open manifold, got %v", pressures)
+	}
+}
+
+func TestCascadePlanBankPressures(t *testing.T) {
+	banks := CylinderList{
+		{Description: "high", CylinderVolume: 50, Pressure: 300},
+		{Description: "empty", CylinderVolume: 50, Pressure: 40},
+	}
+	banks.SetDefaultGasComposition(GasComposition{Nitrogen: 0.79, Oxygen: 0.21})
+	destination := Cylinder{CylinderVolume: 10, Pressure: 50, GasComposition: GasComposition{Nitrogen: 0.79, Oxygen: 0.21}}
+	pressures := PlanCascade(banks, destination, 0, IdealGas, 293.15).BankPressures()
+	if pressures["empty"] != 40 || !compareFloats(float64(pressures["high"]), (50*300+10*50.0)/60) {
+		t.Errorf("Invalid bank pressures %v", pressures)
+	}
+}
+
+func TestBankStateInventory(t *testing.T) {
+	var state BankState
+	if err := state.Add(Bank{Name: "o2-1", Volume: 50, Pressure: 180, Mix: "EAN100", OxygenClean: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.Add(Bank{Name: "ean50", Volume: 50, Pressure: 200, Mix: "EAN50"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.Add(Bank{Name: "o2-1", Volume: 12, Pressure: 200}); err == nil {
+		t.Error("Expected error for duplicate bank")
+	}
+	if err := state.Add(Bank{Volume: 12, Pressure: 200}); err == nil {
+		t.Error("Expected error for bank without a name")
+	}
+	var warnings strings.Builder
+	state.warnOxygenClean(&warnings, []string{"o2-1", "ean50"})
+	if strings.Count(warnings.String(), "Warning") != 1 || !strings.Contains(warnings.String(), "ean50") {
+		t.Errorf("Expected a warning for ean50 only, got %q", warnings.String())
+	}
+
+	bank, _ := state.Bank("ean50")
+	bank.Retired = true
+	if _, err := state.Cylinders([]string{"ean50"}, Metric); err == nil {
+		t.Error("Expected error for retired bank")
+	}
+	var output strings.Builder
+	printBanks(&output, state, false, Metric)
+	if strings.Contains(output.String(), "ean50") || !strings.Contains(output.String(), "o2-1") {
+		t.Errorf("Expected retired bank to be hidden:\n%s", output.String())
+	}
+	output.Reset()
+	printBanks(&output, state, true, Metric)
+	if !strings.Contains(output.String(), "retired") {
+		t.Errorf("Expected retired bank with all:\n%s", output.String())
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// BankPreset is a common storage cylinder or bundle with its rated pressures, in gauge bar. Test pressures are 1.5
+// times the working pressure as in EN ISO 9809.
+type BankPreset struct {
+	Description     string
+	CylinderVolume  CylinderVolume
+	WorkingPressure PressureBar
+	TestPressure    PressureBar
+}
+
+// bankPresets are the storage cylinders selectable by name in place of volume@pressure in cylinder definitions
+var bankPresets = map[string]BankPreset{
+	"50l-200bar":    {Description: "50l storage cylinder, 200bar", CylinderVolume: 50, WorkingPressure: 200, TestPressure: 300},
+	"50l-300bar":    {Description: "50l storage cylinder, 300bar", CylinderVolume: 50, WorkingPressure: 300, TestPressure: 450},
+	"12x50l-200bar": {Description: "bundle of twelve 50l cylinders, 200bar", CylinderVolume: 600, WorkingPressure: 200, TestPressure: 300},
+	"12x50l-300bar": {Description: "bundle of twelve 50l cylinders, 300bar", CylinderVolume: 600, WorkingPressure: 300, TestPressure: 450},
+	"j":             {Description: "J size cylinder, 137bar", CylinderVolume: 47.2, WorkingPressure: 137, TestPressure: 205.5},
+}
+
+// lookupBankPreset returns the preset with the name, ignoring case
+func lookupBankPreset(name string) (BankPreset, bool) {
+	preset, ok := bankPresets[strings.ToLower(strings.TrimSpace(name))]
+	return preset, ok
+}
+
+// bankPresetNames returns the names of the presets in alphabetical order
+func bankPresetNames() []string {
+	names := make([]string, 0, len(bankPresets))
+	for name := range bankPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cylinder returns the preset as a cylinder filled to its working pressure
+func (p BankPreset) cylinder(description string, units UnitSystem) Cylinder {
+	return Cylinder{
+		Description:     description,
+		CylinderVolume:  p.CylinderVolume,
+		Pressure:        units.AbsolutePressure(p.WorkingPressure),
+		WorkingPressure: units.AbsolutePressure(p.WorkingPressure),
+		TestPressure:    units.AbsolutePressure(p.TestPressure),
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BatchScenario is a single scenario of a batch run. Cylinders without a mix get GasComposition, or the default
+// mix when it is nil.
+type BatchScenario struct {
+	Name                 string
+	SourceCylinders      CylinderList
+	DestinationCylinders CylinderList
+	GasComposition       GasComposition
+}
+
+// BatchResult is the result of a scenario: the manifold configuration reaching the highest destination pressure
+type BatchResult struct {
+	Name    string
+	Summary CylinderSummary
+	Err     error
+}
+
+// batchScenarioJSON is a scenario in a JSON batch file
+type batchScenarioJSON struct {
+	Name string `json:"name"`
+	EqualizeRequest
+}
+
+// LoadBatchScenarios reads scenarios from a CSV file (.csv) or a JSON file. CSV files have a header with name,
+// source, destination and optionally mix columns; cylinders are given as on the command line, separated by ";".
+// JSON files hold a list of scenarios in the /equalize request format, each with a name.
+func LoadBatchScenarios(path string, units UnitSystem) ([]BatchScenario, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var scenarios []BatchScenario
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		scenarios, err = parseBatchCSV(file, units)
+	} else {
+		scenarios, err = parseBatchJSON(file, units)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch file %s: %w", path, err)
+	}
+	return scenarios, nil
+}
+
+func parseBatchCSV(r io.Reader, units UnitSystem) ([]BatchScenario, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"source", "destination"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing %s column", required)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	var scenarios []BatchScenario
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return scenarios, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		scenario := BatchScenario{Name: field(record, "name")}
+		if scenario.Name == "" {
+			scenario.Name = fmt.Sprintf("line %d", line)
+		}
+		if scenario.SourceCylinders, err = units.ParseCylinderSpecs(splitBatchCylinders(field(record, "source")), "source"); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if scenario.DestinationCylinders, err = units.ParseCylinderSpecs(splitBatchCylinders(field(record, "destination")), "destination"); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if mix := field(record, "mix"); mix != "" {
+			if scenario.GasComposition, err = ParseGasComposition(mix); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		scenarios = append(scenarios, scenario)
+	}
+}
+
+// splitBatchCylinders splits a list of cylinder definitions separated by ";"
+func splitBatchCylinders(s string) []string {
+	var specs []string
+	for _, spec := range strings.Split(s, ";") {
+		if spec = strings.TrimSpace(spec); spec != "" {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+func parseBatchJSON(r io.Reader, units UnitSystem) ([]BatchScenario, error) {
+	var scenariosJSON []batchScenarioJSON
+	if err := json.NewDecoder(r).Decode(&scenariosJSON); err != nil {
+		return nil, err
+	}
+	scenarios := make([]BatchScenario, len(scenariosJSON))
+	for i, scenarioJSON := range scenariosJSON {
+		scenarios[i].Name = scenarioJSON.Name
+		if scenarios[i].Name == "" {
+			scenarios[i].Name = fmt.Sprintf("scenario %d", i+1)
+		}
+		var err error
+		if scenarios[i].SourceCylinders, scenarios[i].DestinationCylinders, err = scenarioJSON.Cylinders(units); err != nil {
+			return nil, fmt.Errorf("%s: %w", scenarios[i].Name, err)
+		}
+		if scenarioJSON.Mix != "" {
+			if scenarios[i].GasComposition, err = ParseGasComposition(scenarioJSON.Mix); err != nil {
+				return nil, fmt.Errorf("%s: %w", scenarios[i].Name, err)
+			}
+		}
+	}
+	return scenarios, nil
+}
+
+// RunBatch equalizes each scenario, reporting each to the progress reporter. Invalid scenarios are reported in the
+// results instead of stopping the run.
+func RunBatch(scenarios []BatchScenario, s server, gasComposition GasComposition, progress *progressReporter) []BatchResult {
+	results := make([]BatchResult, len(scenarios))
+	for i, scenario := range scenarios {
+		results[i].Name = scenario.Name
+		scenarioGasComposition := gasComposition
+		if scenario.GasComposition != nil {
+			scenarioGasComposition = scenario.GasComposition
+		}
+		cylinderSummaries, err := s.equalizeCylinders(scenario.SourceCylinders, scenario.DestinationCylinders, scenarioGasComposition, nil)
+		progress.step()
+		if err != nil {
+			slog.Debug("scenario failed", "scenario", scenario.Name, "error", err)
+			results[i].Err = err
+			continue
+		}
+		for _, cylinderSummary := range cylinderSummaries {
+			if cylinderSummary.DestinationCylinderPressure > results[i].Summary.DestinationCylinderPressure {
+				results[i].Summary = cylinderSummary
+			}
+		}
+	}
+	return results
+}
+
+// printBatchResults writes a row per scenario as a table in the style, or as CSV when csvOutput is set
+func printBatchResults(w io.Writer, results []BatchResult, units UnitSystem, csvOutput bool, style tableStyle) error {
+	pressureUnit := units.PressureUnit()
+	volumeUnit := units.VolumeUnit()
+	if csvOutput {
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"name", "configuration", "source_" + pressureUnit, "destination_" + pressureUnit, "destination_" + volumeUnit, "destination_mix", "error"})
+		for _, result := range results {
+			if result.Err != nil {
+				writer.Write([]string{result.Name, "", "", "", "", "", result.Err.Error()})
+				continue
+			}
+			summary := result.Summary
+			writer.Write([]string{
+				result.Name,
+				summary.Description,
+				fmt.Sprintf("%.1f", units.Pressure(summary.SourceCylinderPressure)),
+				fmt.Sprintf("%.1f", units.Pressure(summary.DestinationCylinderPressure)),
+				fmt.Sprintf("%.1f", units.Volume(summary.DestinationCylinderGasVolume)),
+				summary.DestinationGasComposition.String(),
+				"",
+			})
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+	var resultTable table
+	resultTable.addColumn("scenario", 24, true)
+	resultTable.addColumn("configuration", 28, true)
+	resultTable.addColumn("src "+pressureUnit, 8, false)
+	resultTable.addColumn("dst "+pressureUnit, 8, false)
+	resultTable.addColumn("dst "+volumeUnit, 8, false)
+	resultTable.addColumn("mix", 0, true)
+	for _, result := range results {
+		if result.Err != nil {
+			resultTable.addNote(fmt.Sprintf("%-24s %s", result.Name, result.Err))
+			continue
+		}
+		summary := result.Summary
+		resultTable.addRow("", result.Name, summary.Description, fmt.Sprintf("%.0f", units.Pressure(summary.SourceCylinderPressure)), fmt.Sprintf("%.0f", units.Pressure(summary.DestinationCylinderPressure)), fmt.Sprintf("%.0f", units.Volume(summary.DestinationCylinderGasVolume)), summary.DestinationGasComposition.String())
+	}
+	resultTable.render(w, style)
+	return nil
+}
+
+func batchMain(args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	gasFlags := registerGasCompositionFlags(fs)
+	var outputFlag = fs.String("output", "table", "Output format: table or csv")
+	var progressFlag = registerProgressFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s batch [flags] <scenarios.csv|scenarios.json>\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	gasSystem, temperature, err := flags.gasSettings(units)
+	if err != nil {
+		return err
+	}
+	gasComposition, err := gasFlags.gasComposition()
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	if *outputFlag != "table" && *outputFlag != "csv" {
+		return errors.New("invalid output; must be table or csv")
+	}
+	style, err := flags.parseTableStyle()
+	if err != nil {
+		return err
+	}
+	scenarios, err := LoadBatchScenarios(fs.Arg(0), units)
+	if err != nil {
+		return err
+	}
+	progress, err := newProgressReporter(os.Stderr, *progressFlag, "batch", len(scenarios))
+	if err != nil {
+		return err
+	}
+	results := RunBatch(scenarios, server{gasSystem: gasSystem, temperature: temperature, units: units}, gasComposition, progress)
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	slog.Info("batch done", "file", fs.Arg(0), "scenarios", len(results), "failed", failed)
+	// CSV output is for spreadsheets and scripts and keeps plain numbers
+	var w io.Writer = os.Stdout
+	if *outputFlag != "csv" {
+		if w, err = flags.output(w, units); err != nil {
+			return err
+		}
+	}
+	return printBatchResults(w, results, units, *outputFlag == "csv", style)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseBatchCSV(t *testing.T) {
+	units := UnitSystem{AmbientPressure: SurfacePressure}
+	content := "name,source,destination,mix\n# comment\ntwelve,12l@232,12l@80,\ntwin,50l@200;50l@300,24l@50,32\n"
+	scenarios, err := parseBatchCSV(strings.NewReader(content), units)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scenarios) != 2 {
+		t.Fatalf("Expected 2 scenarios, got %d", len(scenarios))
+	}
+	if scenarios[0].Name != "twelve" || scenarios[0].GasComposition != nil || len(scenarios[0].SourceCylinders) != 1 {
+		t.Errorf("Invalid scenario %+v", scenarios[0])
+	}
+	if len(scenarios[1].SourceCylinders) != 2 || scenarios[1].SourceCylinders[1].Description != "source 2" || scenarios[1].GasComposition[Oxygen] != 0.32 {
+		t.Errorf("Invalid scenario %+v", scenarios[1])
+	}
+	if _, err := parseBatchCSV(strings.NewReader("name,source\nx,12l@200\n"), units); err == nil {
+		t.Error("Expected an error for a missing destination column")
+	}
+	if _, err := parseBatchCSV(strings.NewReader("source,destination\n12l@200,12l\n"), units); err == nil {
+		t.Error("Expected an error for an invalid cylinder")
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	units := UnitSystem{AmbientPressure: SurfacePressure}
+	content := `[{"name": "twelve", "source": [{"volume": 12, "pressure": 232}], "destination": [{"volume": 12, "pressure": 80}]},
+		{"source": [{"volume": 12, "pressure": 50}], "destination": [{"volume": 12, "pressure": 80}]}]`
+	scenarios, err := parseBatchJSON(strings.NewReader(content), units)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := RunBatch(scenarios, server{gasSystem: IdealGas, temperature: 293.15, units: units}, GasComposition{Oxygen: 0.21, Nitrogen: 0.79}, nil)
+	if results[0].Err != nil || !compareFloats(units.Pressure(results[0].Summary.DestinationCylinderPressure), 156) {
+		t.Errorf("Invalid result %+v", results[0])
+	}
+	if results[1].Name != "scenario 2" || results[1].Err == nil {
+		t.Errorf("Expected an error for scenario 2, got %+v", results[1])
+	}
+	var output bytes.Buffer
+	if err := printBatchResults(&output, results, units, true, tablePlain); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "twelve,") || !strings.Contains(lines[1], ",156.0,") || !strings.Contains(lines[2], "source pressure must be higher") {
+		t.Errorf("Invalid CSV output:\n%s", output.String())
+	}
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// BlendStep is a single gas addition in a partial pressure blend
+type BlendStep struct {
+	Description    string
+	GasComposition GasComposition
+	AddedGasVolume GasVolume
+	AddedPressure  PressureBar
+	FillToPressure PressureBar
+}
+
+// BlendPlan holds the gas additions required to reach the target mix
+type BlendPlan struct {
+	CylinderVolume    CylinderVolume
+	StartPressure     PressureBar
+	StartComposition  GasComposition
+	DrainToPressure   PressureBar
+	DrainRequired     bool
+	TargetComposition GasComposition
+	TargetPressure    PressureBar
+	Steps             []BlendStep
+}
+
+// ErrBlendNotAchievable is returned when target mix can not be blended with the given gases
+var ErrBlendNotAchievable = errors.New("target mix is not achievable")
+
+// blendConstraint is an amount of gas to add, as a function of the fraction of existing gas kept in the cylinder:
+// amount = keep - keptGasFraction * reduce
+type blendConstraint struct {
+	description string
+	keep        GasVolume
+	reduce      GasVolume
+}
+
+// PlanBlend calculates how much helium, oxygen and top-up gas must be added, in that order, to the start cylinder
+// to reach the target mix and pressure. If the existing gas prevents reaching the target, the plan includes the
+// pressure the cylinder must be drained to first; the cylinder cannot be drained below ambientPressure.
+func PlanBlend(start Cylinder, targetComposition GasComposition, targetPressure PressureBar, topUpComposition GasComposition, ambientPressure PressureBar, gasSystem GasSystem, temperature Temperature) (BlendPlan, error) {
+	plan := BlendPlan{
+		CylinderVolume:    start.CylinderVolume,
+		StartPressure:     start.Pressure,
+		StartComposition:  start.GasComposition,
+		DrainToPressure:   start.Pressure,
+		TargetComposition: targetComposition,
+		TargetPressure:    targetPressure,
+	}
+	target := Cylinder{CylinderVolume: start.CylinderVolume, Pressure: targetPressure, GasComposition: targetComposition}
+	targetGasVolumes := target.GasVolumes(gasSystem, temperature)
+	existingGasVolumes := make(map[Gas]GasVolume)
+	if start.Pressure > 0 {
+		existingGasVolumes = start.GasVolumes(gasSystem, temperature)
+	}
+
+	// Top-up gas provides all of the nitrogen; it is zero when the top-up has no nitrogen
+	topUpNitrogen := GasVolume(topUpComposition[Nitrogen])
+	var topUp blendConstraint
+	if topUpNitrogen > 0 {
+		topUp = blendConstraint{SpeciesLookup[Nitrogen].Symbol, targetGasVolumes[Nitrogen] / topUpNitrogen, existingGasVolumes[Nitrogen] / topUpNitrogen}
+	} else if targetGasVolumes[Nitrogen] > 0 {
+		return plan, fmt.Errorf("%w: top-up gas %s contains no nitrogen", ErrBlendNotAchievable, topUpComposition)
+	}
+	constraints := []blendConstraint{topUp}
+	additions := []Gas{Helium, Oxygen}
+	for _, gasType := range additions {
+		constraints = append(constraints, blendConstraint{
+			description: SpeciesLookup[gasType].Symbol,
+			keep:        targetGasVolumes[gasType] - topUp.keep*GasVolume(topUpComposition[gasType]),
+			reduce:      existingGasVolumes[gasType] - topUp.reduce*GasVolume(topUpComposition[gasType]),
+		})
+	}
+	if topUpNitrogen == 0 && existingGasVolumes[Nitrogen] > 0 {
+		// Nitrogen can only be removed by emptying the cylinder
+		constraints = append(constraints, blendConstraint{"N2", 0, existingGasVolumes[Nitrogen]})
+	}
+
+	keptGasFraction := GasVolume(1.0)
+	var drainedGas string
+	for _, constraint := range constraints {
+		if constraint.keep-constraint.reduce*keptGasFraction >= -1e-9 {
+			continue
+		}
+		if constraint.keep < -1e-9 {
+			return plan, fmt.Errorf("%w: top-up gas %s contains too much %s", ErrBlendNotAchievable, topUpComposition, constraint.description)
+		}
+		keptGasFraction = constraint.keep / constraint.reduce
+		drainedGas = constraint.description
+	}
+
+	cylinderGasVolumes := make(map[Gas]GasVolume)
+	var cylinderGasVolume GasVolume
+	for gasType, gasVolume := range existingGasVolumes {
+		cylinderGasVolumes[gasType] = gasVolume * keptGasFraction
+		cylinderGasVolume += gasVolume * keptGasFraction
+	}
+	pressure := start.Pressure
+	if keptGasFraction < 1 {
+		plan.DrainRequired = true
+		pressure = PressureFromGasVolume(start.CylinderVolume, cylinderGasVolume, gasSystem, start.GasComposition, temperature)
+		if pressure < ambientPressure-1e-9 {
+			return plan, fmt.Errorf("%w: draining to ambient pressure still leaves too much %s", ErrBlendNotAchievable, drainedGas)
+		}
+		plan.DrainToPressure = pressure
+	}
+
+	addGas := func(description string, gasComposition GasComposition, gasVolume GasVolume) {
+		for gasType, fraction := range gasComposition {
+			cylinderGasVolumes[gasType] += gasVolume * GasVolume(fraction)
+		}
+		cylinderGasVolume += gasVolume
+		fillToPressure := PressureFromGasVolume(start.CylinderVolume, cylinderGasVolume, gasSystem, GasCompositionFromGasVolumes(cylinderGasVolumes), temperature)
+		plan.Steps = append(plan.Steps, BlendStep{
+			Description:    description,
+			GasComposition: gasComposition,
+			AddedGasVolume: gasVolume,
+			AddedPressure:  fillToPressure - pressure,
+			FillToPressure: fillToPressure,
+		})
+		pressure = fillToPressure
+	}
+	for i, gasType := range additions {
+		constraint := constraints[i+1]
+		if gasVolume := constraint.keep - constraint.reduce*keptGasFraction; gasVolume > 1e-9 {
+			addGas(SpeciesLookup[gasType].Name, GasComposition{gasType: 1}, gasVolume)
+		}
+	}
+	if gasVolume := topUp.keep - topUp.reduce*keptGasFraction; gasVolume > 1e-9 {
+		addGas(topUpComposition.String(), topUpComposition, gasVolume)
+	}
+	return plan, nil
+}
+
+// BlendSupply is a storage bank supplying the gas of a blend step. The bank is sufficient when it stays above the
+// pressure the step fills the cylinder to.
+type BlendSupply struct {
+	Step              int
+	Bank              Cylinder
+	BankPressureAfter PressureBar
+	Sufficient        bool
+}
+
+// PlanBlendSupply picks a bank with the gas of each blend step: the lowest pressure bank that is sufficient or, when
+// none is, the highest pressure bank. Steps without a bank with their gas have no supply.
+func PlanBlendSupply(plan BlendPlan, banks CylinderList, gasSystem GasSystem, temperature Temperature) []BlendSupply {
+	banks = append(CylinderList(nil), banks...)
+	var supplies []BlendSupply
+	for i, step := range plan.Steps {
+		bankI := -1
+		var supply BlendSupply
+		for j, bank := range banks {
+			if !bank.GasComposition.Equal(step.GasComposition) {
+				continue
+			}
+			candidate := BlendSupply{Step: i + 1, Bank: bank, BankPressureAfter: bank.Pressure}
+			if gasVolume := bank.GasVolume(gasSystem, temperature) - step.AddedGasVolume; gasVolume > 0 {
+				pressureAfter := PressureFromGasVolume(bank.CylinderVolume, gasVolume, gasSystem, bank.GasComposition, temperature)
+				if pressureAfter >= step.FillToPressure {
+					candidate.BankPressureAfter = pressureAfter
+					candidate.Sufficient = true
+				}
+			}
+			if bankI == -1 || (candidate.Sufficient && (!supply.Sufficient || bank.Pressure < supply.Bank.Pressure)) || (!candidate.Sufficient && !supply.Sufficient && bank.Pressure > supply.Bank.Pressure) {
+				bankI, supply = j, candidate
+			}
+		}
+		if bankI == -1 {
+			continue
+		}
+		banks[bankI].Pressure = supply.BankPressureAfter
+		supplies = append(supplies, supply)
+	}
+	return supplies
+}
+
+// ContinuousBlendPlan describes a continuous blend where oxygen is injected into the compressor intake
+type ContinuousBlendPlan struct {
+	StartPressure           PressureBar
+	TargetComposition       GasComposition
+	TargetPressure          PressureBar
+	IntakeOxygenFraction    float64
+	OxygenInjectionFraction float64
+	CompressorThroughput    GasVolume
+	OxygenGasVolume         GasVolume
+}
+
+// MaxContinuousBlendIntakeOxygen is the highest intake oxygen fraction commonly allowed for oil lubricated compressors
+const MaxContinuousBlendIntakeOxygen = 0.40
+
+// PlanContinuousBlend calculates the intake oxygen fraction and compressor throughput required to fill the start
+// cylinder to the target nitrox mix with a continuous blender using air as the base gas.
+func PlanContinuousBlend(start Cylinder, targetComposition GasComposition, targetPressure PressureBar, gasSystem GasSystem, temperature Temperature) (ContinuousBlendPlan, error) {
+	plan := ContinuousBlendPlan{
+		StartPressure:     start.Pressure,
+		TargetComposition: targetComposition,
+		TargetPressure:    targetPressure,
+	}
+	if targetComposition[Helium] > 0 {
+		return plan, fmt.Errorf("%w: continuous blending supports nitrox only", ErrBlendNotAchievable)
+	}
+	target := Cylinder{CylinderVolume: start.CylinderVolume, Pressure: targetPressure, GasComposition: targetComposition}
+	targetGasVolumes := target.GasVolumes(gasSystem, temperature)
+	targetGasVolume := target.GasVolume(gasSystem, temperature)
+	var existingOxygen, existingGasVolume GasVolume
+	if start.Pressure > 0 {
+		existingOxygen = start.GasVolumes(gasSystem, temperature)[Oxygen]
+		existingGasVolume = start.GasVolume(gasSystem, temperature)
+	}
+	plan.CompressorThroughput = targetGasVolume - existingGasVolume
+	if plan.CompressorThroughput <= 0 {
+		return plan, fmt.Errorf("%w: cylinder is already at target pressure", ErrBlendNotAchievable)
+	}
+	plan.IntakeOxygenFraction = float64((targetGasVolumes[Oxygen] - existingOxygen) / plan.CompressorThroughput)
+	if plan.IntakeOxygenFraction < 0.21 || plan.IntakeOxygenFraction > 1 {
+		return plan, fmt.Errorf("%w: intake would need %.1f%% oxygen", ErrBlendNotAchievable, plan.IntakeOxygenFraction*100)
+	}
+	plan.OxygenInjectionFraction = (plan.IntakeOxygenFraction - 0.21) / (1 - 0.21)
+	plan.OxygenGasVolume = plan.CompressorThroughput * GasVolume(plan.OxygenInjectionFraction)
+	return plan, nil
+}
+
+func blendMain(args []string) error {
+	fs := flag.NewFlagSet("blend", flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	var targetMixFlag = fs.String("target", "", "Target mix, e.g. 32, EAN32 or 18/45 (oxygen/helium)")
+	var targetPressureFlag = fs.String("target-pressure", "200bar", "Target pressure")
+	var cylinderVolumeFlag = fs.String("cylinder-volume", "12l", "Cylinder volume")
+	var topUpFlag = fs.String("top-up", "air", "Top-up gas mix")
+	var startPressureFlag = fs.String("start-pressure", "0bar", "Pressure of gas already in the cylinder")
+	var startMixFlag = fs.String("start-mix", "air", "Mix of gas already in the cylinder")
+	var methodFlag = fs.String("method", "partial-pressure", "Blending method: partial-pressure or continuous")
+	var logFillFlag = fs.String("log-fill", "", "Append the partial pressure blend to this SQLite fill log; needs the sqlite3 command")
+	var worksheetFlag = fs.String("worksheet", "", "Write a printable PDF worksheet with the steps and fields for the analyzed mix to this file")
+	bankStateFlags := registerBankStateFlags(fs)
+	priceFlags := registerPriceFlags(fs)
+	diveGasFlags := registerDiveGasFlags(fs)
+	analyzerFlags := registerAnalyzerFlags(fs)
+	fs.Parse(args)
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	w, err := flags.output(os.Stdout, units)
+	if err != nil {
+		return err
+	}
+	gasSystem, temperature, err := flags.gasSettings(units)
+	if err != nil {
+		return err
+	}
+	diveGasSettings, err := diveGasFlags.settings(units)
+	if err != nil {
+		return err
+	}
+	analyzerModel, analysisTemperature, err := analyzerFlags.model(temperature, units)
+	if err != nil {
+		return err
+	}
+	targetComposition, err := ParseGasComposition(*targetMixFlag)
+	if err != nil {
+		return fmt.Errorf("invalid target mix: %w", err)
+	}
+	topUpComposition, err := ParseGasComposition(*topUpFlag)
+	if err != nil {
+		return fmt.Errorf("invalid top-up mix: %w", err)
+	}
+	targetPressure, err := units.ParsePressure(*targetPressureFlag)
+	if err != nil {
+		return fmt.Errorf("invalid target pressure: %w", err)
+	}
+	cylinderVolume, err := units.ParseCylinderVolume(*cylinderVolumeFlag)
+	if err != nil {
+		return fmt.Errorf("invalid cylinder volume: %w", err)
+	}
+	startPressure, err := units.ParsePressure(*startPressureFlag)
+	if err != nil {
+		return fmt.Errorf("invalid start pressure: %w", err)
+	}
+	startComposition, err := ParseGasComposition(*startMixFlag)
+	if err != nil {
+		return fmt.Errorf("invalid start mix: %w", err)
+	}
+	if err := checkCylinders(CylinderList{{CylinderVolume: cylinderVolume, Pressure: targetPressure}}, "target", false, units); err != nil {
+		return err
+	}
+	if err := checkCylinders(CylinderList{{CylinderVolume: cylinderVolume, Pressure: startPressure}}, "start", true, units); err != nil {
+		return err
+	}
+	if startPressure > targetPressure {
+		return errors.New("start pressure must not exceed target pressure")
+	}
+
+	start := Cylinder{CylinderVolume: cylinderVolume, Pressure: startPressure, GasComposition: startComposition}
+	switch *methodFlag {
+	case "partial-pressure":
+	case "continuous":
+		plan, err := PlanContinuousBlend(start, targetComposition, targetPressure, gasSystem, temperature)
+		if err != nil {
+			return err
+		}
+		printContinuousBlendPlan(w, plan, units)
+		printDiveGas(w, plan.TargetComposition, diveGasSettings, gasSystem, temperature, units)
+		if analyzerModel != nil {
+			printAnalyzerReading(w, plan.TargetComposition, analyzerModel.Reading(plan.TargetComposition, analysisTemperature), analysisTemperature, units)
+		}
+		if *worksheetFlag != "" {
+			if err := writeWorksheet(*worksheetFlag, continuousBlendWorksheet(plan, units)); err != nil {
+				return fmt.Errorf("unable to write worksheet: %w", err)
+			}
+		}
+		return nil
+	default:
+		return errors.New("invalid blending method; must be partial-pressure or continuous")
+	}
+	plan, err := PlanBlend(start, targetComposition, targetPressure, topUpComposition, units.AmbientPressure, gasSystem, temperature)
+	if err != nil {
+		return err
+	}
+	printBlendPlan(w, plan, units, *flags.verbose)
+	printDiveGas(w, plan.TargetComposition, diveGasSettings, gasSystem, temperature, units)
+	if analyzerModel != nil {
+		printAnalyzerReading(w, plan.TargetComposition, analyzerModel.Reading(plan.TargetComposition, analysisTemperature), analysisTemperature, units)
+	}
+	prices, err := priceFlags.prices(units)
+	if err != nil {
+		return err
+	}
+	if prices != nil {
+		fmt.Fprintln(w, "Gas cost:", prices.Cost(blendGasVolumes(plan)))
+	}
+	banks, err := bankStateFlags.banks(w, units)
+	if err != nil {
+		return err
+	}
+	if len(banks) > 0 {
+		supplies := PlanBlendSupply(plan, banks, gasSystem, temperature)
+		printBlendSupplies(w, plan, supplies, units)
+		pressures := make(map[string]PressureBar)
+		for _, supply := range supplies {
+			if supply.Sufficient {
+				pressures[supply.Bank.Description] = supply.BankPressureAfter
+			}
+		}
+		if err := bankStateFlags.update(w, banks, pressures, units); err != nil {
+			return err
+		}
+	}
+	if *logFillFlag != "" {
+		if err := AppendFillLog(*logFillFlag, blendFillLogEntry(plan, units)); err != nil {
+			return fmt.Errorf("unable to log fill: %w", err)
+		}
+	}
+	if *worksheetFlag != "" {
+		if err := writeWorksheet(*worksheetFlag, blendWorksheet(plan, units)); err != nil {
+			return fmt.Errorf("unable to write worksheet: %w", err)
+		}
+	}
+	return nil
+}
+
+func printBlendPlan(w io.Writer, plan BlendPlan, units UnitSystem, verbose bool) {
+	pressureUnit := units.PressureUnit()
+	fmt.Fprintf(w, "Blending %s to %.0f%s\n", plan.TargetComposition, units.Pressure(plan.TargetPressure), pressureUnit)
+	if plan.StartPressure > 0 {
+		fmt.Fprintf(w, "Starting from %.1f%s of %s\n", units.Pressure(plan.StartPressure), pressureUnit, plan.StartComposition)
+	}
+	// Pressures to drain and fill to are rounded down to the gauge increment, never adding more of a gas or filling
+	// above the target, and additions are the steps between the rounded pressures
+	pressure := plan.StartPressure
+	if plan.DrainRequired {
+		pressure = units.roundPressureDown(plan.DrainToPressure)
+		fmt.Fprintf(w, "Target is not achievable without draining: drain the cylinder to %.1f%s first\n", units.Pressure(pressure), pressureUnit)
+	}
+	for i, step := range plan.Steps {
+		addedPressure := step.AddedPressure
+		fillToPressure := step.FillToPressure
+		if units.GaugeIncrement > 0 {
+			fillToPressure = units.roundPressureDown(fillToPressure)
+			addedPressure = fillToPressure - pressure
+		}
+		pressure = fillToPressure
+		fmt.Fprintf(w, "Step %d: add %.1f%s of %s, fill to %.1f%s\n", i+1, units.PressureDifference(addedPressure), pressureUnit, step.Description, units.Pressure(fillToPressure), pressureUnit)
+		if verbose {
+			fmt.Fprintf(w, "        %.0f%s of gas\n", units.Volume(step.AddedGasVolume), units.VolumeUnit())
+		}
+	}
+}
+
+func printBlendSupplies(w io.Writer, plan BlendPlan, supplies []BlendSupply, units UnitSystem) {
+	pressureUnit := units.PressureUnit()
+	for _, supply := range supplies {
+		step := plan.Steps[supply.Step-1]
+		if supply.Sufficient {
+			fmt.Fprintf(w, "Step %d: %s from bank %s\n", supply.Step, step.Description, supply.Bank.Description)
+			continue
+		}
+		fmt.Fprintf(w, "Step %d: bank %s at %.0f%s can not fill to %.0f%s\n", supply.Step, supply.Bank.Description, units.Pressure(supply.Bank.Pressure), pressureUnit, units.Pressure(units.roundPressureDown(step.FillToPressure)), pressureUnit)
+	}
+}
+
+func printContinuousBlendPlan(w io.Writer, plan ContinuousBlendPlan, units UnitSystem) {
+	pressureUnit := units.PressureUnit()
+	volumeUnit := units.VolumeUnit()
+	fmt.Fprintf(w, "Continuous blending %s to %.0f%s\n", plan.TargetComposition, units.Pressure(plan.TargetPressure), pressureUnit)
+	fmt.Fprintf(w, "Intake oxygen: %.1f%% (inject %.1f%% of intake flow as oxygen)\n", plan.IntakeOxygenFraction*100, plan.OxygenInjectionFraction*100)
+	fmt.Fprintf(w, "Compressor throughput: %.0f%s, of which oxygen %.0f%s\n", units.Volume(plan.CompressorThroughput), volumeUnit, units.Volume(plan.OxygenGasVolume), volumeUnit)
+	if plan.IntakeOxygenFraction > MaxContinuousBlendIntakeOxygen {
+		warnf(w, colorDanger, "Warning: intake oxygen exceeds %.0f%%; compressor must be oxygen compatible\n", MaxContinuousBlendIntakeOxygen*100)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPlanBlendIdealGas(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	plan, err := PlanBlend(Cylinder{CylinderVolume: 12}, GasComposition{Oxygen: 0.18, Helium: 0.45, Nitrogen: 0.37}, 200, air, 0, IdealGas, 293.15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Steps) != 3 {
+		t.Fatalf("Expected 3 steps, got %d", len(plan.Steps))
+	}
+	if !compareFloats(float64(plan.Steps[0].FillToPressure), 90) {
+		t.Errorf("Invalid helium fill pressure %f, expected 90", plan.Steps[0].FillToPressure)
+	}
+	airPressure := 200 * 0.37 / 0.79
+	oxygenPressure := 200*0.18 - airPressure*0.21
+	if !compareFloats(float64(plan.Steps[1].AddedPressure), oxygenPressure) {
+		t.Errorf("Invalid oxygen pressure %f, expected %f", plan.Steps[1].AddedPressure, oxygenPressure)
+	}
+	if !compareFloats(float64(plan.Steps[2].FillToPressure), 200) {
+		t.Errorf("Invalid final pressure %f", plan.Steps[2].FillToPressure)
+	}
+}
+
+func TestPlanBlendNotAchievable(t *testing.T) {
+	_, err := PlanBlend(Cylinder{CylinderVolume: 12}, GasComposition{Oxygen: 0.15, Nitrogen: 0.85}, 200, GasComposition{Oxygen: 0.21, Nitrogen: 0.79}, 0, IdealGas, 293.15)
+	if !errors.Is(err, ErrBlendNotAchievable) {
+		t.Errorf("Expected ErrBlendNotAchievable, got %v", err)
+	}
+}
+
+func TestPlanBlendDrainBelowAmbient(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	start := Cylinder{CylinderVolume: 12, Pressure: 2, GasComposition: air}
+	ean80 := GasComposition{Oxygen: 0.8, Nitrogen: 0.2}
+	// 3 bar of EAN80 holds 0.6 bar of nitrogen, which leaves 0.76 bar of air in the cylinder
+	plan, err := PlanBlend(start, ean80, 3, air, 0.5, IdealGas, 293.15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !compareFloats(float64(plan.DrainToPressure), 0.6/0.79) {
+		t.Errorf("Invalid drain pressure %f, expected %f", plan.DrainToPressure, 0.6/0.79)
+	}
+	if _, err := PlanBlend(start, ean80, 3, air, 1, IdealGas, 293.15); !errors.Is(err, ErrBlendNotAchievable) {
+		t.Errorf("Expected ErrBlendNotAchievable for a drain below ambient pressure, got %v", err)
+	}
+}
+
+func TestPlanBlendTopUp(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	start := Cylinder{CylinderVolume: 12, Pressure: 80, GasComposition: GasComposition{Oxygen: 0.21, Helium: 0.35, Nitrogen: 0.44}}
+	plan, err := PlanBlend(start, GasComposition{Oxygen: 0.21, Helium: 0.35, Nitrogen: 0.44}, 200, air, 0, IdealGas, 293.15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.DrainRequired {
+		t.Error("Topping up the same mix should not require draining")
+	}
+	if !compareFloats(float64(plan.Steps[0].AddedPressure), 42) {
+		t.Errorf("Invalid helium addition %f, expected 42", plan.Steps[0].AddedPressure)
+	}
+}
+
+func TestPlanBlendDrain(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	start := Cylinder{CylinderVolume: 12, Pressure: 180, GasComposition: air}
+	plan, err := PlanBlend(start, GasComposition{Oxygen: 0.36, Nitrogen: 0.64}, 200, air, 0, IdealGas, 293.15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.DrainRequired {
+		t.Fatal("Expected draining to be required")
+	}
+	// 200 bar of EAN36 holds 128 bar of nitrogen, all of which must come from the air already in the cylinder
+	expectedDrainPressure := 128 / 0.79
+	if !compareFloats(float64(plan.DrainToPressure), expectedDrainPressure) {
+		t.Errorf("Invalid drain pressure %f, expected %f", plan.DrainToPressure, expectedDrainPressure)
+	}
+	if len(plan.Steps) != 1 || !compareFloats(float64(plan.Steps[0].FillToPressure), 200) {
+		t.Errorf("Expected a single oxygen fill to 200 bar, got %+v", plan.Steps)
+	}
+}
+
+func TestPlanContinuousBlend(t *testing.T) {
+	start := Cylinder{CylinderVolume: 12, Pressure: 50, GasComposition: GasComposition{Oxygen: 0.32, Nitrogen: 0.68}}
+	plan, err := PlanContinuousBlend(start, GasComposition{Oxygen: 0.32, Nitrogen: 0.68}, 200, IdealGas, 293.15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !compareFloats(plan.IntakeOxygenFraction, 0.32) {
+		t.Errorf("Invalid intake oxygen fraction %f, expected 0.32", plan.IntakeOxygenFraction)
+	}
+	if !compareFloats(float64(plan.CompressorThroughput), 1800) {
+		t.Errorf("Invalid compressor throughput %f, expected 1800", plan.CompressorThroughput)
+	}
+	if !compareFloats(plan.OxygenInjectionFraction, 0.11/0.79) {
+		t.Errorf("Invalid oxygen injection fraction %f", plan.OxygenInjectionFraction)
+	}
+	if _, err := PlanContinuousBlend(start, GasComposition{Oxygen: 0.18, Helium: 0.45, Nitrogen: 0.37}, 200, IdealGas, 293.15); !errors.Is(err, ErrBlendNotAchievable) {
+		t.Errorf("Expected trimix to be rejected, got %v", err)
+	}
+}
+
+func TestPlanBlendSupply(t *testing.T) {
+	start := Cylinder{CylinderVolume: 10, GasComposition: GasComposition{Nitrogen: 0.79, Oxygen: 0.21}}
+	plan, err := PlanBlend(start, GasComposition{Oxygen: 0.18, Helium: 0.45, Nitrogen: 0.37}, 200, GasComposition{Nitrogen: 0.79, Oxygen: 0.21}, 0, IdealGas, 293.15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	banks := CylinderList{
+		{Description: "he-low", CylinderVolume: 50, Pressure: 95, GasComposition: GasComposition{Helium: 1}},
+		{Description: "he-high", CylinderVolume: 50, Pressure: 200, GasComposition: GasComposition{Helium: 1}},
+		{Description: "o2", CylinderVolume: 10, Pressure: 60, GasComposition: GasComposition{Oxygen: 1}},
+	}
+	supplies := PlanBlendSupply(plan, banks, IdealGas, 293.15)
+	if len(supplies) != 2 {
+		t.Fatalf("Expected helium and oxygen supplies, got %+v", supplies)
+	}
+	// 90 bar of helium into 10 l: the 95 bar bank would drop to 77 bar, below the 90 bar fill
+	if supplies[0].Bank.Description != "he-high" || !supplies[0].Sufficient || !compareFloats(float64(supplies[0].BankPressureAfter), 182) {
+		t.Errorf("Invalid helium supply %+v", supplies[0])
+	}
+	if supplies[1].Bank.Description != "o2" || supplies[1].Sufficient {
+		t.Errorf("Expected insufficient oxygen supply, got %+v", supplies[1])
+	}
+}
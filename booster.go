@@ -0,0 +1,74 @@
+package main
+
+// boosterSteps is the number of increments used when integrating drive gas consumption
+const boosterSteps = 200
+
+// Booster is a pneumatic gas booster driven by drive gas, typically compressed air
+type Booster struct {
+	// Ratio is the area ratio of the drive piston to the gas piston
+	Ratio float64
+	// DrivePressure is the pressure of the drive gas
+	DrivePressure PressureBar
+	// MinimumInletPressure is the lowest source pressure the booster can pump from
+	MinimumInletPressure PressureBar
+}
+
+// BoostResult describes gas pushed from the source to the destination with a booster
+type BoostResult struct {
+	DestinationPressureBefore PressureBar
+	DestinationPressureAfter  PressureBar
+	SourcePressureAfter       PressureBar
+	BoostedGasVolume          GasVolume
+	DriveGasVolume            GasVolume
+	TargetReached             bool
+}
+
+// MaxOutletPressure returns the stall pressure of the booster
+func (b Booster) MaxOutletPressure() PressureBar {
+	return PressureBar(b.Ratio) * b.DrivePressure
+}
+
+// Boost pumps gas from the source to the destination until the destination reaches the target pressure, the
+// booster stalls or the source is depleted to the minimum inlet pressure. Drive gas consumption assumes the gas
+// piston fills completely at the source pressure on every stroke.
+func (b Booster) Boost(source *Cylinder, destination *Cylinder, targetPressure PressureBar, gasSystem GasSystem, temperature Temperature) BoostResult {
+	result := BoostResult{
+		DestinationPressureBefore: destination.Pressure,
+		DestinationPressureAfter:  destination.Pressure,
+		SourcePressureAfter:       source.Pressure,
+	}
+	outletPressure := targetPressure
+	if maxOutletPressure := b.MaxOutletPressure(); maxOutletPressure < outletPressure {
+		outletPressure = maxOutletPressure
+	}
+	if destination.Pressure >= outletPressure || source.Pressure <= b.MinimumInletPressure {
+		return result
+	}
+	// Destination mix changes while boosting, but the mix after boosting is a good estimate for required amount
+	estimate := *destination
+	estimateSource := *source
+	estimate.TransferGas(&estimateSource, 1, gasSystem, temperature)
+	requiredGasVolume := Cylinder{CylinderVolume: destination.CylinderVolume, Pressure: outletPressure, GasComposition: estimate.GasComposition}.GasVolume(gasSystem, temperature) - destination.GasVolume(gasSystem, temperature)
+	availableGasVolume := source.GasVolume(gasSystem, temperature) - Cylinder{CylinderVolume: source.CylinderVolume, Pressure: b.MinimumInletPressure, GasComposition: source.GasComposition}.GasVolume(gasSystem, temperature)
+	enoughGas := requiredGasVolume <= availableGasVolume
+	result.TargetReached = enoughGas && outletPressure == targetPressure
+	boostedGasVolume := requiredGasVolume
+	if !enoughGas {
+		boostedGasVolume = availableGasVolume
+	}
+
+	stepGasVolume := boostedGasVolume / boosterSteps
+	for i := 0; i < boosterSteps; i++ {
+		// Gas piston swept volume needed to draw this amount of gas at the current source pressure
+		sweptVolume := float64(stepGasVolume) / float64(source.Pressure)
+		result.DriveGasVolume += GasVolume(sweptVolume * b.Ratio * float64(b.DrivePressure))
+		destination.TransferGas(source, stepGasVolume, gasSystem, temperature)
+	}
+	if enoughGas {
+		destination.Pressure = outletPressure
+	}
+	result.BoostedGasVolume = boostedGasVolume
+	result.DestinationPressureAfter = destination.Pressure
+	result.SourcePressureAfter = source.Pressure
+	return result
+}
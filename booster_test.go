@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestBoosterReachesTarget(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	source := Cylinder{CylinderVolume: 50, Pressure: 150, GasComposition: air}
+	destination := Cylinder{CylinderVolume: 10, Pressure: 150, GasComposition: air}
+	booster := Booster{Ratio: 30, DrivePressure: 8, MinimumInletPressure: 10}
+	result := booster.Boost(&source, &destination, 200, IdealGas, 293.15)
+	if !result.TargetReached || destination.Pressure != 200 {
+		t.Errorf("Expected to reach 200 bar, got %+v", result)
+	}
+	if !compareFloats(float64(result.BoostedGasVolume), 500) {
+		t.Errorf("Invalid boosted gas volume %f, expected 500", result.BoostedGasVolume)
+	}
+	if !compareFloats(float64(source.Pressure), 140) {
+		t.Errorf("Invalid source pressure %f, expected 140", source.Pressure)
+	}
+	// 500 liters drawn at ~140-150 bar is ~3.45 liters of swept volume, driven at 30*8 bar
+	if result.DriveGasVolume < 800 || result.DriveGasVolume > 860 {
+		t.Errorf("Invalid drive gas volume %f", result.DriveGasVolume)
+	}
+}
+
+func TestBoosterStalls(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	source := Cylinder{CylinderVolume: 50, Pressure: 150, GasComposition: air}
+	destination := Cylinder{CylinderVolume: 10, Pressure: 150, GasComposition: air}
+	booster := Booster{Ratio: 20, DrivePressure: 8, MinimumInletPressure: 10}
+	result := booster.Boost(&source, &destination, 200, IdealGas, 293.15)
+	if result.TargetReached || !compareFloats(float64(destination.Pressure), 160) {
+		t.Errorf("Expected booster to stall at 160 bar, got %+v", result)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// CascadeStep describes decanting from a single storage bank
+type CascadeStep struct {
+	Bank                      Cylinder
+	BankPressureAfter         PressureBar
+	DestinationPressureBefore PressureBar
+	DestinationPressureAfter  PressureBar
+	TransferredGasVolume      GasVolume
+	Skipped                   bool
+}
+
+// CascadePlan is the result of a cascade fill
+type CascadePlan struct {
+	Steps                     []CascadeStep
+	DestinationPressure       PressureBar
+	DestinationGasComposition GasComposition
+	TargetReached             bool
+}
+
+// PlanCascade decants from banks into the destination, lowest pressure bank first. Banks not above the
+// destination pressure are skipped. If targetPressure is non-zero, the fill stops at the target pressure.
+func PlanCascade(banks CylinderList, destination Cylinder, targetPressure PressureBar, gasSystem GasSystem, temperature Temperature) CascadePlan {
+	orderedBanks := append(CylinderList(nil), banks...)
+	sort.SliceStable(orderedBanks, func(i, j int) bool {
+		return orderedBanks[i].Pressure < orderedBanks[j].Pressure
+	})
+
+	var plan CascadePlan
+	for _, bank := range orderedBanks {
+		step := CascadeStep{
+			Bank:                      bank,
+			BankPressureAfter:         bank.Pressure,
+			DestinationPressureBefore: destination.Pressure,
+			DestinationPressureAfter:  destination.Pressure,
+		}
+		if plan.TargetReached || bank.Pressure <= destination.Pressure {
+			step.Skipped = true
+			plan.Steps = append(plan.Steps, step)
+			continue
+		}
+		destinationGasBefore := destination.GasVolume(gasSystem, temperature)
+		if targetPressure > 0 {
+			plan.TargetReached = destination.EqualizeUntil(&bank, targetPressure, gasSystem, temperature, nil)
+		} else {
+			destination.Equalize(&bank, gasSystem, temperature, nil)
+		}
+		step.BankPressureAfter = bank.Pressure
+		step.DestinationPressureAfter = destination.Pressure
+		step.TransferredGasVolume = destination.GasVolume(gasSystem, temperature) - destinationGasBefore
+		plan.Steps = append(plan.Steps, step)
+	}
+	plan.DestinationPressure = destination.Pressure
+	plan.DestinationGasComposition = destination.GasComposition
+	return plan
+}
+
+func cascadeMain(args []string) error {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	gasFlags := registerGasCompositionFlags(fs)
+	var bankFlags, destinationFlags stringListFlag
+	fs.Var(&bankFlags, "bank", "Storage bank as [name=]volume@pressure, e.g. bank1=50l@300bar, or a bank preset such as bank1=12x50l-300bar@250bar; repeat for each bank")
+	fs.Var(&destinationFlags, "destination", "Destination cylinder as [name=]volume@pressure; multiple cylinders are filled through an open manifold")
+	var targetPressureFlag = fs.String("target-pressure", "", "Stop filling once the destination reaches this pressure")
+	var bankModeFlag = fs.Bool("bank-mode", false, "Banks are industrial storage cylinders: those without a test pressure may hold up to 700 bar instead of 350 bar, while the fill stops at the pressure limit of the destinations")
+	var separateFlag = fs.Bool("separate", false, "Fill multiple destinations one at a time instead of through a manifold, choosing the order and stopping points")
+	var destinationTargetFlags stringListFlag
+	fs.Var(&destinationTargetFlags, "destination-target", "With -separate: target pressure of a destination as name=pressure, overriding -target-pressure; repeat for each destination")
+	bankStateFlags := registerBankStateFlags(fs)
+	diveGasFlags := registerDiveGasFlags(fs)
+	analyzerFlags := registerAnalyzerFlags(fs)
+	diveRequirementFlags := registerDiveRequirementFlags(fs)
+	mixSpecificationFlags := registerMixSpecificationFlags(fs)
+	fs.Parse(args)
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	w, err := flags.output(os.Stdout, units)
+	if err != nil {
+		return err
+	}
+	gasSystem, temperature, err := flags.gasSettings(units)
+	if err != nil {
+		return err
+	}
+	gasComposition, err := gasFlags.gasComposition()
+	if err != nil {
+		return err
+	}
+	diveGasSettings, err := diveGasFlags.settings(units)
+	if err != nil {
+		return err
+	}
+	analyzerModel, analysisTemperature, err := analyzerFlags.model(temperature, units)
+	if err != nil {
+		return err
+	}
+	diveRequirement, reservePolicy, err := diveRequirementFlags.requirement(diveGasSettings.PlannedDepth, units)
+	if err != nil {
+		return err
+	}
+	mixSpecification, err := mixSpecificationFlags.specification()
+	if err != nil {
+		return err
+	}
+	if (len(bankFlags) == 0 && len(bankStateFlags.useBanks) == 0) || len(destinationFlags) == 0 {
+		return errors.New("at least one -bank or -use-bank and -destination is required")
+	}
+	banks, err := units.ParseCylinderSpecs(bankFlags, "bank")
+	if err != nil {
+		return fmt.Errorf("invalid bank: %w", err)
+	}
+	destinationCylinders, err := units.ParseCylinderSpecs(destinationFlags, "destination")
+	if err != nil {
+		return fmt.Errorf("invalid destination cylinder: %w", err)
+	}
+	savedBanks, err := bankStateFlags.banks(w, units)
+	if err != nil {
+		return err
+	}
+	banks = append(banks, savedBanks...)
+	banks.SetDefaultGasComposition(gasComposition)
+	destinationCylinders.SetDefaultGasComposition(gasComposition)
+	var bankModeWarnings []string
+	if *bankModeFlag {
+		if bankModeWarnings, err = checkBankCylinders(banks, "bank", gasSystem, units); err != nil {
+			return err
+		}
+	} else if err := checkCylinders(banks, "bank", false, units); err != nil {
+		return err
+	}
+	if err := checkCylinders(destinationCylinders, "destination", true, units); err != nil {
+		return err
+	}
+	var targetPressure PressureBar
+	if *targetPressureFlag != "" {
+		if targetPressure, err = units.ParsePressure(*targetPressureFlag); err != nil {
+			return fmt.Errorf("invalid target pressure: %w", err)
+		}
+	}
+	if limit := destinationCylinders.pressureLimit(units); *bankModeFlag && (targetPressure > limit || targetPressure == 0 && banks.MaxPressure() > limit) {
+		if targetPressure > limit {
+			return fmt.Errorf("%w of target; must be <=%.0f%s, the pressure limit of the destinations", ErrInvalidPressure, units.Pressure(limit), units.PressureUnit())
+		}
+		targetPressure = limit
+		bankModeWarnings = append(bankModeWarnings, fmt.Sprintf("Bank mode: the banks are above the %.0f%s limit of the destinations, so the fill stops at it", units.Pressure(limit), units.PressureUnit()))
+	}
+	for _, warning := range bankModeWarnings {
+		fmt.Fprintln(w, colorize(w, colorCaution, warning))
+	}
+
+	if *separateFlag {
+		targetPressures := make([]PressureBar, len(destinationCylinders))
+		for i := range targetPressures {
+			targetPressures[i] = targetPressure
+		}
+		for _, destinationTarget := range destinationTargetFlags {
+			name, pressure, _ := strings.Cut(destinationTarget, "=")
+			i := slices.IndexFunc(destinationCylinders, func(cylinder Cylinder) bool { return cylinder.Description == name })
+			if i < 0 {
+				return fmt.Errorf("invalid destination target; no destination named %s", name)
+			}
+			if targetPressures[i], err = units.ParsePressure(pressure); err != nil {
+				return fmt.Errorf("invalid destination target: %w", err)
+			}
+		}
+		plan := PlanMultiDestinationFill(banks, destinationCylinders, targetPressures, gasSystem, temperature)
+		printMultiDestinationPlan(w, plan, units)
+		if err := bankStateFlags.update(w, savedBanks, plan.BankPressures, units); err != nil {
+			return err
+		}
+		return nil
+	}
+	if len(destinationTargetFlags) > 0 {
+		return errors.New("-destination-target needs -separate")
+	}
+	destination := openManifold(destinationCylinders, "destination", gasSystem, temperature)[0]
+	plan := PlanCascade(banks, destination, targetPressure, gasSystem, temperature)
+	printCascadePlan(w, plan, units)
+	printDiveGas(w, plan.DestinationGasComposition, diveGasSettings, gasSystem, temperature, units)
+	if analyzerModel != nil {
+		printAnalyzerReading(w, plan.DestinationGasComposition, analyzerModel.Reading(plan.DestinationGasComposition, analysisTemperature), analysisTemperature, units)
+	}
+	mixWithinSpecification := true
+	if mixSpecification != nil {
+		mixWithinSpecification = printMixSpecification(w, *mixSpecification, plan.DestinationGasComposition)
+	}
+	if diveRequirement != nil {
+		destination.Pressure, destination.GasComposition = plan.DestinationPressure, plan.DestinationGasComposition
+		printDiveRequirement(w, *diveRequirement, reservePolicy, destination, gasSystem, temperature, units)
+	}
+	if targetPressure > 0 && !plan.TargetReached {
+		destination.Pressure, destination.GasComposition = plan.DestinationPressure, plan.DestinationGasComposition
+		printTargetFeasibility(w, newTargetFeasibility("the cascade", destination, targetPressure, gasSystem, temperature), units)
+	}
+	if err := bankStateFlags.update(w, savedBanks, plan.BankPressures(), units); err != nil {
+		return err
+	}
+	if !mixWithinSpecification {
+		return ErrMixOutOfSpecification
+	}
+	return nil
+}
+
+// BankPressures returns the pressure of each bank after the cascade fill, by bank description
+func (p CascadePlan) BankPressures() map[string]PressureBar {
+	pressures := make(map[string]PressureBar)
+	for _, step := range p.Steps {
+		pressures[step.Bank.Description] = step.BankPressureAfter
+	}
+	return pressures
+}
+
+func printCascadePlan(w io.Writer, plan CascadePlan, units UnitSystem) {
+	pressureUnit := units.PressureUnit()
+	fmt.Fprintf(w, "%20s %10s %10s %10s %10s %10s\n", "bank", "bank "+pressureUnit, "after", "dst "+pressureUnit, "after", units.VolumeUnit()+" moved")
+	for _, step := range plan.Steps {
+		if step.Skipped {
+			fmt.Fprintf(w, "%20s %10.0f %10s\n", step.Bank.Description, units.Pressure(step.Bank.Pressure), "skipped")
+			continue
+		}
+		fmt.Fprintf(w, "%20s %10.0f %10.0f %10.0f %10.0f %10.0f\n", step.Bank.Description, units.Pressure(step.Bank.Pressure), units.Pressure(step.BankPressureAfter), units.Pressure(step.DestinationPressureBefore), units.Pressure(step.DestinationPressureAfter), units.Volume(step.TransferredGasVolume))
+	}
+	fmt.Fprintf(w, "Final destination pressure: %.0f%s, %s\n", units.Pressure(plan.DestinationPressure), pressureUnit, plan.DestinationGasComposition)
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestPlanCascade(t *testing.T) {
+	banks := CylinderList{
+		{Description: "high", CylinderVolume: 50, Pressure: 300},
+		{Description: "low", CylinderVolume: 50, Pressure: 150},
+		{Description: "empty", CylinderVolume: 50, Pressure: 40},
+	}
+	banks.SetDefaultGasComposition(GasComposition{Nitrogen: 0.79, Oxygen: 0.21})
+	destination := Cylinder{Description: "destination", CylinderVolume: 10, Pressure: 50, GasComposition: GasComposition{Nitrogen: 0.79, Oxygen: 0.21}}
+	plan := PlanCascade(banks, destination, 0, IdealGas, 293.15)
+	if len(plan.Steps) != 3 {
+		t.Fatalf("Expected 3 steps, got %d", len(plan.Steps))
+	}
+	if !plan.Steps[0].Skipped || plan.Steps[0].Bank.Description != "empty" {
+		t.Errorf("Expected lowest bank to be skipped first, got %+v", plan.Steps[0])
+	}
+	if plan.Steps[1].Bank.Description != "low" || !compareFloats(float64(plan.Steps[1].DestinationPressureAfter), 400.0/3) {
+		t.Errorf("Invalid second step %+v", plan.Steps[1])
+	}
+	expected := (50*300 + 10*400.0/3) / 60
+	if !compareFloats(float64(plan.DestinationPressure), expected) {
+		t.Errorf("Invalid destination pressure %f, expected %f", plan.DestinationPressure, expected)
+	}
+}
+
+func TestPlanCascadeTargetPressure(t *testing.T) {
+	banks := CylinderList{{Description: "bank", CylinderVolume: 50, Pressure: 300, GasComposition: GasComposition{Oxygen: 1}}}
+	destination := Cylinder{CylinderVolume: 10, Pressure: 50, GasComposition: GasComposition{Nitrogen: 0.79, Oxygen: 0.21}}
+	plan := PlanCascade(banks, destination, 200, IdealGas, 293.15)
+	if !plan.TargetReached || plan.DestinationPressure != 200 {
+		t.Errorf("Expected to reach target, got %+v", plan)
+	}
+	if !compareFloats(float64(plan.Steps[0].BankPressureAfter), 270) {
+		t.Errorf("Invalid bank pressure %f, expected 270", plan.Steps[0].BankPressureAfter)
+	}
+	expectedOxygen := (50*0.21 + 150) / 200
+	if !compareFloats(plan.DestinationGasComposition[Oxygen], expectedOxygen) {
+		t.Errorf("Invalid oxygen fraction %f, expected %f", plan.DestinationGasComposition[Oxygen], expectedOxygen)
+	}
+}
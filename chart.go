@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Chart dimensions in pixels
+const (
+	chartWidth        = 720
+	chartHeight       = 420
+	chartMarginLeft   = 70
+	chartMarginRight  = 180
+	chartMarginTop    = 40
+	chartMarginBottom = 50
+)
+
+// chartColors are colors of chart series, repeated when there are more series
+var chartColors = []color.RGBA{
+	{0x1f, 0x77, 0xb4, 0xff},
+	{0xd6, 0x27, 0x28, 0xff},
+	{0x2c, 0xa0, 0x2c, 0xff},
+	{0xff, 0x7f, 0x0e, 0xff},
+	{0x94, 0x67, 0xbd, 0xff},
+	{0x8c, 0x56, 0x4b, 0xff},
+	{0xe3, 0x77, 0xc2, 0xff},
+	{0x17, 0xbe, 0xcf, 0xff},
+}
+
+// ChartSeries is a line of a chart
+type ChartSeries struct {
+	Name string
+	X    []float64
+	Y    []float64
+}
+
+// Chart is a line chart with a series for each line
+type Chart struct {
+	Title  string
+	XLabel string
+	YLabel string
+	Series []ChartSeries
+}
+
+// chartTicks returns evenly spaced round values covering min to max
+func chartTicks(min float64, max float64) []float64 {
+	if max <= min {
+		min, max = min-1, min+1
+	}
+	rawStep := (max - min) / 5
+	magnitude := math.Pow(10, math.Floor(math.Log10(rawStep)))
+	step := magnitude * 10
+	for _, factor := range []float64{1, 2, 5} {
+		if factor*magnitude >= rawStep {
+			step = factor * magnitude
+			break
+		}
+	}
+	var ticks []float64
+	for tick := math.Floor(min/step) * step; tick < max+step/2; tick += step {
+		ticks = append(ticks, tick)
+	}
+	return ticks
+}
+
+// chartScale maps chart values to pixel coordinates of the plot area
+type chartScale struct {
+	xTicks, yTicks []float64
+}
+
+func (c Chart) scale() chartScale {
+	xMin, xMax, yMin, yMax := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for _, series := range c.Series {
+		for i := range series.X {
+			xMin, xMax = math.Min(xMin, series.X[i]), math.Max(xMax, series.X[i])
+			yMin, yMax = math.Min(yMin, series.Y[i]), math.Max(yMax, series.Y[i])
+		}
+	}
+	if math.IsInf(xMin, 1) {
+		xMin, xMax, yMin, yMax = 0, 1, 0, 1
+	}
+	return chartScale{xTicks: chartTicks(xMin, xMax), yTicks: chartTicks(yMin, yMax)}
+}
+
+func (s chartScale) point(x float64, y float64) (float64, float64) {
+	xFirst, xLast := s.xTicks[0], s.xTicks[len(s.xTicks)-1]
+	yFirst, yLast := s.yTicks[0], s.yTicks[len(s.yTicks)-1]
+	px := chartMarginLeft + (x-xFirst)/(xLast-xFirst)*(chartWidth-chartMarginLeft-chartMarginRight)
+	py := chartHeight - chartMarginBottom - (y-yFirst)/(yLast-yFirst)*(chartHeight-chartMarginTop-chartMarginBottom)
+	return px, py
+}
+
+// formatTick formats an axis value without trailing zeros
+func formatTick(value float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", value), "0"), ".")
+}
+
+// WriteSVG writes the chart as an SVG image with axes, labels and a legend
+func (c Chart) WriteSVG(w io.Writer) error {
+	s := c.scale()
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", chartWidth, chartHeight, chartWidth, chartHeight)
+	fmt.Fprintf(b, `<rect width="%d" height="%d" fill="white"/>`+"\n", chartWidth, chartHeight)
+	fmt.Fprintf(b, `<text x="%d" y="24" font-size="16" text-anchor="middle">%s</text>`+"\n", chartWidth/2, html.EscapeString(c.Title))
+	left, bottom := s.point(s.xTicks[0], s.yTicks[0])
+	right, top := s.point(s.xTicks[len(s.xTicks)-1], s.yTicks[len(s.yTicks)-1])
+	for _, tick := range s.xTicks {
+		x, _ := s.point(tick, s.yTicks[0])
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/>`+"\n", x, top, x, bottom)
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="middle">%s</text>`+"\n", x, bottom+16, formatTick(tick))
+	}
+	for _, tick := range s.yTicks {
+		_, y := s.point(s.xTicks[0], tick)
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/>`+"\n", left, y, right, y)
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="end" dominant-baseline="middle">%s</text>`+"\n", left-6, y, formatTick(tick))
+	}
+	fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="none" stroke="black"/>`+"\n", left, top, right-left, bottom-top)
+	fmt.Fprintf(b, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`+"\n", (left+right)/2, chartHeight-10, html.EscapeString(c.XLabel))
+	fmt.Fprintf(b, `<text x="16" y="%.1f" text-anchor="middle" transform="rotate(-90 16 %.1f)">%s</text>`+"\n", (top+bottom)/2, (top+bottom)/2, html.EscapeString(c.YLabel))
+	for i, series := range c.Series {
+		rgba := chartColors[i%len(chartColors)]
+		stroke := fmt.Sprintf("#%02x%02x%02x", rgba.R, rgba.G, rgba.B)
+		var points []string
+		for j := range series.X {
+			x, y := s.point(series.X[j], series.Y[j])
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+		}
+		fmt.Fprintf(b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`+"\n", strings.Join(points, " "), stroke)
+		legendY := top + 10 + float64(i)*18
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="2"/>`+"\n", right+12, legendY, right+32, legendY, stroke)
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f" dominant-baseline="middle">%s</text>`+"\n", right+38, legendY, html.EscapeString(series.Name))
+	}
+	fmt.Fprintln(b, "</svg>")
+	return b.Flush()
+}
+
+// WritePNG writes the chart as a PNG image. PNG charts have axes, grid lines and series in the colors of the SVG
+// legend, but no text.
+func (c Chart) WritePNG(w io.Writer) error {
+	s := c.scale()
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, chartHeight))
+	fillRect := func(x0, y0, x1, y1 int, col color.RGBA) {
+		for y := y0; y <= y1; y++ {
+			for x := x0; x <= x1; x++ {
+				img.SetRGBA(x, y, col)
+			}
+		}
+	}
+	drawLine := func(x0, y0, x1, y1 float64, col color.RGBA, width int) {
+		steps := int(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))) + 1
+		for i := 0; i <= steps; i++ {
+			x := int(math.Round(x0 + (x1-x0)*float64(i)/float64(steps)))
+			y := int(math.Round(y0 + (y1-y0)*float64(i)/float64(steps)))
+			fillRect(x-width/2, y-width/2, x+(width-1)/2, y+(width-1)/2, col)
+		}
+	}
+	fillRect(0, 0, chartWidth-1, chartHeight-1, color.RGBA{0xff, 0xff, 0xff, 0xff})
+	grid := color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
+	black := color.RGBA{0, 0, 0, 0xff}
+	left, bottom := s.point(s.xTicks[0], s.yTicks[0])
+	right, top := s.point(s.xTicks[len(s.xTicks)-1], s.yTicks[len(s.yTicks)-1])
+	for _, tick := range s.xTicks {
+		x, _ := s.point(tick, s.yTicks[0])
+		drawLine(x, top, x, bottom, grid, 1)
+		drawLine(x, bottom, x, bottom+5, black, 1)
+	}
+	for _, tick := range s.yTicks {
+		_, y := s.point(s.xTicks[0], tick)
+		drawLine(left, y, right, y, grid, 1)
+		drawLine(left-5, y, left, y, black, 1)
+	}
+	drawLine(left, top, right, top, black, 1)
+	drawLine(left, bottom, right, bottom, black, 1)
+	drawLine(left, top, left, bottom, black, 1)
+	drawLine(right, top, right, bottom, black, 1)
+	for i, series := range c.Series {
+		col := chartColors[i%len(chartColors)]
+		for j := 1; j < len(series.X); j++ {
+			x0, y0 := s.point(series.X[j-1], series.Y[j-1])
+			x1, y1 := s.point(series.X[j], series.Y[j])
+			drawLine(x0, y0, x1, y1, col, 2)
+		}
+		legendY := top + 10 + float64(i)*18
+		drawLine(right+12, legendY, right+32, legendY, col, 2)
+	}
+	return png.Encode(w, img)
+}
+
+// writeChart writes the chart to path as SVG or PNG, selected by the file extension
+func writeChart(path string, chart Chart) error {
+	extension := strings.ToLower(filepath.Ext(path))
+	if extension != ".svg" && extension != ".png" {
+		return fmt.Errorf("unknown chart format %q; must be .svg or .png", extension)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if extension == ".png" {
+		err = chart.WritePNG(file)
+	} else {
+		err = chart.WriteSVG(file)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// transferStepChart charts the pressure of each cylinder after each transfer step of a manifold configuration.
+// Cylinders start from their pressure before the first transfer.
+func transferStepChart(steps []TransferStep, configuration string, units UnitSystem) Chart {
+	chart := Chart{
+		Title:  "Cylinder pressures, " + configuration,
+		XLabel: "Transfer step",
+		YLabel: "Pressure (" + units.PressureUnit() + ")",
+	}
+	seriesIndex := make(map[string]int)
+	addPoint := func(name string, step int, before PressureBar, after PressureBar) {
+		i, ok := seriesIndex[name]
+		if !ok {
+			i = len(chart.Series)
+			seriesIndex[name] = i
+			chart.Series = append(chart.Series, ChartSeries{Name: name, X: []float64{0}, Y: []float64{units.Pressure(before)}})
+		}
+		chart.Series[i].X = append(chart.Series[i].X, float64(step))
+		chart.Series[i].Y = append(chart.Series[i].Y, units.Pressure(after))
+	}
+	for _, step := range steps {
+		if step.Configuration != configuration {
+			continue
+		}
+		addPoint(step.Source, step.Step, step.SourcePressureBefore, step.SourcePressureAfter)
+		addPoint(step.Destination, step.Step, step.DestinationPressureBefore, step.DestinationPressureAfter)
+	}
+	return chart
+}
+
+// temperatureSweepChart charts the destination pressure of each manifold configuration against temperature
+func temperatureSweepChart(temperatures []Temperature, sweep [][]CylinderSummary, units UnitSystem) Chart {
+	chart := Chart{
+		Title:  "Destination pressure by temperature",
+		XLabel: "Temperature (°" + units.TemperatureUnit() + ")",
+		YLabel: "Pressure (" + units.PressureUnit() + ")",
+	}
+	if len(sweep) == 0 {
+		return chart
+	}
+	for i, cylinderSummary := range sweep[0] {
+		series := ChartSeries{Name: cylinderSummary.Description}
+		for j, temperature := range temperatures {
+			series.X = append(series.X, units.Temperature(temperature))
+			series.Y = append(series.Y, units.Pressure(sweep[j][i].DestinationCylinderPressure))
+		}
+		chart.Series = append(chart.Series, series)
+	}
+	return chart
+}
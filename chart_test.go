@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestChartTicks(t *testing.T) {
+	ticks := chartTicks(48, 232)
+	if ticks[0] != 0 || ticks[len(ticks)-1] != 250 || len(ticks) != 6 {
+		t.Errorf("Invalid ticks %v", ticks)
+	}
+	if ticks := chartTicks(5, 5); len(ticks) < 2 {
+		t.Errorf("Expected a range around a single value, got %v", ticks)
+	}
+}
+
+func TestTransferStepChart(t *testing.T) {
+	steps := []TransferStep{
+		{Configuration: "destination manifold closed", Step: 1, Source: "source", Destination: "left", SourcePressureBefore: 232, SourcePressureAfter: 200, DestinationPressureBefore: 50, DestinationPressureAfter: 200},
+		{Configuration: "destination manifold closed", Step: 2, Source: "source", Destination: "right", SourcePressureBefore: 200, SourcePressureAfter: 180, DestinationPressureBefore: 60, DestinationPressureAfter: 180},
+		{Configuration: "all manifolds open", Step: 1, Source: "source", Destination: "destination", SourcePressureBefore: 232, SourcePressureAfter: 190, DestinationPressureBefore: 55, DestinationPressureAfter: 190},
+	}
+	chart := transferStepChart(steps, "destination manifold closed", Metric)
+	if len(chart.Series) != 3 || chart.Series[0].Name != "source" || len(chart.Series[0].X) != 3 || chart.Series[0].Y[2] != 180 {
+		t.Fatalf("Invalid chart %+v", chart)
+	}
+	if right := chart.Series[2]; right.Name != "right" || right.X[0] != 0 || right.Y[0] != 60 || right.X[1] != 2 {
+		t.Errorf("Invalid series %+v", right)
+	}
+
+	var svg bytes.Buffer
+	chart.Title = "<fill>"
+	if err := chart.WriteSVG(&svg); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(svg.String(), "<polyline") != 3 || !strings.Contains(svg.String(), "&lt;fill&gt;") {
+		t.Errorf("Invalid SVG:\n%s", svg.String())
+	}
+	var image bytes.Buffer
+	if err := chart.WritePNG(&image); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(&image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds := decoded.Bounds(); bounds.Dx() != chartWidth || bounds.Dy() != chartHeight {
+		t.Errorf("Invalid PNG size %v", bounds)
+	}
+}
+
+func TestWriteChartFormat(t *testing.T) {
+	if err := writeChart(t.TempDir()+"/chart.jpg", Chart{}); err == nil {
+		t.Error("Expected an error for an unknown chart format")
+	}
+}
@@ -0,0 +1,146 @@
+// Package client is a typed Go client for the HTTP API of the scuba transfer whip calculator ("serve" command).
+// Types follow the OpenAPI document served at /openapi.json. Volumes are in liters and pressures in bar.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ScenarioCylinder is a single cylinder of an equalize request
+type ScenarioCylinder struct {
+	Description string  `json:"description,omitempty"`
+	Volume      float64 `json:"volume"`
+	Pressure    float64 `json:"pressure"`
+	Mix         string  `json:"mix,omitempty"`
+	// WorkingPressure and TestPressure are the rated pressures in bar; zero when not known
+	WorkingPressure float64 `json:"working_pressure,omitempty"`
+	TestPressure    float64 `json:"test_pressure,omitempty"`
+	OxygenClean     bool    `json:"oxygen_clean,omitempty"`
+}
+
+// EqualizeRequest describes source and destination cylinders, with a default mix for cylinders without one
+type EqualizeRequest struct {
+	Source      []ScenarioCylinder `json:"source"`
+	Destination []ScenarioCylinder `json:"destination"`
+	Mix         string             `json:"mix,omitempty"`
+}
+
+// EqualizeResponse holds a summary for each manifold configuration
+type EqualizeResponse struct {
+	Summaries []SummaryResponse `json:"summaries"`
+}
+
+// SummaryResponse is the result of a single manifold configuration
+type SummaryResponse struct {
+	Description          string  `json:"description"`
+	SourcePressure       float64 `json:"source_pressure"`
+	SourceGasVolume      float64 `json:"source_gas_volume"`
+	SourceMix            string  `json:"source_mix"`
+	DestinationPressure  float64 `json:"destination_pressure"`
+	DestinationGasVolume float64 `json:"destination_gas_volume"`
+	DestinationMix       string  `json:"destination_mix"`
+	ImprovementPercent   float64 `json:"improvement_percent"`
+}
+
+// BlendRequest describes a partial pressure blend
+type BlendRequest struct {
+	Target         string  `json:"target"`
+	TargetPressure float64 `json:"target_pressure"`
+	CylinderVolume float64 `json:"cylinder_volume"`
+	TopUp          string  `json:"top_up,omitempty"`
+	StartPressure  float64 `json:"start_pressure,omitempty"`
+	StartMix       string  `json:"start_mix,omitempty"`
+}
+
+// BlendResponse is a blend plan
+type BlendResponse struct {
+	Target          string              `json:"target"`
+	TargetPressure  float64             `json:"target_pressure"`
+	DrainRequired   bool                `json:"drain_required"`
+	DrainToPressure float64             `json:"drain_to_pressure,omitempty"`
+	Steps           []BlendStepResponse `json:"steps"`
+}
+
+// BlendStepResponse is a single gas addition of a blend plan
+type BlendStepResponse struct {
+	Description    string  `json:"description"`
+	Mix            string  `json:"mix"`
+	AddedPressure  float64 `json:"added_pressure"`
+	FillToPressure float64 `json:"fill_to_pressure"`
+	AddedGasVolume float64 `json:"added_gas_volume"`
+}
+
+// Error is returned when the server rejects a request
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Client calls the calculator HTTP API
+type Client struct {
+	// BaseURL is the server address, e.g. http://localhost:8080
+	BaseURL string
+	// HTTPClient is used for requests; http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// New returns a client for the server at baseURL
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Equalize calls POST /equalize
+func (c *Client) Equalize(ctx context.Context, request EqualizeRequest) (*EqualizeResponse, error) {
+	var response EqualizeResponse
+	if err := c.post(ctx, "/equalize", request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Blend calls POST /blend
+func (c *Client) Blend(ctx context.Context, request BlendRequest) (*BlendResponse, error) {
+	var response BlendResponse
+	if err := c.post(ctx, "/blend", request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (c *Client) post(ctx context.Context, path string, request interface{}, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	httpResponse, err := httpClient.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode != http.StatusOK {
+		var errorResponse struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(httpResponse.Body).Decode(&errorResponse)
+		return &Error{StatusCode: httpResponse.StatusCode, Message: errorResponse.Error}
+	}
+	return json.NewDecoder(httpResponse.Body).Decode(response)
+}
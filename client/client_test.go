@@ -0,0 +1,22 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"error": "source pressure must be higher than destination pressure"}`))
+	}))
+	defer server.Close()
+	_, err := New(server.URL).Equalize(context.Background(), EqualizeRequest{})
+	var apiError *Error
+	if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusUnprocessableEntity || apiError.Message != "source pressure must be higher than destination pressure" {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
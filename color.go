@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ansiColor is an ANSI escape sequence setting the color of terminal output
+type ansiColor string
+
+const (
+	colorReset ansiColor = "\x1b[0m"
+	// colorCaution marks warnings to check, such as a bottle too small for a planned fill
+	colorCaution ansiColor = "\x1b[33m"
+	// colorDanger marks warnings of unsafe fills: exceeded cylinder ratings, oxygen cleaning and unbreathable gas
+	colorDanger ansiColor = "\x1b[1;31m"
+	// colorBest highlights the best configuration of the summary table
+	colorBest ansiColor = "\x1b[1;32m"
+)
+
+// colorWriter marks output that is printed in color
+type colorWriter struct {
+	io.Writer
+}
+
+// newColorWriter returns a writer printing in color when w is a terminal, or w itself when it is not, with
+// -no-color or with the NO_COLOR environment variable set
+func newColorWriter(w io.Writer, noColor bool) io.Writer {
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return w
+	}
+	if !isTerminal(w) {
+		return w
+	}
+	return &colorWriter{Writer: w}
+}
+
+// isTerminal returns whether w is a terminal
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorEnabled returns whether w prints in color, looking through the locale and language writers wrapping it
+func colorEnabled(w io.Writer) bool {
+	for {
+		switch v := w.(type) {
+		case *colorWriter:
+			return true
+		case *languageWriter:
+			w = v.Writer
+		case *localeWriter:
+			w = v.w
+		default:
+			return false
+		}
+	}
+}
+
+// colorize returns the text in the color when w prints in color. A trailing newline is kept outside of the color.
+func colorize(w io.Writer, color ansiColor, text string) string {
+	if !colorEnabled(w) {
+		return text
+	}
+	line, hasNewline := strings.CutSuffix(text, "\n")
+	text = string(color) + line + string(colorReset)
+	if hasNewline {
+		text += "\n"
+	}
+	return text
+}
+
+// setColor switches the color of the following output when w prints in color
+func setColor(w io.Writer, color ansiColor) {
+	if colorEnabled(w) {
+		io.WriteString(w, string(color))
+	}
+}
+
+// warnf prints a warning in the color
+func warnf(w io.Writer, color ansiColor, format string, args ...any) {
+	fmt.Fprint(w, colorize(w, color, fmt.Sprintf(format, args...)))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestColorize(t *testing.T) {
+	var output strings.Builder
+	w := newLanguageWriter(newLocaleWriter(&colorWriter{Writer: &output}, locales["fi"]), Language{Name: "fi", messages: finnishMessages})
+	warnf(w, colorDanger, tr(w, "Warning: gas density is above the maximum of %.1fg/l\n"), 6.2)
+	if expected := "\x1b[1;31mVaroitus: kaasun tiheys ylittää enimmäisarvon 6,2g/l\x1b[0m\n"; output.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, output.String())
+	}
+	if text := colorize(&output, colorDanger, "Warning\n"); text != "Warning\n" {
+		t.Errorf("Expected no colors without a color writer, got %q", text)
+	}
+}
+
+func TestNewColorWriter(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "output"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if w := newColorWriter(file, false); w != file {
+		t.Error("Expected no colors for a file")
+	}
+	if w := newColorWriter(os.Stdout, true); w != os.Stdout {
+		t.Error("Expected no colors with -no-color")
+	}
+}
+
+func TestPrintSummariesHighlightsBest(t *testing.T) {
+	cylinderSummaries := []CylinderSummary{
+		{Description: "both manifolds closed", DestinationCylinderPressure: 120},
+		{Description: "all manifolds open", DestinationCylinderPressure: 100},
+	}
+	var output strings.Builder
+	printSummaries(&colorWriter{Writer: &output}, cylinderSummaries, summaryOptions{}, Metric, false)
+	lines := strings.Split(output.String(), "\n")
+	if !strings.HasPrefix(lines[1], string(colorBest)) || !strings.HasSuffix(lines[1], string(colorReset)) {
+		t.Errorf("Expected the best configuration highlighted, got %q", lines[1])
+	}
+	if strings.Contains(lines[2], "\x1b") {
+		t.Errorf("Expected other configurations without colors, got %q", lines[2])
+	}
+}
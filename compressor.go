@@ -0,0 +1,55 @@
+package main
+
+// compressorIterations is the number of refinements used when the mix changes during top-off
+const compressorIterations = 5
+
+// Compressor is a breathing air compressor
+type Compressor struct {
+	// FreeAirDelivery is the compressor output in liters of free air per minute
+	FreeAirDelivery float64
+	MaxPressure     PressureBar
+}
+
+// CompressorResult describes a compressor top-off
+type CompressorResult struct {
+	PressureBefore PressureBar
+	PressureAfter  PressureBar
+	GasVolume      GasVolume
+	Minutes        float64
+	TargetReached  bool
+}
+
+// compressorAir is the gas delivered by the compressor
+var compressorAir = GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+
+// TopOff fills the cylinder with air to the target pressure, or to compressor maximum pressure if it is lower,
+// and returns the time required.
+func (c Compressor) TopOff(cylinder *Cylinder, targetPressure PressureBar, gasSystem GasSystem, temperature Temperature) CompressorResult {
+	result := CompressorResult{
+		PressureBefore: cylinder.Pressure,
+		PressureAfter:  cylinder.Pressure,
+		TargetReached:  cylinder.Pressure >= targetPressure,
+	}
+	fillPressure := targetPressure
+	if c.MaxPressure < fillPressure {
+		fillPressure = c.MaxPressure
+	}
+	if cylinder.Pressure >= fillPressure {
+		return result
+	}
+	for i := 0; i < compressorIterations; i++ {
+		// Required amount depends on the resulting mix, so refine with the mix after each addition
+		target := Cylinder{CylinderVolume: cylinder.CylinderVolume, Pressure: fillPressure, GasComposition: cylinder.GasComposition}
+		gasVolume := target.GasVolume(gasSystem, temperature) - cylinder.GasVolume(gasSystem, temperature)
+		if gasVolume <= 0 {
+			break
+		}
+		cylinder.AddGas(compressorAir, gasVolume, gasSystem, temperature)
+		result.GasVolume += gasVolume
+	}
+	cylinder.Pressure = fillPressure
+	result.PressureAfter = fillPressure
+	result.TargetReached = fillPressure >= targetPressure
+	result.Minutes = float64(result.GasVolume) / c.FreeAirDelivery
+	return result
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestCompressorTopOff(t *testing.T) {
+	cylinder := Cylinder{CylinderVolume: 24, Pressure: 150, GasComposition: GasComposition{Oxygen: 0.32, Nitrogen: 0.68}}
+	compressor := Compressor{FreeAirDelivery: 100, MaxPressure: 300}
+	result := compressor.TopOff(&cylinder, 232, IdealGas, 293.15)
+	if !result.TargetReached || cylinder.Pressure != 232 {
+		t.Errorf("Expected to reach 232 bar, got %+v", result)
+	}
+	if !compareFloats(float64(result.GasVolume), 24*82) || !compareFloats(result.Minutes, 24*82/100.0) {
+		t.Errorf("Invalid compressor result %+v", result)
+	}
+	expectedOxygen := (150*0.32 + 82*0.21) / 232
+	if !compareFloats(cylinder.GasComposition[Oxygen], expectedOxygen) {
+		t.Errorf("Invalid oxygen fraction %f, expected %f", cylinder.GasComposition[Oxygen], expectedOxygen)
+	}
+}
+
+func TestCompressorMaxPressure(t *testing.T) {
+	cylinder := Cylinder{CylinderVolume: 12, Pressure: 150, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}
+	compressor := Compressor{FreeAirDelivery: 100, MaxPressure: 200}
+	result := compressor.TopOff(&cylinder, 232, IdealGas, 293.15)
+	if result.TargetReached || result.PressureAfter != 200 {
+		t.Errorf("Expected compressor to stop at 200 bar, got %+v", result)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// ConsumptionPeriod is the oxygen and helium consumed by the fills of a week or a month
+type ConsumptionPeriod struct {
+	Period       string
+	Fills        int
+	OxygenVolume GasVolume
+	HeliumVolume GasVolume
+}
+
+// consumptionPeriodName returns the ISO week ("2024-W07") or the month ("2024-02") of the time
+func consumptionPeriodName(t time.Time, period string) string {
+	if period == "week" {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return t.Format("2006-01")
+}
+
+// ConsumptionByPeriod sums the oxygen and helium of fill log entries by week or month, oldest first
+func ConsumptionByPeriod(entries []FillLogEntry, period string) []ConsumptionPeriod {
+	periods := make(map[string]*ConsumptionPeriod)
+	for _, entry := range entries {
+		name := consumptionPeriodName(entry.Time.Local(), period)
+		consumption, ok := periods[name]
+		if !ok {
+			consumption = &ConsumptionPeriod{Period: name}
+			periods[name] = consumption
+		}
+		consumption.Fills++
+		consumption.OxygenVolume += entry.OxygenVolume
+		consumption.HeliumVolume += entry.HeliumVolume
+	}
+	consumptions := make([]ConsumptionPeriod, 0, len(periods))
+	for _, consumption := range periods {
+		consumptions = append(consumptions, *consumption)
+	}
+	sort.Slice(consumptions, func(i, j int) bool {
+		return consumptions[i].Period < consumptions[j].Period
+	})
+	return consumptions
+}
+
+// ErrNoHeliumFills is returned when the fill log has no fills using helium to forecast from
+var ErrNoHeliumFills = errors.New("no fills using helium in the fill log")
+
+// HeliumForecast tells how long a helium bank lasts at the recent helium consumption. The bank is unusable once it
+// no longer reaches the helium pressure of the typical fill.
+type HeliumForecast struct {
+	TypicalMix         GasComposition
+	TypicalPressure    PressureBar
+	UnusablePressure   PressureBar
+	DailyHeliumVolume  GasVolume
+	UsableHeliumVolume GasVolume
+	Days               float64
+	Date               time.Time
+}
+
+// typicalHeliumFill returns the most common mix of fills using helium and their average pressure, relative to the
+// pressure reference
+func typicalHeliumFill(entries []FillLogEntry) (string, float64) {
+	counts := make(map[string]int)
+	pressures := make(map[string]float64)
+	var typicalMix string
+	for _, entry := range entries {
+		if entry.HeliumVolume <= 0 {
+			continue
+		}
+		counts[entry.Mix]++
+		pressures[entry.Mix] += entry.Pressure
+		if counts[entry.Mix] > counts[typicalMix] || (counts[entry.Mix] == counts[typicalMix] && entry.Mix < typicalMix) {
+			typicalMix = entry.Mix
+		}
+	}
+	if typicalMix == "" {
+		return "", 0
+	}
+	return typicalMix, pressures[typicalMix] / float64(counts[typicalMix])
+}
+
+// ForecastHelium forecasts when the helium bank becomes unusable at the helium consumption of the fills during the
+// window before now. If unusablePressure is zero, it is the pressure the typical fill is filled to with helium in a
+// partial pressure blend.
+func ForecastHelium(entries []FillLogEntry, bank Cylinder, unusablePressure PressureBar, window time.Duration, now time.Time, gasSystem GasSystem, temperature Temperature, units UnitSystem) (HeliumForecast, error) {
+	var forecast HeliumForecast
+	var recentEntries []FillLogEntry
+	var heliumVolume GasVolume
+	for _, entry := range entries {
+		if entry.Time.After(now.Add(-window)) && !entry.Time.After(now) {
+			recentEntries = append(recentEntries, entry)
+			heliumVolume += entry.HeliumVolume
+		}
+	}
+	typicalMix, typicalPressure := typicalHeliumFill(recentEntries)
+	if typicalMix == "" {
+		return forecast, ErrNoHeliumFills
+	}
+	var err error
+	if forecast.TypicalMix, err = ParseGasComposition(typicalMix); err != nil {
+		return forecast, fmt.Errorf("invalid mix in the fill log: %w", err)
+	}
+	forecast.TypicalPressure = units.AbsolutePressure(PressureBar(typicalPressure))
+	forecast.UnusablePressure = unusablePressure
+	if forecast.UnusablePressure == 0 {
+		empty := Cylinder{CylinderVolume: 12, Pressure: units.AmbientPressure, GasComposition: GasComposition{Nitrogen: 0.79, Oxygen: 0.21}}
+		plan, err := PlanBlend(empty, forecast.TypicalMix, forecast.TypicalPressure, empty.GasComposition, units.AmbientPressure, gasSystem, temperature)
+		if err != nil {
+			return forecast, err
+		}
+		for _, step := range plan.Steps {
+			if step.GasComposition[Helium] == 1 {
+				forecast.UnusablePressure = step.FillToPressure
+			}
+		}
+	}
+
+	// Consumption per day over the window, or since the first fill for shorter logs
+	days := now.Sub(recentEntries[0].Time).Hours() / 24
+	if days < 1 {
+		days = 1
+	}
+	forecast.DailyHeliumVolume = heliumVolume / GasVolume(days)
+	unusable := Cylinder{CylinderVolume: bank.CylinderVolume, Pressure: forecast.UnusablePressure, GasComposition: bank.GasComposition}
+	forecast.UsableHeliumVolume = bank.GasVolume(gasSystem, temperature)*GasVolume(bank.GasComposition[Helium]) - unusable.GasVolume(gasSystem, temperature)*GasVolume(unusable.GasComposition[Helium])
+	if forecast.UsableHeliumVolume < 0 {
+		forecast.UsableHeliumVolume = 0
+	}
+	forecast.Days = float64(forecast.UsableHeliumVolume / forecast.DailyHeliumVolume)
+	forecast.Date = now.Add(time.Duration(forecast.Days * 24 * float64(time.Hour)))
+	return forecast, nil
+}
+
+func consumptionMain(args []string) error {
+	fs := flag.NewFlagSet("consumption", flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	var periodFlag = fs.String("period", "week", "Sum consumption by week or month")
+	var heliumBankFlag = fs.String("helium-bank", "", "Forecast when this bank from the bank state file becomes unusable")
+	var bankStateFlag = fs.String("bank-state", "", "Bank state file; defaults to banks.json in the user configuration directory")
+	var unusablePressureFlag = fs.String("unusable-pressure", "", "Pressure below which the helium bank is unusable; defaults to the helium pressure of the typical fill")
+	var forecastWeeksFlag = fs.Int("forecast-weeks", 4, "Forecast from the consumption of this many recent weeks")
+	fs.Parse(args)
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	w, err := flags.output(os.Stdout, units)
+	if err != nil {
+		return err
+	}
+	gasSystem, temperature, err := flags.gasSettings(units)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: consumption [flags] fills.db")
+	}
+	if *periodFlag != "week" && *periodFlag != "month" {
+		return errors.New("invalid period; must be week or month")
+	}
+	if *forecastWeeksFlag <= 0 {
+		return errors.New("invalid forecast weeks; must be >0")
+	}
+	entries, err := ReadFillLog(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("unable to read fill log: %w", err)
+	}
+	printConsumption(w, ConsumptionByPeriod(entries, *periodFlag), units)
+	if *heliumBankFlag == "" {
+		return nil
+	}
+
+	statePath := *bankStateFlag
+	if statePath == "" {
+		statePath = defaultBankStatePath()
+	}
+	state, err := LoadBankState(statePath)
+	if err != nil {
+		return fmt.Errorf("unable to load bank state: %w", err)
+	}
+	banks, err := state.Cylinders([]string{*heliumBankFlag}, units)
+	if err != nil {
+		return fmt.Errorf("invalid bank: %w", err)
+	}
+	banks.SetDefaultGasComposition(GasComposition{Helium: 1})
+	var unusablePressure PressureBar
+	if *unusablePressureFlag != "" {
+		if unusablePressure, err = units.ParsePressure(*unusablePressureFlag); err != nil {
+			return fmt.Errorf("invalid unusable pressure: %w", err)
+		}
+	}
+	forecast, err := ForecastHelium(entries, banks[0], unusablePressure, time.Duration(*forecastWeeksFlag)*7*24*time.Hour, time.Now(), gasSystem, temperature, units)
+	if err != nil {
+		return fmt.Errorf("unable to forecast helium: %w", err)
+	}
+	printHeliumForecast(w, banks[0], forecast, units)
+	return nil
+}
+
+func printConsumption(w io.Writer, consumptions []ConsumptionPeriod, units UnitSystem) {
+	volumeUnit := units.VolumeUnit()
+	fmt.Fprintf(w, "%10s %6s %10s %10s\n", "period", "fills", "O2 "+volumeUnit, "He "+volumeUnit)
+	for _, consumption := range consumptions {
+		fmt.Fprintf(w, "%10s %6d %10.0f %10.0f\n", consumption.Period, consumption.Fills, units.Volume(consumption.OxygenVolume), units.Volume(consumption.HeliumVolume))
+	}
+}
+
+func printHeliumForecast(w io.Writer, bank Cylinder, forecast HeliumForecast, units UnitSystem) {
+	pressureUnit, volumeUnit := units.PressureUnit(), units.VolumeUnit()
+	fmt.Fprintf(w, "Typical helium fill: %s to %.0f%s, %.0f%s of helium per day\n", forecast.TypicalMix, units.Pressure(forecast.TypicalPressure), pressureUnit, units.Volume(forecast.DailyHeliumVolume), volumeUnit)
+	fmt.Fprintf(w, "Helium bank %s: %.0f%s usable above %.0f%s, lasts %.1f days (until %s)\n", bank.Description, units.Volume(forecast.UsableHeliumVolume), volumeUnit, units.Pressure(forecast.UnusablePressure), pressureUnit, forecast.Days, forecast.Date.Format("2006-01-02"))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConsumptionByPeriod(t *testing.T) {
+	entries := []FillLogEntry{
+		{Time: time.Date(2024, 2, 26, 12, 0, 0, 0, time.Local), OxygenVolume: 100, HeliumVolume: 500},
+		{Time: time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local), OxygenVolume: 200, HeliumVolume: 1000},
+		{Time: time.Date(2024, 3, 4, 12, 0, 0, 0, time.Local), OxygenVolume: 50},
+	}
+	weeks := ConsumptionByPeriod(entries, "week")
+	if len(weeks) != 2 || weeks[0].Period != "2024-W09" || weeks[0].Fills != 2 || weeks[0].HeliumVolume != 1500 || weeks[1].OxygenVolume != 50 {
+		t.Errorf("Invalid weekly consumption %+v", weeks)
+	}
+	months := ConsumptionByPeriod(entries, "month")
+	if len(months) != 2 || months[0].Period != "2024-02" || months[1].Period != "2024-03" || months[1].OxygenVolume != 250 {
+		t.Errorf("Invalid monthly consumption %+v", months)
+	}
+}
+
+func TestForecastHelium(t *testing.T) {
+	now := time.Date(2024, 3, 11, 12, 0, 0, 0, time.UTC)
+	entries := []FillLogEntry{
+		{Time: now.Add(-60 * 24 * time.Hour), Mix: "10/70", Pressure: 200, HeliumVolume: 5000},
+		{Time: now.Add(-10 * 24 * time.Hour), Mix: "18.0/45.0", Pressure: 200, HeliumVolume: 600},
+		{Time: now.Add(-5 * 24 * time.Hour), Mix: "18.0/45.0", Pressure: 200, HeliumVolume: 600},
+		{Time: now.Add(-2 * 24 * time.Hour), Mix: "EAN32.0", Pressure: 232, OxygenVolume: 300},
+	}
+	bank := Cylinder{Description: "he", CylinderVolume: 50, Pressure: 200, GasComposition: GasComposition{Helium: 1}}
+	forecast, err := ForecastHelium(entries, bank, 0, 28*24*time.Hour, now, IdealGas, 293.15, Metric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1200 l over 10 days; the typical 18/45 blend to 200 bar fills helium to 90 bar, leaving 110 bar of the bank
+	if !compareFloats(float64(forecast.DailyHeliumVolume), 120) || !compareFloats(float64(forecast.UnusablePressure), 90) {
+		t.Errorf("Invalid forecast %+v", forecast)
+	}
+	if !compareFloats(float64(forecast.UsableHeliumVolume), 50*110) || !compareFloats(forecast.Days, 50*110/120.0) {
+		t.Errorf("Invalid usable helium %+v", forecast)
+	}
+
+	if _, err := ForecastHelium(entries[3:], bank, 0, 28*24*time.Hour, now, IdealGas, 293.15, Metric); !errors.Is(err, ErrNoHeliumFills) {
+		t.Errorf("Expected ErrNoHeliumFills, got %v", err)
+	}
+}
+
+func TestReadFillLog(t *testing.T) {
+	if _, err := exec.LookPath(sqliteCommand); err != nil {
+		t.Skip("sqlite3 is not installed")
+	}
+	path := filepath.Join(t.TempDir(), "fills.db")
+	entry := FillLogEntry{
+		Time:          time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Command:       "blend",
+		Configuration: "partial pressure",
+		Cylinders:     Scenario{Destination: []ScenarioCylinder{{Description: "cylinder, \"left\"", Volume: 12}}},
+		Mix:           "18.0/45.0",
+		Pressure:      200,
+		HeliumVolume:  1100.5,
+		TopUpVolume:   900,
+	}
+	if err := AppendFillLog(path, entry); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ReadFillLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || !entries[0].Time.Equal(entry.Time) || entries[0].Mix != entry.Mix || entries[0].HeliumVolume != 1100.5 || entries[0].TopUpVolume != 900 || entries[0].Cylinders.Destination[0].Description != "cylinder, \"left\"" {
+		t.Errorf("Invalid entries %+v", entries)
+	}
+	if _, err := ReadFillLog(filepath.Join(t.TempDir(), "missing.db")); err == nil {
+		t.Error("Expected error for missing fill log")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ojarva/scuba-whip-calculator-go/units"
+)
+
+// ConvertQuantity parses a pressure, cylinder volume, temperature or length with a unit suffix and returns it in
+// all supported units. Pressures are converted as given, without a gauge or absolute reference.
+func ConvertQuantity(s string) ([]string, error) {
+	_, unit, err := Metric.splitQuantity(s)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(s, "@") {
+		volume, err := Metric.ParseCylinderVolume(s)
+		if err != nil {
+			return nil, err
+		}
+		return []string{fmt.Sprintf("%.1fl", volume), fmt.Sprintf("%.2fcuft", units.LitersToCubicFeet(float64(volume)))}, nil
+	}
+	switch {
+	case unit == "":
+		return nil, fmt.Errorf("%q needs a unit", s)
+	case pressureUnits[unit] != 0:
+		pressure, _ := Metric.ParsePressureDifference(s)
+		return []string{
+			fmt.Sprintf("%.1fbar", pressure),
+			fmt.Sprintf("%.0fpsi", units.BarToPSI(float64(pressure))),
+			fmt.Sprintf("%.2fMPa", units.BarToMPa(float64(pressure))),
+			fmt.Sprintf("%.0fkPa", units.BarToKPa(float64(pressure))),
+			fmt.Sprintf("%.2fatm", units.BarToAtm(float64(pressure))),
+		}, nil
+	case cylinderVolumeUnits[unit] != 0:
+		volume, _ := Metric.ParseCylinderVolume(s)
+		return []string{fmt.Sprintf("%.1fl", volume), fmt.Sprintf("%.2fcuft", units.LitersToCubicFeet(float64(volume)))}, nil
+	case lengthUnits[unit] != 0:
+		length, _ := Metric.ParseLength(s)
+		return []string{fmt.Sprintf("%.1fm", length), fmt.Sprintf("%.0fft", units.MetersToFeet(length))}, nil
+	}
+	temperature, err := Metric.ParseTemperature(s)
+	if err != nil {
+		return nil, fmt.Errorf("unknown unit %q in %q", unit, s)
+	}
+	return []string{
+		fmt.Sprintf("%.1fC", units.KelvinToCelsius(float64(temperature))),
+		fmt.Sprintf("%.1fF", units.KelvinToFahrenheit(float64(temperature))),
+		fmt.Sprintf("%.2fK", temperature),
+	}, nil
+}
+
+func convertMain(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: convert <value with unit>...; e.g. convert 3000psi 12l 77.4cuft@3000psi 68F 100ft")
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	return printConversions(os.Stdout, fs.Args())
+}
+
+// printConversions writes each value converted to all supported units on a line of its own
+func printConversions(w io.Writer, values []string) error {
+	for _, value := range values {
+		conversions, err := ConvertQuantity(value)
+		if err != nil {
+			return fmt.Errorf("invalid value: %w", err)
+		}
+		fmt.Fprintln(w, value, "=", strings.Join(conversions, " = "))
+	}
+	return nil
+}
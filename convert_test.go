@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertQuantity(t *testing.T) {
+	tests := map[string]string{
+		"200bar":           "200.0bar 2901psi 20.00MPa 20000kPa 197.38atm",
+		"12l":              "12.0l 0.42cuft",
+		"30m":              "30.0m 98ft",
+		"68F":              "20.0C 68.0F 293.15K",
+		"77.4cuft@3000psi": "10.9l 0.38cuft",
+	}
+	for value, expected := range tests {
+		conversions, err := ConvertQuantity(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(conversions, " ") != expected {
+			t.Errorf("Invalid conversion for %s, expected %q, got %q", value, expected, strings.Join(conversions, " "))
+		}
+	}
+	if _, err := ConvertQuantity("200"); err == nil {
+		t.Error("Expected an error for a value without a unit")
+	}
+}
+
+func TestPrintConversions(t *testing.T) {
+	var output strings.Builder
+	if err := printConversions(&output, []string{"12l", "30m"}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "12l = 12.0l = 0.42cuft\n30m = 30.0m = 98ft\n"; output.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, output.String())
+	}
+	if err := printConversions(&output, []string{"200"}); err == nil {
+		t.Error("Expected an error for a value without a unit")
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ojarva/scuba-whip-calculator-go/units"
+)
+
+// GasPrices are prices per liter of gas at surface pressure
+type GasPrices struct {
+	Helium   float64
+	Oxygen   float64
+	Air      float64
+	Currency string
+}
+
+// GasCost is the cost of gas, split by the gas it is priced as
+type GasCost struct {
+	Helium   float64
+	Oxygen   float64
+	Air      float64
+	Total    float64
+	Currency string
+}
+
+// String returns the total cost with the split, e.g. "€12.50 (helium €10.00, oxygen €1.00, air €1.50)"
+func (c GasCost) String() string {
+	return fmt.Sprintf("%s%.2f (helium %s%.2f, oxygen %s%.2f, air %s%.2f)", c.Currency, c.Total, c.Currency, c.Helium, c.Currency, c.Oxygen, c.Currency, c.Air)
+}
+
+// Cost returns the cost of gas volumes. Helium is priced as helium; nitrogen and other gases are priced as air, and
+// oxygen not supplied by that air as oxygen.
+func (p GasPrices) Cost(gasVolumes map[Gas]GasVolume) GasCost {
+	var airVolume GasVolume
+	for gasType, gasVolume := range gasVolumes {
+		if gasType != Helium && gasType != Oxygen {
+			airVolume += gasVolume
+		}
+	}
+	airVolume /= GasVolume(1 - compressorAir[Oxygen])
+	oxygenVolume := gasVolumes[Oxygen] - airVolume*GasVolume(compressorAir[Oxygen])
+	if oxygenVolume < 0 {
+		// Hypoxic gas: the air brings more oxygen than there is
+		oxygenVolume = 0
+	}
+	cost := GasCost{
+		Helium:   float64(gasVolumes[Helium]) * p.Helium,
+		Oxygen:   float64(oxygenVolume) * p.Oxygen,
+		Air:      float64(airVolume) * p.Air,
+		Currency: p.Currency,
+	}
+	cost.Total = cost.Helium + cost.Oxygen + cost.Air
+	return cost
+}
+
+// takenGasVolumes returns gas taken from the source cylinders once they hold remainingGasVolume, split by the
+// starting mix of the sources
+func takenGasVolumes(sourceCylinders CylinderList, remainingGasVolume GasVolume, gasSystem GasSystem, temperature Temperature) map[Gas]GasVolume {
+	sourceGasVolumes := make(map[Gas]GasVolume)
+	var sourceGasVolume GasVolume
+	for _, cylinder := range sourceCylinders {
+		for gasType, gasVolume := range cylinder.GasVolumes(gasSystem, temperature) {
+			sourceGasVolumes[gasType] += gasVolume
+			sourceGasVolume += gasVolume
+		}
+	}
+	taken := make(map[Gas]GasVolume)
+	if sourceGasVolume <= 0 {
+		return taken
+	}
+	for gasType, gasVolume := range sourceGasVolumes {
+		taken[gasType] = (sourceGasVolume - remainingGasVolume) * gasVolume / sourceGasVolume
+	}
+	return taken
+}
+
+// blendGasVolumes returns the gas added by the steps of a blend plan
+func blendGasVolumes(plan BlendPlan) map[Gas]GasVolume {
+	gasVolumes := make(map[Gas]GasVolume)
+	for _, step := range plan.Steps {
+		for gasType, fraction := range step.GasComposition {
+			gasVolumes[gasType] += step.AddedGasVolume * GasVolume(fraction)
+		}
+	}
+	return gasVolumes
+}
+
+// gasVolumeUnits maps gas volume unit suffixes of prices to liters
+var gasVolumeUnits = map[string]float64{
+	"l":    1,
+	"m3":   1000,
+	"cuft": units.CubicFeetToLiters(1),
+	"ft3":  units.CubicFeetToLiters(1),
+}
+
+// ParsePrice parses a price of gas such as "0.05/l", "50/m3" or "1.4/cuft" and returns the price per liter. Prices
+// without a unit are per liter, or per cubic foot with imperial units.
+func (u UnitSystem) ParsePrice(s string) (float64, error) {
+	value, unit, _ := strings.Cut(strings.TrimSpace(s), "/")
+	price, err := strconv.ParseFloat(u.Locale.normalizeNumber(strings.TrimSpace(value)), 64)
+	if err != nil || price < 0 {
+		return 0, fmt.Errorf("invalid price %q", s)
+	}
+	unit = strings.ToLower(strings.TrimSpace(unit))
+	if unit == "" {
+		unit = u.VolumeUnit()
+	}
+	liters, ok := gasVolumeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown volume unit %q in price %q", unit, s)
+	}
+	return price / liters, nil
+}
+
+// priceFlags holds flags for gas prices
+type priceFlags struct {
+	helium   *string
+	oxygen   *string
+	air      *string
+	currency *string
+}
+
+func registerPriceFlags(fs *flag.FlagSet) *priceFlags {
+	return &priceFlags{
+		helium:   fs.String("price-helium", "", "Helium price per volume, e.g. 0.05/l or 50/m3; gas costs are reported when any price is set"),
+		oxygen:   fs.String("price-oxygen", "", "Oxygen price per volume, e.g. 0.01/l"),
+		air:      fs.String("price-air", "", "Air price per volume, e.g. 2/m3"),
+		currency: fs.String("currency", "€", "Currency symbol for gas costs"),
+	}
+}
+
+// prices returns the gas prices, or nil when no price is set
+func (f *priceFlags) prices(units UnitSystem) (*GasPrices, error) {
+	if *f.helium == "" && *f.oxygen == "" && *f.air == "" {
+		return nil, nil
+	}
+	prices := GasPrices{Currency: *f.currency}
+	for _, price := range []struct {
+		name  string
+		value string
+		price *float64
+	}{{"helium", *f.helium, &prices.Helium}, {"oxygen", *f.oxygen, &prices.Oxygen}, {"air", *f.air, &prices.Air}} {
+		if price.value == "" {
+			continue
+		}
+		var err error
+		if *price.price, err = units.ParsePrice(price.value); err != nil {
+			return nil, fmt.Errorf("invalid %s price: %w", price.name, err)
+		}
+	}
+	return &prices, nil
+}
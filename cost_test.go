@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParsePrice(t *testing.T) {
+	for _, test := range []struct {
+		units    UnitSystem
+		price    string
+		expected float64
+	}{
+		{Metric, "0.05/l", 0.05},
+		{Metric, "50/m3", 0.05},
+		{Metric, "0.05", 0.05},
+		{Imperial, "1", 1 / LitersPerCubicFoot},
+		{Metric, "2.8/cuft", 2.8 / LitersPerCubicFoot},
+	} {
+		price, err := test.units.ParsePrice(test.price)
+		if err != nil || !compareFloats(price, test.expected) {
+			t.Errorf("ParsePrice(%q) = %f, %v; expected %f", test.price, price, err, test.expected)
+		}
+	}
+	for _, price := range []string{"", "abc/l", "-1/l", "1/gal"} {
+		if _, err := Metric.ParsePrice(price); err == nil {
+			t.Errorf("Expected error for %q", price)
+		}
+	}
+}
+
+func TestGasPricesCost(t *testing.T) {
+	prices := GasPrices{Helium: 0.05, Oxygen: 0.01, Air: 0.002, Currency: "€"}
+	// 1000 l of 21/35: 440 l nitrogen comes with 557 l of air holding 117 l of oxygen, 93 l is pure oxygen
+	cost := prices.Cost(map[Gas]GasVolume{Oxygen: 210, Helium: 350, Nitrogen: 440})
+	airVolume := 440 / 0.79
+	if !compareFloats(cost.Helium, 17.5) || !compareFloats(cost.Air, airVolume*0.002) || !compareFloats(cost.Oxygen, (210-airVolume*0.21)*0.01) {
+		t.Errorf("Invalid cost %+v", cost)
+	}
+	if !compareFloats(cost.Total, cost.Helium+cost.Oxygen+cost.Air) || !strings.HasPrefix(cost.String(), "€") {
+		t.Errorf("Invalid total %s", cost)
+	}
+	if cost := prices.Cost(map[Gas]GasVolume{Oxygen: 10, Helium: 90}); cost.Air != 0 || !compareFloats(cost.Oxygen, 0.1) {
+		t.Errorf("Invalid heliox cost %+v", cost)
+	}
+}
+
+func TestEqualizeGasCost(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "source", CylinderVolume: 12, Pressure: 232, GasComposition: air}},
+		DestinationCylinders: CylinderList{{Description: "destination", CylinderVolume: 12, Pressure: 80, GasComposition: air.Clone()}},
+		Prices:               &GasPrices{Air: 0.002, Currency: "€"},
+	}
+	cylinderSummaries := equalizeAllConfigurations(io.Discard, cylinderConfiguration, IdealGas, 293.15, Metric, false, nil)
+	// Ideal gas: 12l from 232 to 156 bar
+	if cylinderSummaries[0].GasCost == nil || !compareFloats(cylinderSummaries[0].GasCost.Total, 12*76*0.002) {
+		t.Fatalf("Invalid gas cost %+v", cylinderSummaries[0].GasCost)
+	}
+	var output strings.Builder
+	printSummaries(&output, cylinderSummaries, summaryOptions{}, Metric, false)
+	if !strings.Contains(output.String(), "cost €") || !strings.Contains(output.String(), "1.82") {
+		t.Errorf("Expected cost column:\n%s", output.String())
+	}
+}
+
+func TestBlendGasVolumes(t *testing.T) {
+	plan := BlendPlan{Steps: []BlendStep{
+		{GasComposition: GasComposition{Helium: 1}, AddedGasVolume: 1000},
+		{GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}, AddedGasVolume: 100},
+	}}
+	gasVolumes := blendGasVolumes(plan)
+	if gasVolumes[Helium] != 1000 || !compareFloats(float64(gasVolumes[Oxygen]), 21) || !compareFloats(float64(gasVolumes[Nitrogen]), 79) {
+		t.Errorf("Invalid gas volumes %v", gasVolumes)
+	}
+}
@@ -0,0 +1,32 @@
+//go:build cshared
+
+package main
+
+// #include <stdlib.h>
+import "C"
+
+import (
+	"unsafe"
+)
+
+// EqualizeJSON takes an equalize request as a JSON string, in the same format as the HTTP API, and returns a JSON
+// response. Errors are returned as {"error": "..."}. The response must be released with FreeString.
+//
+//export EqualizeJSON
+func EqualizeJSON(request *C.char) *C.char {
+	return C.CString(string(defaultServer.EqualizeJSON([]byte(C.GoString(request)))))
+}
+
+// BlendJSON takes a blend request as a JSON string and returns a JSON response, like EqualizeJSON
+//
+//export BlendJSON
+func BlendJSON(request *C.char) *C.char {
+	return C.CString(string(defaultServer.BlendJSON([]byte(C.GoString(request)))))
+}
+
+// FreeString releases a string returned by EqualizeJSON or BlendJSON
+//
+//export FreeString
+func FreeString(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
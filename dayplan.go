@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxExhaustiveFills is the largest queue for which every fill order is tried; longer queues are ordered greedily
+const maxExhaustiveFills = 8
+
+// FillRequest is a requested fill: a cylinder with the gas it has now, blended to the target mix and pressure
+type FillRequest struct {
+	Name              string
+	Cylinder          Cylinder
+	TargetComposition GasComposition
+	TargetPressure    PressureBar
+}
+
+// ScheduledFill is a fill of the day with its blend and the banks supplying it
+type ScheduledFill struct {
+	Request  FillRequest
+	Plan     BlendPlan
+	Supplies []BlendSupply
+}
+
+// UnachievableFill is a requested fill that can not be done, with the reason
+type UnachievableFill struct {
+	Request FillRequest
+	Reason  string
+}
+
+// DayPlan is the order of fills completing the most requests with the banks, and the bank pressures after them
+type DayPlan struct {
+	Fills         []ScheduledFill
+	Unachievable  []UnachievableFill
+	BankPressures map[string]PressureBar
+}
+
+// scheduleFill returns the banks supplying a blend. Helium and oxygen must come from banks; top-up gas comes from a
+// bank when one is sufficient and from the compressor otherwise. The reason is set when banks can not supply the fill.
+func scheduleFill(plan BlendPlan, banks CylinderList, gasSystem GasSystem, temperature Temperature) ([]BlendSupply, string) {
+	supplies := PlanBlendSupply(plan, banks, gasSystem, temperature)
+	var usedSupplies []BlendSupply
+	for i, step := range plan.Steps {
+		if step.GasComposition[Helium] != 1 && step.GasComposition[Oxygen] != 1 {
+			for _, supply := range supplies {
+				if supply.Step == i+1 && supply.Sufficient {
+					usedSupplies = append(usedSupplies, supply)
+				}
+			}
+			continue
+		}
+		var stepSupply *BlendSupply
+		for j := range supplies {
+			if supplies[j].Step == i+1 {
+				stepSupply = &supplies[j]
+			}
+		}
+		if stepSupply == nil {
+			return nil, "no bank with " + step.Description
+		}
+		if !stepSupply.Sufficient {
+			return nil, step.Description + " banks are too low"
+		}
+		usedSupplies = append(usedSupplies, *stepSupply)
+	}
+	return usedSupplies, ""
+}
+
+// useSupplies returns the banks with pressures after the supplies
+func useSupplies(banks CylinderList, supplies []BlendSupply) CylinderList {
+	banks = append(CylinderList(nil), banks...)
+	for _, supply := range supplies {
+		for i := range banks {
+			if banks[i].Description == supply.Bank.Description {
+				banks[i].Pressure = supply.BankPressureAfter
+			}
+		}
+	}
+	return banks
+}
+
+// PlanFillDay orders the requested fills to complete as many as possible with the banks, each filled with a partial
+// pressure blend topped up with topUpComposition. Queues up to maxExhaustiveFills are searched exhaustively, keeping
+// the queue order among equally good orders; longer queues fill first the requests needing the highest bank pressure.
+func PlanFillDay(requests []FillRequest, banks CylinderList, topUpComposition GasComposition, ambientPressure PressureBar, gasSystem GasSystem, temperature Temperature) DayPlan {
+	var dayPlan DayPlan
+	var plans []BlendPlan
+	var candidates []FillRequest
+	for _, request := range requests {
+		plan, err := PlanBlend(request.Cylinder, request.TargetComposition, request.TargetPressure, topUpComposition, ambientPressure, gasSystem, temperature)
+		if err != nil {
+			dayPlan.Unachievable = append(dayPlan.Unachievable, UnachievableFill{Request: request, Reason: err.Error()})
+			continue
+		}
+		plans = append(plans, plan)
+		candidates = append(candidates, request)
+	}
+
+	var order []int
+	if len(candidates) <= maxExhaustiveFills {
+		order = bestFillOrder(plans, banks, make([]bool, len(plans)), nil, nil, gasSystem, temperature)
+	} else {
+		order = greedyFillOrder(plans, banks, gasSystem, temperature)
+	}
+	scheduled := make([]bool, len(candidates))
+	for _, i := range order {
+		supplies, _ := scheduleFill(plans[i], banks, gasSystem, temperature)
+		banks = useSupplies(banks, supplies)
+		dayPlan.Fills = append(dayPlan.Fills, ScheduledFill{Request: candidates[i], Plan: plans[i], Supplies: supplies})
+		scheduled[i] = true
+	}
+	for i, request := range candidates {
+		if scheduled[i] {
+			continue
+		}
+		_, reason := scheduleFill(plans[i], banks, gasSystem, temperature)
+		if reason == "" {
+			reason = "banks are used up by other fills"
+		}
+		dayPlan.Unachievable = append(dayPlan.Unachievable, UnachievableFill{Request: request, Reason: reason})
+	}
+	dayPlan.BankPressures = make(map[string]PressureBar)
+	for _, bank := range banks {
+		dayPlan.BankPressures[bank.Description] = bank.Pressure
+	}
+	return dayPlan
+}
+
+// bestFillOrder returns the longest order of the remaining fills that banks can supply, extending order. best is
+// the longest order found so far.
+func bestFillOrder(plans []BlendPlan, banks CylinderList, done []bool, order []int, best []int, gasSystem GasSystem, temperature Temperature) []int {
+	if len(order) > len(best) {
+		best = append([]int(nil), order...)
+	}
+	remaining := 0
+	for _, isDone := range done {
+		if !isDone {
+			remaining++
+		}
+	}
+	if len(order)+remaining <= len(best) {
+		return best
+	}
+	for i, plan := range plans {
+		if done[i] {
+			continue
+		}
+		supplies, reason := scheduleFill(plan, banks, gasSystem, temperature)
+		if reason != "" {
+			continue
+		}
+		done[i] = true
+		best = bestFillOrder(plans, useSupplies(banks, supplies), done, append(order, i), best, gasSystem, temperature)
+		done[i] = false
+		if len(best) == len(plans) {
+			break
+		}
+	}
+	return best
+}
+
+// greedyFillOrder repeatedly picks the fill needing the highest helium or oxygen bank pressure among fills banks
+// can supply
+func greedyFillOrder(plans []BlendPlan, banks CylinderList, gasSystem GasSystem, temperature Temperature) []int {
+	var order []int
+	done := make([]bool, len(plans))
+	for {
+		next := -1
+		var nextSupplies []BlendSupply
+		var nextPressure PressureBar
+		for i, plan := range plans {
+			if done[i] {
+				continue
+			}
+			supplies, reason := scheduleFill(plan, banks, gasSystem, temperature)
+			if reason != "" {
+				continue
+			}
+			var requiredPressure PressureBar
+			for _, step := range plan.Steps {
+				if (step.GasComposition[Helium] == 1 || step.GasComposition[Oxygen] == 1) && step.FillToPressure > requiredPressure {
+					requiredPressure = step.FillToPressure
+				}
+			}
+			if next == -1 || requiredPressure > nextPressure {
+				next, nextSupplies, nextPressure = i, supplies, requiredPressure
+			}
+		}
+		if next == -1 {
+			return order
+		}
+		done[next] = true
+		order = append(order, next)
+		banks = useSupplies(banks, nextSupplies)
+	}
+}
+
+// LoadFillRequests reads requested fills from a CSV file with a header with name, cylinder, target and pressure
+// columns. Cylinders are given as on the command line with their current pressure and mix, e.g. 12l@50bar:21/35.
+func LoadFillRequests(path string, units UnitSystem) ([]FillRequest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	requests, err := parseFillRequests(file, units)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fill queue %s: %w", path, err)
+	}
+	return requests, nil
+}
+
+func parseFillRequests(r io.Reader, units UnitSystem) ([]FillRequest, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"cylinder", "target", "pressure"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing %s column", required)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	var requests []FillRequest
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return requests, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		request := FillRequest{Name: field(record, "name")}
+		if request.Name == "" {
+			request.Name = fmt.Sprintf("line %d", line)
+		}
+		if request.Cylinder, err = units.ParseCylinderSpec(field(record, "cylinder"), request.Name); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if request.Cylinder.GasComposition == nil {
+			request.Cylinder.GasComposition = GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+		}
+		if request.TargetComposition, err = ParseGasComposition(field(record, "target")); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if request.TargetPressure, err = units.ParsePressure(field(record, "pressure")); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := checkCylinders(CylinderList{request.Cylinder, {CylinderVolume: request.Cylinder.CylinderVolume, Pressure: request.TargetPressure}}, "fill", true, units); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		requests = append(requests, request)
+	}
+}
+
+func dayMain(args []string) error {
+	fs := flag.NewFlagSet("day", flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	var topUpFlag = fs.String("top-up", "air", "Top-up gas mix, from a bank or the compressor")
+	bankStateFlags := registerBankStateFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s day [flags] <fills.csv>\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	w, err := flags.output(os.Stdout, units)
+	if err != nil {
+		return err
+	}
+	gasSystem, temperature, err := flags.gasSettings(units)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	topUpComposition, err := ParseGasComposition(*topUpFlag)
+	if err != nil {
+		return fmt.Errorf("invalid top-up mix: %w", err)
+	}
+	requests, err := LoadFillRequests(fs.Arg(0), units)
+	if err != nil {
+		return err
+	}
+	banks, err := bankStateFlags.inventory(w, units)
+	if err != nil {
+		return err
+	}
+	dayPlan := PlanFillDay(requests, banks, topUpComposition, units.AmbientPressure, gasSystem, temperature)
+	printDayPlan(w, dayPlan, units)
+	return bankStateFlags.update(w, banks, dayPlan.BankPressures, units)
+}
+
+func printDayPlan(w io.Writer, dayPlan DayPlan, units UnitSystem) {
+	pressureUnit := units.PressureUnit()
+	for i, fill := range dayPlan.Fills {
+		var supplies []string
+		for _, supply := range fill.Supplies {
+			supplies = append(supplies, fmt.Sprintf("%s from %s", fill.Plan.Steps[supply.Step-1].Description, supply.Bank.Description))
+		}
+		if len(supplies) == 0 {
+			supplies = append(supplies, "compressor only")
+		}
+		fmt.Fprintf(w, "%d. %s: %s to %.0f%s; %s\n", i+1, fill.Request.Name, fill.Request.TargetComposition, units.Pressure(fill.Request.TargetPressure), pressureUnit, strings.Join(supplies, ", "))
+		if fill.Plan.DrainRequired {
+			fmt.Fprintf(w, "   drain to %.1f%s first\n", units.Pressure(units.roundPressureDown(fill.Plan.DrainToPressure)), pressureUnit)
+		}
+	}
+	if len(dayPlan.Unachievable) > 0 {
+		fmt.Fprintln(w, "Not achievable:")
+		for _, fill := range dayPlan.Unachievable {
+			fmt.Fprintf(w, "   %s: %s to %.0f%s; %s\n", fill.Request.Name, fill.Request.TargetComposition, units.Pressure(fill.Request.TargetPressure), pressureUnit, fill.Reason)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlanFillDay(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	requests := []FillRequest{
+		// 50 bar of helium into 10 l uses 10 bar of the bank
+		{Name: "low", Cylinder: Cylinder{CylinderVolume: 10, GasComposition: air}, TargetComposition: GasComposition{Oxygen: 0.15, Helium: 0.50, Nitrogen: 0.35}, TargetPressure: 100},
+		// 90 bar of helium uses 18 bar, and the bank must stay above 90 bar
+		{Name: "high", Cylinder: Cylinder{CylinderVolume: 10, GasComposition: air}, TargetComposition: GasComposition{Oxygen: 0.18, Helium: 0.45, Nitrogen: 0.37}, TargetPressure: 200},
+		{Name: "deep", Cylinder: Cylinder{CylinderVolume: 10, GasComposition: air}, TargetComposition: GasComposition{Oxygen: 0.10, Helium: 0.70, Nitrogen: 0.20}, TargetPressure: 200},
+	}
+	banks := CylinderList{
+		{Description: "he", CylinderVolume: 50, Pressure: 110, GasComposition: GasComposition{Helium: 1}},
+		{Description: "o2", CylinderVolume: 50, Pressure: 200, GasComposition: GasComposition{Oxygen: 1}},
+	}
+	dayPlan := PlanFillDay(requests, banks, air, 0, IdealGas, 293.15)
+	if len(dayPlan.Fills) != 2 || dayPlan.Fills[0].Request.Name != "high" || dayPlan.Fills[1].Request.Name != "low" {
+		t.Fatalf("Expected high before low, got %+v", dayPlan.Fills)
+	}
+	if len(dayPlan.Unachievable) != 1 || dayPlan.Unachievable[0].Request.Name != "deep" || dayPlan.Unachievable[0].Reason != "helium banks are too low" {
+		t.Errorf("Invalid unachievable fills %+v", dayPlan.Unachievable)
+	}
+	if !compareFloats(float64(dayPlan.BankPressures["he"]), 82) {
+		t.Errorf("Invalid bank pressure %f, expected 82", dayPlan.BankPressures["he"])
+	}
+
+	var plans []BlendPlan
+	for _, request := range requests[:2] {
+		plan, err := PlanBlend(request.Cylinder, request.TargetComposition, request.TargetPressure, air, 0, IdealGas, 293.15)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plans = append(plans, plan)
+	}
+	if order := greedyFillOrder(plans, banks, IdealGas, 293.15); len(order) != 2 || order[0] != 1 {
+		t.Errorf("Expected greedy order to fill high first, got %v", order)
+	}
+}
+
+func TestParseFillRequests(t *testing.T) {
+	requests, err := parseFillRequests(strings.NewReader("name,cylinder,target,pressure\nalice,12l@50bar:21/35,18/45,200bar\n,24l@0bar,32,232\n"), Metric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || requests[0].Name != "alice" || requests[0].Cylinder.GasComposition[Helium] != 0.35 || requests[0].TargetPressure != 200 {
+		t.Errorf("Invalid requests %+v", requests)
+	}
+	if requests[1].Name != "line 3" || requests[1].Cylinder.GasComposition[Oxygen] != 0.21 || requests[1].TargetComposition[Oxygen] != 0.32 {
+		t.Errorf("Invalid default request %+v", requests[1])
+	}
+	if _, err := parseFillRequests(strings.NewReader("name,cylinder\nalice,12l@50bar\n"), Metric); err == nil {
+		t.Error("Expected error for missing columns")
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DecoStop is time breathing a deco gas at a depth, including the ascent to the next stop
+type DecoStop struct {
+	Depth          Depth
+	Minutes        float64
+	GasComposition GasComposition
+}
+
+// ParseDecoStop parses a stop given as depth:minutes:mix, e.g. 21m:3:50 or 6m:12:oxygen
+func (u UnitSystem) ParseDecoStop(s string) (DecoStop, error) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 {
+		return DecoStop{}, fmt.Errorf("invalid stop %q; must be depth:minutes:mix", s)
+	}
+	var stop DecoStop
+	var err error
+	if stop.Depth, err = u.ParseDepth(parts[0]); err != nil || stop.Depth < 0 {
+		return DecoStop{}, fmt.Errorf("invalid depth in stop %q", s)
+	}
+	if stop.Minutes, err = strconv.ParseFloat(parts[1], 64); err != nil || stop.Minutes <= 0 {
+		return DecoStop{}, fmt.Errorf("invalid minutes in stop %q", s)
+	}
+	if stop.GasComposition, err = ParseGasComposition(parts[2]); err != nil {
+		return DecoStop{}, fmt.Errorf("invalid mix in stop %q: %w", s, err)
+	}
+	return stop, nil
+}
+
+// DecoGasFill is the stage bottle carrying a deco gas and the fill it needs
+type DecoGasFill struct {
+	GasComposition GasComposition
+	// BreathedGasVolume is the gas breathed at the stops; FillGasVolume adds the reserve
+	BreathedGasVolume GasVolume
+	FillGasVolume     GasVolume
+	Bottle            Cylinder
+	FillPressure      PressureBar
+	// Cascade is set when banks hold the deco gas and the bottle is decanted from them; other fills are blended
+	Cascade *CascadePlan
+}
+
+// DecoFillPlan holds the stage bottle fills for a deco schedule, the blends of gases not in the banks, and the bank
+// pressures after all fills
+type DecoFillPlan struct {
+	Fills         []DecoGasFill
+	Blends        DayPlan
+	BankPressures map[string]PressureBar
+}
+
+// PlanDecoFills works out the gas of each deco gas breathed at the stops with the SAC rate, and fills a stage bottle
+// with it and the reserve. Bottles holding a deco gas are used for it; other gases go into an emptyBottle. Deco
+// gases held by banks are decanted from them, lowest pressure bank first; the others are blended from the remaining
+// banks with topUpComposition.
+func PlanDecoFills(stops []DecoStop, surfaceAirConsumption GasVolume, reserve ReservePolicy, bottles CylinderList, emptyBottle Cylinder, banks CylinderList, topUpComposition GasComposition, ambientPressure PressureBar, gasSystem GasSystem, temperature Temperature) DecoFillPlan {
+	var plan DecoFillPlan
+	for _, stop := range stops {
+		i := 0
+		for i < len(plan.Fills) && !plan.Fills[i].GasComposition.Equal(stop.GasComposition) {
+			i++
+		}
+		if i == len(plan.Fills) {
+			plan.Fills = append(plan.Fills, DecoGasFill{GasComposition: stop.GasComposition})
+		}
+		requirement := DiveRequirement{Depth: stop.Depth, Minutes: stop.Minutes, SurfaceAirConsumption: surfaceAirConsumption}
+		plan.Fills[i].BreathedGasVolume += requirement.GasVolume()
+	}
+
+	banks = append(CylinderList(nil), banks...)
+	var requests []FillRequest
+	for i := range plan.Fills {
+		fill := &plan.Fills[i]
+		fill.Bottle = emptyBottle
+		fill.Bottle.Description = fill.GasComposition.String() + " stage"
+		fill.Bottle.GasComposition = fill.GasComposition
+		for _, bottle := range bottles {
+			if bottle.GasComposition.Equal(fill.GasComposition) {
+				fill.Bottle = bottle
+				break
+			}
+		}
+		fill.FillGasVolume = reserve.FillGasVolume(fill.BreathedGasVolume, fill.Bottle.CylinderVolume, fill.GasComposition, gasSystem, temperature)
+		fill.FillPressure = PressureFromGasVolume(fill.Bottle.CylinderVolume, fill.FillGasVolume, gasSystem, fill.GasComposition, temperature)
+		if fill.FillPressure <= fill.Bottle.Pressure {
+			continue
+		}
+		var premixBanks CylinderList
+		for _, bank := range banks {
+			if bank.GasComposition.Equal(fill.GasComposition) {
+				premixBanks = append(premixBanks, bank)
+			}
+		}
+		if len(premixBanks) == 0 {
+			requests = append(requests, FillRequest{Name: fill.Bottle.Description, Cylinder: fill.Bottle, TargetComposition: fill.GasComposition, TargetPressure: fill.FillPressure})
+			continue
+		}
+		cascade := PlanCascade(premixBanks, fill.Bottle, fill.FillPressure, gasSystem, temperature)
+		fill.Cascade = &cascade
+		pressures := cascade.BankPressures()
+		for j := range banks {
+			if pressure, ok := pressures[banks[j].Description]; ok {
+				banks[j].Pressure = pressure
+			}
+		}
+	}
+	plan.Blends = PlanFillDay(requests, banks, topUpComposition, ambientPressure, gasSystem, temperature)
+	plan.BankPressures = plan.Blends.BankPressures
+	return plan
+}
+
+func printDecoFillPlan(w io.Writer, plan DecoFillPlan, reserve ReservePolicy, units UnitSystem) {
+	pressureUnit, volumeUnit := units.PressureUnit(), units.VolumeUnit()
+	for _, fill := range plan.Fills {
+		fmt.Fprintf(w, "%s: %.0f%s breathed, %.0f%s with %s; %s %.1fl to %.0f%s", fill.GasComposition, units.Volume(fill.BreathedGasVolume), volumeUnit, units.Volume(fill.FillGasVolume), volumeUnit, reserve.Name, fill.Bottle.Description, fill.Bottle.CylinderVolume, units.Pressure(units.roundPressureUp(fill.FillPressure)), pressureUnit)
+		switch {
+		case fill.FillPressure <= fill.Bottle.Pressure:
+			fmt.Fprintf(w, ", already at %.0f%s\n", units.Pressure(fill.Bottle.Pressure), pressureUnit)
+		case fill.Cascade == nil:
+			fmt.Fprintln(w, ", blended")
+		case !fill.Cascade.TargetReached:
+			fmt.Fprintf(w, "; banks only reach %.0f%s\n", units.Pressure(fill.Cascade.DestinationPressure), pressureUnit)
+		default:
+			fmt.Fprintln(w)
+		}
+		if fill.Cascade == nil {
+			continue
+		}
+		for _, step := range fill.Cascade.Steps {
+			if !step.Skipped {
+				fmt.Fprintf(w, "   from %s: %.0f%s to %.0f%s\n", step.Bank.Description, units.Pressure(step.Bank.Pressure), pressureUnit, units.Pressure(step.BankPressureAfter), pressureUnit)
+			}
+		}
+	}
+	if len(plan.Blends.Fills) > 0 || len(plan.Blends.Unachievable) > 0 {
+		fmt.Fprintln(w, "Blends:")
+		printDayPlan(w, plan.Blends, units)
+	}
+}
+
+func decoMain(args []string) error {
+	fs := flag.NewFlagSet("deco", flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	var stopFlags, stageFlags, bankFlags stringListFlag
+	fs.Var(&stopFlags, "stop", "Deco stop as depth:minutes:mix, e.g. 21m:3:50 or 6m:12:oxygen; minutes include the ascent to the next stop; repeat for each stop")
+	fs.Var(&stageFlags, "stage", "Stage bottle as [name=]volume@pressure:mix, e.g. 7l@50bar:50; used for the deco gas of its mix; repeat for each bottle")
+	fs.Var(&bankFlags, "bank", "Storage bank as [name=]volume@pressure:mix, e.g. o2=50l@200bar:oxygen; repeat for each bank")
+	var stageVolumeFlag = fs.String("stage-volume", "7l", "Volume of empty stage bottles for deco gases without a -stage")
+	var sacFlag = fs.String("sac", "20l", "Surface air consumption per minute, e.g. 20l or 0.7cuft")
+	var reserveFlag = fs.String("reserve", "thirds", "Reserve: thirds, sixths, halves, all or a pressure left in the bottles, e.g. 50bar")
+	var topUpFlag = fs.String("top-up", "air", "Top-up gas mix for blending deco gases not held by banks")
+	bankStateFlags := registerBankStateFlags(fs)
+	fs.Parse(args)
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	w, err := flags.output(os.Stdout, units)
+	if err != nil {
+		return err
+	}
+	gasSystem, temperature, err := flags.gasSettings(units)
+	if err != nil {
+		return err
+	}
+	if len(stopFlags) == 0 {
+		return errors.New("at least one -stop is required")
+	}
+	var stops []DecoStop
+	for _, stopFlag := range stopFlags {
+		stop, err := units.ParseDecoStop(stopFlag)
+		if err != nil {
+			return err
+		}
+		stops = append(stops, stop)
+	}
+	sac, err := units.ParseConsumption(*sacFlag)
+	if err != nil || sac <= 0 {
+		return fmt.Errorf("invalid SAC rate: %s", *sacFlag)
+	}
+	reserve, err := units.ParseReservePolicy(*reserveFlag)
+	if err != nil {
+		return err
+	}
+	stageVolume, err := units.ParseCylinderVolume(*stageVolumeFlag)
+	if err != nil {
+		return fmt.Errorf("invalid stage volume: %w", err)
+	}
+	topUpComposition, err := ParseGasComposition(*topUpFlag)
+	if err != nil {
+		return fmt.Errorf("invalid top-up mix: %w", err)
+	}
+	stages, err := units.ParseCylinderSpecs(stageFlags, "stage")
+	if err != nil {
+		return fmt.Errorf("invalid stage bottle: %w", err)
+	}
+	stages.SetDefaultGasComposition(GasComposition{Oxygen: 0.21, Nitrogen: 0.79})
+	if err := checkCylinders(stages, "stage", true, units); err != nil {
+		return err
+	}
+	banks, err := units.ParseCylinderSpecs(bankFlags, "bank")
+	if err != nil {
+		return fmt.Errorf("invalid bank: %w", err)
+	}
+	banks.SetDefaultGasComposition(GasComposition{Oxygen: 0.21, Nitrogen: 0.79})
+	if err := checkCylinders(banks, "bank", false, units); err != nil {
+		return err
+	}
+	savedBanks, err := bankStateFlags.banks(w, units)
+	if err != nil {
+		return err
+	}
+	banks = append(banks, savedBanks...)
+
+	emptyBottle := Cylinder{CylinderVolume: stageVolume, Pressure: units.AbsolutePressure(0)}
+	plan := PlanDecoFills(stops, sac, reserve, stages, emptyBottle, banks, topUpComposition, units.AmbientPressure, gasSystem, temperature)
+	printDecoFillPlan(w, plan, reserve, units)
+	for _, fill := range plan.Fills {
+		if limit := fill.Bottle.pressureLimit(units); fill.FillPressure > limit {
+			warnf(w, colorCaution, "Warning: %s would need %.0f%s, above %.0f%s; use a larger bottle or two\n", fill.Bottle.Description, units.Pressure(fill.FillPressure), units.PressureUnit(), units.Pressure(limit), units.PressureUnit())
+		}
+	}
+	return bankStateFlags.update(w, savedBanks, plan.BankPressures, units)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDecoStop(t *testing.T) {
+	stop, err := Metric.ParseDecoStop("21m:3:50")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if stop.Depth != 21 || stop.Minutes != 3 || !compareFloats(stop.GasComposition[Oxygen], 0.5) {
+		t.Errorf("Invalid stop %+v", stop)
+	}
+	for _, invalid := range []string{"21m:3", "21m:0:50", "x:3:50", "21m:3:nope"} {
+		if _, err := Metric.ParseDecoStop(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestPlanDecoFills(t *testing.T) {
+	ean50 := GasComposition{Oxygen: 0.5, Nitrogen: 0.5}
+	oxygen := GasComposition{Oxygen: 1}
+	stops := []DecoStop{
+		{Depth: 21, Minutes: 2, GasComposition: ean50},
+		{Depth: 9, Minutes: 4, GasComposition: ean50},
+		{Depth: 6, Minutes: 10, GasComposition: oxygen},
+	}
+	reserve, _ := Metric.ParseReservePolicy("thirds")
+	banks := CylinderList{
+		{Description: "o2", CylinderVolume: 50, Pressure: 200, GasComposition: oxygen},
+		{Description: "air", CylinderVolume: 50, Pressure: 300, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}},
+	}
+	emptyBottle := Cylinder{CylinderVolume: 7, Pressure: 0}
+	plan := PlanDecoFills(stops, 20, reserve, nil, emptyBottle, banks, GasComposition{Oxygen: 0.21, Nitrogen: 0.79}, 0, IdealGas, 293.15)
+	if len(plan.Fills) != 2 {
+		t.Fatalf("Expected fills for two gases, got %+v", plan.Fills)
+	}
+	breathed := GasVolume(20 * (2*(SurfacePressure+2.1) + 4*(SurfacePressure+0.9)) / SurfacePressure)
+	if !compareFloats(float64(plan.Fills[0].BreathedGasVolume), float64(breathed)) || !compareFloats(float64(plan.Fills[0].FillGasVolume), float64(breathed)*1.5) {
+		t.Errorf("Invalid EAN50 gas %+v, expected %f breathed", plan.Fills[0], breathed)
+	}
+	if !compareFloats(float64(plan.Fills[0].FillPressure), float64(breathed)*1.5/7) {
+		t.Errorf("Invalid EAN50 fill pressure %f", plan.Fills[0].FillPressure)
+	}
+	// Oxygen is in the banks and decanted; EAN50 is blended with oxygen from the same bank
+	if plan.Fills[1].Cascade == nil || !plan.Fills[1].Cascade.TargetReached || plan.Fills[0].Cascade != nil {
+		t.Errorf("Expected oxygen to be decanted and EAN50 blended, got %+v", plan.Fills)
+	}
+	if len(plan.Blends.Fills) != 1 || plan.Blends.Fills[0].Request.Name != "EAN50.0 stage" {
+		t.Errorf("Expected a blend of EAN50, got %+v", plan.Blends)
+	}
+	if plan.BankPressures["o2"] >= 200 {
+		t.Errorf("Expected oxygen to be taken from the bank, got %f", plan.BankPressures["o2"])
+	}
+
+	var output strings.Builder
+	printDecoFillPlan(&output, plan, reserve, Metric)
+	for _, expected := range []string{"EAN50.0: ", "with thirds; EAN50.0 stage 7.0l to ", ", blended\n", "   from o2: 200bar to ", "Blends:\n"} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("Expected %q in output %q", expected, output.String())
+		}
+	}
+}
+
+func TestPlanDecoFillsUsesStageBottles(t *testing.T) {
+	oxygen := GasComposition{Oxygen: 1}
+	stops := []DecoStop{{Depth: 6, Minutes: 5, GasComposition: oxygen}}
+	reserve, _ := Metric.ParseReservePolicy("all")
+	stage := Cylinder{Description: "deco", CylinderVolume: 5.5, Pressure: 150, GasComposition: oxygen}
+	plan := PlanDecoFills(stops, 20, reserve, CylinderList{stage}, Cylinder{CylinderVolume: 7}, nil, GasComposition{Oxygen: 0.21, Nitrogen: 0.79}, 0, IdealGas, 293.15)
+	if plan.Fills[0].Bottle.Description != "deco" || plan.Fills[0].Cascade != nil || len(plan.Blends.Fills) != 0 {
+		t.Errorf("Expected the stage bottle to hold enough oxygen already, got %+v", plan)
+	}
+}
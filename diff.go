@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// diffFile is a scenario in the /equalize request format or a saved /equalize response
+type diffFile struct {
+	EqualizeRequest
+	Summaries []SummaryResponse `json:"summaries"`
+}
+
+// loadDiffResults returns the results of a file: the summaries of a saved response as is, or the scenario of a
+// request equalized with the settings of the server
+func (s server) loadDiffResults(path string) (EqualizeResponse, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return EqualizeResponse{}, err
+	}
+	var file diffFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return EqualizeResponse{}, fmt.Errorf("invalid scenario or result file %s: %w", path, err)
+	}
+	if file.Summaries != nil {
+		return EqualizeResponse{Summaries: file.Summaries}, nil
+	}
+	response, err := s.Equalize(file.EqualizeRequest)
+	if err != nil {
+		return EqualizeResponse{}, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	return response, nil
+}
+
+// diffField is a compared value of a manifold configuration; text fields have no value
+type diffField struct {
+	name     string
+	decimals int
+	value    func(SummaryResponse) float64
+	text     func(SummaryResponse) string
+}
+
+// diffFields returns the compared fields of summaries in the /equalize response units, printed in the units
+func diffFields(units UnitSystem) []diffField {
+	decimals := units.decimals(0)
+	pressure := func(p float64) float64 { return units.round(units.PressureDifference(PressureBar(p))) }
+	volume := func(v float64) float64 { return units.round(units.Volume(GasVolume(v))) }
+	return []diffField{
+		{name: "src " + units.PressureUnit(), decimals: decimals, value: func(s SummaryResponse) float64 { return pressure(s.SourcePressure) }},
+		{name: "src " + units.VolumeUnit(), decimals: decimals, value: func(s SummaryResponse) float64 { return volume(s.SourceGasVolume) }},
+		{name: "src mix", text: func(s SummaryResponse) string { return s.SourceMix }},
+		{name: "dst " + units.PressureUnit(), decimals: decimals, value: func(s SummaryResponse) float64 { return pressure(s.DestinationPressure) }},
+		{name: "dst " + units.VolumeUnit(), decimals: decimals, value: func(s SummaryResponse) float64 { return volume(s.DestinationGasVolume) }},
+		{name: "dst mix", text: func(s SummaryResponse) string { return s.DestinationMix }},
+		{name: "improvement %", decimals: 2, value: func(s SummaryResponse) float64 { return s.ImprovementPercent }},
+	}
+}
+
+// printResultDiff prints the fields of each manifold configuration in the results a and b, named nameA and nameB,
+// with the change from a to b. Configurations run in only one of them are listed without changes.
+func printResultDiff(w io.Writer, nameA string, a EqualizeResponse, nameB string, b EqualizeResponse, units UnitSystem, style tableStyle) {
+	var diffTable table
+	diffTable.addColumn("configuration", 0, true)
+	diffTable.addColumn("field", 0, true)
+	diffTable.addColumn(nameA, 0, false)
+	diffTable.addColumn(nameB, 0, false)
+	diffTable.addColumn("change", 0, false)
+	summariesB := make(map[string]SummaryResponse)
+	for _, summary := range b.Summaries {
+		summariesB[summary.Description] = summary
+	}
+	found := make(map[string]bool)
+	for _, summaryA := range a.Summaries {
+		summaryB, ok := summariesB[summaryA.Description]
+		if !ok {
+			diffTable.addRow("", summaryA.Description, "", "run", "not run", "")
+			continue
+		}
+		found[summaryA.Description] = true
+		for _, field := range diffFields(units) {
+			if field.text != nil {
+				var change string
+				if field.text(summaryA) != field.text(summaryB) {
+					change = "changed"
+				}
+				diffTable.addRow("", summaryA.Description, field.name, field.text(summaryA), field.text(summaryB), change)
+				continue
+			}
+			valueA, valueB := field.value(summaryA), field.value(summaryB)
+			diffTable.addRow("", summaryA.Description, field.name, fmt.Sprintf("%.*f", field.decimals, valueA), fmt.Sprintf("%.*f", field.decimals, valueB), fmt.Sprintf("%+.*f", field.decimals, valueB-valueA))
+		}
+	}
+	for _, summaryB := range b.Summaries {
+		if !found[summaryB.Description] {
+			diffTable.addRow("", summaryB.Description, "", "not run", "run", "")
+		}
+	}
+	diffTable.render(w, style)
+}
+
+func diffMain(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s diff [flags] <a.json> <b.json>\n\nEach file is a scenario in the /equalize request format or a saved /equalize response.\n\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	gasSystem, temperature, err := flags.gasSettings(units)
+	if err != nil {
+		return err
+	}
+	style, err := flags.parseTableStyle()
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errUsage
+	}
+	// Scenarios and saved responses are in bar and liters like the HTTP API
+	apiUnits := units
+	apiUnits.Imperial = false
+	s := server{gasSystem: gasSystem, temperature: temperature, units: apiUnits}
+	a, err := s.loadDiffResults(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := s.loadDiffResults(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	w, err := flags.output(os.Stdout, units)
+	if err != nil {
+		return err
+	}
+	printResultDiff(w, filepath.Base(fs.Arg(0)), a, filepath.Base(fs.Arg(1)), b, units, style)
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadDiffResults(t *testing.T) {
+	directory := t.TempDir()
+	scenario := `{"source": [{"volume": 12, "pressure": 232}], "destination": [{"volume": 12, "pressure": 50}]}`
+	scenarioPath := filepath.Join(directory, "scenario.json")
+	resultPath := filepath.Join(directory, "result.json")
+	if err := os.WriteFile(scenarioPath, []byte(scenario), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(resultPath, defaultServer.EqualizeJSON([]byte(scenario)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ran, err := defaultServer.loadDiffResults(scenarioPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := defaultServer.loadDiffResults(resultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran.Summaries) != 1 || len(saved.Summaries) != 1 || ran.Summaries[0] != saved.Summaries[0] {
+		t.Errorf("Expected the same results from the scenario and the saved result, got %+v and %+v", ran, saved)
+	}
+	if _, err := defaultServer.loadDiffResults(filepath.Join(directory, "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestPrintResultDiff(t *testing.T) {
+	a := EqualizeResponse{Summaries: []SummaryResponse{{Description: "all manifolds open", DestinationPressure: 150, DestinationMix: "air"}}}
+	b := EqualizeResponse{Summaries: []SummaryResponse{
+		{Description: "both manifolds closed", DestinationPressure: 170, DestinationMix: "EAN32"},
+		{Description: "all manifolds open", DestinationPressure: 160, DestinationMix: "EAN32"},
+	}}
+	var output strings.Builder
+	printResultDiff(&output, "a.json", a, "b.json", b, Metric, tablePlain)
+	lines := strings.Split(output.String(), "\n")
+	for _, expected := range []string{
+		"all manifolds open    dst bar           150    160     +10",
+		"all manifolds open    dst mix           air  EAN32 changed",
+		"both manifolds closed               not run    run",
+	} {
+		found := false
+		for _, line := range lines {
+			found = found || line == expected
+		}
+		if !found {
+			t.Errorf("Expected the line %q in %q", expected, output.String())
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MaximumOperatingDepth returns the deepest depth at which the oxygen partial pressure of the mix stays within the
+// limit, or a negative depth when the mix is above the limit already at the surface
+func MaximumOperatingDepth(gasComposition GasComposition, oxygenPartialPressureLimit PressureBar) Depth {
+	return Depth((float64(oxygenPartialPressureLimit)/gasComposition[Oxygen] - SurfacePressure) * 10)
+}
+
+// defaultHypoxicThreshold is the oxygen fraction below which a mix is not breathed at the surface
+const defaultHypoxicThreshold = 0.18
+
+// MinimumOperatingDepth returns the shallowest depth at which the oxygen partial pressure of the mix reaches the
+// minimum, or a negative depth when it does already at the surface
+func MinimumOperatingDepth(gasComposition GasComposition, oxygenPartialPressureMinimum PressureBar) Depth {
+	return MaximumOperatingDepth(gasComposition, oxygenPartialPressureMinimum)
+}
+
+// airNitrogenFraction is the nitrogen fraction of air used as the reference for equivalent depths
+const airNitrogenFraction = 0.79
+
+// EquivalentAirDepth returns the depth at which air has the nitrogen partial pressure of the mix at the depth
+func EquivalentAirDepth(gasComposition GasComposition, depth Depth) Depth {
+	return Depth((float64(depth.AmbientPressure())*gasComposition[Nitrogen]/airNitrogenFraction - SurfacePressure) * 10)
+}
+
+// EquivalentNarcoticDepth returns the depth at which air is as narcotic as the mix at the depth. Nitrogen is
+// narcotic; oxygen is counted as equally narcotic when oxygenNarcotic is set, both in the mix and in air.
+func EquivalentNarcoticDepth(gasComposition GasComposition, depth Depth, oxygenNarcotic bool) Depth {
+	narcoticFraction, airNarcoticFraction := gasComposition[Nitrogen], airNitrogenFraction
+	if oxygenNarcotic {
+		narcoticFraction, airNarcoticFraction = narcoticFraction+gasComposition[Oxygen], 1
+	}
+	return Depth((float64(depth.AmbientPressure())*narcoticFraction/airNarcoticFraction - SurfacePressure) * 10)
+}
+
+// recommendedGasDensity and maxGasDensity are the recommended and the highest acceptable breathing gas densities
+// in g/l (Anthony and Mitchell, 2016)
+const (
+	recommendedGasDensity = 5.2
+	maxGasDensity         = 6.2
+)
+
+// GasDensity returns the density of the mix in g/l at the depth and temperature
+func GasDensity(gasComposition GasComposition, depth Depth, gasSystem GasSystem, temperature Temperature) float64 {
+	moles := gasSystem.Moles(1, depth.AmbientPressure(), temperature, gasComposition)
+	var density float64
+	for gasType, fraction := range gasComposition {
+		density += float64(GasWeightFromMole(moles*MoleCount(fraction), SpeciesLookup[gasType].MolarMass))
+	}
+	return density
+}
+
+// ErrNoNitrox is returned when even air is above the oxygen partial pressure limit at the depth
+var ErrNoNitrox = errors.New("air exceeds the oxygen partial pressure limit at this depth; a hypoxic mix is needed")
+
+// BestNitrox returns the nitrox with the most oxygen, in whole percents, that stays within the oxygen partial
+// pressure limit at the depth
+func BestNitrox(depth Depth, oxygenPartialPressureLimit PressureBar) (GasComposition, error) {
+	oxygen := math.Floor(float64(oxygenPartialPressureLimit/depth.AmbientPressure())*100+1e-9) / 100
+	if oxygen > 1 {
+		oxygen = 1
+	}
+	if oxygen < 0.21 {
+		return nil, ErrNoNitrox
+	}
+	return GasComposition{Oxygen: oxygen, Nitrogen: 1 - oxygen}, nil
+}
+
+// BestTrimix returns the trimix with the least helium, in whole percents, that stays within the oxygen partial
+// pressure limit and the equivalent narcotic depth limit at the depth. Oxygen is as rich as the limit allows, and
+// counts as narcotic when oxygenNarcotic is set.
+func BestTrimix(depth Depth, oxygenPartialPressureLimit PressureBar, narcoticDepthLimit Depth, oxygenNarcotic bool) (GasComposition, error) {
+	ambientPressure := float64(depth.AmbientPressure())
+	oxygen := math.Floor(float64(oxygenPartialPressureLimit)/ambientPressure*100+1e-9) / 100
+	if oxygen > 1 {
+		oxygen = 1
+	}
+	// narcotic is the highest narcotic fraction keeping the equivalent narcotic depth within the limit
+	narcotic := float64(narcoticDepthLimit.AmbientPressure()) / ambientPressure
+	var helium float64
+	if oxygenNarcotic {
+		if oxygen > narcotic {
+			return nil, fmt.Errorf("oxygen at pO2 %.2g alone is narcotic deeper than the END limit", oxygenPartialPressureLimit)
+		}
+		helium = 1 - narcotic
+	} else {
+		helium = 1 - oxygen - narcotic*airNitrogenFraction
+	}
+	helium = math.Ceil(helium*100-1e-9) / 100
+	if helium < 0 {
+		helium = 0
+	}
+	if oxygen+helium > 1 {
+		helium = 1 - oxygen
+	}
+	gasComposition := GasComposition{Oxygen: oxygen, Helium: helium, Nitrogen: 1 - oxygen - helium}
+	if gasComposition[Nitrogen] < 1e-9 {
+		delete(gasComposition, Nitrogen)
+	}
+	return gasComposition, nil
+}
+
+// diveGasFlags holds flags for reporting how the resulting mix can be dived
+type diveGasFlags struct {
+	oxygenPartialPressures *string
+	plannedDepth           *string
+	oxygenNarcotic         *bool
+	hypoxicThreshold       *float64
+}
+
+func registerDiveGasFlags(fs *flag.FlagSet) *diveGasFlags {
+	return &diveGasFlags{
+		oxygenPartialPressures: fs.String("ppo2-limits", "1.4,1.6", "Oxygen partial pressure limits in bar for the maximum operating depth of the resulting mix, e.g. 1.4,1.6; empty disables"),
+		plannedDepth:           fs.String("planned-depth", "", "Depth the resulting mix is planned for (m or ft); reports its equivalent air depth, or equivalent narcotic depth with helium"),
+		oxygenNarcotic:         fs.Bool("oxygen-narcotic", true, "Count oxygen as narcotic for the equivalent narcotic depth"),
+		hypoxicThreshold:       fs.Float64("hypoxic-threshold", defaultHypoxicThreshold, "Warn when the resulting mix has less oxygen than this fraction, and report the depth it becomes breathable at"),
+	}
+}
+
+// DiveGasSettings selects what is reported about breathing the resulting mix
+type DiveGasSettings struct {
+	// OxygenPartialPressureLimits are the limits maximum operating depths are reported for
+	OxygenPartialPressureLimits []PressureBar
+	// PlannedDepth is the depth equivalent depths are reported for; zero disables
+	PlannedDepth Depth
+	// OxygenNarcotic counts oxygen as narcotic for the equivalent narcotic depth
+	OxygenNarcotic bool
+	// HypoxicThreshold is the oxygen fraction below which the mix is not breathable at the surface; zero disables
+	// the warning
+	HypoxicThreshold float64
+}
+
+// settings returns the dive gas settings
+func (f *diveGasFlags) settings(units UnitSystem) (DiveGasSettings, error) {
+	settings := DiveGasSettings{OxygenNarcotic: *f.oxygenNarcotic, HypoxicThreshold: *f.hypoxicThreshold}
+	if settings.HypoxicThreshold < 0 || settings.HypoxicThreshold >= 1 {
+		return DiveGasSettings{}, errors.New("invalid hypoxic threshold; must be an oxygen fraction between 0 and 1")
+	}
+	for _, value := range strings.Split(*f.oxygenPartialPressures, ",") {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		limit, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "bar"), 64)
+		if err != nil || limit <= 0 {
+			return DiveGasSettings{}, fmt.Errorf("invalid oxygen partial pressure limit: %s", value)
+		}
+		settings.OxygenPartialPressureLimits = append(settings.OxygenPartialPressureLimits, PressureBar(limit))
+	}
+	if *f.plannedDepth != "" {
+		var err error
+		if settings.PlannedDepth, err = units.ParseDepth(*f.plannedDepth); err != nil || settings.PlannedDepth <= 0 {
+			return DiveGasSettings{}, fmt.Errorf("%w: planned depth %s", ErrInvalidLength, *f.plannedDepth)
+		}
+	}
+	return settings, nil
+}
+
+// printDiveGas reports the maximum operating depths of the mix, whether it is hypoxic, and its equivalent depth and
+// density at the planned depth
+func printDiveGas(w io.Writer, gasComposition GasComposition, settings DiveGasSettings, gasSystem GasSystem, temperature Temperature, units UnitSystem) {
+	printMaximumOperatingDepths(w, gasComposition, settings.OxygenPartialPressureLimits, units)
+	if gasComposition[Oxygen] < settings.HypoxicThreshold {
+		if gasComposition[Oxygen] <= 0 {
+			warnf(w, colorDanger, tr(w, "Warning: %s has no oxygen and is not breathable\n"), gasComposition)
+		} else {
+			// The mix becomes breathable where its oxygen partial pressure matches the threshold mix at the surface
+			depth := MinimumOperatingDepth(gasComposition, PressureBar(settings.HypoxicThreshold*SurfacePressure))
+			warnf(w, colorDanger, tr(w, "Warning: %s is hypoxic with under %.0f%% oxygen; breathable from %.1f%s, use a travel gas above that\n"), gasComposition, settings.HypoxicThreshold*100, units.Depth(depth), units.DepthUnit())
+		}
+	}
+	if settings.PlannedDepth <= 0 {
+		return
+	}
+	depthUnit := units.DepthUnit()
+	density := GasDensity(gasComposition, settings.PlannedDepth, gasSystem, temperature)
+	fmt.Fprintf(w, tr(w, "Gas density at %.1f%s: %.2fg/l\n"), units.Depth(settings.PlannedDepth), depthUnit, density)
+	if density > maxGasDensity {
+		warnf(w, colorDanger, tr(w, "Warning: gas density is above the maximum of %.1fg/l\n"), maxGasDensity)
+	} else if density > recommendedGasDensity {
+		warnf(w, colorCaution, tr(w, "Warning: gas density is above the recommended %.1fg/l\n"), recommendedGasDensity)
+	}
+	if gasComposition[Helium] > 0 {
+		convention := "oxygen not narcotic"
+		if settings.OxygenNarcotic {
+			convention = "oxygen narcotic"
+		}
+		fmt.Fprintf(w, tr(w, "END at %.1f%s: %.1f%s (%s)\n"), units.Depth(settings.PlannedDepth), depthUnit, units.Depth(EquivalentNarcoticDepth(gasComposition, settings.PlannedDepth, settings.OxygenNarcotic)), depthUnit, tr(w, convention))
+		return
+	}
+	fmt.Fprintf(w, tr(w, "EAD at %.1f%s: %.1f%s\n"), units.Depth(settings.PlannedDepth), depthUnit, units.Depth(EquivalentAirDepth(gasComposition, settings.PlannedDepth)), depthUnit)
+}
+
+func printMaximumOperatingDepths(w io.Writer, gasComposition GasComposition, oxygenPartialPressureLimits []PressureBar, units UnitSystem) {
+	if len(oxygenPartialPressureLimits) == 0 || gasComposition[Oxygen] <= 0 {
+		return
+	}
+	depths := make([]string, len(oxygenPartialPressureLimits))
+	for i, limit := range oxygenPartialPressureLimits {
+		depth := MaximumOperatingDepth(gasComposition, limit)
+		if depth < 0 {
+			depths[i] = fmt.Sprintf(tr(w, "above pO2 %.2g at the surface"), limit)
+			continue
+		}
+		depths[i] = fmt.Sprintf(tr(w, "%.1f%s at pO2 %.2g"), units.Depth(depth), units.DepthUnit(), limit)
+	}
+	fmt.Fprintf(w, tr(w, "MOD of %s: %s\n"), gasComposition, strings.Join(depths, ", "))
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMaximumOperatingDepth(t *testing.T) {
+	for _, test := range []struct {
+		gasComposition GasComposition
+		limit          PressureBar
+		expected       Depth
+	}{
+		{GasComposition{Oxygen: 0.32, Nitrogen: 0.68}, 1.4, (1.4/0.32 - SurfacePressure) * 10},
+		{GasComposition{Oxygen: 0.21, Nitrogen: 0.79}, 1.6, (1.6/0.21 - SurfacePressure) * 10},
+		{GasComposition{Oxygen: 1}, 1.6, (1.6 - SurfacePressure) * 10},
+		{GasComposition{Oxygen: 1}, 1, (1 - SurfacePressure) * 10},
+	} {
+		if depth := MaximumOperatingDepth(test.gasComposition, test.limit); !compareFloats(float64(depth), float64(test.expected)) {
+			t.Errorf("Invalid MOD of %s at %.1f: %f, expected %f", test.gasComposition, test.limit, depth, test.expected)
+		}
+	}
+}
+
+func TestPrintDiveGas(t *testing.T) {
+	var output strings.Builder
+	printDiveGas(&output, GasComposition{Oxygen: 0.32, Nitrogen: 0.68}, DiveGasSettings{OxygenPartialPressureLimits: []PressureBar{1.4, 1.6}}, IdealGas, 293.15, Metric)
+	if expected := "MOD of EAN32.0: 33.6m at pO2 1.4, 39.9m at pO2 1.6\n"; output.String() != expected {
+		t.Errorf("Invalid output %q, expected %q", output.String(), expected)
+	}
+	output.Reset()
+	printDiveGas(&output, GasComposition{Oxygen: 1}, DiveGasSettings{OxygenPartialPressureLimits: []PressureBar{1}}, IdealGas, 293.15, Metric)
+	if !strings.Contains(output.String(), "above pO2 1 at the surface") {
+		t.Errorf("Expected oxygen to be above the limit at the surface, got %q", output.String())
+	}
+}
+
+func TestEquivalentDepths(t *testing.T) {
+	ean32 := GasComposition{Oxygen: 0.32, Nitrogen: 0.68}
+	if depth := EquivalentAirDepth(ean32, 30); !compareFloats(float64(depth), ((30/10.0+SurfacePressure)*0.68/0.79-SurfacePressure)*10) {
+		t.Errorf("Invalid EAD of EAN32 at 30m: %f", depth)
+	}
+	if depth := EquivalentAirDepth(GasComposition{Oxygen: 0.21, Nitrogen: 0.79}, 30); !compareFloats(float64(depth), 30) {
+		t.Errorf("Expected EAD of air to be the depth, got %f", depth)
+	}
+	trimix := GasComposition{Oxygen: 0.18, Helium: 0.45, Nitrogen: 0.37}
+	if depth := EquivalentNarcoticDepth(trimix, 60, true); !compareFloats(float64(depth), ((60/10.0+SurfacePressure)*0.55-SurfacePressure)*10) {
+		t.Errorf("Invalid END of 18/45 at 60m with narcotic oxygen: %f", depth)
+	}
+	if depth := EquivalentNarcoticDepth(trimix, 60, false); !compareFloats(float64(depth), ((60/10.0+SurfacePressure)*0.37/0.79-SurfacePressure)*10) {
+		t.Errorf("Invalid END of 18/45 at 60m without narcotic oxygen: %f", depth)
+	}
+	if EquivalentNarcoticDepth(ean32, 30, false) != EquivalentAirDepth(ean32, 30) {
+		t.Errorf("Expected END without narcotic oxygen to match EAD for nitrox")
+	}
+}
+
+func TestPrintEquivalentDepths(t *testing.T) {
+	var output strings.Builder
+	settings := DiveGasSettings{PlannedDepth: 60, OxygenNarcotic: true}
+	printDiveGas(&output, GasComposition{Oxygen: 0.18, Helium: 0.45, Nitrogen: 0.37}, settings, IdealGas, 293.15, Metric)
+	if !strings.Contains(output.String(), "END at 60.0m: 28.4m (oxygen narcotic)\n") {
+		t.Errorf("Invalid output %q", output.String())
+	}
+	output.Reset()
+	settings.PlannedDepth = 30
+	printDiveGas(&output, GasComposition{Oxygen: 0.32, Nitrogen: 0.68}, settings, IdealGas, 293.15, Metric)
+	if !strings.Contains(output.String(), "EAD at 30.0m: 24.4m\n") {
+		t.Errorf("Invalid output %q", output.String())
+	}
+}
+
+func TestGasDensity(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	expected := float64(Depth(30).AmbientPressure()) / (R * 293.15) * (0.21*float64(SpeciesLookup[Oxygen].MolarMass) + 0.79*float64(SpeciesLookup[Nitrogen].MolarMass))
+	if density := GasDensity(air, 30, IdealGas, 293.15); !compareFloats(density, expected) {
+		t.Errorf("Invalid density of air at 30m: %f, expected %f", density, expected)
+	}
+	if GasDensity(GasComposition{Oxygen: 0.18, Helium: 0.45, Nitrogen: 0.37}, 30, IdealGas, 293.15) >= expected {
+		t.Errorf("Expected trimix to be less dense than air")
+	}
+}
+
+func TestGasDensityWarnings(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	for _, test := range []struct {
+		depth    Depth
+		expected string
+	}{
+		{30, ""},
+		{40, "above the recommended"},
+		{50, "above the maximum"},
+	} {
+		var output strings.Builder
+		printDiveGas(&output, air, DiveGasSettings{PlannedDepth: test.depth}, IdealGas, 293.15, Metric)
+		if test.expected == "" && strings.Contains(output.String(), "Warning") || test.expected != "" && !strings.Contains(output.String(), test.expected) {
+			t.Errorf("Invalid output at %.0fm: %q", test.depth, output.String())
+		}
+	}
+}
+
+func TestBestNitrox(t *testing.T) {
+	for _, test := range []struct {
+		depth    Depth
+		limit    PressureBar
+		expected float64
+	}{
+		{32, 1.4, 0.33},
+		{30, 1.4, 0.34},
+		{3, 1.6, 1},
+		{21, 1.6, 0.51},
+	} {
+		gasComposition, err := BestNitrox(test.depth, test.limit)
+		if err != nil {
+			t.Errorf("Unexpected error at %.0fm: %s", test.depth, err)
+			continue
+		}
+		if !compareFloats(gasComposition[Oxygen], test.expected) || !compareFloats(gasComposition[Nitrogen], 1-test.expected) {
+			t.Errorf("Invalid best nitrox at %.0fm and pO2 %.1f: %s, expected %.0f%%", test.depth, test.limit, gasComposition, test.expected*100)
+		}
+		if MaximumOperatingDepth(gasComposition, test.limit) < test.depth {
+			t.Errorf("Best nitrox %s exceeds pO2 %.1f at %.0fm", gasComposition, test.limit, test.depth)
+		}
+	}
+	if _, err := BestNitrox(60, 1.4); err != ErrNoNitrox {
+		t.Errorf("Expected ErrNoNitrox at 60m, got %v", err)
+	}
+}
+
+func TestBestTrimix(t *testing.T) {
+	for _, test := range []struct {
+		depth          Depth
+		endLimit       Depth
+		oxygenNarcotic bool
+		oxygen         float64
+		helium         float64
+	}{
+		// 7.01 bar ambient: 19% oxygen, narcotic fraction at most 4.01/7.01
+		{60, 30, true, 0.19, 0.43},
+		{60, 30, false, 0.19, 0.36},
+		{30, 30, true, 0.34, 0},
+		{100, 30, true, 0.12, 0.64},
+	} {
+		gasComposition, err := BestTrimix(test.depth, 1.4, test.endLimit, test.oxygenNarcotic)
+		if err != nil {
+			t.Errorf("Unexpected error at %.0fm: %s", test.depth, err)
+			continue
+		}
+		if !compareFloats(gasComposition[Oxygen], test.oxygen) || !compareFloats(gasComposition[Helium], test.helium) || !compareFloats(gasComposition[Oxygen]+gasComposition[Helium]+gasComposition[Nitrogen], 1) {
+			t.Errorf("Invalid best trimix at %.0fm with END %.0fm: %s, expected %.0f/%.0f", test.depth, test.endLimit, gasComposition, test.oxygen*100, test.helium*100)
+		}
+		if EquivalentNarcoticDepth(gasComposition, test.depth, test.oxygenNarcotic) > test.endLimit+1e-6 {
+			t.Errorf("Best trimix %s exceeds END %.0fm at %.0fm", gasComposition, test.endLimit, test.depth)
+		}
+	}
+	if _, err := BestTrimix(30, 1.6, 0, true); err == nil {
+		t.Errorf("Expected an error when oxygen alone exceeds the END limit")
+	}
+}
+
+func TestHypoxicWarning(t *testing.T) {
+	settings := DiveGasSettings{HypoxicThreshold: 0.18}
+	var output strings.Builder
+	printDiveGas(&output, GasComposition{Oxygen: 0.10, Helium: 0.70, Nitrogen: 0.20}, settings, IdealGas, 293.15, Metric)
+	depth := (0.18*SurfacePressure/0.10 - SurfacePressure) * 10
+	if expected := "breathable from " + strconv.FormatFloat(depth, 'f', 1, 64) + "m"; !strings.Contains(output.String(), expected) {
+		t.Errorf("Invalid output %q, expected %q", output.String(), expected)
+	}
+	output.Reset()
+	printDiveGas(&output, GasComposition{Oxygen: 0.21, Helium: 0.35, Nitrogen: 0.44}, settings, IdealGas, 293.15, Metric)
+	if strings.Contains(output.String(), "Warning") {
+		t.Errorf("Unexpected warning for normoxic trimix: %q", output.String())
+	}
+	output.Reset()
+	printDiveGas(&output, GasComposition{Oxygen: 0.10, Helium: 0.90}, DiveGasSettings{}, IdealGas, 293.15, Metric)
+	if strings.Contains(output.String(), "Warning") {
+		t.Errorf("Unexpected warning without a threshold: %q", output.String())
+	}
+}
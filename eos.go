@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// CriticalProperties holds critical point data and acentric factor of a gas, used by cubic equations of state
+type CriticalProperties struct {
+	Temperature    Temperature
+	Pressure       PressureBar
+	AcentricFactor float64
+}
+
+// ParseGasSystem returns gas system matching the name
+func ParseGasSystem(name string) (GasSystem, error) {
+	switch strings.ToLower(name) {
+	case "ideal":
+		return IdealGas, nil
+	case "vdw", "van-der-waals":
+		return VanDerWaals, nil
+	case "rk", "redlich-kwong":
+		return RedlichKwong, nil
+	case "srk", "soave-redlich-kwong":
+		return SoaveRedlichKwong, nil
+	case "pr", "peng-robinson":
+		return PengRobinson, nil
+	case "z", "z-table":
+		return CompressibilityTableGas, nil
+	case "virial":
+		return Virial, nil
+	}
+	return IdealGas, fmt.Errorf("unknown gas system %q; must be ideal, vdw, rk, srk, pr, z-table or virial", name)
+}
+
+// redlichKwongPressure returns pressure for the given molar volume (l/mol) with the Redlich-Kwong equation of
+// state, or with the Soave modification when soave is set.
+func redlichKwongPressure(molarVolume float64, temperature Temperature, criticalProperties CriticalProperties, soave bool) float64 {
+	T := float64(temperature)
+	Tc := float64(criticalProperties.Temperature)
+	Pc := float64(criticalProperties.Pressure)
+	b := 0.08664 * R * Tc / Pc
+	var attraction float64
+	if soave {
+		omega := criticalProperties.AcentricFactor
+		m := 0.480 + 1.574*omega - 0.176*omega*omega
+		alpha := math.Pow(1+m*(1-math.Sqrt(T/Tc)), 2)
+		attraction = 0.42748 * R * R * Tc * Tc / Pc * alpha
+	} else {
+		attraction = 0.42748 * R * R * math.Pow(Tc, 2.5) / Pc / math.Sqrt(T)
+	}
+	return R*T/(molarVolume-b) - attraction/(molarVolume*(molarVolume+b))
+}
+
+// pengRobinsonPressure returns pressure for the given molar volume (l/mol) with the Peng-Robinson equation of state
+func pengRobinsonPressure(molarVolume float64, temperature Temperature, criticalProperties CriticalProperties) float64 {
+	T := float64(temperature)
+	Tc := float64(criticalProperties.Temperature)
+	Pc := float64(criticalProperties.Pressure)
+	omega := criticalProperties.AcentricFactor
+	b := 0.07780 * R * Tc / Pc
+	kappa := 0.37464 + 1.54226*omega - 0.26992*omega*omega
+	alpha := math.Pow(1+kappa*(1-math.Sqrt(T/Tc)), 2)
+	attraction := 0.45724 * R * R * Tc * Tc / Pc * alpha
+	return R*T/(molarVolume-b) - attraction/(molarVolume*molarVolume+2*b*molarVolume-b*b)
+}
+
+// solveMolarVolume finds the gas phase molar volume (l/mol) for the given pressure using Newton's method,
+// starting from the ideal gas molar volume.
+func solveMolarVolume(pressure PressureBar, temperature Temperature, pressureFunc func(molarVolume float64) float64) float64 {
+	P := float64(pressure)
+	molarVolume := R * float64(temperature) / P
+	for i := 0; i < 100; i++ {
+		difference := pressureFunc(molarVolume) - P
+		step := molarVolume * 1e-6
+		derivative := (pressureFunc(molarVolume+step) - pressureFunc(molarVolume-step)) / (2 * step)
+		if derivative >= 0 {
+			// Outside the mechanically stable region; move towards larger volume
+			molarVolume *= 1.1
+			continue
+		}
+		next := molarVolume - difference/derivative
+		if next <= 0 {
+			next = molarVolume / 2
+		}
+		if math.Abs(next-molarVolume) < 1e-12*molarVolume {
+			return next
+		}
+		molarVolume = next
+	}
+	return molarVolume
+}
+
+// pureGasSystem calculates a single gas at its partial pressure. Mixes are handled by adding up the gases
+// (Dalton's law).
+type pureGasSystem interface {
+	gasMoles(cylinderVolume CylinderVolume, pressure PressureBar, gasType Gas, temperature Temperature) MoleCount
+	gasPressure(cylinderVolume CylinderVolume, moleCount MoleCount, gasType Gas, temperature Temperature) PressureBar
+}
+
+// additiveMoles returns moles of a mix as a sum of each gas at its partial pressure
+func additiveMoles(gasSystem pureGasSystem, cylinderVolume CylinderVolume, pressure PressureBar, temperature Temperature, gasComposition GasComposition) MoleCount {
+	var moles MoleCount
+	for gasType, gasInfo := range gasComposition {
+		if gasInfo == 0 || pressure <= 0 {
+			continue
+		}
+		moles += gasSystem.gasMoles(cylinderVolume, pressure.PartialPressure(gasInfo), gasType, temperature)
+	}
+	return moles
+}
+
+// additivePressure returns pressure at which additiveMoles matches the given number of moles. The partial pressure
+// sum is used as the starting point for Newton's method.
+func additivePressure(gasSystem pureGasSystem, cylinderVolume CylinderVolume, moleCount MoleCount, temperature Temperature, gasComposition GasComposition) PressureBar {
+	if moleCount <= 0 {
+		return 0
+	}
+	var pressure PressureBar
+	for gasType, gasInfo := range gasComposition {
+		if gasInfo == 0 {
+			continue
+		}
+		pressure += gasSystem.gasPressure(cylinderVolume, MoleCount(float64(moleCount)*gasInfo), gasType, temperature)
+	}
+	for i := 0; i < 50 && pressure > 0; i++ {
+		difference := additiveMoles(gasSystem, cylinderVolume, pressure, temperature, gasComposition) - moleCount
+		step := pressure * 1e-6
+		derivative := (additiveMoles(gasSystem, cylinderVolume, pressure+step, temperature, gasComposition) - additiveMoles(gasSystem, cylinderVolume, pressure-step, temperature, gasComposition)) / MoleCount(2*step)
+		if derivative <= 0 {
+			break
+		}
+		next := pressure - PressureBar(difference/derivative)
+		if math.Abs(float64(next-pressure)) < 1e-12*float64(pressure) {
+			return next
+		}
+		pressure = next
+	}
+	return pressure
+}
+
+// IdealGasSystem uses ideal gas equations which do not compensate for pressure and temperature
+type IdealGasSystem struct{}
+
+// IdealGas is the ideal gas system
+var IdealGas = IdealGasSystem{}
+
+// Moles returns number of moles of the gas mix
+func (s IdealGasSystem) Moles(cylinderVolume CylinderVolume, pressure PressureBar, temperature Temperature, gasComposition GasComposition) MoleCount {
+	return additiveMoles(s, cylinderVolume, pressure, temperature, gasComposition)
+}
+
+// Pressure returns pressure of the gas mix
+func (s IdealGasSystem) Pressure(cylinderVolume CylinderVolume, moleCount MoleCount, temperature Temperature, gasComposition GasComposition) PressureBar {
+	return additivePressure(s, cylinderVolume, moleCount, temperature, gasComposition)
+}
+
+func (IdealGasSystem) gasMoles(cylinderVolume CylinderVolume, pressure PressureBar, gasType Gas, temperature Temperature) MoleCount {
+	return MoleCount(float64(pressure) * float64(cylinderVolume) / (R * float64(temperature)))
+}
+
+func (IdealGasSystem) gasPressure(cylinderVolume CylinderVolume, moleCount MoleCount, gasType Gas, temperature Temperature) PressureBar {
+	return PressureBar(float64(moleCount) * R * float64(temperature) / float64(cylinderVolume))
+}
+
+// VanDerWaalsSystem uses Van Der Waals equations to compensate for temperature and pressure. Mixes are treated as a
+// single fluid with constants from mixing rules, or as a sum of partial pressures when Additive is set.
+type VanDerWaalsSystem struct {
+	Additive bool
+}
+
+// VanDerWaals is the Van der Waals gas system using mixing rules
+var VanDerWaals = VanDerWaalsSystem{}
+
+// AdditiveVanDerWaals is the Van der Waals gas system summing each gas at its partial pressure
+var AdditiveVanDerWaals = VanDerWaalsSystem{Additive: true}
+
+// MixVanDerWaalsConstants returns Van der Waals constants for a gas mix using the classical mixing rules:
+// a is combined pairwise from geometric means, b is the mole fraction weighted mean.
+func MixVanDerWaalsConstants(gasComposition GasComposition) VanDerWaalsConstant {
+	var mixed VanDerWaalsConstant
+	for gas1, fraction1 := range gasComposition {
+		for gas2, fraction2 := range gasComposition {
+			mixed.A += fraction1 * fraction2 * math.Sqrt(SpeciesLookup[gas1].VanDerWaals.A*SpeciesLookup[gas2].VanDerWaals.A)
+		}
+		mixed.B += fraction1 * SpeciesLookup[gas1].VanDerWaals.B
+	}
+	return mixed
+}
+
+// Moles returns number of moles of the gas mix
+func (s VanDerWaalsSystem) Moles(cylinderVolume CylinderVolume, pressure PressureBar, temperature Temperature, gasComposition GasComposition) MoleCount {
+	if s.Additive {
+		return additiveMoles(s, cylinderVolume, pressure, temperature, gasComposition)
+	}
+	if pressure <= 0 {
+		return 0
+	}
+	return vanDerWaalsMoles(cylinderVolume, pressure, MixVanDerWaalsConstants(gasComposition), temperature)
+}
+
+// vanDerWaalsMoles solves the gas phase molar volume numerically. Unlike the closed form GasToMoles it also works
+// for strongly attracting gases such as CO2, where the cubic has three real roots.
+func vanDerWaalsMoles(cylinderVolume CylinderVolume, pressure PressureBar, vdwConstants VanDerWaalsConstant, temperature Temperature) MoleCount {
+	T := float64(temperature)
+	molarVolume := solveMolarVolume(pressure, temperature, func(molarVolume float64) float64 {
+		return R*T/(molarVolume-vdwConstants.B) - vdwConstants.A/(molarVolume*molarVolume)
+	})
+	return MoleCount(float64(cylinderVolume) / molarVolume)
+}
+
+// Pressure returns pressure of the gas mix
+func (s VanDerWaalsSystem) Pressure(cylinderVolume CylinderVolume, moleCount MoleCount, temperature Temperature, gasComposition GasComposition) PressureBar {
+	if s.Additive {
+		return additivePressure(s, cylinderVolume, moleCount, temperature, gasComposition)
+	}
+	if moleCount <= 0 {
+		return 0
+	}
+	return MolesToPressure(cylinderVolume, moleCount, temperature, MixVanDerWaalsConstants(gasComposition))
+}
+
+func (VanDerWaalsSystem) gasMoles(cylinderVolume CylinderVolume, pressure PressureBar, gasType Gas, temperature Temperature) MoleCount {
+	return vanDerWaalsMoles(cylinderVolume, pressure, SpeciesLookup[gasType].VanDerWaals, temperature)
+}
+
+func (VanDerWaalsSystem) gasPressure(cylinderVolume CylinderVolume, moleCount MoleCount, gasType Gas, temperature Temperature) PressureBar {
+	return MolesToPressure(cylinderVolume, moleCount, temperature, SpeciesLookup[gasType].VanDerWaals)
+}
+
+// RedlichKwongSystem uses Redlich-Kwong equation of state, which is more accurate than Van der Waals at high
+// pressures. Soave selects the Soave temperature dependence.
+type RedlichKwongSystem struct {
+	Soave bool
+}
+
+// RedlichKwong is the Redlich-Kwong gas system
+var RedlichKwong = RedlichKwongSystem{}
+
+// SoaveRedlichKwong is the Soave-Redlich-Kwong gas system
+var SoaveRedlichKwong = RedlichKwongSystem{Soave: true}
+
+// Moles returns number of moles of the gas mix
+func (s RedlichKwongSystem) Moles(cylinderVolume CylinderVolume, pressure PressureBar, temperature Temperature, gasComposition GasComposition) MoleCount {
+	return additiveMoles(s, cylinderVolume, pressure, temperature, gasComposition)
+}
+
+// Pressure returns pressure of the gas mix
+func (s RedlichKwongSystem) Pressure(cylinderVolume CylinderVolume, moleCount MoleCount, temperature Temperature, gasComposition GasComposition) PressureBar {
+	return additivePressure(s, cylinderVolume, moleCount, temperature, gasComposition)
+}
+
+func (s RedlichKwongSystem) gasMoles(cylinderVolume CylinderVolume, pressure PressureBar, gasType Gas, temperature Temperature) MoleCount {
+	criticalProperties := SpeciesLookup[gasType].Critical
+	molarVolume := solveMolarVolume(pressure, temperature, func(molarVolume float64) float64 {
+		return redlichKwongPressure(molarVolume, temperature, criticalProperties, s.Soave)
+	})
+	return MoleCount(float64(cylinderVolume) / molarVolume)
+}
+
+func (s RedlichKwongSystem) gasPressure(cylinderVolume CylinderVolume, moleCount MoleCount, gasType Gas, temperature Temperature) PressureBar {
+	molarVolume := float64(cylinderVolume) / float64(moleCount)
+	return PressureBar(redlichKwongPressure(molarVolume, temperature, SpeciesLookup[gasType].Critical, s.Soave))
+}
+
+// PengRobinsonSystem uses Peng-Robinson equation of state with per-gas acentric factors.
+type PengRobinsonSystem struct{}
+
+// PengRobinson is the Peng-Robinson gas system
+var PengRobinson = PengRobinsonSystem{}
+
+// Moles returns number of moles of the gas mix
+func (s PengRobinsonSystem) Moles(cylinderVolume CylinderVolume, pressure PressureBar, temperature Temperature, gasComposition GasComposition) MoleCount {
+	return additiveMoles(s, cylinderVolume, pressure, temperature, gasComposition)
+}
+
+// Pressure returns pressure of the gas mix
+func (s PengRobinsonSystem) Pressure(cylinderVolume CylinderVolume, moleCount MoleCount, temperature Temperature, gasComposition GasComposition) PressureBar {
+	return additivePressure(s, cylinderVolume, moleCount, temperature, gasComposition)
+}
+
+func (PengRobinsonSystem) gasMoles(cylinderVolume CylinderVolume, pressure PressureBar, gasType Gas, temperature Temperature) MoleCount {
+	criticalProperties := SpeciesLookup[gasType].Critical
+	molarVolume := solveMolarVolume(pressure, temperature, func(molarVolume float64) float64 {
+		return pengRobinsonPressure(molarVolume, temperature, criticalProperties)
+	})
+	return MoleCount(float64(cylinderVolume) / molarVolume)
+}
+
+func (PengRobinsonSystem) gasPressure(cylinderVolume CylinderVolume, moleCount MoleCount, gasType Gas, temperature Temperature) PressureBar {
+	molarVolume := float64(cylinderVolume) / float64(moleCount)
+	return PressureBar(pengRobinsonPressure(molarVolume, temperature, SpeciesLookup[gasType].Critical))
+}
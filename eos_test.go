@@ -0,0 +1,83 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRedlichKwongRoundTrip(t *testing.T) {
+	for _, gasSystem := range []pureGasSystem{RedlichKwong, SoaveRedlichKwong, PengRobinson} {
+		for _, gasType := range []Gas{Helium, Nitrogen, Oxygen} {
+			moles := gasSystem.gasMoles(12, 232, gasType, 293.15)
+			pressure := gasSystem.gasPressure(12, moles, gasType, 293.15)
+			if math.Abs(float64(pressure)-232) > 1e-6 {
+				t.Errorf("Gas system %+v gas %d: pressure %f does not match 232", gasSystem, gasType, pressure)
+			}
+		}
+	}
+}
+
+func TestGasSystemMixRoundTrip(t *testing.T) {
+	trimix := GasComposition{Oxygen: 0.18, Helium: 0.45, Nitrogen: 0.37}
+	for _, gasSystem := range []GasSystem{IdealGas, VanDerWaals, RedlichKwong, SoaveRedlichKwong, PengRobinson, CompressibilityTableGas, Virial} {
+		moles := gasSystem.Moles(12, 232, 293.15, trimix)
+		pressure := gasSystem.Pressure(12, moles, 293.15, trimix)
+		if math.Abs(float64(pressure)-232) > 1e-6 {
+			t.Errorf("Gas system %T: pressure %f does not match 232", gasSystem, pressure)
+		}
+	}
+}
+
+func TestRedlichKwongCompressibility(t *testing.T) {
+	// Nitrogen at 300 bar and 20C is noticeably less compressible than an ideal gas (Z around 1.1)
+	moles := SoaveRedlichKwong.gasMoles(1, 300, Nitrogen, 293.15)
+	z := 300 * 1 / (float64(moles) * R * 293.15)
+	if z < 1.05 || z > 1.2 {
+		t.Errorf("Invalid nitrogen compressibility factor %f", z)
+	}
+	// Ideal gas and low pressure results should match closely
+	idealMoles := IdealGas.gasMoles(1, 1, Nitrogen, 293.15)
+	realMoles := RedlichKwong.gasMoles(1, 1, Nitrogen, 293.15)
+	if math.Abs(float64(realMoles/idealMoles)-1) > 0.001 {
+		t.Errorf("Real gas moles %f differ from ideal %f at 1 bar", realMoles, idealMoles)
+	}
+}
+
+func TestPengRobinsonNitrogen(t *testing.T) {
+	// Measured compressibility factor of nitrogen at 300 bar and 20C is about 1.1
+	moles := PengRobinson.gasMoles(1, 300, Nitrogen, 293.15)
+	z := 300 / (float64(moles) * R * 293.15)
+	if z < 1.05 || z > 1.15 {
+		t.Errorf("Invalid nitrogen compressibility factor %f", z)
+	}
+}
+
+func TestMixVanDerWaalsConstants(t *testing.T) {
+	pure := MixVanDerWaalsConstants(GasComposition{Helium: 1})
+	if !compareFloats(pure.A, SpeciesLookup[Helium].VanDerWaals.A) || !compareFloats(pure.B, SpeciesLookup[Helium].VanDerWaals.B) {
+		t.Errorf("Invalid constants %+v for pure helium", pure)
+	}
+	heliox := MixVanDerWaalsConstants(GasComposition{Helium: 0.5, Oxygen: 0.5})
+	expectedA := 0.25*0.0346 + 0.25*1.382 + 0.5*math.Sqrt(0.0346*1.382)
+	if !compareFloats(heliox.A, expectedA) {
+		t.Errorf("Invalid mixed a %f, expected %f", heliox.A, expectedA)
+	}
+	if !compareFloats(heliox.B, (0.0238+0.03186)/2) {
+		t.Errorf("Invalid mixed b %f", heliox.B)
+	}
+}
+
+func TestVirialNitrogen(t *testing.T) {
+	B, C := VirialCoefficients(Nitrogen, 293.15)
+	if B > -0.004 || B < -0.008 {
+		t.Errorf("Second virial coefficient %f for nitrogen is out of range", B)
+	}
+	if C < 0.001 || C > 0.002 {
+		t.Errorf("Third virial coefficient %f for nitrogen is out of range", C)
+	}
+	moles := Virial.Moles(1, 200, 293.15, GasComposition{Nitrogen: 1})
+	z := 200 / (float64(moles) * R * 293.15)
+	if z < 1.02 || z > 1.06 {
+		t.Errorf("Compressibility %f for nitrogen at 200 bar is out of range", z)
+	}
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/template"
+)
+
+func equalizeMain(args []string) error {
+	fs := flag.NewFlagSet("equalize", flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	gasFlags := registerGasCompositionFlags(fs)
+	var sourceCylinderVolumeFlag = fs.String("source-cylinder-volume", "24l", "Source cylinder volume (l or cuft), or rated capacity such as 77.4cuft@3000psi")
+	var destinationCylinderVolumeFlag = fs.String("destination-cylinder-volume", "24l", "Destination cylinder volume (l or cuft), or rated capacity such as 77.4cuft@3000psi")
+	var sourceCylinderPressureFlag = fs.String("source-cylinder-pressure", "232bar", "Source cylinder pressure (bar, psi, MPa, kPa or atm)")
+	var destinationCylinderPressureFlag = fs.String("destination-cylinder-pressure", "100bar", "Destination cylinder pressure (bar, psi, MPa, kPa or atm)")
+	var sourceCylinderIsTwinsetFlag = fs.Bool("source-cylinder-twinset", false, "Source cylinder is a twinset with a closeable manifold")
+	var destinationCylinderIsTwinsetFlag = fs.Bool("destination-cylinder-twinset", false, "Destination cylinder is a twinset with a closeable manifold")
+	var sourceCylinderSetFlag = fs.Int("source-cylinder-set", 0, "Source cylinder is a manifolded set of this many equal cylinders, e.g. 4 for a bank quad, with an isolator between neighbouring cylinders")
+	var destinationCylinderSetFlag = fs.Int("destination-cylinder-set", 0, "Destination cylinder is a manifolded set of this many equal cylinders, e.g. 3 for a rebreather triple, with an isolator between neighbouring cylinders")
+	var compareIsolatorsFlag = fs.Bool("compare-isolators", false, "Also compare each combination of open and closed isolators between neighbouring -source or -destination cylinders of a side with more than two; on by default with a -source-cylinder-set or -destination-cylinder-set above 2")
+	var sourceFlags, destinationFlags stringListFlag
+	fs.Var(&sourceFlags, "source", "Source cylinder as [name=]volume@pressure[:mix][,o2clean], e.g. 50l@200bar:32,o2clean, or a bank preset such as bank1=50l-300bar, filled to its working pressure unless @pressure follows; repeat for multiple cylinders")
+	fs.Var(&destinationFlags, "destination", "Destination cylinder as [name=]volume@pressure[:mix][,wp=pressure][,tp=pressure][,o2clean], e.g. left=12l@50bar:21/35,wp=232bar; repeat for multiple cylinders")
+	var fillProcessFlag = fs.String("fill-process", "isothermal", "Gas temperature during transfers: isothermal, adiabatic (fast fill without heat exchange) or polytropic; results are reported after cooling down")
+	var stopAtDestinationPressureFlag = fs.String("stop-at-destination-pressure", "", "Stop each transfer when the destination reaches this pressure instead of equalizing, and report the pressure left in the sources")
+	var bankModeFlag = fs.Bool("bank-mode", false, "Sources are industrial storage cylinders: those without a test pressure may hold up to 700 bar instead of 350 bar, while transfers stop at the pressure limit of the destinations")
+	var independentDestinationsFlag = fs.Bool("independent-destinations", false, "Destination cylinders are independent, such as sidemount cylinders, and never joined by a manifold; prints the pressure of each and a recommended fill order with targets")
+	var manifoldStrategyFlags stringListFlag
+	fs.Var(&manifoldStrategyFlags, "manifold-strategy", "Also compare opening the destination isolator during the fill: open, closed or crack:fraction for each transfer in turn, e.g. crack:0.3 or closed,open; repeat for multiple strategies")
+	var explainFlag = fs.Bool("explain", false, "Print the equations, substituted values and intermediate results, such as moles of each gas and partial pressures, of each transfer")
+	cyclesFlag := registerCyclesFlag(fs)
+	var polytropicExponentFlag = fs.Float64("polytropic-exponent", 1.2, "Polytropic exponent for -fill-process polytropic; 1 is isothermal")
+	var scenarioFlag = fs.String("scenario", "", "JSON scenario file describing source and destination cylinders")
+	var boosterRatioFlag = fs.Float64("booster-ratio", 0, "Booster drive to gas piston area ratio; boosting is disabled when 0")
+	var boosterDrivePressureFlag = fs.String("booster-drive-pressure", "8bar", "Booster drive gas pressure")
+	var boosterTargetPressureFlag = fs.String("booster-target-pressure", "232bar", "Pressure the booster fills the destination to")
+	var boosterMinimumInletPressureFlag = fs.String("booster-min-inlet-pressure", "10bar", "Lowest source pressure the booster can pump from")
+	var compressorFreeAirDeliveryFlag = fs.Float64("compressor-fad", 0, "Compressor free air delivery in l/min; compressor top-off is disabled when 0")
+	var compressorMaxPressureFlag = fs.String("compressor-max-pressure", "300bar", "Compressor maximum pressure")
+	var compressorTargetPressureFlag = fs.String("compressor-target-pressure", "232bar", "Pressure the compressor fills the destination to")
+	var fillRateFlag = fs.String("fill-rate", "", "Throttle the fill to this pressure rise per minute, e.g. 20bar, and report the destination temperature and the highest rate below -temperature-limit")
+	var temperatureLimitFlag = fs.String("temperature-limit", "60C", "Highest destination temperature for -fill-rate")
+	var heatTransferFlag = fs.Float64("heat-transfer", 20, "Heat transfer coefficient between the destination gas and the surroundings in W/K for -fill-rate")
+	var valveCvFlag = fs.Float64("valve-cv", 0, "Flow coefficient (Cv) of the whip and valves; estimates how long transfers take when set")
+	var whipVolumeFlag = fs.String("whip-volume", "", "Internal volume of the transfer whip, e.g. 0.05l; gas left in the whip is vented after each transfer")
+	var whipConnectionsFlag = fs.Int("whip-connections", 1, "Connect and disconnect cycles of the whip per transfer, e.g. for topping up in several goes")
+	var strictFlag = fs.Bool("strict", false, "Refuse transfers taking a destination above its working or test pressure, or putting oxygen into equipment that is not oxygen clean, instead of warning")
+	var oxygenCleanRulesFlag = fs.String("oxygen-clean-rules", "us", "Oxygen fraction above which cylinders and the whip must be oxygen clean: us (40%) or eu (21%)")
+	var oxygenCleanThresholdFlag = fs.Float64("oxygen-clean-threshold", 0, "Oxygen percentage above which cylinders and the whip must be oxygen clean, overriding -oxygen-clean-rules")
+	var whipO2CleanFlag = fs.Bool("whip-o2-clean", false, "The whip is oxygen clean")
+	var targetPressureFlag = fs.String("target-pressure", "", "Report whether transfers reach this destination pressure and, if not, the shortfall and the compressor or booster work needed")
+	var solveFlag = fs.String("solve", "", "Solve for the source instead of equalizing: source-pressure finds the pressure the sources need and source-volume the source volume needed at their pressures to fill the destination to -target-pressure")
+	var sensitivityFlag = fs.Bool("sensitivity", false, "Report how sensitive destination pressures are to each pressure reading and the temperature")
+	var gaugeErrorFlag = fs.String("gauge-error", "5bar", "Reading error of pressure gauges for -sensitivity")
+	var temperatureErrorFlag = fs.String("temperature-error", "2C", "Reading error of the temperature for -sensitivity")
+	var sweepTemperatureFlag = fs.String("sweep-temperature", "", "Rerun at temperatures start:end:step, e.g. 0:40:5, and print destination pressures at each temperature")
+	var progressFlag = registerProgressFlag(fs)
+	var reportFlag = fs.String("report", "", "Write an HTML report of the cylinders, transfers, results and warnings to this file")
+	var exportFlag = fs.String("export", "", "Write the worked calculation of the transfers, with the equations and the numbers substituted, to this .md (Markdown) or .tex (LaTeX) file")
+	var worksheetFlag = fs.String("worksheet", "", "Write a printable PDF worksheet with the transfers of the best configuration and fields for the analyzed mix to this file")
+	var logFillFlag = fs.String("log-fill", "", "Append the cylinders and the result of the best configuration to this SQLite fill log; needs the sqlite3 command")
+	bankFlags := registerBankStateFlags(fs)
+	priceFlags := registerPriceFlags(fs)
+	diveGasFlags := registerDiveGasFlags(fs)
+	analyzerFlags := registerAnalyzerFlags(fs)
+	diveRequirementFlags := registerDiveRequirementFlags(fs)
+	mixSpecificationFlags := registerMixSpecificationFlags(fs)
+	var chartFlag = fs.String("chart", "", "Write a chart to this .svg or .png file: cylinder pressures across transfer steps of the best configuration, or destination pressures by temperature with -sweep-temperature")
+	var templateFlag = fs.String("template", "", "Print only this Go template applied to the summary, e.g. '{{printf \"%.0f\" .DestinationCylinderPressure}}'; fields are those of the best configuration and .Summaries lists all configurations")
+	var quietFlag = fs.Bool("quiet", false, "Print only the -output value of the configuration reaching the highest destination pressure, for shell scripts and spreadsheets")
+	var outputFlag = fs.String("output", "dest-pressure", "Value printed with -quiet: dest-pressure, dest-volume, dest-weight, dest-mix, src-pressure, src-volume, src-weight, src-mix, whip-volume, cost or configuration")
+	var sortFlag = fs.String("sort", "configuration", "Order of the summary table: configuration, dst-pressure, improvement, dst-volume, src-pressure or cost")
+	var minImprovementFlag = fs.Float64("min-improvement", 0, "Hide configurations improving the destination pressure by less than this many percent on the baseline")
+	var baselineFlag = fs.String("baseline", "worst", "Configuration improvements are relative to: worst, or a configuration such as \"all manifolds open\"")
+	fs.Parse(args)
+
+	// All results are written to w; logs go to stderr
+	var w io.Writer = os.Stdout
+	summaryOptions, err := newSummaryOptions(*sortFlag, *minImprovementFlag, *baselineFlag)
+	if err != nil {
+		return err
+	}
+	if summaryOptions.style, err = flags.parseTableStyle(); err != nil {
+		return err
+	}
+	var outputTemplate *template.Template
+	if *quietFlag && *templateFlag != "" {
+		return errors.New("-quiet and -template can not be used together")
+	}
+	if *templateFlag != "" || *quietFlag {
+		if *quietFlag {
+			outputTemplate, err = quietOutputTemplate(*outputFlag)
+		} else {
+			outputTemplate, err = parseOutputTemplate(*templateFlag)
+		}
+		if err != nil {
+			return err
+		}
+		w = io.Discard
+	}
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	if w, err = flags.output(w, units); err != nil {
+		return err
+	}
+	gasSystem, temperature, err := flags.gasSettings(units)
+	if err != nil {
+		return err
+	}
+	gasComposition, err := gasFlags.gasComposition()
+	if err != nil {
+		return err
+	}
+	diveGasSettings, err := diveGasFlags.settings(units)
+	if err != nil {
+		return err
+	}
+	analyzerModel, analysisTemperature, err := analyzerFlags.model(temperature, units)
+	if err != nil {
+		return err
+	}
+	diveRequirement, reservePolicy, err := diveRequirementFlags.requirement(diveGasSettings.PlannedDepth, units)
+	if err != nil {
+		return err
+	}
+	mixSpecification, err := mixSpecificationFlags.specification()
+	if err != nil {
+		return err
+	}
+	var sourceCylinders, destinationCylinders CylinderList
+	if *scenarioFlag != "" {
+		scenario, err := LoadScenario(*scenarioFlag)
+		if err != nil {
+			return fmt.Errorf("unable to load scenario: %w", err)
+		}
+		sourceCylinders, destinationCylinders, err = scenario.Cylinders(units)
+		if err != nil {
+			return fmt.Errorf("invalid scenario: %w", err)
+		}
+	}
+	if len(sourceFlags) > 0 {
+		sourceCylinders, err = units.ParseCylinderSpecs(sourceFlags, "source")
+		if err != nil {
+			return fmt.Errorf("invalid source cylinder: %w", err)
+		}
+	}
+	banks, err := bankFlags.banks(w, units)
+	if err != nil {
+		return err
+	}
+	sourceCylinders = append(sourceCylinders, banks...)
+	if len(destinationFlags) > 0 {
+		destinationCylinders, err = units.ParseCylinderSpecs(destinationFlags, "destination")
+		if err != nil {
+			return fmt.Errorf("invalid destination cylinder: %w", err)
+		}
+	}
+	if *sourceCylinderSetFlag < 0 || *sourceCylinderSetFlag > maxCylinderSet || *destinationCylinderSetFlag < 0 || *destinationCylinderSetFlag > maxCylinderSet {
+		return fmt.Errorf("invalid cylinder set; must be between 0 and %d cylinders", maxCylinderSet)
+	}
+	if len(sourceCylinders) == 0 {
+		sourceCylinderVolume, err := units.ParseCylinderVolume(*sourceCylinderVolumeFlag)
+		if err != nil {
+			return fmt.Errorf("invalid source cylinder volume: %w", err)
+		}
+		sourceCylinderPressure, err := units.ParsePressure(*sourceCylinderPressureFlag)
+		if err != nil {
+			return fmt.Errorf("invalid source cylinder pressure: %w", err)
+		}
+		if *sourceCylinderSetFlag > 1 {
+			sourceCylinders = NewCylinderSet(sourceCylinderVolume, sourceCylinderPressure, *sourceCylinderSetFlag)
+		} else if *sourceCylinderIsTwinsetFlag {
+			sourceCylinders = NewTwinset(sourceCylinderVolume, sourceCylinderPressure)
+		} else {
+			sourceCylinders = CylinderList{{Description: "source", CylinderVolume: sourceCylinderVolume, Pressure: sourceCylinderPressure}}
+		}
+	}
+	if len(destinationCylinders) == 0 {
+		destinationCylinderVolume, err := units.ParseCylinderVolume(*destinationCylinderVolumeFlag)
+		if err != nil {
+			return fmt.Errorf("invalid destination cylinder volume: %w", err)
+		}
+		destinationCylinderPressure, err := units.ParsePressure(*destinationCylinderPressureFlag)
+		if err != nil {
+			return fmt.Errorf("invalid destination cylinder pressure: %w", err)
+		}
+		if *destinationCylinderSetFlag > 1 {
+			destinationCylinders = NewCylinderSet(destinationCylinderVolume, destinationCylinderPressure, *destinationCylinderSetFlag)
+		} else if *destinationCylinderIsTwinsetFlag {
+			destinationCylinders = NewTwinset(destinationCylinderVolume, destinationCylinderPressure)
+		} else {
+			destinationCylinders = CylinderList{{Description: "destination", CylinderVolume: destinationCylinderVolume, Pressure: destinationCylinderPressure}}
+		}
+	}
+
+	if err := checkCylinders(destinationCylinders, "destination", true, units); err != nil {
+		return err
+	}
+	var bankModeWarnings []string
+	if *bankModeFlag {
+		if bankModeWarnings, err = checkBankCylinders(sourceCylinders, "source", gasSystem, units); err != nil {
+			return err
+		}
+	} else if err := checkCylinders(sourceCylinders, "source", false, units); err != nil {
+		return err
+	}
+	sourceCylinders.SetDefaultGasComposition(gasComposition)
+	destinationCylinders.SetDefaultGasComposition(gasComposition)
+	if *solveFlag == "" && sourceCylinders.MaxPressure() < destinationCylinders.MaxPressure() {
+		return errors.New("source pressure must be higher than destination pressure")
+	}
+	cylinderConfiguration := CylinderConfiguration{
+		DestinationCylinders: destinationCylinders,
+		SourceCylinders:      sourceCylinders,
+	}
+	if cylinderConfiguration.FillProcess, err = ParseFillProcess(*fillProcessFlag, *polytropicExponentFlag); err != nil {
+		return err
+	}
+	if *cyclesFlag < 1 {
+		return errors.New("invalid cycles; must be >=1")
+	}
+	cylinderConfiguration.Cycles = *cyclesFlag
+	if *stopAtDestinationPressureFlag != "" {
+		if !cylinderConfiguration.FillProcess.Isothermal() {
+			return errors.New("-stop-at-destination-pressure needs an isothermal -fill-process")
+		}
+		if cylinderConfiguration.StopPressure, err = units.ParsePressure(*stopAtDestinationPressureFlag); err != nil {
+			return fmt.Errorf("invalid stop pressure: %w", err)
+		}
+	}
+	if limit := destinationCylinders.pressureLimit(units); *bankModeFlag && (cylinderConfiguration.StopPressure > limit || cylinderConfiguration.StopPressure == 0 && sourceCylinders.MaxPressure() > limit) {
+		if cylinderConfiguration.StopPressure > limit || !cylinderConfiguration.FillProcess.Isothermal() {
+			return fmt.Errorf("-bank-mode with sources above the %.0f%s limit of the destinations needs an isothermal -fill-process and a -stop-at-destination-pressure within the limit", units.Pressure(limit), units.PressureUnit())
+		}
+		cylinderConfiguration.StopPressure = limit
+		bankModeWarnings = append(bankModeWarnings, fmt.Sprintf("Bank mode: the sources are above the %.0f%s limit of the destinations, so transfers stop at it", units.Pressure(limit), units.PressureUnit()))
+	}
+	for _, warning := range bankModeWarnings {
+		fmt.Fprintln(w, colorize(w, colorCaution, warning))
+	}
+	cylinderConfiguration.IndependentDestinations = *independentDestinationsFlag
+	cylinderConfiguration.CompareIsolators = *compareIsolatorsFlag || *sourceCylinderSetFlag > 2 || *destinationCylinderSetFlag > 2
+	for _, value := range manifoldStrategyFlags {
+		if !cylinderConfiguration.FillProcess.Isothermal() || cylinderConfiguration.StopPressure > 0 {
+			return errors.New("-manifold-strategy needs an isothermal -fill-process and cannot be used with -stop-at-destination-pressure")
+		}
+		strategy, err := ParseManifoldStrategy(value)
+		if err != nil {
+			return err
+		}
+		cylinderConfiguration.ManifoldStrategies = append(cylinderConfiguration.ManifoldStrategies, strategy)
+	}
+	cylinderConfiguration.Explain = *explainFlag
+	if cylinderConfiguration.Prices, err = priceFlags.prices(units); err != nil {
+		return err
+	}
+	if *boosterRatioFlag > 0 {
+		booster := Booster{Ratio: *boosterRatioFlag}
+		if booster.DrivePressure, err = units.ParsePressureDifference(*boosterDrivePressureFlag); err != nil {
+			return fmt.Errorf("invalid booster drive pressure: %w", err)
+		}
+		if booster.MinimumInletPressure, err = units.ParsePressure(*boosterMinimumInletPressureFlag); err != nil {
+			return fmt.Errorf("invalid booster minimum inlet pressure: %w", err)
+		}
+		if cylinderConfiguration.BoostTargetPressure, err = units.ParsePressure(*boosterTargetPressureFlag); err != nil {
+			return fmt.Errorf("invalid booster target pressure: %w", err)
+		}
+		if limit := destinationCylinders.pressureLimit(units); cylinderConfiguration.BoostTargetPressure > limit {
+			return fmt.Errorf("invalid booster target pressure; must be <=%.0f%s", units.Pressure(limit), units.PressureUnit())
+		}
+		cylinderConfiguration.Booster = &booster
+	}
+	if *compressorFreeAirDeliveryFlag > 0 {
+		compressor := Compressor{FreeAirDelivery: *compressorFreeAirDeliveryFlag}
+		if compressor.MaxPressure, err = units.ParsePressure(*compressorMaxPressureFlag); err != nil {
+			return fmt.Errorf("invalid compressor maximum pressure: %w", err)
+		}
+		if cylinderConfiguration.CompressorTargetPressure, err = units.ParsePressure(*compressorTargetPressureFlag); err != nil {
+			return fmt.Errorf("invalid compressor target pressure: %w", err)
+		}
+		if limit := destinationCylinders.pressureLimit(units); cylinderConfiguration.CompressorTargetPressure > limit {
+			return fmt.Errorf("invalid compressor target pressure; must be <=%.0f%s", units.Pressure(limit), units.PressureUnit())
+		}
+		cylinderConfiguration.Compressor = &compressor
+	}
+	if cylinderConfiguration.IndependentDestinations && (cylinderConfiguration.Booster != nil || cylinderConfiguration.Compressor != nil) {
+		return errors.New("-independent-destinations cannot be used with -booster-ratio or -compressor-fad")
+	}
+	var slowFill *SlowFill
+	var temperatureLimit Temperature
+	if *fillRateFlag != "" {
+		slowFill = &SlowFill{HeatTransfer: *heatTransferFlag}
+		if slowFill.Rate, err = units.ParsePressureDifference(*fillRateFlag); err != nil || slowFill.Rate <= 0 {
+			return errors.New("invalid fill rate; must be >0")
+		}
+		if slowFill.HeatTransfer < 0 {
+			return errors.New("invalid heat transfer coefficient; must be >=0")
+		}
+		if temperatureLimit, err = units.ParseTemperature(*temperatureLimitFlag); err != nil {
+			return fmt.Errorf("invalid temperature limit: %w", err)
+		}
+	}
+	if *valveCvFlag < 0 {
+		return errors.New("invalid valve Cv; must be >=0")
+	}
+	cylinderConfiguration.FlowCoefficient = *valveCvFlag
+	if *whipVolumeFlag != "" {
+		whip := Whip{Connections: *whipConnectionsFlag}
+		if whip.Volume, err = units.ParseCylinderVolume(*whipVolumeFlag); err != nil || whip.Volume < 0 {
+			return errors.New("invalid whip volume; must be >=0")
+		}
+		if whip.Connections < 1 {
+			return errors.New("invalid whip connections; must be >=1")
+		}
+		cylinderConfiguration.Whip = &whip
+	}
+	oxygenCleanThreshold, err := ParseOxygenCleanRules(*oxygenCleanRulesFlag)
+	if err != nil {
+		return err
+	}
+	if *oxygenCleanThresholdFlag < 0 || *oxygenCleanThresholdFlag > 100 {
+		return errors.New("invalid oxygen clean threshold; must be between 0 and 100")
+	}
+	if *oxygenCleanThresholdFlag > 0 {
+		oxygenCleanThreshold = *oxygenCleanThresholdFlag / 100
+	}
+	if warnings := oxygenCleanViolations(cylinderConfiguration, oxygenCleanThreshold, *whipO2CleanFlag); len(warnings) > 0 {
+		for _, warning := range warnings {
+			fmt.Fprintln(w, colorize(w, colorDanger, warning))
+		}
+		if *strictFlag {
+			return errors.New("equipment is not oxygen clean; refusing with -strict")
+		}
+	}
+	var targetPressure PressureBar
+	if *targetPressureFlag != "" {
+		if targetPressure, err = units.ParsePressure(*targetPressureFlag); err != nil {
+			return fmt.Errorf("invalid target pressure: %w", err)
+		}
+	}
+	if *solveFlag != "" {
+		if targetPressure == 0 {
+			return errors.New("-solve needs -target-pressure")
+		}
+		switch *solveFlag {
+		case "source-pressure":
+			solution, err := SolveSourcePressure(cylinderConfiguration, targetPressure, gasSystem, temperature, units)
+			if err != nil {
+				return fmt.Errorf("unable to solve source pressure: %w", err)
+			}
+			printSourcePressureSolution(w, solution, targetPressure, units)
+		case "source-volume":
+			solution, err := SolveSourceVolume(cylinderConfiguration, targetPressure, gasSystem, temperature, units)
+			if err != nil {
+				return fmt.Errorf("unable to solve source volume: %w", err)
+			}
+			printSourceVolumeSolution(w, solution, targetPressure, units)
+		default:
+			return errors.New("invalid solve mode; must be source-pressure or source-volume")
+		}
+		return nil
+	}
+	if *sweepTemperatureFlag != "" {
+		temperatures, err := units.ParseTemperatureSweep(*sweepTemperatureFlag)
+		if err != nil {
+			return err
+		}
+		progress, err := newProgressReporter(os.Stderr, *progressFlag, "temperature sweep", len(temperatures))
+		if err != nil {
+			return err
+		}
+		sweep := temperatureSweep(cylinderConfiguration, gasSystem, temperatures, units, progress)
+		printTemperatureSweep(w, temperatures, sweep, units)
+		if *chartFlag != "" {
+			if err := writeChart(*chartFlag, temperatureSweepChart(temperatures, sweep, units)); err != nil {
+				return fmt.Errorf("unable to write chart: %w", err)
+			}
+		}
+		return nil
+	}
+	if *sensitivityFlag {
+		gaugeError, err := units.ParsePressureDifference(*gaugeErrorFlag)
+		if err != nil || gaugeError < 0 {
+			return errors.New("invalid gauge error; must be >=0")
+		}
+		temperatureError, err := units.ParseTemperatureDifference(*temperatureErrorFlag)
+		if err != nil || temperatureError < 0 {
+			return errors.New("invalid temperature error; must be >=0")
+		}
+		printSensitivities(w, pressureSensitivities(cylinderConfiguration, gasSystem, temperature, units, gaugeError, temperatureError), units)
+		return nil
+	}
+	var transferSteps []TransferStep
+	cylinderConfiguration.OnTransferStep = func(step TransferStep) {
+		transferSteps = append(transferSteps, step)
+	}
+	var explanations []equalizeExplanation
+	if *exportFlag != "" {
+		cylinderConfiguration.OnExplanation = func(explanation equalizeExplanation) {
+			explanations = append(explanations, explanation)
+		}
+	}
+	cylinderSummaries := equalizeAllConfigurations(w, cylinderConfiguration, gasSystem, temperature, units, *flags.verbose, slog.Default())
+	printSummaries(w, cylinderSummaries, summaryOptions, units, *flags.verbose)
+	best := bestSummary(cylinderSummaries)
+	if warnings := ratedPressureWarnings(destinationCylinders, transferSteps, cylinderSummaries, units); len(warnings) > 0 {
+		for _, warning := range warnings {
+			fmt.Fprintln(w, colorize(w, colorDanger, warning))
+		}
+		if *strictFlag {
+			return errors.New("rated cylinder pressures exceeded; refusing with -strict")
+		}
+	}
+	if cylinderConfiguration.IndependentDestinations && len(destinationCylinders) > 1 {
+		targetPressures := make([]PressureBar, len(destinationCylinders))
+		for i := range targetPressures {
+			targetPressures[i] = targetPressure
+		}
+		fmt.Fprintln(w, tr(w, "Recommended fill order of the independent destinations:"))
+		printMultiDestinationPlan(w, PlanMultiDestinationFill(sourceCylinders, destinationCylinders, targetPressures, gasSystem, temperature), units)
+	}
+	printDiveGas(w, best.DestinationGasComposition, diveGasSettings, gasSystem, temperature, units)
+	if analyzerModel != nil {
+		printAnalyzerReading(w, best.DestinationGasComposition, analyzerModel.Reading(best.DestinationGasComposition, analysisTemperature), analysisTemperature, units)
+	}
+	mixWithinSpecification := true
+	if mixSpecification != nil {
+		mixWithinSpecification = printMixSpecification(w, *mixSpecification, best.DestinationGasComposition)
+	}
+	if diveRequirement != nil {
+		destination := Cylinder{CylinderVolume: destinationCylinders.TotalVolume(), Pressure: best.DestinationCylinderPressure, GasComposition: best.DestinationGasComposition}
+		printDiveRequirement(w, *diveRequirement, reservePolicy, destination, gasSystem, temperature, units)
+	}
+	if slowFill != nil {
+		destination := openManifold(append(CylinderList(nil), destinationCylinders...), "destination", gasSystem, temperature)[0]
+		source := openManifold(append(CylinderList(nil), sourceCylinders...), "source", gasSystem, temperature)[0]
+		result := slowFill.Simulate(destination, best.DestinationCylinderPressure, source.GasComposition, gasSystem, temperature)
+		recommendedRate := slowFill.RecommendedFillRate(destination, best.DestinationCylinderPressure, source.GasComposition, temperatureLimit, gasSystem, temperature)
+		printSlowFill(w, *slowFill, result, recommendedRate, temperatureLimit, units)
+	}
+	if targetPressure > 0 {
+		printTargetFeasibility(w, targetFeasibility(cylinderConfiguration, targetPressure, gasSystem, temperature, units), units)
+	}
+	if *chartFlag != "" {
+		if err := writeChart(*chartFlag, transferStepChart(transferSteps, best.Description, units)); err != nil {
+			return fmt.Errorf("unable to write chart: %w", err)
+		}
+	}
+	if *worksheetFlag != "" {
+		if err := writeWorksheet(*worksheetFlag, transferWorksheet(transferSteps, best, units)); err != nil {
+			return fmt.Errorf("unable to write worksheet: %w", err)
+		}
+	}
+	if *logFillFlag != "" {
+		if err := AppendFillLog(*logFillFlag, transferFillLogEntry(cylinderConfiguration, best, gasSystem, temperature, units)); err != nil {
+			return fmt.Errorf("unable to log fill: %w", err)
+		}
+	}
+	if err := bankFlags.update(w, banks, bankPressuresAfterTransfer(cylinderConfiguration, bankFlags.useBanks, transferSteps, best), units); err != nil {
+		return err
+	}
+	if *reportFlag != "" {
+		report := newReport(cylinderConfiguration, flags.gasSystemName(), temperature, units, transferSteps, cylinderSummaries)
+		if err := writeReport(*reportFlag, report); err != nil {
+			return fmt.Errorf("unable to write report: %w", err)
+		}
+	}
+	if *exportFlag != "" {
+		export := calculationExport{Report: newReport(cylinderConfiguration, flags.gasSystemName(), temperature, units, transferSteps, cylinderSummaries), Explanations: explanations}
+		if err := writeExport(*exportFlag, export); err != nil {
+			return fmt.Errorf("unable to write export: %w", err)
+		}
+	}
+	if outputTemplate != nil {
+		if err := executeOutputTemplate(os.Stdout, outputTemplate, cylinderSummaries, units); err != nil {
+			return err
+		}
+	}
+	if !mixWithinSpecification {
+		return ErrMixOutOfSpecification
+	}
+	return nil
+}
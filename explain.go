@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// gasSystemEquation returns the name and equation of state of the gas system
+func gasSystemEquation(gasSystem GasSystem) string {
+	switch s := gasSystem.(type) {
+	case IdealGasSystem:
+		return "ideal gas: P·V = n·R·T"
+	case VanDerWaalsSystem:
+		if s.Additive {
+			return "Van der Waals for each gas at its partial pressure: (Pᵢ + aᵢ·nᵢ²/V²)·(V − nᵢ·bᵢ) = nᵢ·R·T, P = ΣPᵢ"
+		}
+		return "Van der Waals: (P + a·n²/V²)·(V − n·b) = n·R·T, with a = ΣΣ xᵢ·xⱼ·√(aᵢ·aⱼ) and b = Σ xᵢ·bᵢ of the mix"
+	case RedlichKwongSystem:
+		if s.Soave {
+			return "Soave-Redlich-Kwong: P·V = Z·n·R·T"
+		}
+		return "Redlich-Kwong: P·V = Z·n·R·T"
+	case PengRobinsonSystem:
+		return "Peng-Robinson: P·V = Z·n·R·T"
+	case CompressibilityTableSystem:
+		return "tabulated compressibility: P·V = Z·n·R·T"
+	case VirialSystem:
+		return "virial: P·V = Z·n·R·T"
+	}
+	return "P·V = Z·n·R·T"
+}
+
+// explainGases returns the amounts of each gas of the mix, such as "O2 5.34mol, N2 20.09mol", in the order of the
+// gases
+func explainGases(gasComposition GasComposition, total float64, unit string) string {
+	gases := explainedGases(gasComposition)
+	amounts := make([]string, len(gases))
+	for i, gasType := range gases {
+		amounts[i] = fmt.Sprintf("%s %.2f%s", SpeciesLookup[gasType].Symbol, gasComposition[gasType]*total, unit)
+	}
+	return strings.Join(amounts, ", ")
+}
+
+// explainedGases returns the gases of the mix in the order of the gases
+func explainedGases(gasComposition GasComposition) []Gas {
+	gases := make([]Gas, 0, len(gasComposition))
+	for gasType, fraction := range gasComposition {
+		if fraction > 0 {
+			gases = append(gases, gasType)
+		}
+	}
+	sort.Slice(gases, func(i, j int) bool { return gases[i] < gases[j] })
+	return gases
+}
+
+// cylinderExplanation is a cylinder of an equalizing explanation with the moles of gas in it
+type cylinderExplanation struct {
+	Cylinder
+	Moles MoleCount
+	// VanDerWaals are the constants of the mix from the Van der Waals mixing rules; nil with other gas systems
+	VanDerWaals *VanDerWaalsConstant
+	// Z is the compressibility factor P·V/(n·R·T); zero with ideal gas
+	Z float64
+}
+
+func newCylinderExplanation(cylinder Cylinder, moles MoleCount, gasSystem GasSystem, temperature Temperature) cylinderExplanation {
+	explanation := cylinderExplanation{Cylinder: cylinder, Moles: moles}
+	if s, ok := gasSystem.(VanDerWaalsSystem); ok && !s.Additive {
+		vdwConstants := MixVanDerWaalsConstants(cylinder.GasComposition)
+		explanation.VanDerWaals = &vdwConstants
+	}
+	if _, ideal := gasSystem.(IdealGasSystem); !ideal && moles > 0 {
+		explanation.Z = float64(cylinder.Pressure) * float64(cylinder.CylinderVolume) / (float64(moles) * R * float64(temperature))
+	}
+	return explanation
+}
+
+// equalizeExplanation is how equalizing cylinders is calculated: the moles of each gas in each cylinder are solved
+// from its pressure with the equation of state, and the pressure of all the moles in the combined volume is solved
+// from them. Values are in bar, liters and kelvin, with absolute pressures.
+type equalizeExplanation struct {
+	// Configuration is the description of the manifold configuration
+	Configuration string
+	Step          int
+	GasSystem     GasSystem
+	Temperature   Temperature
+	Cylinders     []cylinderExplanation
+	// Combined is the gas of all the cylinders in their combined volume; its moles are zero without any gas
+	Combined cylinderExplanation
+}
+
+func newEqualizeExplanation(configuration string, step int, cylinders []Cylinder, gasSystem GasSystem, temperature Temperature) equalizeExplanation {
+	explanation := equalizeExplanation{Configuration: configuration, Step: step, GasSystem: gasSystem, Temperature: temperature}
+	descriptions := make([]string, len(cylinders))
+	combined := Cylinder{GasComposition: make(GasComposition)}
+	var totalMoles MoleCount
+	gasMoles := make(map[Gas]float64)
+	for i, cylinder := range cylinders {
+		descriptions[i] = cylinder.Description
+		moles := gasSystem.Moles(cylinder.CylinderVolume, cylinder.Pressure, temperature, cylinder.GasComposition)
+		explanation.Cylinders = append(explanation.Cylinders, newCylinderExplanation(cylinder, moles, gasSystem, temperature))
+		totalMoles += moles
+		combined.CylinderVolume += cylinder.CylinderVolume
+		for gasType, fraction := range cylinder.GasComposition {
+			gasMoles[gasType] += fraction * float64(moles)
+		}
+	}
+	combined.Description = strings.Join(descriptions, " and ")
+	if totalMoles > 0 {
+		for gasType, moles := range gasMoles {
+			combined.GasComposition[gasType] = moles / float64(totalMoles)
+		}
+		combined.Pressure = gasSystem.Pressure(combined.CylinderVolume, totalMoles, temperature, combined.GasComposition)
+	}
+	explanation.Combined = newCylinderExplanation(combined, totalMoles, gasSystem, temperature)
+	return explanation
+}
+
+// writeText writes the explanation as the text of -explain
+func (e equalizeExplanation) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Step %d: equalizing %s with %s\n", e.Step, e.Combined.Description, gasSystemEquation(e.GasSystem))
+	fmt.Fprintf(w, "  R = %g l·bar/(K·mol), T = %.2fK, absolute pressures\n", R, e.Temperature)
+	for _, cylinder := range e.Cylinders {
+		fmt.Fprintf(w, "  %s: P = %.2fbar, V = %.1fl", cylinder.Description, cylinder.Pressure, cylinder.CylinderVolume)
+		if cylinder.VanDerWaals != nil {
+			fmt.Fprintf(w, ", a = %.4f, b = %.5f", cylinder.VanDerWaals.A, cylinder.VanDerWaals.B)
+		}
+		fmt.Fprintf(w, " → n = %.2fmol", cylinder.Moles)
+		if cylinder.Z != 0 {
+			fmt.Fprintf(w, ", Z = %.4f", cylinder.Z)
+		}
+		fmt.Fprintf(w, ": %s\n", explainGases(cylinder.GasComposition, float64(cylinder.Moles), "mol"))
+	}
+	if e.Combined.Moles <= 0 {
+		return
+	}
+	fmt.Fprintf(w, "  together: n = %.2fmol in V = %.1fl → P = %.2fbar, partial pressures Pᵢ = xᵢ·P: %s\n", e.Combined.Moles, e.Combined.CylinderVolume, e.Combined.Pressure, explainGases(e.Combined.GasComposition, float64(e.Combined.Pressure), "bar"))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExplainEqualize(t *testing.T) {
+	for _, gasSystem := range []GasSystem{IdealGas, VanDerWaals, AdditiveVanDerWaals, PengRobinson} {
+		cylinders := []Cylinder{
+			{Description: "destination", CylinderVolume: 12, Pressure: 51, GasComposition: GasComposition{Oxygen: 0.32, Nitrogen: 0.68}},
+			{Description: "source", CylinderVolume: 12, Pressure: 233, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}},
+		}
+		var output strings.Builder
+		newEqualizeExplanation("all manifolds open", 1, cylinders, gasSystem, 293.15).writeText(&output)
+		cylinders[0].Equalize(&cylinders[1], gasSystem, 293.15, nil)
+		lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+		if len(lines) != 5 || !strings.HasPrefix(lines[0], "Step 1: equalizing destination and source with "+gasSystemEquation(gasSystem)) {
+			t.Errorf("Unexpected explanation with %T: %q", gasSystem, output.String())
+			continue
+		}
+		// The explained pressure is the equalized pressure
+		if expected := fmt.Sprintf("→ P = %.2fbar, partial pressures", cylinders[0].Pressure); !strings.Contains(lines[4], expected) {
+			t.Errorf("Expected %q with %T, got %q", expected, gasSystem, lines[4])
+		}
+	}
+}
+
+func TestExplainReport(t *testing.T) {
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "source", CylinderVolume: 12, Pressure: 233, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+		DestinationCylinders: CylinderList{{Description: "destination", CylinderVolume: 12, Pressure: 51, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+		Explain:              true,
+	}
+	var output strings.Builder
+	equalizeAndReport(&output, cylinderConfiguration, IdealGas, 293.15, Metric, false, nil, false)
+	if !strings.Contains(output.String(), "  destination: P = 51.00bar, V = 12.0l → n = 25.12mol: O2 5.28mol, N2 19.85mol\n") {
+		t.Errorf("Expected the moles of the destination, got %q", output.String())
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// calculationExport is a report with the worked calculation of each isothermal transfer, for course handouts
+type calculationExport struct {
+	Report
+	Explanations []equalizeExplanation
+}
+
+// exportMarkup writes the parts of an exported calculation in a markup language. Text is escaped with escape
+// before writing it, apart from table cells which are escaped by table. Math is LaTeX in all markups, inline
+// between dollar signs.
+type exportMarkup interface {
+	escape(text string) string
+	heading(level int, title string)
+	paragraph(text string)
+	math(math string)
+	list(items []string)
+	table(columns []tableColumn, rows [][]string)
+}
+
+// markdownMarkup writes Markdown with LaTeX math, as rendered by GitHub, Pandoc and most notebooks
+type markdownMarkup struct {
+	w *strings.Builder
+}
+
+func (m markdownMarkup) escape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "$", `\$`, "#", `\#`).Replace(text)
+}
+
+func (m markdownMarkup) heading(level int, title string) {
+	fmt.Fprintf(m.w, "%s %s\n\n", strings.Repeat("#", level), title)
+}
+
+func (m markdownMarkup) paragraph(text string) {
+	fmt.Fprintf(m.w, "%s\n\n", text)
+}
+
+func (m markdownMarkup) math(math string) {
+	fmt.Fprintf(m.w, "$$\n%s\n$$\n\n", math)
+}
+
+func (m markdownMarkup) list(items []string) {
+	for _, item := range items {
+		fmt.Fprintf(m.w, "- %s\n", item)
+	}
+	m.w.WriteString("\n")
+}
+
+func (m markdownMarkup) table(columns []tableColumn, rows [][]string) {
+	exportTable := table{columns: columns}
+	for _, row := range rows {
+		exportTable.addRow("", row...)
+	}
+	exportTable.render(m.w, tableMarkdown)
+	m.w.WriteString("\n")
+}
+
+// latexMarkup writes the body of a LaTeX document using amsmath
+type latexMarkup struct {
+	w *strings.Builder
+}
+
+func (m latexMarkup) escape(text string) string {
+	return strings.NewReplacer(
+		`\`, `\textbackslash{}`, "{", `\{`, "}", `\}`, "$", `\$`, "&", `\&`, "%", `\%`, "#", `\#`, "_", `\_`,
+		"^", `\textasciicircum{}`, "~", `\textasciitilde{}`, "°", `\textdegree{}`, "→", `$\rightarrow$`,
+	).Replace(text)
+}
+
+func (m latexMarkup) heading(level int, title string) {
+	command := "subsubsection"
+	switch level {
+	case 1:
+		command = "section"
+	case 2:
+		command = "subsection"
+	}
+	fmt.Fprintf(m.w, "\\%s*{%s}\n\n", command, title)
+}
+
+func (m latexMarkup) paragraph(text string) {
+	fmt.Fprintf(m.w, "%s\n\n", text)
+}
+
+func (m latexMarkup) math(math string) {
+	fmt.Fprintf(m.w, "\\begin{equation*}\n%s\n\\end{equation*}\n\n", math)
+}
+
+func (m latexMarkup) list(items []string) {
+	m.w.WriteString("\\begin{itemize}\n")
+	for _, item := range items {
+		fmt.Fprintf(m.w, "\\item %s\n", item)
+	}
+	m.w.WriteString("\\end{itemize}\n\n")
+}
+
+func (m latexMarkup) table(columns []tableColumn, rows [][]string) {
+	alignments := make([]string, len(columns))
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		alignments[i] = "r"
+		if column.alignLeft {
+			alignments[i] = "l"
+		}
+		headers[i] = m.escape(column.header)
+	}
+	fmt.Fprintf(m.w, "\\begin{tabular}{%s}\n%s \\\\\n\\hline\n", strings.Join(alignments, ""), strings.Join(headers, " & "))
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = m.escape(cell)
+		}
+		fmt.Fprintf(m.w, "%s \\\\\n", strings.Join(cells, " & "))
+	}
+	m.w.WriteString("\\end{tabular}\n\n")
+}
+
+// latexGas returns the symbol of the gas in math with numbers as subscripts, such as \mathrm{O_2}
+func latexGas(gasType Gas) string {
+	symbol := SpeciesLookup[gasType].Symbol
+	digits := strings.IndexAny(symbol, "0123456789")
+	if digits > 0 {
+		symbol = symbol[:digits] + "_" + symbol[digits:]
+	}
+	return `\mathrm{` + symbol + `}`
+}
+
+// latexEquationOfState returns the equation of state of the gas system in math
+func latexEquationOfState(gasSystem GasSystem) string {
+	if s, ok := gasSystem.(VanDerWaalsSystem); ok {
+		if s.Additive {
+			return `\left(P_i + \frac{a_i n_i^2}{V^2}\right)\left(V - n_i b_i\right) = n_i R T, \quad P = \sum_i P_i`
+		}
+		return `\left(P + \frac{a n^2}{V^2}\right)\left(V - n b\right) = n R T, \quad a = \sum_i \sum_j x_i x_j \sqrt{a_i a_j}, \quad b = \sum_i x_i b_i`
+	}
+	if _, ok := gasSystem.(IdealGasSystem); ok {
+		return `P V = n R T`
+	}
+	return `P V = Z n R T`
+}
+
+// latexMoles returns the moles of gas in the cylinder solved from its pressure, with the values substituted
+func latexMoles(cylinder cylinderExplanation, temperature Temperature) string {
+	switch {
+	case cylinder.VanDerWaals != nil:
+		return fmt.Sprintf(`\left(%.2f + \frac{%.4f\,n^2}{%.1f^2}\right)\left(%.1f - %.5f\,n\right) = n \cdot %g \cdot %.2f \;\Rightarrow\; n = %.2f\,\mathrm{mol}`,
+			cylinder.Pressure, cylinder.VanDerWaals.A, cylinder.CylinderVolume, cylinder.CylinderVolume, cylinder.VanDerWaals.B, R, temperature, cylinder.Moles)
+	case cylinder.Z != 0:
+		return fmt.Sprintf(`n = \frac{P V}{Z R T} = \frac{%.2f \cdot %.1f}{%.4f \cdot %g \cdot %.2f} = %.2f\,\mathrm{mol}`,
+			cylinder.Pressure, cylinder.CylinderVolume, cylinder.Z, R, temperature, cylinder.Moles)
+	}
+	return fmt.Sprintf(`n = \frac{P V}{R T} = \frac{%.2f \cdot %.1f}{%g \cdot %.2f} = %.2f\,\mathrm{mol}`,
+		cylinder.Pressure, cylinder.CylinderVolume, R, temperature, cylinder.Moles)
+}
+
+// latexPressure returns the pressure of the combined gas solved from its moles, with the values substituted
+func latexPressure(combined cylinderExplanation, temperature Temperature) string {
+	switch {
+	case combined.VanDerWaals != nil:
+		return fmt.Sprintf(`a = %.4f, \quad b = %.5f, \quad P = \frac{n R T}{V - n b} - \frac{a n^2}{V^2} = \frac{%.2f \cdot %g \cdot %.2f}{%.1f - %.2f \cdot %.5f} - \frac{%.4f \cdot %.2f^2}{%.1f^2} = %.2f\,\mathrm{bar}`,
+			combined.VanDerWaals.A, combined.VanDerWaals.B, combined.Moles, R, temperature, combined.CylinderVolume, combined.Moles, combined.VanDerWaals.B,
+			combined.VanDerWaals.A, combined.Moles, combined.CylinderVolume, combined.Pressure)
+	case combined.Z != 0:
+		return fmt.Sprintf(`P = \frac{Z n R T}{V} = \frac{%.4f \cdot %.2f \cdot %g \cdot %.2f}{%.1f} = %.2f\,\mathrm{bar}`,
+			combined.Z, combined.Moles, R, temperature, combined.CylinderVolume, combined.Pressure)
+	}
+	return fmt.Sprintf(`P = \frac{n R T}{V} = \frac{%.2f \cdot %g \cdot %.2f}{%.1f} = %.2f\,\mathrm{bar}`,
+		combined.Moles, R, temperature, combined.CylinderVolume, combined.Pressure)
+}
+
+// latexGasShares returns the share of each gas of the total, such as the moles or the partial pressure of each gas
+func latexGasShares(gasComposition GasComposition, symbol string, total float64, unit string) string {
+	var shares []string
+	for _, gasType := range explainedGases(gasComposition) {
+		gas := latexGas(gasType)
+		shares = append(shares, fmt.Sprintf(`%s_{%s} = x_{%s} %s = %.4f \cdot %.2f = %.2f\,\mathrm{%s}`, symbol, gas, gas, symbol, gasComposition[gasType], total, gasComposition[gasType]*total, unit))
+	}
+	return strings.Join(shares, `, \quad `)
+}
+
+// writeMarkup writes the calculation with the markup
+func (e calculationExport) writeMarkup(m exportMarkup) {
+	m.heading(1, "Equalizing calculation")
+	m.paragraph(m.escape(fmt.Sprintf("Gas system: %s. Temperature: %s. Fill process: %s.", e.GasSystem, e.Temperature, e.FillProcess)))
+
+	m.heading(2, "Cylinders")
+	var cylinderRows [][]string
+	for _, side := range []struct {
+		name      string
+		cylinders []ReportCylinder
+	}{{"source", e.Source}, {"destination", e.Destination}} {
+		for _, cylinder := range side.cylinders {
+			cylinderRows = append(cylinderRows, []string{side.name, cylinder.Description, cylinder.Volume, cylinder.Pressure, cylinder.Mix})
+		}
+	}
+	m.table([]tableColumn{{header: "side", alignLeft: true}, {header: "cylinder", alignLeft: true}, {header: "volume"}, {header: "pressure"}, {header: "mix", alignLeft: true}}, cylinderRows)
+
+	if len(e.Explanations) > 0 {
+		explanation := e.Explanations[0]
+		m.heading(2, "Equations")
+		m.paragraph(m.escape("Each transfer equalizes two cylinders. The moles of gas n in each cylinder are solved from its pressure P and volume V with the equation of state, and the pressure after the transfer from the moles of both cylinders in their combined volume:"))
+		m.math(latexEquationOfState(explanation.GasSystem))
+		m.paragraph(m.escape("Pressures are absolute, in bar, and volumes in liters, with ") + fmt.Sprintf(`$R = %g\,\mathrm{l\,bar/(K\,mol)}$ and $T = %.2f\,\mathrm{K}$`, R, explanation.Temperature) + m.escape(". Each gas keeps its fraction x of the moles, and the partial pressure of a gas is its fraction of the pressure."))
+	} else {
+		m.paragraph(m.escape("Only isothermal transfers are worked out; the results below are calculated without the steps."))
+	}
+
+	for _, configuration := range e.Configurations {
+		var explanations []equalizeExplanation
+		for _, explanation := range e.Explanations {
+			if explanation.Configuration == configuration.Description {
+				explanations = append(explanations, explanation)
+			}
+		}
+		if len(explanations) == 0 {
+			continue
+		}
+		m.heading(2, m.escape(configuration.Description))
+		for _, explanation := range explanations {
+			m.heading(3, m.escape(fmt.Sprintf("Step %d: %s", explanation.Step, explanation.Combined.Description)))
+			var items []string
+			for _, cylinder := range explanation.Cylinders {
+				items = append(items, fmt.Sprintf("%s: $%s$, $%s$", m.escape(cylinder.Description), latexMoles(cylinder, explanation.Temperature), latexGasShares(cylinder.GasComposition, "n", float64(cylinder.Moles), "mol")))
+			}
+			m.list(items)
+			if explanation.Combined.Moles <= 0 {
+				continue
+			}
+			var moles, volumes []string
+			for _, cylinder := range explanation.Cylinders {
+				moles = append(moles, fmt.Sprintf("%.2f", cylinder.Moles))
+				volumes = append(volumes, fmt.Sprintf("%.1f", cylinder.CylinderVolume))
+			}
+			m.paragraph(m.escape("Together:"))
+			m.math(fmt.Sprintf(`n = %s = %.2f\,\mathrm{mol}, \quad V = %s = %.1f\,\mathrm{l}`, strings.Join(moles, " + "), explanation.Combined.Moles, strings.Join(volumes, " + "), explanation.Combined.CylinderVolume))
+			m.math(latexPressure(explanation.Combined, explanation.Temperature))
+			m.math(latexGasShares(explanation.Combined.GasComposition, "P", float64(explanation.Combined.Pressure), "bar"))
+		}
+	}
+
+	m.heading(2, "Results")
+	var resultRows [][]string
+	for _, configuration := range e.Configurations {
+		resultRows = append(resultRows, []string{configuration.Description, configuration.SourcePressure, configuration.SourceGasVolume, configuration.SourceMix, configuration.DestinationPressure, configuration.DestinationGasVolume, configuration.DestinationMix, configuration.Improvement})
+	}
+	m.table([]tableColumn{
+		{header: "configuration", alignLeft: true}, {header: "src pressure"}, {header: "src gas"}, {header: "src mix", alignLeft: true},
+		{header: "dst pressure"}, {header: "dst gas"}, {header: "dst mix", alignLeft: true}, {header: "improvement"},
+	}, resultRows)
+	if len(e.Warnings) > 0 {
+		var warnings []string
+		for _, warning := range e.Warnings {
+			warnings = append(warnings, m.escape(warning))
+		}
+		m.heading(2, "Warnings")
+		m.list(warnings)
+	}
+}
+
+// WriteMarkdown writes the calculation as Markdown with LaTeX math
+func (e calculationExport) WriteMarkdown(w io.Writer) error {
+	var document strings.Builder
+	e.writeMarkup(markdownMarkup{&document})
+	_, err := io.WriteString(w, strings.TrimSuffix(document.String(), "\n"))
+	return err
+}
+
+// WriteLaTeX writes the calculation as a LaTeX document
+func (e calculationExport) WriteLaTeX(w io.Writer) error {
+	var document strings.Builder
+	document.WriteString("\\documentclass{article}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amsmath}\n\\usepackage{textcomp}\n\\begin{document}\n\n")
+	e.writeMarkup(latexMarkup{&document})
+	document.WriteString("\\end{document}\n")
+	_, err := io.WriteString(w, document.String())
+	return err
+}
+
+// writeExport writes the calculation to path as Markdown or LaTeX, selected by the file extension
+func writeExport(path string, export calculationExport) error {
+	extension := strings.ToLower(filepath.Ext(path))
+	if extension != ".md" && extension != ".tex" {
+		return fmt.Errorf("unknown export format %q; must be .md or .tex", extension)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if extension == ".tex" {
+		err = export.WriteLaTeX(file)
+	} else {
+		err = export.WriteMarkdown(file)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testCalculationExport() calculationExport {
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "source", CylinderVolume: 12, Pressure: 233, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+		DestinationCylinders: CylinderList{{Description: "destination", CylinderVolume: 12, Pressure: 51, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+	}
+	var export calculationExport
+	cylinderConfiguration.OnExplanation = func(explanation equalizeExplanation) {
+		export.Explanations = append(export.Explanations, explanation)
+	}
+	cylinderSummaries := equalizeAllConfigurations(io.Discard, cylinderConfiguration, IdealGas, 293.15, Metric, false, nil)
+	export.Report = newReport(cylinderConfiguration, "ideal", 293.15, Metric, nil, cylinderSummaries)
+	return export
+}
+
+func TestWriteExport(t *testing.T) {
+	export := testCalculationExport()
+	if len(export.Explanations) != 1 {
+		t.Fatalf("Expected an explanation of the transfer, got %+v", export.Explanations)
+	}
+	directory := t.TempDir()
+	for name, expected := range map[string][]string{
+		"calculation.md": {
+			"## all manifolds open\n",
+			`$n = \frac{P V}{R T} = \frac{51.00 \cdot 12.0}{0.0831 \cdot 293.15} = 25.12\,\mathrm{mol}$`,
+			"| configuration      |",
+		},
+		"calculation.tex": {
+			"\\begin{document}\n",
+			"\\subsection*{all manifolds open}\n",
+			`P = \frac{n R T}{V} = \frac{`,
+			"Temperature: 20\\textdegree{}C.",
+			"\\end{document}\n",
+		},
+	} {
+		path := filepath.Join(directory, name)
+		if err := writeExport(path, export); err != nil {
+			t.Fatal(err)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, part := range expected {
+			if !strings.Contains(string(content), part) {
+				t.Errorf("Expected %q in %s, got %q", part, name, content)
+			}
+		}
+	}
+	if err := writeExport(filepath.Join(directory, "calculation.pdf"), export); err == nil {
+		t.Error("Expected an error for an unknown export format")
+	}
+}
+
+func TestExportEscape(t *testing.T) {
+	if escaped := (latexMarkup{}).escape("EAN32 over 40% & 50°C"); escaped != `EAN32 over 40\% \& 50\textdegree{}C` {
+		t.Errorf("Unexpected LaTeX escaping %q", escaped)
+	}
+	if escaped := (markdownMarkup{}).escape("left_1 *"); escaped != `left\_1 \*` {
+		t.Errorf("Unexpected Markdown escaping %q", escaped)
+	}
+	if gas := latexGas(Oxygen); gas != `\mathrm{O_2}` {
+		t.Errorf("Unexpected gas %q", gas)
+	}
+}
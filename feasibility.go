@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// TargetFeasibility tells whether transfers reach the target pressure and, if not, how much is missing and the work
+// needed to finish with a compressor or a booster
+type TargetFeasibility struct {
+	// Configuration is the manifold configuration reaching the highest destination pressure
+	Configuration       string
+	TargetPressure      PressureBar
+	DestinationPressure PressureBar
+	Reached             bool
+	Shortfall           PressureBar
+	MissingGasVolume    GasVolume
+	// Compressor and Booster are set when the target is not reached and the configuration has them; they finish
+	// from the result of the transfers
+	Compressor *CompressorResult
+	Booster    *BoostResult
+}
+
+// missingGasVolume returns the gas the destination needs to reach the target pressure with its current mix
+func missingGasVolume(destination Cylinder, targetPressure PressureBar, gasSystem GasSystem, temperature Temperature) GasVolume {
+	target := Cylinder{CylinderVolume: destination.CylinderVolume, Pressure: targetPressure, GasComposition: destination.GasComposition}
+	return target.GasVolume(gasSystem, temperature) - destination.GasVolume(gasSystem, temperature)
+}
+
+// bestTransfer equalizes the cylinders of the configuration without a booster or a compressor and returns the
+// summary of the manifold configuration reaching the highest destination pressure
+func bestTransfer(cylinderConfiguration CylinderConfiguration, gasSystem GasSystem, temperature Temperature, units UnitSystem) CylinderSummary {
+	transfers := cylinderConfiguration
+	transfers.Booster, transfers.Compressor, transfers.Prices, transfers.OnTransferStep, transfers.OnExplanation, transfers.Observer = nil, nil, nil, nil, nil, nil
+	return bestSummary(equalizeAllConfigurations(io.Discard, transfers, gasSystem, temperature, units, false, nil))
+}
+
+// targetFeasibility equalizes the cylinders of the configuration without a booster or a compressor and checks the
+// best manifold configuration against the target pressure. When it falls short, the booster and the compressor of
+// the configuration finish separately from the result.
+func targetFeasibility(cylinderConfiguration CylinderConfiguration, targetPressure PressureBar, gasSystem GasSystem, temperature Temperature, units UnitSystem) TargetFeasibility {
+	best := bestTransfer(cylinderConfiguration, gasSystem, temperature, units)
+	destination := Cylinder{Description: "destination", CylinderVolume: cylinderConfiguration.DestinationCylinders.TotalVolume(), Pressure: best.DestinationCylinderPressure, GasComposition: best.DestinationGasComposition}
+	feasibility := newTargetFeasibility(best.Description, destination, targetPressure, gasSystem, temperature)
+	if feasibility.Reached {
+		return feasibility
+	}
+	if cylinderConfiguration.Compressor != nil {
+		compressorDestination := destination
+		compressorDestination.GasComposition = destination.GasComposition.Clone()
+		result := cylinderConfiguration.Compressor.TopOff(&compressorDestination, targetPressure, gasSystem, temperature)
+		feasibility.Compressor = &result
+	}
+	if cylinderConfiguration.Booster != nil {
+		source := Cylinder{Description: "source", CylinderVolume: cylinderConfiguration.SourceCylinders.TotalVolume(), Pressure: best.SourceCylinderPressure, GasComposition: best.SourceGasComposition.Clone()}
+		boosterDestination := destination
+		boosterDestination.GasComposition = destination.GasComposition.Clone()
+		result := cylinderConfiguration.Booster.Boost(&source, &boosterDestination, targetPressure, gasSystem, temperature)
+		feasibility.Booster = &result
+	}
+	return feasibility
+}
+
+// newTargetFeasibility checks the destination after transfers with the configuration against the target pressure
+func newTargetFeasibility(configuration string, destination Cylinder, targetPressure PressureBar, gasSystem GasSystem, temperature Temperature) TargetFeasibility {
+	feasibility := TargetFeasibility{
+		Configuration:       configuration,
+		TargetPressure:      targetPressure,
+		DestinationPressure: destination.Pressure,
+		Reached:             destination.Pressure >= targetPressure,
+	}
+	if !feasibility.Reached {
+		feasibility.Shortfall = targetPressure - destination.Pressure
+		feasibility.MissingGasVolume = missingGasVolume(destination, targetPressure, gasSystem, temperature)
+	}
+	return feasibility
+}
+
+func printTargetFeasibility(w io.Writer, feasibility TargetFeasibility, units UnitSystem) {
+	pressureUnit, volumeUnit := units.PressureUnit(), units.VolumeUnit()
+	if feasibility.Reached {
+		fmt.Fprintf(w, "Target %.0f%s: reached with %s (%.0f%s)\n", units.Pressure(feasibility.TargetPressure), pressureUnit, feasibility.Configuration, units.Pressure(feasibility.DestinationPressure), pressureUnit)
+		return
+	}
+	fmt.Fprintf(w, "Target %.0f%s: not reached, %.0f%s short with %s (%.0f%s); %.0f%s of gas missing\n", units.Pressure(feasibility.TargetPressure), pressureUnit, units.PressureDifference(feasibility.Shortfall), pressureUnit, feasibility.Configuration, units.Pressure(feasibility.DestinationPressure), pressureUnit, units.Volume(feasibility.MissingGasVolume), volumeUnit)
+	if compressor := feasibility.Compressor; compressor != nil {
+		if compressor.TargetReached {
+			fmt.Fprintf(w, "Compressor: %.0f minutes to the target (%.0f%s of air)\n", compressor.Minutes, units.Volume(compressor.GasVolume), volumeUnit)
+		} else {
+			fmt.Fprintf(w, "Compressor: stops at its maximum pressure %.0f%s after %.0f minutes\n", units.Pressure(compressor.PressureAfter), pressureUnit, compressor.Minutes)
+		}
+	}
+	if booster := feasibility.Booster; booster != nil {
+		if booster.TargetReached {
+			fmt.Fprintf(w, "Booster: reaches the target moving %.0f%s of gas with %.0f%s of drive gas\n", units.Volume(booster.BoostedGasVolume), volumeUnit, units.Volume(booster.DriveGasVolume), volumeUnit)
+		} else {
+			fmt.Fprintf(w, "Booster: stops at %.0f%s (stall pressure or source depleted) using %.0f%s of drive gas\n", units.Pressure(booster.DestinationPressureAfter), pressureUnit, units.Volume(booster.DriveGasVolume), volumeUnit)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTargetFeasibility(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "source", CylinderVolume: 50, Pressure: 200, GasComposition: air}},
+		DestinationCylinders: CylinderList{{Description: "destination", CylinderVolume: 12, Pressure: 50, GasComposition: air}},
+		Compressor:           &Compressor{FreeAirDelivery: 100, MaxPressure: 300},
+	}
+	feasibility := targetFeasibility(cylinderConfiguration, 200, IdealGas, 293.15, Metric)
+	expectedPressure := (50*200 + 12*50) / 62.0
+	if feasibility.Reached || !compareFloats(float64(feasibility.DestinationPressure), expectedPressure) {
+		t.Fatalf("Expected to fall short at %f bar, got %+v", expectedPressure, feasibility)
+	}
+	if !compareFloats(float64(feasibility.Shortfall), 200-expectedPressure) || !compareFloats(float64(feasibility.MissingGasVolume), 12*(200-expectedPressure)) {
+		t.Errorf("Invalid shortfall %+v", feasibility)
+	}
+	if feasibility.Compressor == nil || !feasibility.Compressor.TargetReached || !compareFloats(feasibility.Compressor.Minutes, 12*(200-expectedPressure)/100) {
+		t.Errorf("Invalid compressor work %+v", feasibility.Compressor)
+	}
+	if feasibility.Booster != nil {
+		t.Errorf("Expected no booster work without a booster, got %+v", feasibility.Booster)
+	}
+	if cylinderConfiguration.DestinationCylinders[0].Pressure != 50 || cylinderConfiguration.SourceCylinders[0].Pressure != 200 {
+		t.Errorf("Cylinders were modified: %+v", cylinderConfiguration)
+	}
+
+	feasibility = targetFeasibility(cylinderConfiguration, 150, IdealGas, 293.15, Metric)
+	if !feasibility.Reached || feasibility.Shortfall != 0 || feasibility.Compressor != nil {
+		t.Errorf("Expected to reach 150 bar without the compressor, got %+v", feasibility)
+	}
+}
+
+func TestTargetFeasibilityRealGas(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "source", CylinderVolume: 50, Pressure: 232, GasComposition: air}},
+		DestinationCylinders: CylinderList{{Description: "destination", CylinderVolume: 12, Pressure: 50, GasComposition: air}},
+	}
+	gasVolume := cylinderConfiguration.SourceCylinders.TotalGasVolume(VanDerWaals, 293.15) + cylinderConfiguration.DestinationCylinders.TotalGasVolume(VanDerWaals, 293.15)
+	expectedPressure := PressureFromGasVolume(62, gasVolume, VanDerWaals, air, 293.15)
+	// The gas volume over the cylinder volume is below the pressure the gauge shows
+	volumePressure := PressureFromVolumes(gasVolume, 62)
+	feasibility := targetFeasibility(cylinderConfiguration, (volumePressure+expectedPressure)/2, VanDerWaals, 293.15, Metric)
+	if !feasibility.Reached || math.Abs(float64(feasibility.DestinationPressure-expectedPressure)) > 0.01 {
+		t.Errorf("Expected to reach the target at %f bar, got %+v", expectedPressure, feasibility)
+	}
+	feasibility = targetFeasibility(cylinderConfiguration, expectedPressure+10, VanDerWaals, 293.15, Metric)
+	if feasibility.Reached || math.Abs(float64(feasibility.Shortfall)-10) > 0.01 {
+		t.Errorf("Expected to fall 10 bar short, got %+v", feasibility)
+	}
+	destination := Cylinder{CylinderVolume: 12, Pressure: expectedPressure, GasComposition: air}
+	if expectedGasVolume := missingGasVolume(destination, expectedPressure+10, VanDerWaals, 293.15); math.Abs(float64(feasibility.MissingGasVolume-expectedGasVolume)) > 0.1 {
+		t.Errorf("Invalid missing gas %f, expected %f", feasibility.MissingGasVolume, expectedGasVolume)
+	}
+}
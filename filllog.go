@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sqliteCommand is the SQLite command line shell used for the fill log, keeping the calculator free of
+// dependencies
+var sqliteCommand = "sqlite3"
+
+// fillLogSchema creates the fills table. Pressures are in bar relative to the pressure reference and gas volumes
+// in liters at surface pressure.
+const fillLogSchema = `CREATE TABLE IF NOT EXISTS fills (
+	id INTEGER PRIMARY KEY,
+	timestamp TEXT NOT NULL,
+	command TEXT NOT NULL,
+	configuration TEXT NOT NULL,
+	cylinders TEXT NOT NULL,
+	mix TEXT NOT NULL,
+	pressure REAL NOT NULL,
+	gas_l REAL NOT NULL,
+	oxygen_l REAL NOT NULL,
+	helium_l REAL NOT NULL,
+	nitrogen_l REAL NOT NULL,
+	top_up_l REAL NOT NULL
+);
+`
+
+// fillLogColumns are the columns of the fills table other than id, in FillLogEntry field order
+const fillLogColumns = "timestamp, command, configuration, cylinders, mix, pressure, gas_l, oxygen_l, helium_l, nitrogen_l, top_up_l"
+
+// FillLogEntry is a fill recorded in the fill log. Gas volumes are gas taken from the source cylinders or added
+// from supply gases; gas topped up from a compressor is counted separately.
+type FillLogEntry struct {
+	Time           time.Time
+	Command        string
+	Configuration  string
+	Cylinders      Scenario
+	Mix            string
+	Pressure       float64
+	GasVolume      GasVolume
+	OxygenVolume   GasVolume
+	HeliumVolume   GasVolume
+	NitrogenVolume GasVolume
+	TopUpVolume    GasVolume
+}
+
+// sqlQuote returns s as an SQL string literal
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqlNumber returns a number as an SQL literal
+func sqlNumber[T ~float64](value T) string {
+	return strconv.FormatFloat(float64(value), 'f', -1, 64)
+}
+
+// insertSQL returns the statement appending the entry to the fills table
+func (e FillLogEntry) insertSQL() (string, error) {
+	cylinders, err := json.Marshal(e.Cylinders)
+	if err != nil {
+		return "", err
+	}
+	values := []string{
+		sqlQuote(e.Time.UTC().Format(time.RFC3339)),
+		sqlQuote(e.Command),
+		sqlQuote(e.Configuration),
+		sqlQuote(string(cylinders)),
+		sqlQuote(e.Mix),
+		sqlNumber(e.Pressure),
+		sqlNumber(e.GasVolume),
+		sqlNumber(e.OxygenVolume),
+		sqlNumber(e.HeliumVolume),
+		sqlNumber(e.NitrogenVolume),
+		sqlNumber(e.TopUpVolume),
+	}
+	return fmt.Sprintf("INSERT INTO fills (%s) VALUES (%s);\n", fillLogColumns, strings.Join(values, ", ")), nil
+}
+
+// runSQLite runs SQL statements on the database at path, returning what the statements print
+func runSQLite(path string, statements string, args ...string) ([]byte, error) {
+	cmd := exec.Command(sqliteCommand, append(append([]string{"-bail"}, args...), path)...)
+	cmd.Stdin = strings.NewReader(statements)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%s: %s", sqliteCommand, message)
+		}
+		return nil, fmt.Errorf("unable to run %s: %w", sqliteCommand, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// AppendFillLog appends the entry to the SQLite fill log at path, creating the database when needed
+func AppendFillLog(path string, entry FillLogEntry) error {
+	insert, err := entry.insertSQL()
+	if err != nil {
+		return err
+	}
+	_, err = runSQLite(path, fillLogSchema+"BEGIN;\n"+insert+"COMMIT;\n")
+	return err
+}
+
+// ReadFillLog returns the fills of the SQLite fill log at path, oldest first
+func ReadFillLog(path string) ([]FillLogEntry, error) {
+	// sqlite3 would create a missing database
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	output, err := runSQLite(path, "SELECT "+fillLogColumns+" FROM fills ORDER BY timestamp, id;\n", "-csv")
+	if err != nil {
+		return nil, err
+	}
+	records, err := csv.NewReader(bytes.NewReader(output)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid fill log output: %w", err)
+	}
+	entries := make([]FillLogEntry, len(records))
+	for i, record := range records {
+		if len(record) != 11 {
+			return nil, fmt.Errorf("invalid fill log row %d: expected 11 columns, got %d", i+1, len(record))
+		}
+		entry := FillLogEntry{Command: record[1], Configuration: record[2], Mix: record[4]}
+		if entry.Time, err = time.Parse(time.RFC3339, record[0]); err != nil {
+			return nil, fmt.Errorf("invalid fill log row %d: %w", i+1, err)
+		}
+		if err := json.Unmarshal([]byte(record[3]), &entry.Cylinders); err != nil {
+			return nil, fmt.Errorf("invalid fill log row %d: %w", i+1, err)
+		}
+		numbers := make([]float64, 6)
+		for j := range numbers {
+			if numbers[j], err = strconv.ParseFloat(record[5+j], 64); err != nil {
+				return nil, fmt.Errorf("invalid fill log row %d: %w", i+1, err)
+			}
+		}
+		entry.Pressure = numbers[0]
+		entry.GasVolume, entry.OxygenVolume, entry.HeliumVolume = GasVolume(numbers[1]), GasVolume(numbers[2]), GasVolume(numbers[3])
+		entry.NitrogenVolume, entry.TopUpVolume = GasVolume(numbers[4]), GasVolume(numbers[5])
+		entries[i] = entry
+	}
+	return entries, nil
+}
+
+// transferFillLogEntry returns the fill log entry for equalizing with the manifold configuration of the summary.
+// Gas taken from the sources is split by the starting mix of the sources.
+func transferFillLogEntry(cylinderConfiguration CylinderConfiguration, cylinderSummary CylinderSummary, gasSystem GasSystem, temperature Temperature, units UnitSystem) FillLogEntry {
+	entry := FillLogEntry{
+		Time:          time.Now(),
+		Command:       "equalize",
+		Configuration: cylinderSummary.Description,
+		Cylinders:     NewScenario(cylinderConfiguration.SourceCylinders, cylinderConfiguration.DestinationCylinders, units),
+		Mix:           cylinderSummary.DestinationGasComposition.String(),
+		Pressure:      float64(cylinderSummary.DestinationCylinderPressure - units.AmbientPressure),
+	}
+	takenGasVolumes := takenGasVolumes(cylinderConfiguration.SourceCylinders, cylinderSummary.SourceCylinderGasVolume, gasSystem, temperature)
+	for _, gasVolume := range takenGasVolumes {
+		entry.GasVolume += gasVolume
+	}
+	entry.OxygenVolume = takenGasVolumes[Oxygen]
+	entry.HeliumVolume = takenGasVolumes[Helium]
+	entry.NitrogenVolume = takenGasVolumes[Nitrogen]
+	return entry
+}
+
+// blendFillLogEntry returns the fill log entry for a partial pressure blend. Pure helium and oxygen come from
+// supply banks; the top-up gas is counted as top-up.
+func blendFillLogEntry(plan BlendPlan, units UnitSystem) FillLogEntry {
+	start := Cylinder{Description: "cylinder", CylinderVolume: plan.CylinderVolume, Pressure: plan.StartPressure, GasComposition: plan.StartComposition}
+	entry := FillLogEntry{
+		Time:          time.Now(),
+		Command:       "blend",
+		Configuration: "partial pressure",
+		Cylinders:     NewScenario(nil, CylinderList{start}, units),
+		Mix:           plan.TargetComposition.String(),
+		Pressure:      float64(plan.TargetPressure - units.AmbientPressure),
+	}
+	for _, step := range plan.Steps {
+		if step.GasComposition[Helium] != 1 && step.GasComposition[Oxygen] != 1 {
+			entry.TopUpVolume += step.AddedGasVolume
+			continue
+		}
+		entry.GasVolume += step.AddedGasVolume
+		entry.OxygenVolume += step.AddedGasVolume * GasVolume(step.GasComposition[Oxygen])
+		entry.HeliumVolume += step.AddedGasVolume * GasVolume(step.GasComposition[Helium])
+		entry.NitrogenVolume += step.AddedGasVolume * GasVolume(step.GasComposition[Nitrogen])
+	}
+	return entry
+}
+
+// printFillLog writes the fills of the fill log as a table, oldest first
+func printFillLog(w io.Writer, entries []FillLogEntry, style tableStyle, units UnitSystem) {
+	volumeUnit := units.VolumeUnit()
+	var fillTable table
+	fillTable.addColumn("time", 16, true)
+	fillTable.addColumn("command", 8, true)
+	fillTable.addColumn("configuration", 28, true)
+	fillTable.addColumn("mix", 10, true)
+	fillTable.addColumn(units.PressureUnit(), 6, false)
+	fillTable.addColumn("gas "+volumeUnit, 8, false)
+	fillTable.addColumn("O2 "+volumeUnit, 8, false)
+	fillTable.addColumn("He "+volumeUnit, 8, false)
+	fillTable.addColumn("top-up "+volumeUnit, 9, false)
+	for _, entry := range entries {
+		fillTable.addRow("", entry.Time.Local().Format("2006-01-02 15:04"), entry.Command, entry.Configuration, entry.Mix,
+			fmt.Sprintf("%.0f", units.PressureDifference(PressureBar(entry.Pressure))),
+			fmt.Sprintf("%.0f", units.Volume(entry.GasVolume)),
+			fmt.Sprintf("%.0f", units.Volume(entry.OxygenVolume)),
+			fmt.Sprintf("%.0f", units.Volume(entry.HeliumVolume)),
+			fmt.Sprintf("%.0f", units.Volume(entry.TopUpVolume)))
+	}
+	fillTable.render(w, style)
+}
+
+// fillLogActions are the actions of the log command
+const fillLogActions = "list"
+
+func fillLogMain(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("usage: log <action> [flags] fills.db; action is %s", fillLogActions)
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("log "+action, flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	fs.Parse(args[1:])
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	w, err := flags.output(os.Stdout, units)
+	if err != nil {
+		return err
+	}
+	style, err := flags.parseTableStyle()
+	if err != nil {
+		return err
+	}
+	switch action {
+	case "list":
+		if fs.NArg() != 1 {
+			return errors.New("usage: log list [flags] fills.db")
+		}
+		entries, err := ReadFillLog(fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("unable to read fill log: %w", err)
+		}
+		printFillLog(w, entries, style, units)
+		return nil
+	}
+	return fmt.Errorf("unknown log action %s; must be %s", action, fillLogActions)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFillLogInsertSQL(t *testing.T) {
+	entry := FillLogEntry{
+		Time:          time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Command:       "equalize",
+		Configuration: "all manifolds open",
+		Cylinders:     Scenario{Source: []ScenarioCylinder{{Description: "o'neil", Volume: 12, Pressure: 232}}},
+		Mix:           "air",
+		Pressure:      156.5,
+		GasVolume:     900,
+	}
+	insert, err := entry.insertSQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(insert, `'2024-05-01T12:00:00Z', 'equalize', 'all manifolds open', '{"source":[{"description":"o''neil"`) || !strings.Contains(insert, "'air', 156.5, 900, 0, 0, 0, 0);") {
+		t.Errorf("Invalid insert %s", insert)
+	}
+}
+
+func TestBlendFillLogEntry(t *testing.T) {
+	plan := BlendPlan{
+		CylinderVolume:    12,
+		TargetComposition: GasComposition{Oxygen: 0.18, Helium: 0.45, Nitrogen: 0.37},
+		TargetPressure:    200,
+		Steps: []BlendStep{
+			{Description: "helium", GasComposition: GasComposition{Helium: 1}, AddedGasVolume: 1000},
+			{Description: "oxygen", GasComposition: GasComposition{Oxygen: 1}, AddedGasVolume: 200},
+			{Description: "air", GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}, AddedGasVolume: 1100},
+		},
+	}
+	entry := blendFillLogEntry(plan, Metric)
+	if entry.HeliumVolume != 1000 || entry.OxygenVolume != 200 || entry.GasVolume != 1200 || entry.TopUpVolume != 1100 || entry.Mix != "18.0/45.0" {
+		t.Errorf("Invalid entry %+v", entry)
+	}
+}
+
+func TestTransferFillLogEntry(t *testing.T) {
+	trimix := GasComposition{Oxygen: 0.21, Helium: 0.35, Nitrogen: 0.44}
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "bank", CylinderVolume: 12, Pressure: 232, GasComposition: trimix}},
+		DestinationCylinders: CylinderList{{Description: "stage", CylinderVolume: 12, Pressure: 80, GasComposition: trimix.Clone()}},
+	}
+	cylinderSummaries := equalizeAllConfigurations(&strings.Builder{}, cylinderConfiguration, IdealGas, 293.15, Metric, false, nil)
+	entry := transferFillLogEntry(cylinderConfiguration, cylinderSummaries[0], IdealGas, 293.15, Metric)
+	// Ideal gas at 293.15K: 12l from 232 to 156 bar
+	if !compareFloats(float64(entry.GasVolume), 12*76) || !compareFloats(float64(entry.HeliumVolume), 12*76*0.35) || !compareFloats(entry.Pressure, 156) {
+		t.Errorf("Invalid entry %+v", entry)
+	}
+}
+
+func TestAppendFillLog(t *testing.T) {
+	if _, err := exec.LookPath(sqliteCommand); err != nil {
+		t.Skip("sqlite3 is not installed")
+	}
+	path := filepath.Join(t.TempDir(), "fills.db")
+	for i := 0; i < 2; i++ {
+		if err := AppendFillLog(path, FillLogEntry{Time: time.Now(), Command: "blend", Mix: "18.0/45.0", HeliumVolume: 500}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	output, err := runSQLite(path, "SELECT count(*), sum(helium_l) FROM fills;\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(output)) != "2|1000.0" {
+		t.Errorf("Invalid fill log contents %q", output)
+	}
+}
+
+func TestPrintFillLog(t *testing.T) {
+	if _, err := exec.LookPath(sqliteCommand); err != nil {
+		t.Skip("sqlite3 is not installed")
+	}
+	path := filepath.Join(t.TempDir(), "fills.db")
+	entries := []FillLogEntry{
+		{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local), Command: "equalize", Configuration: "all manifolds open", Mix: "air", Pressure: 156, GasVolume: 900},
+		{Time: time.Date(2024, 5, 2, 9, 30, 0, 0, time.Local), Command: "blend", Configuration: "partial pressure", Mix: "18.0/45.0", Pressure: 200, GasVolume: 1200, OxygenVolume: 200, HeliumVolume: 1000, TopUpVolume: 1100},
+	}
+	for _, entry := range entries {
+		if err := AppendFillLog(path, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read, err := ReadFillLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output strings.Builder
+	printFillLog(&output, read, tablePlain, Metric)
+	lines := strings.Split(strings.TrimRight(output.String(), "\n"), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "configuration") {
+		t.Fatalf("Expected a header and two fills, got\n%s", output.String())
+	}
+	for i, expected := range []string{"2024-05-01 12:00 equalize all manifolds open", "2024-05-02 09:30 blend    partial pressure"} {
+		if fields := strings.Join(strings.Fields(lines[i+1]), " "); !strings.HasPrefix(fields, strings.Join(strings.Fields(expected), " ")) {
+			t.Errorf("Expected fill %d to start with %q, got %q", i+1, expected, lines[i+1])
+		}
+	}
+	if fields := strings.Fields(lines[2]); strings.Join(fields[len(fields)-6:], " ") != "18.0/45.0 200 1200 200 1000 1100" {
+		t.Errorf("Invalid blend row %q", lines[2])
+	}
+}
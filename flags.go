@@ -0,0 +1,295 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+)
+
+// errUsage is returned by commands after printing their usage for invalid arguments
+var errUsage = errors.New("invalid arguments")
+
+// commonFlags holds command line flags shared by all modes
+type commonFlags struct {
+	verbose     *bool
+	debug       *bool
+	logFormat   *string
+	units       *string
+	useIdealGas *bool
+	gasSystem   *string
+	mixing      *string
+	temperature *string
+	customGases stringListFlag
+	gasesFile   *string
+	reference   *string
+	ambient     *string
+	altitude    *string
+	locale      *string
+	language    *string
+	precision   *int
+	roundTo     *float64
+	gaugeStep   *string
+	dualUnits   *bool
+	noColor     *bool
+	tableStyle  *string
+}
+
+func registerCommonFlags(fs *flag.FlagSet) *commonFlags {
+	f := &commonFlags{
+		verbose:     fs.Bool("verbose", false, "Print detailed information"),
+		debug:       fs.Bool("debug", false, "Log debug information"),
+		logFormat:   fs.String("log-format", "text", "Format of logs written to stderr: text or json"),
+		units:       fs.String("units", "metric", "Units for values without a unit suffix and for output: metric or imperial"),
+		useIdealGas: fs.Bool("use-ideal-gas", false, "Use ideal gas equations instead of Van der Waals; same as -gas-system ideal"),
+		gasSystem:   fs.String("gas-system", "vdw", "Equation of state: ideal, vdw (Van der Waals), rk (Redlich-Kwong), srk (Soave-Redlich-Kwong), pr (Peng-Robinson), z-table (tabulated compressibility factors) or virial"),
+		mixing:      fs.String("mixing", "mixing-rules", "Van der Waals mix model: mixing-rules (mix as a single fluid) or additive (sum of partial pressures)"),
+		temperature: fs.String("temperature", "20C", "Gas temperature for real gas equations (C, F or K; celsius or fahrenheit without a suffix)"),
+		gasesFile:   fs.String("gases-file", "", "JSON file with custom gases"),
+		reference:   fs.String("pressure-reference", "gauge", "Pressures are gauge (as shown by a pressure gauge) or absolute"),
+		ambient:     fs.String("ambient-pressure", "", "Ambient pressure for gauge pressures; defaults to standard pressure at -altitude"),
+		altitude:    fs.String("altitude", "0m", "Altitude of the fill station (m or ft)"),
+		locale:      fs.String("locale", "", "Number format: en, de, fi, fr, nl or sv; locales with a decimal comma also accept it in values, e.g. -temperature 21,5"),
+		language:    fs.String("lang", "en", "Language of printed results: en or fi"),
+		precision:   fs.Int("precision", -1, "Decimals of reported pressures and volumes, e.g. 1 to compare equations of state; -1 uses the default of each report"),
+		roundTo:     fs.Float64("round-to", 0, "Round reported pressures and volumes to a multiple of this, e.g. 5 for 5 bar or 0.5; 0 does not round"),
+		dualUnits:   fs.Bool("dual-units", false, "Print the summary in both metric and imperial units side by side"),
+		noColor:     fs.Bool("no-color", false, "Print without colors; colors are used only on a terminal"),
+		tableStyle:  fs.String("table-style", "plain", "Style of tables: plain, markdown for pasting into wikis, or box"),
+		gaugeStep:   fs.String("gauge-increment", "", "Round recommended fill pressures to this gauge increment, e.g. 10bar, or auto for 5 bar or 100 psi; pressures to fill to are rounded down and pressures needed up"),
+	}
+	fs.Var(&f.customGases, "custom-gas", "Custom gas as key=value pairs: symbol, name, mass and either a, b (Van der Waals) or tc, pc, omega (critical point); repeat for multiple gases")
+	return f
+}
+
+// configureLogging sets up the default logger on stderr, at debug level with -debug
+func (f *commonFlags) configureLogging() error {
+	level := slog.LevelInfo
+	if *f.debug {
+		level = slog.LevelDebug
+	}
+	handler, err := newLogHandler(os.Stderr, *f.logFormat, level)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// registerCustomGases registers gases given with -gases-file and -custom-gas. It must be called before parsing any
+// mixes.
+func (f *commonFlags) registerCustomGases() error {
+	var customSpecies []CustomSpecies
+	if *f.gasesFile != "" {
+		loaded, err := LoadCustomSpecies(*f.gasesFile)
+		if err != nil {
+			return fmt.Errorf("unable to load gases: %w", err)
+		}
+		customSpecies = append(customSpecies, loaded...)
+	}
+	for _, spec := range f.customGases {
+		custom, err := ParseCustomSpecies(spec)
+		if err != nil {
+			return fmt.Errorf("invalid custom gas: %w", err)
+		}
+		customSpecies = append(customSpecies, custom)
+	}
+	if err := RegisterCustomSpecies(customSpecies); err != nil {
+		return fmt.Errorf("invalid custom gas: %w", err)
+	}
+	return nil
+}
+
+// unitSystem returns the parsed unit system
+func (f *commonFlags) unitSystem() (UnitSystem, error) {
+	units, err := ParseUnitSystem(*f.units)
+	if err != nil {
+		return UnitSystem{}, err
+	}
+	if units.Locale, err = ParseLocale(*f.locale); err != nil {
+		return UnitSystem{}, err
+	}
+	switch {
+	case *f.precision > 10:
+		return UnitSystem{}, errors.New("invalid precision; must be at most 10 decimals")
+	case *f.precision >= 0:
+		units.Decimals = f.precision
+	case *f.precision != -1:
+		return UnitSystem{}, errors.New("invalid precision; must be >=0, or -1 for the default")
+	}
+	if *f.roundTo < 0 || math.IsNaN(*f.roundTo) || math.IsInf(*f.roundTo, 0) {
+		return UnitSystem{}, errors.New("invalid rounding; must be >=0")
+	}
+	units.RoundTo = *f.roundTo
+	units.DualUnits = *f.dualUnits
+	switch *f.gaugeStep {
+	case "":
+	case "auto":
+		units.GaugeIncrement = 5
+		if units.Imperial {
+			units.GaugeIncrement = 100 / PSIPerBar
+		}
+	default:
+		if units.GaugeIncrement, err = units.ParsePressureDifference(*f.gaugeStep); err != nil || units.GaugeIncrement <= 0 {
+			return UnitSystem{}, fmt.Errorf("invalid gauge increment %q; must be a pressure >0 or auto", *f.gaugeStep)
+		}
+	}
+	switch *f.reference {
+	case "absolute":
+		return units, nil
+	case "gauge":
+	default:
+		return UnitSystem{}, errors.New("invalid pressure reference; must be gauge or absolute")
+	}
+	if *f.ambient != "" {
+		if units.AmbientPressure, err = units.ParsePressureDifference(*f.ambient); err != nil || units.AmbientPressure <= 0 {
+			return UnitSystem{}, fmt.Errorf("%w: ambient pressure %s", ErrInvalidPressure, *f.ambient)
+		}
+		return units, nil
+	}
+	altitude, err := units.ParseLength(*f.altitude)
+	if err != nil || altitude < -500 || altitude > 9000 {
+		return UnitSystem{}, fmt.Errorf("invalid altitude; must be between -500m and 9000m: %s", *f.altitude)
+	}
+	units.AmbientPressure = AmbientPressureAtAltitude(altitude)
+	return units, nil
+}
+
+// output returns w printing numbers in the -locale format and messages in the -lang language
+func (f *commonFlags) output(w io.Writer, units UnitSystem) (io.Writer, error) {
+	language, err := ParseLanguage(*f.language)
+	if err != nil {
+		return nil, err
+	}
+	return newLanguageWriter(newLocaleWriter(newColorWriter(w, *f.noColor), units.Locale), language), nil
+}
+
+// parseTableStyle returns the style of -table-style
+func (f *commonFlags) parseTableStyle() (tableStyle, error) {
+	return ParseTableStyle(*f.tableStyle)
+}
+
+// gasSettings returns the gas system and temperature
+func (f *commonFlags) gasSettings(units UnitSystem) (GasSystem, Temperature, error) {
+	temperature, err := units.ParseTemperature(*f.temperature)
+	if err != nil || temperature < ZeroCelsius-30 || temperature > ZeroCelsius+80 {
+		return IdealGas, 0, fmt.Errorf("%w %s; must be >-30°C and <80°C", ErrInvalidTemperature, *f.temperature)
+	}
+	if *f.useIdealGas {
+		return IdealGas, temperature, nil
+	}
+	gasSystem, err := ParseGasSystem(*f.gasSystem)
+	if err != nil {
+		return IdealGas, 0, err
+	}
+	switch *f.mixing {
+	case "mixing-rules":
+	case "additive":
+		if gasSystem != VanDerWaals {
+			return IdealGas, 0, errors.New("additive mixing is only available for -gas-system vdw")
+		}
+		gasSystem = AdditiveVanDerWaals
+	default:
+		return IdealGas, 0, errors.New("invalid mixing; must be mixing-rules or additive")
+	}
+	return gasSystem, temperature, nil
+}
+
+// gasSystemName returns the name of the equation of state selected with -gas-system, -use-ideal-gas and -mixing
+func (f *commonFlags) gasSystemName() string {
+	if *f.useIdealGas {
+		return "ideal"
+	}
+	if *f.mixing == "additive" {
+		return *f.gasSystem + " (additive)"
+	}
+	return *f.gasSystem
+}
+
+// registerProgressFlag registers -progress for commands with long runs
+func registerProgressFlag(fs *flag.FlagSet) *string {
+	return fs.String("progress", "auto", "Report progress of long runs to stderr: auto (text on a terminal), text, ndjson or off")
+}
+
+// registerCyclesFlag registers -cycles, and -cool-down-cycles counting only the cycles after the first for older
+// scripts
+func registerCyclesFlag(fs *flag.FlagSet) *int {
+	cycles := fs.Int("cycles", 1, "Connect, equalize and disconnect the cylinders this many times, printing the gain of each cycle; with a non-isothermal -fill-process the cylinders cool down in between")
+	fs.Func("cool-down-cycles", "Deprecated: same as -cycles N+1", func(value string) error {
+		coolDownCycles, err := strconv.Atoi(value)
+		if err != nil || coolDownCycles < 0 {
+			return errors.New("must be >=0")
+		}
+		*cycles = coolDownCycles + 1
+		return nil
+	})
+	return cycles
+}
+
+// gasCompositionFlags holds flags defining the default gas composition
+type gasCompositionFlags struct {
+	heliumPercent   *float64
+	oxygenPercent   *float64
+	neonPercent     *float64
+	argonPercent    *float64
+	hydrogenPercent *float64
+	co2PPM          *float64
+	coPPM           *float64
+}
+
+func registerGasCompositionFlags(fs *flag.FlagSet) *gasCompositionFlags {
+	return &gasCompositionFlags{
+		heliumPercent:   fs.Float64("helium", 0.0, "Percentage of helium"),
+		oxygenPercent:   fs.Float64("oxygen", 0.21, "Percentage of oxygen"),
+		neonPercent:     fs.Float64("neon", 0, "Percentage of neon"),
+		argonPercent:    fs.Float64("argon", 0, "Percentage of argon"),
+		hydrogenPercent: fs.Float64("hydrogen", 0, "Percentage of hydrogen"),
+		co2PPM:          fs.Float64("co2-ppm", 0, "Carbon dioxide in ppm"),
+		coPPM:           fs.Float64("co-ppm", 0, "Carbon monoxide in ppm"),
+	}
+}
+
+// gasComposition returns the gas composition with nitrogen as the balance
+func (f *gasCompositionFlags) gasComposition() (GasComposition, error) {
+	if *f.co2PPM < 0 || *f.coPPM < 0 {
+		return nil, fmt.Errorf("%w: trace gases must not be negative", ErrInvalidGasMix)
+	}
+	gasSum := *f.heliumPercent + *f.oxygenPercent + *f.neonPercent + *f.argonPercent + *f.hydrogenPercent + (*f.co2PPM+*f.coPPM)/1e6
+	if gasSum > 1.0 {
+		return nil, fmt.Errorf("defined gases must not exceed 100%% (1.0): %w", ErrCompositionExceeds100)
+	}
+	nitrogenPercent := 1.0 - gasSum
+	return GasComposition{
+		Argon:          *f.argonPercent,
+		Helium:         *f.heliumPercent,
+		Hydrogen:       *f.hydrogenPercent,
+		Neon:           *f.neonPercent,
+		Nitrogen:       nitrogenPercent,
+		Oxygen:         *f.oxygenPercent,
+		CarbonDioxide:  *f.co2PPM / 1e6,
+		CarbonMonoxide: *f.coPPM / 1e6,
+	}, nil
+}
+
+// checkCylinders checks cylinder pressures (as gauge pressures when units use one) against the test pressure of each
+// cylinder, or 350 bar when it is not known, and volumes
+func checkCylinders(cylinders CylinderList, side string, allowEmpty bool, units UnitSystem) error {
+	for _, cylinder := range cylinders {
+		pressure := cylinder.Pressure - units.AmbientPressure
+		maxPressure := cylinder.pressureLimit(units) - units.AmbientPressure
+		if allowEmpty && (pressure > maxPressure || pressure < 0) {
+			return fmt.Errorf("%w of %s cylinder; must be >= 0 and <=%.0f", ErrInvalidPressure, side, maxPressure)
+		}
+		if !allowEmpty && (pressure > maxPressure || pressure <= 0) {
+			return fmt.Errorf("%w of %s cylinder; must be > 0 and <=%.0f", ErrInvalidPressure, side, maxPressure)
+		}
+		if _, err := NewCylinderVolume(float64(cylinder.CylinderVolume)); err != nil {
+			return fmt.Errorf("%s cylinder: %w", side, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import "math"
+
+// flowConstant is N2 of the valve sizing equations for gas flow in standard liters per minute with absolute
+// pressures in bar and temperatures in kelvins
+const flowConstant = 6950
+
+// airMolarMass is the molar mass of air, the reference for specific gravities of gases
+const airMolarMass = 28.96
+
+// TransferTime is the time a transfer through a valve or whip takes to get close to equalized
+type TransferTime struct {
+	// Minutes90 and Minutes99 are minutes until 90% and 99% of the pressure change of the destination is done
+	Minutes90 float64
+	Minutes99 float64
+}
+
+// specificGravity returns the specific gravity of the gas mix relative to air
+func specificGravity(gasComposition GasComposition) float64 {
+	var molarMass float64
+	for gasType, fraction := range gasComposition {
+		molarMass += fraction * float64(SpeciesLookup[gasType].MolarMass)
+	}
+	return molarMass / airMolarMass
+}
+
+// ValveFlow returns the flow in standard liters per minute through a valve with the flow coefficient Cv from
+// inletPressure to outletPressure (absolute). Flow is choked when the outlet pressure is below half of the inlet
+// pressure.
+func ValveFlow(flowCoefficient float64, inletPressure PressureBar, outletPressure PressureBar, gasComposition GasComposition, temperature Temperature) GasVolume {
+	if inletPressure <= outletPressure {
+		return 0
+	}
+	p1 := float64(inletPressure)
+	gravityTemperature := specificGravity(gasComposition) * float64(temperature)
+	if outletPressure <= inletPressure/2 {
+		return GasVolume(0.471 * flowConstant * flowCoefficient * p1 * math.Sqrt(1/gravityTemperature))
+	}
+	dp := float64(inletPressure - outletPressure)
+	return GasVolume(flowConstant * flowCoefficient * p1 * (1 - 2*dp/(3*p1)) * math.Sqrt(dp/(p1*gravityTemperature)))
+}
+
+// EstimateTransferTime integrates the flow through a valve with the flow coefficient Cv from the source to the
+// destination until they are close to equalized. Time steps are sized to move a small part of the remaining gas.
+func EstimateTransferTime(source Cylinder, destination Cylinder, flowCoefficient float64, gasSystem GasSystem, temperature Temperature) TransferTime {
+	var transferTime TransferTime
+	if source.Pressure <= destination.Pressure || flowCoefficient <= 0 {
+		return transferTime
+	}
+	equalizedSource, equalizedDestination := source, destination
+	equalizedDestination.Equalize(&equalizedSource, gasSystem, temperature, nil)
+	pressure90 := destination.Pressure + (equalizedDestination.Pressure-destination.Pressure)*0.9
+	pressure99 := destination.Pressure + (equalizedDestination.Pressure-destination.Pressure)*0.99
+	remainingGasVolume := equalizedDestination.GasVolume(gasSystem, temperature) - destination.GasVolume(gasSystem, temperature)
+
+	var minutes float64
+	for destination.Pressure < pressure99 && remainingGasVolume > 0 {
+		flow := ValveFlow(flowCoefficient, source.Pressure, destination.Pressure, source.GasComposition, temperature)
+		if flow <= 0 {
+			break
+		}
+		stepGasVolume := remainingGasVolume / 100
+		minutes += float64(stepGasVolume / flow)
+		destination.TransferGas(&source, stepGasVolume, gasSystem, temperature)
+		remainingGasVolume -= stepGasVolume
+		if transferTime.Minutes90 == 0 && destination.Pressure >= pressure90 {
+			transferTime.Minutes90 = minutes
+		}
+	}
+	transferTime.Minutes99 = minutes
+	return transferTime
+}
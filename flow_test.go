@@ -0,0 +1,47 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestValveFlow(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	// Choked flow does not depend on the outlet pressure
+	choked := ValveFlow(0.1, 200, 50, air, 293.15)
+	if choked != ValveFlow(0.1, 200, 1, air, 293.15) {
+		t.Errorf("Expected choked flow to ignore the outlet pressure")
+	}
+	expected := 0.471 * flowConstant * 0.1 * 200 / math.Sqrt(specificGravity(air)*293.15)
+	if !compareFloats(float64(choked), expected) {
+		t.Errorf("Invalid choked flow %f, expected %f", choked, expected)
+	}
+	if flow := ValveFlow(0.1, 200, 150, air, 293.15); flow <= 0 || flow >= choked {
+		t.Errorf("Expected subcritical flow below choked flow, got %f", flow)
+	}
+	if flow := ValveFlow(0.1, 150, 200, air, 293.15); flow != 0 {
+		t.Errorf("Expected no flow against the pressure, got %f", flow)
+	}
+	// Helium is lighter and flows faster
+	if ValveFlow(0.1, 200, 50, GasComposition{Helium: 1}, 293.15) <= choked {
+		t.Errorf("Expected helium to flow faster than air")
+	}
+}
+
+func TestEstimateTransferTime(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	source := Cylinder{CylinderVolume: 50, Pressure: 200, GasComposition: air}
+	destination := Cylinder{CylinderVolume: 12, Pressure: 50, GasComposition: air}
+	transferTime := EstimateTransferTime(source, destination, 0.1, IdealGas, 293.15)
+	if transferTime.Minutes90 <= 0 || transferTime.Minutes99 <= transferTime.Minutes90 {
+		t.Fatalf("Invalid transfer time %+v", transferTime)
+	}
+	// Flow is proportional to Cv
+	slower := EstimateTransferTime(source, destination, 0.05, IdealGas, 293.15)
+	if !compareFloats(slower.Minutes90, 2*transferTime.Minutes90) {
+		t.Errorf("Expected half the Cv to take twice as long, got %+v and %+v", slower, transferTime)
+	}
+	if source.Pressure != 200 || destination.Pressure != 50 {
+		t.Errorf("Cylinders were modified")
+	}
+}
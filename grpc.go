@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// gRPC status codes used by the server
+const (
+	grpcOK              = 0
+	grpcInvalidArgument = 3
+	grpcUnimplemented   = 12
+)
+
+// grpcError is a failed call with a gRPC status code
+type grpcError struct {
+	code    int
+	message string
+}
+
+func (e grpcError) Error() string {
+	return e.message
+}
+
+// readGRPCMessage reads a single length prefixed gRPC message
+func readGRPCMessage(r io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, grpcError{grpcInvalidArgument, "invalid request: " + err.Error()}
+	}
+	if header[0] != 0 {
+		return nil, grpcError{grpcUnimplemented, "compressed messages are not supported"}
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > maxRequestSize {
+		return nil, grpcError{grpcInvalidArgument, "request is too large"}
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, grpcError{grpcInvalidArgument, "invalid request: " + err.Error()}
+	}
+	return content, nil
+}
+
+// writeGRPCMessage writes a single length prefixed gRPC message and flushes it to the client
+func writeGRPCMessage(w http.ResponseWriter, content []byte) {
+	header := []byte{0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(header[1:], uint32(len(content)))
+	w.Write(append(header, content...))
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// writeGRPCStatus sets the status trailers of a gRPC response. Err is nil for a successful call.
+func writeGRPCStatus(w http.ResponseWriter, err error) {
+	var status grpcError
+	if err != nil && !errors.As(err, &status) {
+		status = grpcError{grpcInvalidArgument, err.Error()}
+	}
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", fmt.Sprint(status.code))
+	if status.message != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", grpcPercentEncode(status.message))
+	}
+}
+
+// grpcPercentEncode encodes a status message as required for the grpc-message trailer
+func grpcPercentEncode(message string) string {
+	var encoded strings.Builder
+	for _, b := range []byte(message) {
+		if b < 0x20 || b > 0x7e || b == '%' {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		} else {
+			encoded.WriteByte(b)
+		}
+	}
+	return encoded.String()
+}
+
+// decodeEqualizeRequestProto decodes a whip.EqualizeRequest message
+func decodeEqualizeRequestProto(content []byte) (EqualizeRequest, error) {
+	var request EqualizeRequest
+	err := decodeProto(content, func(field protoField) error {
+		switch field.number {
+		case 1, 2:
+			cylinder, err := decodeCylinderProto(field.content)
+			if err != nil {
+				return err
+			}
+			if field.number == 1 {
+				request.Source = append(request.Source, cylinder)
+			} else {
+				request.Destination = append(request.Destination, cylinder)
+			}
+		case 3:
+			request.Mix = string(field.content)
+		}
+		return nil
+	})
+	return request, err
+}
+
+// decodeCylinderProto decodes a whip.Cylinder message
+func decodeCylinderProto(content []byte) (ScenarioCylinder, error) {
+	var cylinder ScenarioCylinder
+	err := decodeProto(content, func(field protoField) error {
+		switch field.number {
+		case 1:
+			cylinder.Description = string(field.content)
+		case 2:
+			cylinder.Volume = field.double()
+		case 3:
+			cylinder.Pressure = field.double()
+		case 4:
+			cylinder.Mix = string(field.content)
+		}
+		return nil
+	})
+	return cylinder, err
+}
+
+// encodeTransferStepEvent encodes a whip.EqualizeEvent holding a transfer step
+func (s server) encodeTransferStepEvent(step TransferStep) []byte {
+	var e protoEncoder
+	e.stringField(1, step.Configuration)
+	e.intField(2, step.Step)
+	e.intField(3, step.Cycle)
+	e.stringField(4, step.Source)
+	e.stringField(5, step.Destination)
+	e.doubleField(6, s.units.Pressure(step.SourcePressureBefore))
+	e.doubleField(7, s.units.Pressure(step.SourcePressureAfter))
+	e.doubleField(8, s.units.Pressure(step.DestinationPressureBefore))
+	e.doubleField(9, s.units.Pressure(step.DestinationPressureAfter))
+	e.doubleField(10, float64(step.TransferredGasVolume))
+	var event protoEncoder
+	event.bytesField(1, e.content)
+	return event.content
+}
+
+// encodeSummaryEvent encodes a whip.EqualizeEvent holding a summary
+func encodeSummaryEvent(summary SummaryResponse) []byte {
+	var e protoEncoder
+	e.stringField(1, summary.Description)
+	e.doubleField(2, summary.SourcePressure)
+	e.doubleField(3, summary.SourceGasVolume)
+	e.stringField(4, summary.SourceMix)
+	e.doubleField(5, summary.DestinationPressure)
+	e.doubleField(6, summary.DestinationGasVolume)
+	e.stringField(7, summary.DestinationMix)
+	e.doubleField(8, summary.ImprovementPercent)
+	var event protoEncoder
+	event.bytesField(2, e.content)
+	return event.content
+}
+
+// handleGRPCEqualize serves /whip.Whip/Equalize, streaming each transfer step as it is calculated and then the
+// summaries
+func (s server) handleGRPCEqualize(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		writeJSON(w, http.StatusUnsupportedMediaType, ErrorResponse{Error: "expected a gRPC request"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.WriteHeader(http.StatusOK)
+	content, err := readGRPCMessage(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		writeGRPCStatus(w, err)
+		return
+	}
+	request, err := decodeEqualizeRequestProto(content)
+	if err != nil {
+		writeGRPCStatus(w, grpcError{grpcInvalidArgument, "invalid request: " + err.Error()})
+		return
+	}
+	response, err := s.equalizeRequest(request, func(step TransferStep) {
+		writeGRPCMessage(w, s.encodeTransferStepEvent(step))
+	})
+	if err != nil {
+		writeGRPCStatus(w, err)
+		return
+	}
+	for _, summary := range response.Summaries {
+		writeGRPCMessage(w, encodeSummaryEvent(summary))
+	}
+	writeGRPCStatus(w, nil)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGRPCEqualize(t *testing.T) {
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	httpServer := httptest.NewUnstartedServer(newServer(IdealGas, 293.15, Metric))
+	httpServer.Config.Protocols = &protocols
+	httpServer.Start()
+	defer httpServer.Close()
+	client := http.Client{Transport: &http.Transport{Protocols: &protocols}}
+
+	var source, destination, request protoEncoder
+	source.doubleField(2, 12)
+	source.doubleField(3, 232)
+	destination.stringField(1, "left")
+	destination.doubleField(2, 6)
+	destination.doubleField(3, 80)
+	request.bytesField(1, source.content)
+	request.bytesField(2, destination.content)
+	request.bytesField(2, destination.content)
+	body := []byte{0, 0, 0, 0, byte(len(request.content))}
+	response, err := client.Post(httpServer.URL+"/whip.Whip/Equalize", "application/grpc", bytes.NewReader(append(body, request.content...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	var steps, summaries int
+	var lastDestinationPressure float64
+	for {
+		content, err := readGRPCMessage(response.Body)
+		if err != nil {
+			break
+		}
+		err = decodeProto(content, func(event protoField) error {
+			if event.number == 1 {
+				steps++
+			} else {
+				summaries++
+			}
+			return decodeProto(event.content, func(field protoField) error {
+				if event.number == 2 && field.number == 5 {
+					lastDestinationPressure = field.double()
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if response.Trailer.Get("Grpc-Status") != "0" {
+		t.Fatalf("Unexpected status %q: %s", response.Trailer.Get("Grpc-Status"), response.Trailer.Get("Grpc-Message"))
+	}
+	// Destination manifold closed: one step per destination cylinder, then a single step with the manifold open
+	if steps != 3 || summaries != 2 || !compareFloats(lastDestinationPressure, 156) {
+		t.Errorf("Expected 3 steps and 2 summaries ending at 156 bar, got %d steps, %d summaries, %.1f bar", steps, summaries, lastDestinationPressure)
+	}
+}
+
+func TestGRPCEqualizeError(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/whip.Whip/Equalize", bytes.NewReader([]byte{0, 0, 0, 0, 0}))
+	request.Header.Set("Content-Type", "application/grpc")
+	newServer(IdealGas, 293.15, Metric).ServeHTTP(recorder, request)
+	trailer := recorder.Result().Trailer
+	if trailer.Get("Grpc-Status") != "3" || trailer.Get("Grpc-Message") != "at least one source and destination cylinder is required" {
+		t.Errorf("Unexpected trailers %v", trailer)
+	}
+}
+
+func TestGRPCPercentEncode(t *testing.T) {
+	if encoded := grpcPercentEncode("100% at 20°C"); encoded != "100%25 at 20%C2%B0C" {
+		t.Errorf("Unexpected encoding %q", encoded)
+	}
+}
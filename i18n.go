@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Language selects the language of printed results. The zero value is English.
+type Language struct {
+	Name string
+	// messages maps English messages to translations; messages missing from it are printed in English
+	messages map[string]string
+}
+
+// catalogs lists the message catalogs by language; English needs none
+var catalogs = map[string]map[string]string{
+	"en": nil,
+	"fi": finnishMessages,
+}
+
+// ParseLanguage returns the language of a code such as "fi", also given as "fi-FI" or "fi_FI.UTF-8". An empty code
+// returns English.
+func ParseLanguage(name string) (Language, error) {
+	if name == "" {
+		return Language{Name: "en"}, nil
+	}
+	code, _, _ := strings.Cut(strings.ToLower(name), ".")
+	code, _, _ = strings.Cut(code, "_")
+	code, _, _ = strings.Cut(code, "-")
+	messages, ok := catalogs[code]
+	if !ok {
+		codes := make([]string, 0, len(catalogs))
+		for code := range catalogs {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		return Language{}, fmt.Errorf("unknown language %q; must be one of %s", name, strings.Join(codes, ", "))
+	}
+	return Language{Name: code, messages: messages}, nil
+}
+
+// translate returns the message in the language, or the message itself without a translation
+func (l Language) translate(message string) string {
+	if translation, ok := l.messages[message]; ok {
+		return translation
+	}
+	return message
+}
+
+// languageWriter carries the language of the output to the functions printing to it
+type languageWriter struct {
+	io.Writer
+	language Language
+}
+
+// newLanguageWriter returns a writer translating messages passed through tr, or w itself for English
+func newLanguageWriter(w io.Writer, language Language) io.Writer {
+	if len(language.messages) == 0 {
+		return w
+	}
+	return &languageWriter{Writer: w, language: language}
+}
+
+// tr returns the message, a format string or a fixed text, in the language of the writer
+func tr(w io.Writer, message string) string {
+	if lw, ok := w.(*languageWriter); ok {
+		return lw.language.translate(message)
+	}
+	return message
+}
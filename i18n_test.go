@@ -0,0 +1,55 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestParseLanguage(t *testing.T) {
+	for _, name := range []string{"fi", "fi_FI.UTF-8", "FI-fi"} {
+		if language, err := ParseLanguage(name); err != nil || language.Name != "fi" {
+			t.Errorf("Invalid language %+v for %q: %v", language, name, err)
+		}
+	}
+	if language, err := ParseLanguage(""); err != nil || language.Name != "en" {
+		t.Errorf("Expected English, got %+v: %v", language, err)
+	}
+	if _, err := ParseLanguage("xx"); err == nil {
+		t.Error("Expected an error for an unknown language")
+	}
+}
+
+// formatVerbs matches fmt verbs, which translations must keep in the same order
+var formatVerbs = regexp.MustCompile(`%[-+# 0-9.*]*[a-zA-Z%]`)
+
+func TestCatalogFormatVerbs(t *testing.T) {
+	for code, messages := range catalogs {
+		for message, translation := range messages {
+			expected := strings.Join(formatVerbs.FindAllString(message, -1), " ")
+			if verbs := strings.Join(formatVerbs.FindAllString(translation, -1), " "); verbs != expected {
+				t.Errorf("%s translation %q has verbs %q, expected %q", code, translation, verbs, expected)
+			}
+		}
+	}
+}
+
+func TestTranslatedReport(t *testing.T) {
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "source", CylinderVolume: 12, Pressure: 232, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+		DestinationCylinders: CylinderList{{Description: "destination", CylinderVolume: 12, Pressure: 80, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+	}
+	language, _ := ParseLanguage("fi")
+	var output strings.Builder
+	w := newLanguageWriter(&output, language)
+	summary := equalizeAndReport(w, cylinderConfiguration, IdealGas, 293.15, Metric, false, nil, false)
+	if !strings.HasPrefix(output.String(), "Tasaus, kaikki jakotukit auki\n") || !strings.Contains(output.String(), "Kohdepullot: ") {
+		t.Errorf("Unexpected output %q", output.String())
+	}
+	if summary.Description != "all manifolds open" {
+		t.Errorf("Expected an untranslated description, got %q", summary.Description)
+	}
+	if english := newLanguageWriter(&output, Language{Name: "en"}); english != &output || tr(english, "Gas cost:") != "Gas cost:" {
+		t.Error("Expected English to print messages as is")
+	}
+}
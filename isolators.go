@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxCylinderSet is the largest manifolded set of cylinders; each of its isolators doubles the configurations compared
+const maxCylinderSet = 8
+
+// NewCylinderSet returns a manifolded set of the given number of cylinders of equal size, such as a twinset, a
+// rebreather triple or a bank quad, with an isolator between each pair of neighbouring cylinders
+func NewCylinderSet(cylinderVolume CylinderVolume, pressure PressureBar, count int) CylinderList {
+	var names []string
+	switch count {
+	case 2:
+		names = []string{"left", "right"}
+	case 3:
+		names = []string{"left", "center", "right"}
+	}
+	cylinders := make(CylinderList, count)
+	for i := range cylinders {
+		cylinders[i] = Cylinder{Description: fmt.Sprintf("cylinder %d", i+1), CylinderVolume: cylinderVolume / CylinderVolume(count), Pressure: pressure}
+		if names != nil {
+			cylinders[i].Description = names[i]
+		}
+	}
+	return cylinders
+}
+
+// joinIsolated combines the cylinders joined by open isolators into single cylinders; isolator i joins cylinders i
+// and i+1
+func joinIsolated(cylinders CylinderList, isolatorsOpen []bool, gasSystem GasSystem, temperature Temperature) CylinderList {
+	var joined CylinderList
+	start := 0
+	for i := range cylinders {
+		if i < len(isolatorsOpen) && isolatorsOpen[i] {
+			continue
+		}
+		group := append(CylinderList(nil), cylinders[start:i+1]...)
+		if len(group) == 1 {
+			joined = append(joined, group[0])
+		} else {
+			descriptions := make([]string, len(group))
+			for j, cylinder := range group {
+				descriptions[j] = cylinder.Description
+			}
+			joined = append(joined, openManifold(group, strings.Join(descriptions, "+"), gasSystem, temperature)...)
+		}
+		start = i + 1
+	}
+	return joined
+}
+
+// mixedIsolatorStates returns each combination of open and closed isolators between n cylinders, except all open and
+// all closed, which are the open and closed manifold
+func mixedIsolatorStates(n int) [][]bool {
+	var states [][]bool
+	for mask := 1; mask < 1<<(n-1)-1; mask++ {
+		state := make([]bool, n-1)
+		for i := range state {
+			state[i] = mask&(1<<i) != 0
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// isolatorDescription describes the manifolds of a configuration with isolators of a side set, such as "source
+// manifold closed, destination isolators open,closed"
+func isolatorDescription(cylinderConfiguration CylinderConfiguration) string {
+	sides := []struct {
+		name           string
+		cylinders      CylinderList
+		isolatorsOpen  []bool
+		manifoldClosed bool
+	}{
+		{"source", cylinderConfiguration.SourceCylinders, cylinderConfiguration.SourceIsolators, cylinderConfiguration.SourceManifoldClosed},
+		{"destination", cylinderConfiguration.DestinationCylinders, cylinderConfiguration.DestinationIsolators, cylinderConfiguration.DestinationManifoldClosed},
+	}
+	var parts []string
+	for _, side := range sides {
+		switch {
+		case side.isolatorsOpen != nil:
+			states := make([]string, len(side.isolatorsOpen))
+			for i, open := range side.isolatorsOpen {
+				states[i] = "closed"
+				if open {
+					states[i] = "open"
+				}
+			}
+			parts = append(parts, side.name+" isolators "+strings.Join(states, ","))
+		case len(side.cylinders) > 1 && side.manifoldClosed:
+			parts = append(parts, side.name+" manifold closed")
+		case len(side.cylinders) > 1:
+			parts = append(parts, side.name+" manifold open")
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+func TestNewCylinderSet(t *testing.T) {
+	triple := NewCylinderSet(9, 200, 3)
+	if len(triple) != 3 || triple[1].Description != "center" || triple[2].CylinderVolume != 3 {
+		t.Errorf("Unexpected triple %+v", triple)
+	}
+	if quad := NewCylinderSet(200, 232, 4); quad[3].Description != "cylinder 4" || quad.TotalVolume() != 200 {
+		t.Errorf("Unexpected quad %+v", quad)
+	}
+}
+
+func TestJoinIsolated(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	quad := CylinderList{
+		{Description: "a", CylinderVolume: 10, Pressure: 100, GasComposition: air},
+		{Description: "b", CylinderVolume: 10, Pressure: 200, GasComposition: air},
+		{Description: "c", CylinderVolume: 10, Pressure: 50, GasComposition: air},
+		{Description: "d", CylinderVolume: 10, Pressure: 150, GasComposition: air},
+	}
+	joined := joinIsolated(quad, []bool{true, false, true}, IdealGas, 293.15)
+	if len(joined) != 2 || joined[0].Description != "a+b" || joined[1].CylinderVolume != 20 || !compareFloats(float64(joined[1].Pressure), 100) {
+		t.Errorf("Unexpected cylinders %+v", joined)
+	}
+	if quad[0].Pressure != 100 {
+		t.Errorf("Expected the cylinders to stay as they were, got %+v", quad)
+	}
+	if states := mixedIsolatorStates(4); len(states) != 6 {
+		t.Errorf("Expected six mixed isolator states, got %v", states)
+	}
+}
+
+func TestCompareIsolators(t *testing.T) {
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "source", CylinderVolume: 50, Pressure: 232, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+		DestinationCylinders: NewCylinderSet(9, 50, 3),
+		CompareIsolators:     true,
+	}
+	cylinderConfiguration.DestinationCylinders.SetDefaultGasComposition(GasComposition{Oxygen: 0.21, Nitrogen: 0.79})
+	cylinderSummaries := equalizeAllConfigurations(io.Discard, cylinderConfiguration, IdealGas, 293.15, Metric, false, nil)
+	if len(cylinderSummaries) != 4 || cylinderSummaries[2].Description != "destination isolators open,closed" {
+		t.Fatalf("Unexpected configurations %+v", cylinderSummaries)
+	}
+	// A partly open set fills between the closed and the open manifold
+	closed, open := cylinderSummaries[0].DestinationCylinderPressure, cylinderSummaries[1].DestinationCylinderPressure
+	if pressure := cylinderSummaries[2].DestinationCylinderPressure; pressure >= closed || pressure <= open {
+		t.Errorf("Expected a pressure between %f and %f, got %f", open, closed, pressure)
+	}
+}
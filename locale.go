@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Locale selects the decimal and thousands separators of numbers. The zero value prints numbers as formatted by fmt
+// and reads a decimal point only.
+type Locale struct {
+	Name             string
+	DecimalSeparator string
+	GroupSeparator   string
+}
+
+// locales lists the supported locales by language
+var locales = map[string]Locale{
+	"en": {Name: "en", DecimalSeparator: ".", GroupSeparator: ","},
+	"de": {Name: "de", DecimalSeparator: ",", GroupSeparator: "."},
+	"fi": {Name: "fi", DecimalSeparator: ",", GroupSeparator: " "},
+	"fr": {Name: "fr", DecimalSeparator: ",", GroupSeparator: " "},
+	"nl": {Name: "nl", DecimalSeparator: ",", GroupSeparator: "."},
+	"sv": {Name: "sv", DecimalSeparator: ",", GroupSeparator: " "},
+}
+
+// ParseLocale returns the locale of a language such as "fi", also given as "fi-FI" or "fi_FI.UTF-8". An empty name
+// returns the zero locale.
+func ParseLocale(name string) (Locale, error) {
+	if name == "" {
+		return Locale{}, nil
+	}
+	language, _, _ := strings.Cut(strings.ToLower(name), ".")
+	language, _, _ = strings.Cut(language, "_")
+	language, _, _ = strings.Cut(language, "-")
+	locale, ok := locales[language]
+	if !ok {
+		names := make([]string, 0, len(locales))
+		for name := range locales {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return Locale{}, fmt.Errorf("unknown locale %q; must be one of %s", name, strings.Join(names, ", "))
+	}
+	return locale, nil
+}
+
+// normalizeNumber returns the number with a decimal comma replaced with a decimal point, for locales writing
+// decimals with a comma. A decimal point is accepted in all locales.
+func (l Locale) normalizeNumber(s string) string {
+	if l.DecimalSeparator != "," {
+		return s
+	}
+	return strings.Replace(s, ",", ".", 1)
+}
+
+// Format returns the text with the separators of numbers replaced with those of the locale: "1234.5" is "1 234,5"
+// in Finnish. Integers are grouped from five digits up and numbers with several points, such as addresses, are
+// kept as is.
+func (l Locale) Format(s string) string {
+	if l.DecimalSeparator == "" {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if !isDigit(s[i]) {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		end := i
+		for end < len(s) && (isDigit(s[end]) || s[end] == '.') {
+			end++
+		}
+		// A trailing point ends a sentence
+		for end > i && s[end-1] == '.' {
+			end--
+		}
+		b.WriteString(l.formatNumber(s[i:end]))
+		i = end
+	}
+	return b.String()
+}
+
+// formatNumber formats digits with an optional decimal point
+func (l Locale) formatNumber(number string) string {
+	if strings.Count(number, ".") > 1 {
+		return number
+	}
+	integer, fraction, hasFraction := strings.Cut(number, ".")
+	if len(integer) >= 5 {
+		var grouped strings.Builder
+		for i, digit := range integer {
+			if i > 0 && (len(integer)-i)%3 == 0 {
+				grouped.WriteString(l.GroupSeparator)
+			}
+			grouped.WriteRune(digit)
+		}
+		integer = grouped.String()
+	}
+	if !hasFraction {
+		return integer
+	}
+	return integer + l.DecimalSeparator + fraction
+}
+
+// width returns the number of characters of the text once formatted with the locale
+func (l Locale) width(s string) int {
+	return utf8.RuneCountInString(l.Format(s))
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// localeWriter formats numbers written to it with a locale. Each write is formatted on its own, so a number must not
+// be split across writes.
+type localeWriter struct {
+	w      io.Writer
+	locale Locale
+}
+
+// newLocaleWriter returns a writer formatting numbers with the locale, or w itself for the zero locale
+func newLocaleWriter(w io.Writer, locale Locale) io.Writer {
+	if locale.DecimalSeparator == "" {
+		return w
+	}
+	return &localeWriter{w: w, locale: locale}
+}
+
+// writerLocale returns the locale formatting the numbers written to w, or the zero locale
+func writerLocale(w io.Writer) Locale {
+	for {
+		switch v := w.(type) {
+		case *localeWriter:
+			return v.locale
+		case *languageWriter:
+			w = v.Writer
+		default:
+			return Locale{}
+		}
+	}
+}
+
+func (lw *localeWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(lw.w, lw.locale.Format(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
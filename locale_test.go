@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLocale(t *testing.T) {
+	for _, name := range []string{"fi", "fi-FI", "fi_FI.UTF-8", "FI"} {
+		if locale, err := ParseLocale(name); err != nil || locale.Name != "fi" {
+			t.Errorf("Invalid locale %+v for %q: %v", locale, name, err)
+		}
+	}
+	if locale, err := ParseLocale(""); err != nil || locale.DecimalSeparator != "" {
+		t.Errorf("Expected the zero locale, got %+v: %v", locale, err)
+	}
+	if _, err := ParseLocale("xx"); err == nil {
+		t.Error("Expected an error for an unknown locale")
+	}
+}
+
+func TestLocaleFormat(t *testing.T) {
+	tests := []struct {
+		locale   string
+		input    string
+		expected string
+	}{
+		{"fi", "Destination: 156.3bar, 12345l of EAN32.0.", "Destination: 156,3bar, 12 345l of EAN32,0."},
+		{"fi", "Listening on 127.0.0.1:8080 at 1234 m", "Listening on 127.0.0.1:8080 at 1234 m"},
+		{"de", "1234567.25 l", "1.234.567,25 l"},
+		{"en", "12345.5 cuft", "12,345.5 cuft"},
+		{"", "12345.5 cuft", "12345.5 cuft"},
+	}
+	for _, test := range tests {
+		locale, _ := ParseLocale(test.locale)
+		if output := locale.Format(test.input); output != test.expected {
+			t.Errorf("Invalid %q output for %q: expected %q, got %q", test.locale, test.input, test.expected, output)
+		}
+	}
+}
+
+func TestLocaleWriter(t *testing.T) {
+	var output strings.Builder
+	locale, _ := ParseLocale("fi")
+	w := newLocaleWriter(&output, locale)
+	if n, err := w.Write([]byte("21.5C\n")); err != nil || n != 6 {
+		t.Errorf("Invalid write of %d bytes: %v", n, err)
+	}
+	if output.String() != "21,5C\n" {
+		t.Errorf("Unexpected output %q", output.String())
+	}
+	if w := newLocaleWriter(&output, Locale{}); w != &output {
+		t.Error("Expected the zero locale to return the writer itself")
+	}
+}
+
+func TestParseDecimalComma(t *testing.T) {
+	locale, _ := ParseLocale("fi")
+	units := UnitSystem{Locale: locale}
+	if temperature, err := units.ParseTemperature("21,5"); err != nil || !compareFloats(float64(temperature), 294.65) {
+		t.Errorf("Invalid temperature %f: %v", temperature, err)
+	}
+	if pressure, err := units.ParsePressure("2,5bar"); err != nil || pressure != 2.5 {
+		t.Errorf("Invalid pressure %f: %v", pressure, err)
+	}
+	if pressure, err := units.ParsePressure("2.5bar"); err != nil || pressure != 2.5 {
+		t.Errorf("Expected a decimal point to be accepted, got %f: %v", pressure, err)
+	}
+	if _, err := Metric.ParseTemperature("21,5"); err == nil {
+		t.Error("Expected an error for a decimal comma without a locale")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewLogHandler(t *testing.T) {
+	var output strings.Builder
+	handler, err := newLogHandler(&output, "json", slog.LevelInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(handler)
+	logger.Debug("hidden")
+	logger.Info("listening", "address", "localhost:8080")
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(output.String()), &record); err != nil {
+		t.Fatalf("Log is not a single JSON record: %q", output.String())
+	}
+	if record["msg"] != "listening" || record["address"] != "localhost:8080" {
+		t.Errorf("Unexpected record %v", record)
+	}
+	if _, err := newLogHandler(&output, "xml", slog.LevelInfo); err == nil {
+		t.Error("Expected an error for an invalid log format")
+	}
+}
+
+func TestLogRequests(t *testing.T) {
+	var output strings.Builder
+	handler, _ := newLogHandler(&output, "json", slog.LevelInfo)
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(handler))
+	defer slog.SetDefault(defaultLogger)
+	recorder := httptest.NewRecorder()
+	logRequests(newServer(IdealGas, 293.15, Metric)).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/equalize", nil))
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(output.String()), &record); err != nil {
+		t.Fatalf("Log is not a single JSON record: %q", output.String())
+	}
+	if record["path"] != "/equalize" || record["status"] != float64(http.StatusMethodNotAllowed) {
+		t.Errorf("Unexpected record %v", record)
+	}
+}
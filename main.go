@@ -0,0 +1,113 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// command is a subcommand of the calculator
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+var commands = []command{
+	{"equalize", "Equalize source and destination cylinders through a whip (default)", equalizeMain},
+	{"blend", "Plan a partial pressure blend", blendMain},
+	{"plan", "Plan a cascade fill from storage banks", cascadeMain},
+	{"analyze", "Show contents of cylinders", analyzeMain},
+	{"convert", "Convert pressures, volumes, temperatures and lengths between units", convertMain},
+	{"trace", "Show exposure to trace gases in a mix", traceMain},
+	{"serve", "Serve equalize and blend calculations over HTTP", serveMain},
+	{"tui", "Adjust cylinders interactively and see results update", tuiMain},
+	{"mqtt", "Predict equalization from fill panel pressure sensors over MQTT", mqttMain},
+	{"serial", "Predict equalization from a pressure transducer on a serial port", serialMain},
+	{"batch", "Run many equalize scenarios from a CSV or JSON file", batchMain},
+	{"diff", "Compare two scenarios or saved results field by field", diffMain},
+	{"topology", "Equalize cylinders connected by valves, whips and manifolds described in a JSON file", topologyMain},
+	{"script", "Run transfer scripts opening valves and equalizing cylinders of a topology step by step", scriptMain},
+	{"bank", "Manage the inventory of storage banks: add, list, set-pressure, retire", bankMain},
+	{"log", "Review the fills of the fill log: list", fillLogMain},
+	{"consumption", "Report oxygen and helium consumed from the fill log and forecast the helium bank", consumptionMain},
+	{"day", "Order a queue of requested blends to complete the most with the bank inventory", dayMain},
+	{"deco", "Plan stage bottle fills for the deco gases of a schedule", decoMain},
+	{"ccr", "Top off rebreather diluent and oxygen bottles from banks", ccrMain},
+}
+
+// commandAliases maps older subcommand names to current ones
+var commandAliases = map[string]string{
+	"cascade": "plan",
+}
+
+func usage() {
+	name := filepath.Base(os.Args[0])
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", name)
+	for _, command := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", command.name, command.description)
+	}
+	fmt.Fprintf(os.Stderr, "\nUse \"%s <command> -h\" for flags of a command.\n", name)
+}
+
+// exitCodes are the exit statuses of errors scripts can tell apart from invalid input, which exits with status 1.
+// Mixes over 100% exit with status 11 as they always have, and fills predicted outside the -expected-mix tolerance
+// with status 12.
+var exitCodes = []struct {
+	err  error
+	code int
+}{
+	{ErrCompositionExceeds100, 11},
+	{ErrMixOutOfSpecification, 12},
+}
+
+// exitCode returns the exit status of an error
+func exitCode(err error) int {
+	for _, exitCode := range exitCodes {
+		if errors.Is(err, exitCode.err) {
+			return exitCode.code
+		}
+	}
+	return 1
+}
+
+// exit prints the error of a command and exits with its status, or returns when there is none. Commands print
+// their own usage before returning errUsage.
+func exit(err error) {
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, errUsage) {
+		println(err.Error())
+	}
+	os.Exit(exitCode(err))
+}
+
+func main() {
+	// Without a command, flags are for equalize
+	if len(os.Args) < 2 || strings.HasPrefix(os.Args[1], "-") {
+		exit(equalizeMain(os.Args[1:]))
+		return
+	}
+	name := os.Args[1]
+	if alias, ok := commandAliases[name]; ok {
+		name = alias
+	}
+	if name == "help" {
+		usage()
+		return
+	}
+	for _, command := range commands {
+		if command.name == name {
+			exit(command.run(os.Args[2:]))
+			return
+		}
+	}
+	println("Unknown command:", os.Args[1])
+	usage()
+	os.Exit(1)
+}
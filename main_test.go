@@ -0,0 +1,43 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{fmt.Errorf("defined gases must not exceed 100%% (1.0): %w", ErrCompositionExceeds100), 11},
+		{fmt.Errorf("invalid destination cylinder: %w", fmt.Errorf("%w %q", ErrInvalidGasMix, "EAN")), 1},
+		{ErrMixOutOfSpecification, 12},
+		{fmt.Errorf("%w of source cylinder", ErrInvalidPressure), 1},
+		{errors.New("source pressure must be higher than destination pressure"), 1},
+	}
+	for _, test := range tests {
+		if code := exitCode(test.err); code != test.expected {
+			t.Errorf("Expected exit status %d for %q, got %d", test.expected, test.err, code)
+		}
+	}
+}
+
+func TestExitCodeOfParsedMix(t *testing.T) {
+	tests := []struct {
+		mix      string
+		expected int
+	}{
+		{"foo", 1},
+		{"50/60", 11},
+	}
+	for _, test := range tests {
+		_, err := ParseGasComposition(test.mix)
+		if code := exitCode(err); err == nil || code != test.expected {
+			t.Errorf("Expected exit status %d for mix %q, got %d (%v)", test.expected, test.mix, code, err)
+		}
+	}
+}
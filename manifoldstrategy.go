@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// crackIncrements is the number of increments a transfer through a partially open isolator is simulated in
+const crackIncrements = 10
+
+// ManifoldStrategy opens the destination isolator partially, or opens and closes it in a sequence, during the fill
+// instead of keeping it fully open or closed. Each transfer between a source and a destination cylinder uses the next
+// opening, starting over after the last one. The isolator is opened fully after all transfers.
+type ManifoldStrategy struct {
+	// Name is the strategy as given, such as "crack:0.3" or "closed,open"
+	Name string
+	// Openings are how far the isolator is open during each transfer: 0 is closed and 1 fully open. With a partially
+	// open isolator the transfer is made in increments, and in each increment the destinations exchange this fraction
+	// of the gas they would exchange through a fully open isolator.
+	Openings []float64
+}
+
+// ParseManifoldStrategy parses the openings of the isolator in the order of transfers, separated by commas: open,
+// closed or crack:fraction, such as "crack:0.3" or "closed,open,closed"
+func ParseManifoldStrategy(s string) (ManifoldStrategy, error) {
+	strategy := ManifoldStrategy{Name: s}
+	for _, part := range strings.Split(s, ",") {
+		switch part = strings.ToLower(strings.TrimSpace(part)); {
+		case part == "open":
+			strategy.Openings = append(strategy.Openings, 1)
+		case part == "closed":
+			strategy.Openings = append(strategy.Openings, 0)
+		case strings.HasPrefix(part, "crack:"):
+			opening, err := strconv.ParseFloat(strings.TrimPrefix(part, "crack:"), 64)
+			if err != nil || opening < 0 || opening > 1 {
+				return ManifoldStrategy{}, fmt.Errorf("invalid isolator opening %q in manifold strategy %q; must be between 0 and 1", part, s)
+			}
+			strategy.Openings = append(strategy.Openings, opening)
+		default:
+			return ManifoldStrategy{}, fmt.Errorf("invalid manifold strategy %q; must be open, closed or crack:fraction separated by commas", s)
+		}
+	}
+	return strategy, nil
+}
+
+// Description returns the description of the strategy in the summary table
+func (s ManifoldStrategy) Description() string {
+	return "isolator " + s.Name
+}
+
+// opening returns how far the isolator is open during the transfer with the given index, counting from 0
+func (s ManifoldStrategy) opening(transfer int) float64 {
+	return s.Openings[transfer%len(s.Openings)]
+}
+
+// transfer equalizes the source with the destination the whip is connected to, while the isolator connects it to the
+// other destinations
+func (s ManifoldStrategy) transfer(transferI int, source *Cylinder, destinations CylinderList, destinationI int, gasSystem GasSystem, temperature Temperature, logger *slog.Logger) {
+	opening := s.opening(transferI)
+	switch {
+	case opening <= 0:
+		destinations[destinationI].Equalize(source, gasSystem, temperature, logger)
+	case opening >= 1:
+		cylinders := []*Cylinder{source}
+		for i := range destinations {
+			cylinders = append(cylinders, &destinations[i])
+		}
+		Equalize(cylinders, gasSystem, temperature, logger)
+	default:
+		for range crackIncrements {
+			for i := range destinations {
+				if i != destinationI {
+					destinations[destinationI].partialEqualize(&destinations[i], opening, gasSystem, temperature)
+				}
+			}
+			destinations[destinationI].Equalize(source, gasSystem, temperature, logger)
+		}
+	}
+}
+
+// partialEqualize moves the given fraction of the gas that equalizing the cylinders would move between them
+func (c1 *Cylinder) partialEqualize(c2 *Cylinder, fraction float64, gasSystem GasSystem, temperature Temperature) {
+	equalized1, equalized2 := *c1, *c2
+	equalized1.Equalize(&equalized2, gasSystem, temperature, nil)
+	moved := equalized1.GasVolume(gasSystem, temperature) - c1.GasVolume(gasSystem, temperature)
+	if moved > 0 {
+		c1.TransferGas(c2, moved*GasVolume(fraction), gasSystem, temperature)
+	} else if moved < 0 {
+		c2.TransferGas(c1, -moved*GasVolume(fraction), gasSystem, temperature)
+	}
+}
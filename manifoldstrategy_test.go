@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+func TestParseManifoldStrategy(t *testing.T) {
+	strategy, err := ParseManifoldStrategy("closed, crack:0.25,Open")
+	if err != nil || len(strategy.Openings) != 3 || strategy.Openings[1] != 0.25 || strategy.opening(5) != 1 {
+		t.Errorf("Unexpected strategy %+v: %v", strategy, err)
+	}
+	for _, invalid := range []string{"", "ajar", "crack:1.5", "crack:x"} {
+		if _, err := ParseManifoldStrategy(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestManifoldStrategies(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "low", CylinderVolume: 50, Pressure: 150, GasComposition: air}, {Description: "high", CylinderVolume: 50, Pressure: 232, GasComposition: air}},
+		DestinationCylinders: CylinderList{{Description: "left", CylinderVolume: 12, Pressure: 50, GasComposition: air}, {Description: "right", CylinderVolume: 12, Pressure: 100, GasComposition: air}},
+	}
+	for _, name := range []string{"closed", "crack:0.05", "crack:0.3", "open"} {
+		strategy, _ := ParseManifoldStrategy(name)
+		cylinderConfiguration.ManifoldStrategies = append(cylinderConfiguration.ManifoldStrategies, strategy)
+	}
+	cylinderSummaries := equalizeAllConfigurations(io.Discard, cylinderConfiguration, IdealGas, 293.15, Metric, false, nil)
+	if len(cylinderSummaries) != 8 || cylinderSummaries[4].Description != "isolator closed" {
+		t.Fatalf("Expected the strategies after the configurations, got %+v", cylinderSummaries)
+	}
+	// A closed isolator is the same as both manifolds closed, and an open one the same as the source manifold closed
+	if !compareFloats(float64(cylinderSummaries[4].DestinationCylinderPressure), float64(cylinderSummaries[0].DestinationCylinderPressure)) {
+		t.Errorf("Expected %f with the isolator closed, got %f", cylinderSummaries[0].DestinationCylinderPressure, cylinderSummaries[4].DestinationCylinderPressure)
+	}
+	if !compareFloats(float64(cylinderSummaries[7].DestinationCylinderPressure), float64(cylinderSummaries[2].DestinationCylinderPressure)) {
+		t.Errorf("Expected %f with the isolator open, got %f", cylinderSummaries[2].DestinationCylinderPressure, cylinderSummaries[7].DestinationCylinderPressure)
+	}
+	// The more the isolator is cracked, the closer the fill gets to an open isolator
+	for i := 5; i < 8; i++ {
+		if cylinderSummaries[i].DestinationCylinderPressure > cylinderSummaries[i-1].DestinationCylinderPressure {
+			t.Errorf("%s reached %f, above %s", cylinderSummaries[i].Description, cylinderSummaries[i].DestinationCylinderPressure, cylinderSummaries[i-1].Description)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+// finnishMessages translates the equalizing report and dive gas information to Finnish
+var finnishMessages = map[string]string{
+	// Equalizing
+	"Before any transfers:":                                                   "Ennen siirtoja:",
+	"Source cylinders:":                                                       "Lähdepullot:",
+	"Destination cylinders:":                                                  "Kohdepullot:",
+	"of gas, pressure":                                                        "kaasua, paine",
+	"Equalizing with %s\n":                                                    "Tasaus, %s\n",
+	"both manifolds closed":                                                   "molemmat jakotukit suljettu",
+	"destination manifold closed":                                             "kohteen jakotukki suljettu",
+	"source manifold closed":                                                  "lähteen jakotukki suljettu",
+	"all manifolds open":                                                      "kaikki jakotukit auki",
+	"independent destinations":                                                "erilliset kohdepullot",
+	"source manifold closed, independent destinations":                        "lähteen jakotukki suljettu, erilliset kohdepullot",
+	"Independent destinations:":                                               "Erilliset kohdepullot:",
+	"Recommended fill order of the independent destinations:":                 "Erillisten kohdepullojen suositeltu täyttöjärjestys:",
+	"Step %d: %.1f minutes to 90%% equalized, %.1f minutes to 99%%\n":         "Vaihe %d: %.1f minuuttia 90 %%:n tasaukseen, %.1f minuuttia 99 %%:n\n",
+	"Step %d: %s hot %.*f%s at %.0f°%s, settles to %.*f%s\n":                  "Vaihe %d: %s kuumana %.*f%s lämpötilassa %.0f°%s, tasaantuu %.*f%s\n",
+	"Step %d: from %s to %s; transferred %.*f%s of gas\n":                     "Vaihe %d: %s → %s; siirretty %.*f%s kaasua\n",
+	"Cycle %d: destination settles to %.*f%s, up %.*f%s with %.*f%s of gas\n": "Kierros %d: kohde tasaantuu %.*f%s, nousua %.*f%s ja %.*f%s kaasua\n",
+	"Fill (%s): destination up to %.*f%s at %.0f°%s while filling\n":          "Täyttö (%s): kohde enintään %.*f%s ja %.0f°%s täytön aikana\n",
+	"Transfers stop at %.*f%s, reached: %t; left in the sources: %s\n":        "Siirrot pysähtyvät paineeseen %.*f%s, saavutettu: %t; lähteissä jäljellä: %s\n",
+	"Transfers take %.1f minutes to 90%% equalized (%.1f minutes to 99%%)\n":  "Siirrot kestävät %.1f minuuttia 90 %%:n tasaukseen (%.1f minuuttia 99 %%:n)\n",
+	"Whip vented %.*f%s of gas over %d connections\n":                         "Letkusta vapautui %.*f%s kaasua %d kytkennässä\n",
+	"Booster: destination %.*f%s to %.*f%s, target reached: %t\n":             "Booster: kohde %.*f%s → %.*f%s, tavoite saavutettu: %t\n",
+	"Booster moved %.*f%s of gas using %.*f%s of drive gas\n":                 "Booster siirsi %.*f%s kaasua ja käytti %.*f%s käyttökaasua\n",
+	"Compressor needs %.0f minutes to finish to %.*f%s (%.*f%s of air)\n":     "Kompressori tarvitsee %.0f minuuttia täyttöön paineeseen %.*f%s (%.*f%s ilmaa)\n",
+	"Compressor maximum pressure %.*f%s is below the target\n":                "Kompressorin enimmäispaine %.*f%s on tavoitetta pienempi\n",
+	"Source cylinders: %.*f%s, %.*f%s\n":                                      "Lähdepullot: %.*f%s, %.*f%s\n",
+	"Destination cylinders: %.*f%s, %.*f%s\n":                                 "Kohdepullot: %.*f%s, %.*f%s\n",
+	"Destination mix:":                                                        "Kohteen seos:",
+	"Gas cost:":                                                               "Kaasun hinta:",
+
+	// Summary table
+	"src ":        "läh ",
+	"dst ":        "koh ",
+	"cost ":       "hinta ",
+	"impr ":       "par ",
+	"improvement": "parannus",
+	"                            Gas weight %6.0f%-2s        %6.0f%s\n": "                          Kaasun paino %6.0f%-2s        %6.0f%s\n",
+
+	// Dive gas
+	"Warning: %s has no oxygen and is not breathable\n":                                                      "Varoitus: %s ei sisällä happea eikä ole hengityskelpoinen\n",
+	"Warning: %s is hypoxic with under %.0f%% oxygen; breathable from %.1f%s, use a travel gas above that\n": "Varoitus: %s on hypoksinen, happea alle %.0f %%; hengityskelpoinen syvyydestä %.1f%s, käytä sen yläpuolella matkakaasua\n",
+	"Gas density at %.1f%s: %.2fg/l\n":                                                                       "Kaasun tiheys syvyydessä %.1f%s: %.2fg/l\n",
+	"Warning: gas density is above the maximum of %.1fg/l\n":                                                 "Varoitus: kaasun tiheys ylittää enimmäisarvon %.1fg/l\n",
+	"Warning: gas density is above the recommended %.1fg/l\n":                                                "Varoitus: kaasun tiheys ylittää suositusarvon %.1fg/l\n",
+	"oxygen not narcotic":           "happi ei narkoottinen",
+	"oxygen narcotic":               "happi narkoottinen",
+	"END at %.1f%s: %.1f%s (%s)\n":  "END syvyydessä %.1f%s: %.1f%s (%s)\n",
+	"EAD at %.1f%s: %.1f%s\n":       "EAD syvyydessä %.1f%s: %.1f%s\n",
+	"above pO2 %.2g at the surface": "pintapaineessa yli pO2 %.2g",
+	"%.1f%s at pO2 %.2g":            "%.1f%s (pO2 %.2g)",
+	"MOD of %s: %s\n":               "Seoksen %s MOD: %s\n",
+
+	// Banks
+	"Warning: bank %s contains %s with over %.0f%% oxygen but is not oxygen clean\n": "Varoitus: pankissa %s on %s, jossa on yli %.0f %% happea, mutta se ei ole happipuhdas\n",
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Clone returns a copy of the gas composition
+func (gc GasComposition) Clone() GasComposition {
+	if gc == nil {
+		return nil
+	}
+	clone := make(GasComposition, len(gc))
+	for gasType, fraction := range gc {
+		clone[gasType] = fraction
+	}
+	return clone
+}
+
+// Equal returns true if both compositions have the same fractions. Missing gases are treated as zero.
+func (gc GasComposition) Equal(other GasComposition) bool {
+	for gasType, fraction := range gc {
+		if math.Abs(fraction-other[gasType]) > 1e-9 {
+			return false
+		}
+	}
+	for gasType, fraction := range other {
+		if math.Abs(fraction-gc[gasType]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+// TraceGasFraction is the fraction below which gases are printed in ppm
+const TraceGasFraction = 0.0005
+
+// String returns the composition in common diving notation: air, EAN32 or 21/35 (oxygen/helium). Other gases are
+// appended as percentages, or in ppm for trace amounts.
+func (gc GasComposition) String() string {
+	oxygen := gc[Oxygen]
+	helium := gc[Helium]
+	var others, traces []string
+	for gasType, fraction := range gc {
+		if gasType == Oxygen || gasType == Helium || gasType == Nitrogen {
+			continue
+		}
+		if fraction > TraceGasFraction {
+			others = append(others, fmt.Sprintf("%s %.1f%%", SpeciesLookup[gasType].Symbol, fraction*100))
+		} else if fraction >= 0.05e-6 {
+			traces = append(traces, fmt.Sprintf("%s %sppm", SpeciesLookup[gasType].Symbol, strconv.FormatFloat(math.Round(fraction*1e7)/10, 'f', -1, 64)))
+		}
+	}
+	sort.Strings(others)
+	sort.Strings(traces)
+	var name string
+	if len(others) > 0 {
+		name = fmt.Sprintf("%.1f/%.1f + %s", oxygen*100, helium*100, strings.Join(others, ", "))
+	} else if helium > 0.0005 {
+		name = fmt.Sprintf("%.1f/%.1f", oxygen*100, helium*100)
+	} else if math.Abs(oxygen-0.21) < 0.0005 {
+		name = "air"
+	} else {
+		name = fmt.Sprintf("EAN%.1f", oxygen*100)
+	}
+	if len(traces) > 0 {
+		name += " + " + strings.Join(traces, ", ")
+	}
+	return name
+}
+
+// GasCompositionFromGasVolumes returns gas composition for the given amounts of each gas
+func GasCompositionFromGasVolumes(gasVolumes map[Gas]GasVolume) GasComposition {
+	var totalGasVolume GasVolume
+	for _, gasVolume := range gasVolumes {
+		totalGasVolume += gasVolume
+	}
+	gasComposition := make(GasComposition, len(gasVolumes))
+	if totalGasVolume == 0 {
+		return gasComposition
+	}
+	for gasType, gasVolume := range gasVolumes {
+		gasComposition[gasType] = float64(gasVolume / totalGasVolume)
+	}
+	return gasComposition
+}
+
+// Errors returned for invalid mixes; parse errors wrap them with the invalid input
+var (
+	ErrInvalidGasMix         = errors.New("invalid gas mix")
+	ErrUnknownGas            = errors.New("unknown gas")
+	ErrCompositionExceeds100 = errors.New("composition exceeds 100%")
+)
+
+// ParseGasComposition parses a mix such as "air", "oxygen", "helium", "EAN32", "32" or "18/45" (oxygen/helium percentages).
+// Nitrogen is used for the balance. Other gases can be added with "+", e.g. "air+10ppmCO" or "32+0.5%Ar"; they
+// dilute the rest of the mix.
+func ParseGasComposition(s string) (GasComposition, error) {
+	parts := strings.Split(s, "+")
+	gasComposition, err := parseBaseGasComposition(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	additions := make(GasComposition)
+	var additionSum float64
+	for _, part := range parts[1:] {
+		part = strings.ToLower(strings.TrimSpace(part))
+		unitStart := strings.IndexFunc(part, func(r rune) bool { return unicode.IsLetter(r) || r == '%' })
+		if unitStart == -1 {
+			return nil, fmt.Errorf("%w %q: invalid gas %q; use ppm or %% such as 10ppmCO", ErrInvalidGasMix, s, part)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(part[:unitStart]), 64)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("%w %q: invalid gas %q", ErrInvalidGasMix, s, part)
+		}
+		var fraction float64
+		var name string
+		switch unit := part[unitStart:]; {
+		case strings.HasPrefix(unit, "ppm"):
+			fraction, name = value/1e6, unit[len("ppm"):]
+		case strings.HasPrefix(unit, "%"):
+			fraction, name = value/100, unit[len("%"):]
+		default:
+			return nil, fmt.Errorf("%w %q: invalid gas %q; use ppm or %% such as 10ppmCO", ErrInvalidGasMix, s, part)
+		}
+		gasType, ok := LookupSpecies(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("%w %q in %q", ErrUnknownGas, name, s)
+		}
+		additions[gasType] += fraction
+		additionSum += fraction
+	}
+	if additionSum > 1.0+1e-9 {
+		return nil, fmt.Errorf("%w %q: added gas %w", ErrInvalidGasMix, s, ErrCompositionExceeds100)
+	}
+	for gasType := range gasComposition {
+		gasComposition[gasType] *= 1 - additionSum
+	}
+	for gasType, fraction := range additions {
+		gasComposition[gasType] += fraction
+	}
+	return gasComposition, nil
+}
+
+func parseBaseGasComposition(s string) (GasComposition, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	switch name {
+	case "air":
+		return GasComposition{Oxygen: 0.21, Nitrogen: 0.79}, nil
+	case "oxygen", "o2":
+		return GasComposition{Oxygen: 1}, nil
+	case "helium", "he":
+		return GasComposition{Helium: 1}, nil
+	}
+	for _, prefix := range []string{"ean", "nx", "tx"} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	parts := strings.Split(name, "/")
+	if len(parts) > 2 {
+		return nil, fmt.Errorf("%w %q", ErrInvalidGasMix, s)
+	}
+	var fractions [2]float64
+	for i, part := range parts {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("%w %q", ErrInvalidGasMix, s)
+		}
+		fractions[i] = percent / 100
+	}
+	if fractions[0]+fractions[1] > 1.0+1e-9 {
+		return nil, fmt.Errorf("%w %q: oxygen and helium %w", ErrInvalidGasMix, s, ErrCompositionExceeds100)
+	}
+	return GasComposition{
+		Oxygen:   fractions[0],
+		Helium:   fractions[1],
+		Nitrogen: math.Max(0, 1-fractions[0]-fractions[1]),
+	}, nil
+}
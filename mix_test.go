@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseGasComposition(t *testing.T) {
+	gasComposition, err := ParseGasComposition("18/45")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gasComposition.Equal(GasComposition{Oxygen: 0.18, Helium: 0.45, Nitrogen: 0.37}) {
+		t.Errorf("Invalid gas composition %v", gasComposition)
+	}
+	gasComposition, err = ParseGasComposition("EAN32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gasComposition.String() != "EAN32.0" {
+		t.Errorf("Invalid gas composition %s", gasComposition)
+	}
+	if _, err := ParseGasComposition("60/60"); !errors.Is(err, ErrCompositionExceeds100) || !errors.Is(err, ErrInvalidGasMix) {
+		t.Errorf("Expected ErrCompositionExceeds100 for mix exceeding 100%%, got %v", err)
+	}
+	if _, err := ParseGasComposition("air+10ppmXe"); !errors.Is(err, ErrUnknownGas) {
+		t.Errorf("Expected ErrUnknownGas, got %v", err)
+	}
+}
+
+func TestEqualizeMixesGases(t *testing.T) {
+	nitrox := Cylinder{CylinderVolume: 10, Pressure: 100, GasComposition: GasComposition{Oxygen: 0.5, Nitrogen: 0.5}}
+	air := Cylinder{CylinderVolume: 10, Pressure: 100, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}
+	nitrox.Equalize(&air, IdealGas, 293.15, nil)
+	if !compareFloats(nitrox.GasComposition[Oxygen], 0.355) || !compareFloats(air.GasComposition[Oxygen], 0.355) {
+		t.Errorf("Invalid mixed oxygen fraction %f", nitrox.GasComposition[Oxygen])
+	}
+	if !compareFloats(float64(air.Pressure), 100) {
+		t.Errorf("Invalid pressure %f", air.Pressure)
+	}
+}
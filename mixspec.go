@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// ErrMixOutOfSpecification is returned by commands when the resulting mix is not within -expected-mix
+var ErrMixOutOfSpecification = errors.New("mix is out of spec")
+
+// MixSpecification is the mix a fill should end up with, and how far the result may be from it
+type MixSpecification struct {
+	Target GasComposition
+	// OxygenTolerance and HeliumTolerance are the largest accepted differences in the oxygen and helium fractions
+	OxygenTolerance float64
+	HeliumTolerance float64
+}
+
+// Deviations returns how much the oxygen and helium fractions of the mix differ from the target
+func (s MixSpecification) Deviations(gasComposition GasComposition) (oxygen float64, helium float64) {
+	return gasComposition[Oxygen] - s.Target[Oxygen], gasComposition[Helium] - s.Target[Helium]
+}
+
+// Within tells whether the oxygen and helium fractions of the mix are within the tolerances of the target
+func (s MixSpecification) Within(gasComposition GasComposition) bool {
+	oxygen, helium := s.Deviations(gasComposition)
+	// Tolerances are whole percents; allow for rounding of the fractions
+	return math.Abs(oxygen) <= s.OxygenTolerance+1e-9 && math.Abs(helium) <= s.HeliumTolerance+1e-9
+}
+
+// printMixSpecification reports whether the mix is within the specification and returns true when it is
+func printMixSpecification(w io.Writer, specification MixSpecification, gasComposition GasComposition) bool {
+	tolerances := fmt.Sprintf("O2 ±%.1f%%, He ±%.1f%%", specification.OxygenTolerance*100, specification.HeliumTolerance*100)
+	if specification.Within(gasComposition) {
+		fmt.Fprintf(w, "Mix %s is within %s (%s)\n", gasComposition, specification.Target, tolerances)
+		return true
+	}
+	oxygen, helium := specification.Deviations(gasComposition)
+	var deviations []string
+	if math.Abs(oxygen) > specification.OxygenTolerance+1e-9 {
+		deviations = append(deviations, fmt.Sprintf("O2 %+.1f%%", oxygen*100))
+	}
+	if math.Abs(helium) > specification.HeliumTolerance+1e-9 {
+		deviations = append(deviations, fmt.Sprintf("He %+.1f%%", helium*100))
+	}
+	fmt.Fprintf(w, "Mix %s is out of spec for %s (%s): %s\n", gasComposition, specification.Target, tolerances, strings.Join(deviations, ", "))
+	return false
+}
+
+// mixSpecificationFlags holds flags for checking the resulting mix against a target
+type mixSpecificationFlags struct {
+	expectedMix     *string
+	oxygenTolerance *float64
+	heliumTolerance *float64
+}
+
+func registerMixSpecificationFlags(fs *flag.FlagSet) *mixSpecificationFlags {
+	return &mixSpecificationFlags{
+		expectedMix:     fs.String("expected-mix", "", "Check the resulting mix against this mix, e.g. 32 or 18/45, and exit with status 12 when it is out of spec"),
+		oxygenTolerance: fs.Float64("o2-tolerance", 1, "Accepted oxygen difference from -expected-mix in percentage points"),
+		heliumTolerance: fs.Float64("he-tolerance", 2, "Accepted helium difference from -expected-mix in percentage points"),
+	}
+}
+
+// specification returns the mix specification, or nil when no mix is expected
+func (f *mixSpecificationFlags) specification() (*MixSpecification, error) {
+	if *f.expectedMix == "" {
+		return nil, nil
+	}
+	target, err := ParseGasComposition(*f.expectedMix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expected mix: %w", err)
+	}
+	if *f.oxygenTolerance < 0 || *f.heliumTolerance < 0 {
+		return nil, errors.New("invalid mix tolerance; must be >=0")
+	}
+	return &MixSpecification{Target: target, OxygenTolerance: *f.oxygenTolerance / 100, HeliumTolerance: *f.heliumTolerance / 100}, nil
+}
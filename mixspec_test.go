@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMixSpecificationWithin(t *testing.T) {
+	specification := MixSpecification{Target: GasComposition{Oxygen: 0.18, Helium: 0.45, Nitrogen: 0.37}, OxygenTolerance: 0.01, HeliumTolerance: 0.02}
+	for _, test := range []struct {
+		gasComposition GasComposition
+		expected       bool
+	}{
+		{GasComposition{Oxygen: 0.18, Helium: 0.45, Nitrogen: 0.37}, true},
+		{GasComposition{Oxygen: 0.19, Helium: 0.43, Nitrogen: 0.38}, true},
+		{GasComposition{Oxygen: 0.165, Helium: 0.45, Nitrogen: 0.385}, false},
+		{GasComposition{Oxygen: 0.18, Helium: 0.475, Nitrogen: 0.345}, false},
+		{GasComposition{Oxygen: 0.18, Nitrogen: 0.82}, false},
+	} {
+		if within := specification.Within(test.gasComposition); within != test.expected {
+			t.Errorf("Invalid result for %s: %v, expected %v", test.gasComposition, within, test.expected)
+		}
+	}
+}
+
+func TestPrintMixSpecification(t *testing.T) {
+	specification := MixSpecification{Target: GasComposition{Oxygen: 0.32, Nitrogen: 0.68}, OxygenTolerance: 0.01, HeliumTolerance: 0.02}
+	var output strings.Builder
+	if !printMixSpecification(&output, specification, GasComposition{Oxygen: 0.315, Nitrogen: 0.685}) {
+		t.Errorf("Expected EAN31.5 to be within spec")
+	}
+	if expected := "Mix EAN31.5 is within EAN32.0 (O2 ±1.0%, He ±2.0%)\n"; output.String() != expected {
+		t.Errorf("Invalid output %q, expected %q", output.String(), expected)
+	}
+	output.Reset()
+	if printMixSpecification(&output, specification, GasComposition{Oxygen: 0.30, Nitrogen: 0.70}) {
+		t.Errorf("Expected EAN30 to be out of spec")
+	}
+	if !strings.HasSuffix(output.String(), "out of spec for EAN32.0 (O2 ±1.0%, He ±2.0%): O2 -2.0%\n") {
+		t.Errorf("Invalid output %q", output.String())
+	}
+}
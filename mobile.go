@@ -0,0 +1,316 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The Mobile types are an API restricted to what gomobile can bind: basic types, pointers to structs and methods.
+// Mixes are structs of fractions instead of GasComposition maps, and lists are read by index. Pressures are gauge
+// pressures in bar at the given altitude, volumes in liters and temperatures in celsius.
+
+// MobileMix is a gas mix as fractions of each built-in gas
+type MobileMix struct {
+	Oxygen         float64
+	Helium         float64
+	Nitrogen       float64
+	Argon          float64
+	Neon           float64
+	Hydrogen       float64
+	CarbonDioxide  float64
+	CarbonMonoxide float64
+}
+
+// NewMobileMix parses a mix in the same format as the command line, e.g. "air", "32" or "18/45"
+func NewMobileMix(mix string) (*MobileMix, error) {
+	gasComposition, err := ParseGasComposition(mix)
+	if err != nil {
+		return nil, err
+	}
+	return newMobileMix(gasComposition)
+}
+
+func newMobileMix(gasComposition GasComposition) (*MobileMix, error) {
+	mix := &MobileMix{}
+	for gasType, fraction := range gasComposition {
+		switch gasType {
+		case Oxygen:
+			mix.Oxygen = fraction
+		case Helium:
+			mix.Helium = fraction
+		case Nitrogen:
+			mix.Nitrogen = fraction
+		case Argon:
+			mix.Argon = fraction
+		case Neon:
+			mix.Neon = fraction
+		case Hydrogen:
+			mix.Hydrogen = fraction
+		case CarbonDioxide:
+			mix.CarbonDioxide = fraction
+		case CarbonMonoxide:
+			mix.CarbonMonoxide = fraction
+		default:
+			if fraction > 0 {
+				return nil, fmt.Errorf("custom gas %s is not supported in mobile mixes", SpeciesLookup[gasType].Symbol)
+			}
+		}
+	}
+	return mix, nil
+}
+
+// gasComposition returns the mix as a gas composition; a nil mix is air
+func (m *MobileMix) gasComposition() GasComposition {
+	if m == nil {
+		return GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	}
+	gasComposition := make(GasComposition)
+	for gasType, fraction := range map[Gas]float64{
+		Oxygen: m.Oxygen, Helium: m.Helium, Nitrogen: m.Nitrogen, Argon: m.Argon, Neon: m.Neon,
+		Hydrogen: m.Hydrogen, CarbonDioxide: m.CarbonDioxide, CarbonMonoxide: m.CarbonMonoxide,
+	} {
+		if fraction != 0 {
+			gasComposition[gasType] = fraction
+		}
+	}
+	return gasComposition
+}
+
+// String returns the mix in common diving notation
+func (m *MobileMix) String() string {
+	return m.gasComposition().String()
+}
+
+// MobileCalculator holds calculator settings and the cylinders of an equalization
+type MobileCalculator struct {
+	server               server
+	sourceCylinders      CylinderList
+	destinationCylinders CylinderList
+}
+
+// NewMobileCalculator returns a calculator using the named gas system (as -gas-system), gas temperature in celsius
+// and fill station altitude in meters
+func NewMobileCalculator(gasSystem string, temperature float64, altitude float64) (*MobileCalculator, error) {
+	system, err := ParseGasSystem(gasSystem)
+	if err != nil {
+		return nil, err
+	}
+	if temperature < -30 || temperature > 80 {
+		return nil, fmt.Errorf("invalid temperature %.1f°C; must be >-30°C and <80°C", temperature)
+	}
+	if altitude < -500 || altitude > 9000 {
+		return nil, fmt.Errorf("invalid altitude %.0fm; must be between -500m and 9000m", altitude)
+	}
+	units := UnitSystem{AmbientPressure: AmbientPressureAtAltitude(altitude)}
+	return &MobileCalculator{server: server{gasSystem: system, temperature: Temperature(ZeroCelsius + temperature), units: units}}, nil
+}
+
+func (c *MobileCalculator) cylinder(description string, volume float64, pressure float64, mix *MobileMix) Cylinder {
+	return Cylinder{
+		Description:    description,
+		CylinderVolume: CylinderVolume(volume),
+		Pressure:       c.server.units.AbsolutePressure(PressureBar(pressure)),
+		GasComposition: mix.gasComposition(),
+	}
+}
+
+// AddSource adds a source cylinder; a nil mix is air
+func (c *MobileCalculator) AddSource(description string, volume float64, pressure float64, mix *MobileMix) {
+	if description == "" {
+		description = fmt.Sprintf("source %d", len(c.sourceCylinders)+1)
+	}
+	c.sourceCylinders = append(c.sourceCylinders, c.cylinder(description, volume, pressure, mix))
+}
+
+// AddDestination adds a destination cylinder; a nil mix is air
+func (c *MobileCalculator) AddDestination(description string, volume float64, pressure float64, mix *MobileMix) {
+	if description == "" {
+		description = fmt.Sprintf("destination %d", len(c.destinationCylinders)+1)
+	}
+	c.destinationCylinders = append(c.destinationCylinders, c.cylinder(description, volume, pressure, mix))
+}
+
+// ClearCylinders removes all source and destination cylinders
+func (c *MobileCalculator) ClearCylinders() {
+	c.sourceCylinders = nil
+	c.destinationCylinders = nil
+}
+
+// MobileTransfer is the source and the destination cylinder of a transfer at its start or end
+type MobileTransfer struct {
+	Configuration       string
+	Step                int
+	Source              string
+	SourcePressure      float64
+	SourceMix           *MobileMix
+	Destination         string
+	DestinationPressure float64
+	DestinationMix      *MobileMix
+}
+
+// MobileTransferObserver is notified of each transfer of Equalize, e.g. to animate the cylinders
+type MobileTransferObserver interface {
+	OnTransferStart(before *MobileTransfer)
+	OnTransferComplete(before *MobileTransfer, after *MobileTransfer)
+}
+
+// mobileObserver notifies a MobileTransferObserver of transfers
+type mobileObserver struct {
+	observer MobileTransferObserver
+	units    UnitSystem
+}
+
+func (o mobileObserver) transfer(state TransferState) *MobileTransfer {
+	// Mixes with custom gases have no mobile mix and are left nil
+	sourceMix, _ := newMobileMix(state.Source.GasComposition)
+	destinationMix, _ := newMobileMix(state.Destination.GasComposition)
+	return &MobileTransfer{
+		Configuration:       state.Configuration,
+		Step:                state.Step,
+		Source:              state.Source.Description,
+		SourcePressure:      o.units.Pressure(state.Source.Pressure),
+		SourceMix:           sourceMix,
+		Destination:         state.Destination.Description,
+		DestinationPressure: o.units.Pressure(state.Destination.Pressure),
+		DestinationMix:      destinationMix,
+	}
+}
+
+func (o mobileObserver) OnTransferStart(before TransferState) {
+	o.observer.OnTransferStart(o.transfer(before))
+}
+
+func (o mobileObserver) OnTransferComplete(before TransferState, after TransferState) {
+	o.observer.OnTransferComplete(o.transfer(before), o.transfer(after))
+}
+
+// SetObserver sets the observer notified of each transfer of Equalize; nil removes it
+func (c *MobileCalculator) SetObserver(observer MobileTransferObserver) {
+	c.server.observer = nil
+	if observer != nil {
+		c.server.observer = mobileObserver{observer: observer, units: c.server.units}
+	}
+}
+
+// MobileSummary is the result of a single manifold configuration
+type MobileSummary struct {
+	Description          string
+	SourcePressure       float64
+	SourceGasVolume      float64
+	SourceMix            *MobileMix
+	DestinationPressure  float64
+	DestinationGasVolume float64
+	DestinationMix       *MobileMix
+	ImprovementPercent   float64
+}
+
+// MobileEqualizeResult holds a summary for each manifold configuration
+type MobileEqualizeResult struct {
+	summaries []*MobileSummary
+}
+
+// Count returns the number of summaries
+func (r *MobileEqualizeResult) Count() int {
+	return len(r.summaries)
+}
+
+// Summary returns the summary at index i, or nil when out of range
+func (r *MobileEqualizeResult) Summary(i int) *MobileSummary {
+	if i < 0 || i >= len(r.summaries) {
+		return nil
+	}
+	return r.summaries[i]
+}
+
+// Equalize equalizes the added cylinders with each manifold configuration
+func (c *MobileCalculator) Equalize() (*MobileEqualizeResult, error) {
+	sourceCylinders := append(CylinderList(nil), c.sourceCylinders...)
+	destinationCylinders := append(CylinderList(nil), c.destinationCylinders...)
+	cylinderSummaries, err := c.server.equalizeCylinders(sourceCylinders, destinationCylinders, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	worstDestinationPressure := worstDestinationPressure(cylinderSummaries)
+	result := &MobileEqualizeResult{}
+	for _, cylinderSummary := range cylinderSummaries {
+		sourceMix, err := newMobileMix(cylinderSummary.SourceGasComposition)
+		if err != nil {
+			return nil, err
+		}
+		destinationMix, err := newMobileMix(cylinderSummary.DestinationGasComposition)
+		if err != nil {
+			return nil, err
+		}
+		result.summaries = append(result.summaries, &MobileSummary{
+			Description:          cylinderSummary.Description,
+			SourcePressure:       c.server.units.Pressure(cylinderSummary.SourceCylinderPressure),
+			SourceGasVolume:      float64(cylinderSummary.SourceCylinderGasVolume),
+			SourceMix:            sourceMix,
+			DestinationPressure:  c.server.units.Pressure(cylinderSummary.DestinationCylinderPressure),
+			DestinationGasVolume: float64(cylinderSummary.DestinationCylinderGasVolume),
+			DestinationMix:       destinationMix,
+			ImprovementPercent:   float64(100 * (cylinderSummary.DestinationCylinderPressure - worstDestinationPressure) / worstDestinationPressure),
+		})
+	}
+	return result, nil
+}
+
+// MobileBlendStep is a single gas addition of a blend plan
+type MobileBlendStep struct {
+	Description    string
+	Mix            *MobileMix
+	AddedPressure  float64
+	FillToPressure float64
+	AddedGasVolume float64
+}
+
+// MobileBlendPlan is a partial pressure blend plan
+type MobileBlendPlan struct {
+	DrainRequired   bool
+	DrainToPressure float64
+	steps           []*MobileBlendStep
+}
+
+// Count returns the number of steps
+func (p *MobileBlendPlan) Count() int {
+	return len(p.steps)
+}
+
+// Step returns the step at index i, or nil when out of range
+func (p *MobileBlendPlan) Step(i int) *MobileBlendStep {
+	if i < 0 || i >= len(p.steps) {
+		return nil
+	}
+	return p.steps[i]
+}
+
+// Blend plans a partial pressure blend to target in a cylinder holding startPressure of startMix, topping up with
+// topUp. Nil start and top-up mixes are air.
+func (c *MobileCalculator) Blend(target *MobileMix, targetPressure float64, cylinderVolume float64, startPressure float64, startMix *MobileMix, topUp *MobileMix) (*MobileBlendPlan, error) {
+	if target == nil {
+		return nil, errors.New("target mix is required")
+	}
+	start := c.cylinder("", cylinderVolume, startPressure, startMix)
+	plan, err := c.server.planBlend(start, target.gasComposition(), c.server.units.AbsolutePressure(PressureBar(targetPressure)), topUp.gasComposition())
+	if err != nil {
+		return nil, err
+	}
+	result := &MobileBlendPlan{DrainRequired: plan.DrainRequired}
+	if plan.DrainRequired {
+		result.DrainToPressure = c.server.units.Pressure(plan.DrainToPressure)
+	}
+	for _, step := range plan.Steps {
+		mix, err := newMobileMix(step.GasComposition)
+		if err != nil {
+			return nil, err
+		}
+		result.steps = append(result.steps, &MobileBlendStep{
+			Description:    step.Description,
+			Mix:            mix,
+			AddedPressure:  c.server.units.PressureDifference(step.AddedPressure),
+			FillToPressure: c.server.units.Pressure(step.FillToPressure),
+			AddedGasVolume: float64(step.AddedGasVolume),
+		})
+	}
+	return result, nil
+}
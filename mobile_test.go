@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestMobileMix(t *testing.T) {
+	mix, err := NewMobileMix("18/45")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !compareFloats(mix.Oxygen, 0.18) || !compareFloats(mix.Helium, 0.45) || !compareFloats(mix.Nitrogen, 0.37) {
+		t.Errorf("Invalid mix %+v", mix)
+	}
+	if mix.String() != "18.0/45.0" {
+		t.Errorf("Expected 18.0/45.0, got %s", mix.String())
+	}
+	var air *MobileMix
+	if air.String() != "air" {
+		t.Errorf("Expected nil mix to be air, got %s", air.String())
+	}
+}
+
+func TestMobileCalculatorEqualize(t *testing.T) {
+	calculator, err := NewMobileCalculator("ideal", 20, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calculator.AddSource("", 12, 232, nil)
+	calculator.AddDestination("left", 6, 80, nil)
+	calculator.AddDestination("right", 6, 80, nil)
+	result, err := calculator.Equalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Count() != 2 || result.Summary(2) != nil {
+		t.Fatalf("Expected 2 summaries, got %d", result.Count())
+	}
+	summary := result.Summary(1)
+	if summary.Description != "all manifolds open" || !compareFloats(summary.DestinationPressure, 156) || summary.DestinationMix.String() != "air" {
+		t.Errorf("Invalid summary %+v", summary)
+	}
+	calculator.ClearCylinders()
+	if _, err := calculator.Equalize(); err == nil {
+		t.Error("Expected an error without cylinders")
+	}
+}
+
+func TestMobileCalculatorBlend(t *testing.T) {
+	calculator, err := NewMobileCalculator("ideal", 20, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, _ := NewMobileMix("32")
+	plan, err := calculator.Blend(target, 200, 12, 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Count() != 2 || plan.DrainRequired || !compareFloats(plan.Step(1).FillToPressure, 200) {
+		t.Errorf("Invalid plan %+v", plan)
+	}
+}
+
+// recordingMobileObserver records the destination pressures of the transfers it is notified of
+type recordingMobileObserver struct {
+	pressures []float64
+}
+
+func (o *recordingMobileObserver) OnTransferStart(before *MobileTransfer) {
+	o.pressures = append(o.pressures, before.DestinationPressure)
+}
+
+func (o *recordingMobileObserver) OnTransferComplete(before *MobileTransfer, after *MobileTransfer) {
+	o.pressures = append(o.pressures, after.DestinationPressure)
+}
+
+func TestMobileCalculatorObserver(t *testing.T) {
+	calculator, err := NewMobileCalculator("ideal", 20, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calculator.AddSource("", 12, 232, nil)
+	calculator.AddDestination("", 12, 50, nil)
+	observer := &recordingMobileObserver{}
+	calculator.SetObserver(observer)
+	if _, err := calculator.Equalize(); err != nil {
+		t.Fatal(err)
+	}
+	if len(observer.pressures) != 2 || !compareFloats(observer.pressures[0], 50) || !compareFloats(observer.pressures[1], 141) {
+		t.Errorf("Expected a transfer from 50 to 141bar, got %v", observer.pressures)
+	}
+	calculator.SetObserver(nil)
+	if _, err := calculator.Equalize(); err != nil || len(observer.pressures) != 2 {
+		t.Errorf("Expected no notifications without an observer, got %v (%v)", observer.pressures, err)
+	}
+}
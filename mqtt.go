@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// MQTT 3.1.1 control packet types
+const (
+	mqttConnect     = 1
+	mqttConnAck     = 2
+	mqttPublish     = 3
+	mqttSubscribe   = 8
+	mqttSubAck      = 9
+	mqttPingRequest = 12
+	mqttPingReply   = 13
+)
+
+// mqttClient is a minimal MQTT 3.1.1 client: it publishes and subscribes with QoS 0
+type mqttClient struct {
+	conn         net.Conn
+	reader       *bufio.Reader
+	writeLock    sync.Mutex
+	nextPacketID uint16
+}
+
+// appendMQTTString appends a length prefixed UTF-8 string
+func appendMQTTString(packet []byte, s string) []byte {
+	packet = binary.BigEndian.AppendUint16(packet, uint16(len(s)))
+	return append(packet, s...)
+}
+
+// writePacket writes a control packet with the given type and flags
+func (c *mqttClient) writePacket(packetType byte, flags byte, body []byte) error {
+	packet := []byte{packetType<<4 | flags}
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if length == 0 {
+			break
+		}
+	}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	_, err := c.conn.Write(append(packet, body...))
+	return err
+}
+
+// readPacket reads a control packet, returning its type, flags and body
+func (c *mqttClient) readPacket() (byte, byte, []byte, error) {
+	header, err := c.reader.ReadByte()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	var length, multiplier int = 0, 1
+	for i := 0; ; i++ {
+		digit, err := c.reader.ReadByte()
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		multiplier *= 128
+		if digit&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, 0, nil, errors.New("invalid MQTT packet length")
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, body); err != nil {
+		return 0, 0, nil, err
+	}
+	return header >> 4, header & 0x0f, body, nil
+}
+
+// newMQTTClient connects to a broker over conn. Username and password are optional. The client sends pings to keep
+// the connection alive until conn is closed.
+func newMQTTClient(conn net.Conn, clientID string, username string, password string, keepAlive time.Duration) (*mqttClient, error) {
+	c := &mqttClient{conn: conn, reader: bufio.NewReader(conn)}
+	flags := byte(0x02) // clean session
+	if username != "" {
+		flags |= 0x80
+	}
+	if password != "" {
+		flags |= 0x40
+	}
+	body := appendMQTTString(nil, "MQTT")
+	body = append(body, 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(keepAlive/time.Second))
+	body = appendMQTTString(body, clientID)
+	if username != "" {
+		body = appendMQTTString(body, username)
+	}
+	if password != "" {
+		body = appendMQTTString(body, password)
+	}
+	if err := c.writePacket(mqttConnect, 0, body); err != nil {
+		return nil, err
+	}
+	packetType, _, reply, err := c.readPacket()
+	if err != nil {
+		return nil, err
+	}
+	if packetType != mqttConnAck || len(reply) != 2 {
+		return nil, errors.New("unexpected reply to MQTT connect")
+	}
+	if reply[1] != 0 {
+		return nil, fmt.Errorf("MQTT broker refused connection with code %d", reply[1])
+	}
+	if keepAlive > 0 {
+		go func() {
+			for range time.Tick(keepAlive / 2) {
+				if c.writePacket(mqttPingRequest, 0, nil) != nil {
+					return
+				}
+			}
+		}()
+	}
+	return c, nil
+}
+
+// Subscribe subscribes to topics; failures are reported by ReadMessage
+func (c *mqttClient) Subscribe(topics []string) error {
+	c.nextPacketID++
+	body := binary.BigEndian.AppendUint16(nil, c.nextPacketID)
+	for _, topic := range topics {
+		body = append(appendMQTTString(body, topic), 0)
+	}
+	return c.writePacket(mqttSubscribe, 0x02, body)
+}
+
+// Publish publishes a message with QoS 0
+func (c *mqttClient) Publish(topic string, payload []byte, retain bool) error {
+	var flags byte
+	if retain {
+		flags = 0x01
+	}
+	return c.writePacket(mqttPublish, flags, append(appendMQTTString(nil, topic), payload...))
+}
+
+// ReadMessage returns the next message published to a subscribed topic
+func (c *mqttClient) ReadMessage() (string, []byte, error) {
+	for {
+		packetType, flags, body, err := c.readPacket()
+		if err != nil {
+			return "", nil, err
+		}
+		switch packetType {
+		case mqttPublish:
+			if len(body) < 2 {
+				return "", nil, errors.New("invalid MQTT publish packet")
+			}
+			topicLength := int(binary.BigEndian.Uint16(body))
+			payloadStart := 2 + topicLength
+			if flags&0x06 != 0 {
+				// QoS 1 and 2 messages have a packet identifier
+				payloadStart += 2
+			}
+			if len(body) < payloadStart {
+				return "", nil, errors.New("invalid MQTT publish packet")
+			}
+			return string(body[2 : 2+topicLength]), body[payloadStart:], nil
+		case mqttSubAck:
+			if len(body) < 2 {
+				return "", nil, errors.New("invalid MQTT subscribe reply")
+			}
+			for _, code := range body[2:] {
+				if code == 0x80 {
+					return "", nil, errors.New("MQTT broker refused subscription")
+				}
+			}
+		case mqttPingReply:
+		default:
+			return "", nil, fmt.Errorf("unexpected MQTT packet type %d", packetType)
+		}
+	}
+}
+
+func mqttMain(args []string) error {
+	fs := flag.NewFlagSet("mqtt", flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	gasFlags := registerGasCompositionFlags(fs)
+	var brokerFlag = fs.String("broker", "localhost:1883", "MQTT broker address")
+	var clientIDFlag = fs.String("client-id", "scuba-whip-calculator", "MQTT client identifier")
+	var usernameFlag = fs.String("username", "", "MQTT username")
+	var passwordFlag = fs.String("password", "", "MQTT password")
+	var temperatureTopicFlag = fs.String("temperature-topic", "", "Topic publishing the gas temperature; -temperature is used without one")
+	var publishTopicFlag = fs.String("publish-topic", "whip/prediction", "Topic to publish predicted pressures to, as JSON like the HTTP API")
+	var sourceFlags, destinationFlags stringListFlag
+	fs.Var(&sourceFlags, "source", "Source cylinder as [name=]volume@topic[:mix], e.g. bank1=50l@panel/bank1/pressure, or volume@pressure without a sensor; repeat for multiple cylinders")
+	fs.Var(&destinationFlags, "destination", "Destination cylinder as [name=]volume@topic[:mix], e.g. 12l@panel/fill/pressure, or volume@pressure without a sensor; repeat for multiple cylinders")
+	fs.Parse(args)
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	w, err := flags.output(os.Stdout, units)
+	if err != nil {
+		return err
+	}
+	gasSystem, temperature, err := flags.gasSettings(units)
+	if err != nil {
+		return err
+	}
+	gasComposition, err := gasFlags.gasComposition()
+	if err != nil {
+		return err
+	}
+	if len(sourceFlags) == 0 || len(destinationFlags) == 0 {
+		return errors.New("at least one -source and -destination is required")
+	}
+	sourceCylinders, sourceTopics, err := parsePanelCylinderSpecs(units, sourceFlags, "source")
+	if err != nil {
+		return fmt.Errorf("invalid source cylinder: %w", err)
+	}
+	destinationCylinders, destinationTopics, err := parsePanelCylinderSpecs(units, destinationFlags, "destination")
+	if err != nil {
+		return fmt.Errorf("invalid destination cylinder: %w", err)
+	}
+	model := newPanelModel(units, gasSystem, temperature, sourceCylinders, sourceTopics, destinationCylinders, destinationTopics, gasComposition)
+
+	conn, err := net.Dial("tcp", *brokerFlag)
+	if err != nil {
+		return fmt.Errorf("unable to connect to MQTT broker: %w", err)
+	}
+	defer conn.Close()
+	client, err := newMQTTClient(conn, *clientIDFlag, *usernameFlag, *passwordFlag, 60*time.Second)
+	if err != nil {
+		return err
+	}
+	topics := model.Inputs()
+	if *temperatureTopicFlag != "" {
+		topics = append(topics, *temperatureTopicFlag)
+	}
+	if err := client.Subscribe(topics); err != nil {
+		return err
+	}
+	for {
+		topic, payload, err := client.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if topic == *temperatureTopicFlag {
+			err = model.SetTemperature(string(payload))
+		} else {
+			_, err = model.SetPressure(topic, string(payload))
+		}
+		if err != nil {
+			fmt.Fprintf(w, "Invalid reading %q from %s: %s\n", payload, topic, err)
+			continue
+		}
+		if !model.Ready() {
+			continue
+		}
+		prediction, err := model.Predict()
+		if err != nil {
+			fmt.Fprintln(w, err.Error())
+			continue
+		}
+		model.printPrediction(w, prediction)
+		content, _ := json.Marshal(prediction)
+		if err := client.Publish(*publishTopicFlag, content, true); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestMQTTClient(t *testing.T) {
+	clientConn, brokerConn := net.Pipe()
+	defer clientConn.Close()
+	broker := &mqttClient{conn: brokerConn, reader: bufio.NewReader(brokerConn)}
+	done := make(chan error, 1)
+	go func() {
+		packetType, _, body, err := broker.readPacket()
+		if err != nil || packetType != mqttConnect || !bytes.Contains(body, []byte("whip")) {
+			done <- err
+			return
+		}
+		broker.writePacket(mqttConnAck, 0, []byte{0, 0})
+		if packetType, _, _, err = broker.readPacket(); err != nil || packetType != mqttSubscribe {
+			done <- err
+			return
+		}
+		broker.writePacket(mqttSubAck, 0, []byte{0, 1, 0})
+		broker.writePacket(mqttPublish, 0, append(appendMQTTString(nil, "panel/bank"), "232"...))
+		packetType, flags, body, err := broker.readPacket()
+		if err == nil && (packetType != mqttPublish || flags != 1 || !bytes.HasSuffix(body, []byte("prediction"))) {
+			t.Errorf("Unexpected publish %d %d %q", packetType, flags, body)
+		}
+		done <- err
+	}()
+	client, err := newMQTTClient(clientConn, "whip", "", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Subscribe([]string{"panel/bank"}); err != nil {
+		t.Fatal(err)
+	}
+	topic, payload, err := client.ReadMessage()
+	if err != nil || topic != "panel/bank" || string(payload) != "232" {
+		t.Fatalf("Unexpected message %q %q: %v", topic, payload, err)
+	}
+	if err := client.Publish("whip/prediction", []byte("prediction"), true); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxExhaustiveDestinations is the largest number of separate destinations for which every fill order is tried;
+// more destinations are filled in the given order
+const maxExhaustiveDestinations = 6
+
+// DestinationFill is the cascade fill of one of several separate destinations, stopping at StopPressure when set
+type DestinationFill struct {
+	Destination  Cylinder
+	StopPressure PressureBar
+	Plan         CascadePlan
+}
+
+// MultiDestinationPlan fills separate destination cylinders one at a time from the same banks
+type MultiDestinationPlan struct {
+	// Fills are in fill order
+	Fills []DestinationFill
+	// MinimumPressure is the lowest final destination pressure
+	MinimumPressure PressureBar
+	// TargetsReached counts destinations reaching their stop pressure
+	TargetsReached int
+	BankPressures  map[string]PressureBar
+}
+
+// fillDestinations cascades from banks into the destinations in order, each stopping at its stop pressure
+func fillDestinations(banks CylinderList, destinations CylinderList, stopPressures []PressureBar, order []int, gasSystem GasSystem, temperature Temperature) MultiDestinationPlan {
+	banks = append(CylinderList(nil), banks...)
+	var plan MultiDestinationPlan
+	for n, i := range order {
+		fillBanks := banks
+		if stopPressures[i] > 0 && destinations[i].Pressure >= stopPressures[i] {
+			// Already at the stopping pressure
+			fillBanks = nil
+		}
+		cascadePlan := PlanCascade(fillBanks, destinations[i], stopPressures[i], gasSystem, temperature)
+		for _, step := range cascadePlan.Steps {
+			for j := range banks {
+				if banks[j].Description == step.Bank.Description {
+					banks[j].Pressure = step.BankPressureAfter
+				}
+			}
+		}
+		plan.Fills = append(plan.Fills, DestinationFill{Destination: destinations[i], StopPressure: stopPressures[i], Plan: cascadePlan})
+		if n == 0 || cascadePlan.DestinationPressure < plan.MinimumPressure {
+			plan.MinimumPressure = cascadePlan.DestinationPressure
+		}
+		if stopPressures[i] > 0 && cascadePlan.DestinationPressure >= stopPressures[i] {
+			plan.TargetsReached++
+		}
+	}
+	plan.BankPressures = make(map[string]PressureBar)
+	for _, bank := range banks {
+		plan.BankPressures[bank.Description] = bank.Pressure
+	}
+	return plan
+}
+
+// fillOrders returns the fill orders to try: every order for up to maxExhaustiveDestinations destinations, and the
+// given order otherwise
+func fillOrders(n int) [][]int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	if n > maxExhaustiveDestinations {
+		return [][]int{order}
+	}
+	var orders [][]int
+	var permute func(k int)
+	permute = func(k int) {
+		if k == n {
+			orders = append(orders, append([]int(nil), order...))
+			return
+		}
+		for i := k; i < n; i++ {
+			order[k], order[i] = order[i], order[k]
+			permute(k + 1)
+			order[k], order[i] = order[i], order[k]
+		}
+	}
+	permute(0)
+	return orders
+}
+
+// PlanMultiDestinationFill fills separate destinations one at a time from the banks. Without targets it finds the
+// fill order and a common stopping pressure maximizing the lowest final destination pressure. Destinations with a
+// target stop there; the fill order reaching the most targets, then the highest lowest pressure, is chosen.
+func PlanMultiDestinationFill(banks CylinderList, destinations CylinderList, targetPressures []PressureBar, gasSystem GasSystem, temperature Temperature) MultiDestinationPlan {
+	orders := fillOrders(len(destinations))
+	hasTargets := false
+	for _, targetPressure := range targetPressures {
+		hasTargets = hasTargets || targetPressure > 0
+	}
+	if hasTargets {
+		var best MultiDestinationPlan
+		for n, order := range orders {
+			plan := fillDestinations(banks, destinations, targetPressures, order, gasSystem, temperature)
+			if n == 0 || plan.TargetsReached > best.TargetsReached || (plan.TargetsReached == best.TargetsReached && plan.MinimumPressure > best.MinimumPressure) {
+				best = plan
+			}
+		}
+		return best
+	}
+
+	// Bisect the highest common stopping pressure all destinations reach in some order
+	stopPressures := make([]PressureBar, len(destinations))
+	reachesAll := func(stopPressure PressureBar) (MultiDestinationPlan, bool) {
+		for i := range stopPressures {
+			stopPressures[i] = stopPressure
+		}
+		for _, order := range orders {
+			plan := fillDestinations(banks, destinations, stopPressures, order, gasSystem, temperature)
+			if plan.TargetsReached == len(destinations) {
+				return plan, true
+			}
+		}
+		return MultiDestinationPlan{}, false
+	}
+	low, high := destinations.MinPressure(), banks.MaxPressure()
+	// Every destination is at least at the lowest destination pressure
+	best, _ := reachesAll(low)
+	for high-low > solverTolerance {
+		middle := (low + high) / 2
+		if plan, ok := reachesAll(middle); ok {
+			best, low = plan, middle
+		} else {
+			high = middle
+		}
+	}
+	return best
+}
+
+func printMultiDestinationPlan(w io.Writer, plan MultiDestinationPlan, units UnitSystem) {
+	pressureUnit := units.PressureUnit()
+	for i, fill := range plan.Fills {
+		var bankNames []string
+		for _, step := range fill.Plan.Steps {
+			if !step.Skipped {
+				bankNames = append(bankNames, fmt.Sprintf("%s to %.0f%s", step.Bank.Description, units.Pressure(step.DestinationPressureAfter), pressureUnit))
+			}
+		}
+		if len(bankNames) == 0 {
+			bankNames = append(bankNames, "not filled")
+		}
+		stop := ""
+		if fill.StopPressure > 0 {
+			stop = fmt.Sprintf(", stop at %.0f%s", units.Pressure(fill.StopPressure), pressureUnit)
+			if fill.Plan.DestinationPressure < fill.StopPressure {
+				stop += " (not reached)"
+			}
+		}
+		fmt.Fprintf(w, "%d. %s: %.0f%s -> %.0f%s%s; %s\n", i+1, fill.Destination.Description, units.Pressure(fill.Destination.Pressure), pressureUnit, units.Pressure(fill.Plan.DestinationPressure), pressureUnit, stop, strings.Join(bankNames, ", "))
+	}
+	fmt.Fprintf(w, "Lowest final destination pressure: %.0f%s\n", units.Pressure(plan.MinimumPressure), pressureUnit)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPlanMultiDestinationFillMaximin(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	banks := CylinderList{{Description: "bank", CylinderVolume: 50, Pressure: 300, GasComposition: air}}
+	destinations := CylinderList{
+		{Description: "a", CylinderVolume: 12, Pressure: 50, GasComposition: air},
+		{Description: "b", CylinderVolume: 12, Pressure: 100, GasComposition: air},
+	}
+	plan := PlanMultiDestinationFill(banks, destinations, make([]PressureBar, 2), IdealGas, 293.15)
+	// Both destinations and the bank end at the same pressure
+	expected := (50*300 + 12*50 + 12*100) / 74.0
+	if len(plan.Fills) != 2 || float64(plan.MinimumPressure) < expected-0.1 || float64(plan.MinimumPressure) > expected {
+		t.Errorf("Invalid lowest pressure %f, expected %f", plan.MinimumPressure, expected)
+	}
+	if bankPressure := float64(plan.BankPressures["bank"]); math.Abs(bankPressure-expected) > 0.1 {
+		t.Errorf("Invalid bank pressure %f, expected %f", bankPressure, expected)
+	}
+}
+
+func TestPlanMultiDestinationFillTargets(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	banks := CylinderList{{Description: "bank", CylinderVolume: 20, Pressure: 250, GasComposition: air}}
+	destinations := CylinderList{
+		{Description: "a", CylinderVolume: 10, Pressure: 50, GasComposition: air},
+		{Description: "b", CylinderVolume: 10, Pressure: 150, GasComposition: air},
+	}
+	// Only b reaches its target, and only when filled first
+	plan := PlanMultiDestinationFill(banks, destinations, []PressureBar{200, 200}, IdealGas, 293.15)
+	if plan.TargetsReached != 1 || plan.Fills[0].Destination.Description != "b" || plan.Fills[0].Plan.DestinationPressure != 200 {
+		t.Errorf("Expected to fill b to 200 bar first, got %+v", plan)
+	}
+	if !compareFloats(float64(plan.Fills[1].Plan.DestinationPressure), (20*225+10*50)/30.0) {
+		t.Errorf("Invalid pressure of a %f", plan.Fills[1].Plan.DestinationPressure)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// apiVersion is the version of the HTTP API described by the OpenAPI document
+const apiVersion = "1.0.0"
+
+// apiOperation describes an endpoint for the OpenAPI document
+type apiOperation struct {
+	path     string
+	summary  string
+	request  interface{}
+	response interface{}
+}
+
+var apiOperations = []apiOperation{
+	{"/equalize", "Equalize source and destination cylinders with each manifold configuration", EqualizeRequest{}, EqualizeResponse{}},
+	{"/blend", "Plan a partial pressure blend", BlendRequest{}, BlendResponse{}},
+}
+
+// OpenAPIDocument returns the OpenAPI 3 description of the HTTP API. Schemas are derived from the request and
+// response types, so the document stays in sync with the server.
+func OpenAPIDocument() map[string]interface{} {
+	schemas := map[string]interface{}{}
+	errorSchema := apiSchema(reflect.TypeOf(ErrorResponse{}), schemas)
+	paths := map[string]interface{}{}
+	for _, operation := range apiOperations {
+		paths[operation.path] = map[string]interface{}{
+			"post": map[string]interface{}{
+				"summary": operation.summary,
+				"requestBody": map[string]interface{}{
+					"required": true,
+					"content":  apiJSONContent(apiSchema(reflect.TypeOf(operation.request), schemas)),
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{"description": "Success", "content": apiJSONContent(apiSchema(reflect.TypeOf(operation.response), schemas))},
+					"400": map[string]interface{}{"description": "Malformed request", "content": apiJSONContent(errorSchema)},
+					"422": map[string]interface{}{"description": "Invalid cylinders, mixes or pressures", "content": apiJSONContent(errorSchema)},
+				},
+			},
+		}
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Scuba transfer whip calculator",
+			"version":     apiVersion,
+			"description": "Volumes are in liters and pressures in bar.",
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": schemas},
+	}
+}
+
+func apiJSONContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// apiSchema returns the schema for a type; structs are added to schemas and referenced by name
+func apiSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": apiSchema(t.Elem(), schemas)}
+	case reflect.Struct:
+		if _, ok := schemas[t.Name()]; !ok {
+			schemas[t.Name()] = nil
+			properties := map[string]interface{}{}
+			required := []string{}
+			apiStructProperties(t, schemas, properties, &required)
+			schema := map[string]interface{}{"type": "object", "properties": properties}
+			if len(required) > 0 {
+				schema["required"] = required
+			}
+			schemas[t.Name()] = schema
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	panic("unsupported type in API schema: " + t.String())
+}
+
+// apiStructProperties adds fields of the struct, including embedded structs, to properties
+func apiStructProperties(t reflect.Type, schemas map[string]interface{}, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			apiStructProperties(field.Type, schemas, properties, required)
+			continue
+		}
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		if tag[0] == "" || tag[0] == "-" {
+			continue
+		}
+		properties[tag[0]] = apiSchema(field.Type, schemas)
+		if len(tag) == 1 || tag[1] != "omitempty" {
+			*required = append(*required, tag[0])
+		}
+	}
+}
+
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, OpenAPIDocument())
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ojarva/scuba-whip-calculator-go/client"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	document := OpenAPIDocument()
+	if _, err := json.Marshal(document); err != nil {
+		t.Fatal(err)
+	}
+	schemas := document["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	request := schemas["EqualizeRequest"].(map[string]interface{})["properties"].(map[string]interface{})
+	for _, property := range []string{"source", "destination", "mix"} {
+		if _, ok := request[property]; !ok {
+			t.Errorf("EqualizeRequest schema is missing %s", property)
+		}
+	}
+}
+
+func TestClientMatchesServer(t *testing.T) {
+	pairs := [][2]interface{}{
+		{EqualizeRequest{}, client.EqualizeRequest{}},
+		{EqualizeResponse{}, client.EqualizeResponse{}},
+		{SummaryResponse{}, client.SummaryResponse{}},
+		{ScenarioCylinder{}, client.ScenarioCylinder{}},
+		{BlendRequest{}, client.BlendRequest{}},
+		{BlendResponse{}, client.BlendResponse{}},
+		{BlendStepResponse{}, client.BlendStepResponse{}},
+	}
+	for _, pair := range pairs {
+		serverSchemas, clientSchemas := map[string]interface{}{}, map[string]interface{}{}
+		apiSchema(reflect.TypeOf(pair[0]), serverSchemas)
+		apiSchema(reflect.TypeOf(pair[1]), clientSchemas)
+		name := reflect.TypeOf(pair[0]).Name()
+		if !reflect.DeepEqual(serverSchemas[name], clientSchemas[name]) {
+			t.Errorf("Client %s does not match the server\nserver: %v\nclient: %v", name, serverSchemas[name], clientSchemas[name])
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(newServer(IdealGas, 293.15, Metric))
+	defer server.Close()
+	response, err := client.New(server.URL).Equalize(context.Background(), client.EqualizeRequest{
+		Source:      []client.ScenarioCylinder{{Volume: 12, Pressure: 232}},
+		Destination: []client.ScenarioCylinder{{Volume: 12, Pressure: 80}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Summaries) != 1 || !compareFloats(response.Summaries[0].DestinationPressure, 156) {
+		t.Errorf("Invalid response %+v", response)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// OxygenCleanRules are oxygen fractions above which equipment must be oxygen clean, by jurisdiction or practice
+var OxygenCleanRules = map[string]float64{
+	// The 40% rule followed by US agencies and most training organizations
+	"us": OxygenCleanFraction,
+	// EN 144-3: equipment for nitrox with more oxygen than air is dedicated to oxygen service
+	"eu": 0.21,
+}
+
+// ParseOxygenCleanRules returns the oxygen fraction of the named rules
+func ParseOxygenCleanRules(name string) (float64, error) {
+	if fraction, ok := OxygenCleanRules[strings.ToLower(name)]; ok {
+		return fraction, nil
+	}
+	names := make([]string, 0, len(OxygenCleanRules))
+	for name := range OxygenCleanRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return 0, fmt.Errorf("unknown oxygen clean rules %q; must be %s", name, strings.Join(names, " or "))
+}
+
+// oxygenCleanViolations returns warnings for cylinders and the whip that would hold or pass gas with more oxygen than
+// the threshold without being oxygen clean. Gas from the sources flows through the whip into every destination.
+func oxygenCleanViolations(cylinderConfiguration CylinderConfiguration, threshold float64, whipO2Clean bool) []string {
+	var warnings []string
+	var sourceOxygen float64
+	for _, cylinder := range cylinderConfiguration.SourceCylinders {
+		if cylinder.GasComposition[Oxygen] > sourceOxygen {
+			sourceOxygen = cylinder.GasComposition[Oxygen]
+		}
+		if cylinder.GasComposition[Oxygen] > threshold && !cylinder.O2Clean {
+			warnings = append(warnings, fmt.Sprintf("Warning: %s holds %s with over %.0f%% oxygen but is not oxygen clean", cylinder.Description, cylinder.GasComposition, threshold*100))
+		}
+	}
+	for _, cylinder := range cylinderConfiguration.DestinationCylinders {
+		if cylinder.O2Clean {
+			continue
+		}
+		if sourceOxygen > threshold {
+			warnings = append(warnings, fmt.Sprintf("Warning: the transfer puts %.0f%% oxygen into %s, which is not oxygen clean", sourceOxygen*100, cylinder.Description))
+		} else if cylinder.GasComposition[Oxygen] > threshold {
+			warnings = append(warnings, fmt.Sprintf("Warning: %s holds %s with over %.0f%% oxygen but is not oxygen clean", cylinder.Description, cylinder.GasComposition, threshold*100))
+		}
+	}
+	if sourceOxygen > threshold && !whipO2Clean {
+		warnings = append(warnings, fmt.Sprintf("Warning: the whip passes %.0f%% oxygen but is not oxygen clean; use -whip-o2-clean when it is", sourceOxygen*100))
+	}
+	return warnings
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOxygenCleanViolations(t *testing.T) {
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders: CylinderList{{Description: "bank", CylinderVolume: 50, Pressure: 200, GasComposition: GasComposition{Oxygen: 0.5, Nitrogen: 0.5}, O2Clean: true}},
+		DestinationCylinders: CylinderList{
+			{Description: "stage", CylinderVolume: 7, Pressure: 50, GasComposition: GasComposition{Oxygen: 0.5, Nitrogen: 0.5}, O2Clean: true},
+			{Description: "back gas", CylinderVolume: 12, Pressure: 50, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}},
+		},
+	}
+	warnings := oxygenCleanViolations(cylinderConfiguration, OxygenCleanRules["us"], false)
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "50% oxygen into back gas") || !strings.Contains(warnings[1], "whip") {
+		t.Errorf("Invalid warnings %q", warnings)
+	}
+	cylinderConfiguration.DestinationCylinders = cylinderConfiguration.DestinationCylinders[:1]
+	if warnings := oxygenCleanViolations(cylinderConfiguration, OxygenCleanRules["us"], true); len(warnings) != 0 {
+		t.Errorf("Expected no warnings with oxygen clean equipment, got %q", warnings)
+	}
+
+	// EAN32 is fine under the 40% rule but needs oxygen clean equipment under EN 144-3
+	cylinderConfiguration.SourceCylinders[0].GasComposition = GasComposition{Oxygen: 0.32, Nitrogen: 0.68}
+	cylinderConfiguration.SourceCylinders[0].O2Clean = false
+	cylinderConfiguration.DestinationCylinders[0].GasComposition = GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	if warnings := oxygenCleanViolations(cylinderConfiguration, OxygenCleanRules["us"], false); len(warnings) != 0 {
+		t.Errorf("Expected no warnings for EAN32 under the 40%% rule, got %q", warnings)
+	}
+	if warnings := oxygenCleanViolations(cylinderConfiguration, OxygenCleanRules["eu"], false); len(warnings) != 2 {
+		t.Errorf("Expected warnings for the bank and the whip under EU rules, got %q", warnings)
+	}
+}
+
+func TestParseOxygenCleanRules(t *testing.T) {
+	if fraction, err := ParseOxygenCleanRules("EU"); err != nil || fraction != 0.21 {
+		t.Errorf("Invalid EU rules %f, %v", fraction, err)
+	}
+	if _, err := ParseOxygenCleanRules("mars"); err == nil {
+		t.Error("Expected an error for unknown rules")
+	}
+}
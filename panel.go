@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// panelCylinder is a cylinder of a fill panel whose pressure is read from a sensor
+type panelCylinder struct {
+	side  string
+	index int
+	// input identifies the sensor, such as an MQTT topic or a channel of a serial transducer
+	input string
+}
+
+// panelModel is a live model of a fill panel. Cylinder sizes and mixes are fixed; pressures and temperature are
+// updated from sensor readings, which use units for values without a unit suffix. Predictions are in bar like the
+// HTTP API.
+type panelModel struct {
+	units                UnitSystem
+	server               server
+	sourceCylinders      CylinderList
+	destinationCylinders CylinderList
+	cylinders            []panelCylinder
+	pressureKnown        map[string][]bool
+}
+
+// newPanelModel returns a panel model for cylinders defined with parsePanelCylinderSpec. Cylinders without a mix
+// get gasComposition.
+func newPanelModel(units UnitSystem, gasSystem GasSystem, temperature Temperature, sourceCylinders CylinderList, sourceInputs []string, destinationCylinders CylinderList, destinationInputs []string, gasComposition GasComposition) *panelModel {
+	m := &panelModel{
+		units:                units,
+		server:               server{gasSystem: gasSystem, temperature: temperature, units: UnitSystem{AmbientPressure: units.AmbientPressure}},
+		sourceCylinders:      sourceCylinders,
+		destinationCylinders: destinationCylinders,
+		pressureKnown:        map[string][]bool{"source": make([]bool, len(sourceCylinders)), "destination": make([]bool, len(destinationCylinders))},
+	}
+	sourceCylinders.SetDefaultGasComposition(gasComposition)
+	destinationCylinders.SetDefaultGasComposition(gasComposition)
+	for _, side := range []struct {
+		name   string
+		inputs []string
+	}{{"source", sourceInputs}, {"destination", destinationInputs}} {
+		for i, input := range side.inputs {
+			if input == "" {
+				m.pressureKnown[side.name][i] = true
+				continue
+			}
+			m.cylinders = append(m.cylinders, panelCylinder{side.name, i, input})
+		}
+	}
+	return m
+}
+
+// parsePanelCylinderSpec parses a panel cylinder such as "bank1=50l@panel/bank1:32": a cylinder definition with
+// the sensor input in place of the pressure. Cylinders without a sensor have a pressure with a unit suffix, such as
+// "12l@50bar", and an empty input.
+func parsePanelCylinderSpec(units UnitSystem, spec string, defaultDescription string) (Cylinder, string, error) {
+	cylinder := Cylinder{Description: defaultDescription}
+	if i := strings.Index(spec, "="); i != -1 {
+		cylinder.Description = strings.TrimSpace(spec[:i])
+		spec = spec[i+1:]
+	}
+	if i := strings.LastIndex(spec, ":"); i != -1 {
+		gasComposition, err := ParseGasComposition(spec[i+1:])
+		if err != nil {
+			return cylinder, "", err
+		}
+		cylinder.GasComposition = gasComposition
+		spec = spec[:i]
+	}
+	volume, input, found := strings.Cut(spec, "@")
+	input = strings.TrimSpace(input)
+	if !found || input == "" {
+		return cylinder, "", fmt.Errorf("invalid cylinder %q; expected volume@input", spec)
+	}
+	var err error
+	if cylinder.CylinderVolume, err = units.ParseCylinderVolume(volume); err != nil {
+		return cylinder, "", err
+	}
+	// A pressure with a unit suffix is a cylinder without a sensor
+	if _, unit, err := units.splitQuantity(input); err == nil && unit != "" {
+		if cylinder.Pressure, err = units.ParsePressure(input); err != nil {
+			return cylinder, "", err
+		}
+		return cylinder, "", nil
+	}
+	return cylinder, input, nil
+}
+
+// parsePanelCylinderSpecs parses a list of panel cylinders, returning the cylinders and their inputs
+func parsePanelCylinderSpecs(units UnitSystem, specs []string, side string) (CylinderList, []string, error) {
+	cylinders := make(CylinderList, len(specs))
+	inputs := make([]string, len(specs))
+	for i, spec := range specs {
+		var err error
+		if cylinders[i], inputs[i], err = parsePanelCylinderSpec(units, spec, defaultCylinderDescription(side, i, len(specs))); err != nil {
+			return nil, nil, err
+		}
+	}
+	return cylinders, inputs, nil
+}
+
+// SetPressure updates pressures of cylinders read from the input. The reading is parsed like a pressure flag, so
+// it may have a unit suffix. It returns false when no cylinder uses the input.
+func (m *panelModel) SetPressure(input string, reading string) (bool, error) {
+	pressure, err := m.units.ParsePressure(reading)
+	if err != nil {
+		return false, err
+	}
+	var found bool
+	for _, cylinder := range m.cylinders {
+		if cylinder.input != input {
+			continue
+		}
+		found = true
+		cylinders := m.sourceCylinders
+		if cylinder.side == "destination" {
+			cylinders = m.destinationCylinders
+		}
+		cylinders[cylinder.index].Pressure = pressure
+		m.pressureKnown[cylinder.side][cylinder.index] = true
+	}
+	return found, nil
+}
+
+// SetTemperature updates the gas temperature from a reading, parsed like the -temperature flag
+func (m *panelModel) SetTemperature(reading string) error {
+	temperature, err := m.units.ParseTemperature(reading)
+	if err != nil {
+		return err
+	}
+	if temperature < ZeroCelsius-30 || temperature > ZeroCelsius+80 {
+		return fmt.Errorf("temperature %s out of range", reading)
+	}
+	m.server.temperature = temperature
+	return nil
+}
+
+// Inputs returns the sensor inputs used by the cylinders
+func (m *panelModel) Inputs() []string {
+	var inputs []string
+	for _, cylinder := range m.cylinders {
+		if !slices.Contains(inputs, cylinder.input) {
+			inputs = append(inputs, cylinder.input)
+		}
+	}
+	return inputs
+}
+
+// Ready returns true when pressures of all cylinders have been read
+func (m *panelModel) Ready() bool {
+	for _, known := range m.pressureKnown {
+		for _, ok := range known {
+			if !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Predict returns the pressures after equalizing with the current readings
+func (m *panelModel) Predict() (EqualizeResponse, error) {
+	if !m.Ready() {
+		return EqualizeResponse{}, errors.New("waiting for all pressure readings")
+	}
+	sourceCylinders := append(CylinderList(nil), m.sourceCylinders...)
+	destinationCylinders := append(CylinderList(nil), m.destinationCylinders...)
+	cylinderSummaries, err := m.server.equalizeCylinders(sourceCylinders, destinationCylinders, nil, nil)
+	if err != nil {
+		return EqualizeResponse{}, err
+	}
+	return m.server.equalizeResponse(cylinderSummaries), nil
+}
+
+// printPrediction writes the destination pressure of each manifold configuration on a single line
+func (m *panelModel) printPrediction(w io.Writer, response EqualizeResponse) {
+	var predictions []string
+	for _, summary := range response.Summaries {
+		pressure := m.units.Pressure(m.server.units.AbsolutePressure(PressureBar(summary.DestinationPressure)))
+		predictions = append(predictions, fmt.Sprintf("%s %.0f%s", summary.Description, pressure, m.units.PressureUnit()))
+	}
+	fmt.Fprintln(w, "If equalized now, destination ends at:", strings.Join(predictions, ", "))
+}
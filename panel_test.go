@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPanelModel(t *testing.T) {
+	units := UnitSystem{AmbientPressure: SurfacePressure}
+	sourceCylinders, sourceInputs, err := parsePanelCylinderSpecs(units, []string{"bank=12l@panel/bank:32"}, "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sourceInputs[0] != "panel/bank" || sourceCylinders[0].Description != "bank" || sourceCylinders[0].GasComposition.String() != "EAN32.0" {
+		t.Fatalf("Invalid cylinder %+v from %q", sourceCylinders[0], sourceInputs[0])
+	}
+	destinationCylinders, destinationInputs, err := parsePanelCylinderSpecs(units, []string{"12l@panel/fill"}, "destination")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := newPanelModel(units, IdealGas, 293.15, sourceCylinders, sourceInputs, destinationCylinders, destinationInputs, GasComposition{Oxygen: 0.21, Nitrogen: 0.79})
+	if found, err := model.SetPressure("panel/bank", "232"); !found || err != nil || model.Ready() {
+		t.Fatalf("Expected the model to wait for the destination, got %t %v", found, err)
+	}
+	if found, _ := model.SetPressure("panel/other", "100"); found {
+		t.Error("Expected an unknown input to be ignored")
+	}
+	model.SetPressure("panel/fill", "80bar")
+	prediction, err := model.Predict()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prediction.Summaries) != 1 || !compareFloats(prediction.Summaries[0].DestinationPressure, 156) {
+		t.Errorf("Invalid prediction %+v", prediction)
+	}
+	if err := model.SetTemperature("200"); err == nil {
+		t.Error("Expected an error for an out of range temperature")
+	}
+}
+
+func TestParsePanelCylinderSpecErrors(t *testing.T) {
+	for _, spec := range []string{"12l", "12l@", "x@topic", "12l@topic:nonsense"} {
+		if _, _, err := parsePanelCylinderSpec(Metric, spec, "source"); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// progressInterval is the minimum time between progress reports
+const progressInterval = time.Second
+
+// progressReporter reports how far a long run is, with an estimate of the time left, as text or as ndjson events.
+// A nil reporter reports nothing.
+type progressReporter struct {
+	w      io.Writer
+	ndjson bool
+	// overwrite rewrites a single text line on a terminal instead of printing a line per report
+	overwrite bool
+	task      string
+	total     int
+	done      int
+	start     time.Time
+	reported  time.Time
+	now       func() time.Time
+}
+
+// progressEvent is an ndjson progress report
+type progressEvent struct {
+	Event          string  `json:"event"`
+	Task           string  `json:"task"`
+	Done           int     `json:"done"`
+	Total          int     `json:"total"`
+	Percent        float64 `json:"percent"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	ETASeconds     float64 `json:"eta_seconds"`
+}
+
+// newProgressReporter returns a reporter of a task with total steps in the format of -progress: auto reports text
+// when w is a terminal, text, ndjson, or off, which returns nil
+func newProgressReporter(w io.Writer, format string, task string, total int) (*progressReporter, error) {
+	p := &progressReporter{w: w, task: task, total: total, overwrite: isTerminal(w), now: time.Now}
+	switch format {
+	case "auto":
+		if !p.overwrite {
+			return nil, nil
+		}
+	case "text":
+	case "ndjson":
+		p.ndjson = true
+	case "off":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("invalid progress %q; must be auto, text, ndjson or off", format)
+	}
+	p.start = p.now()
+	p.reported = p.start
+	return p, nil
+}
+
+// step records a finished step, reporting at most once per progressInterval and always after the last step
+func (p *progressReporter) step() {
+	if p == nil {
+		return
+	}
+	p.done++
+	now := p.now()
+	if p.done < p.total && now.Sub(p.reported) < progressInterval {
+		return
+	}
+	p.reported = now
+	elapsed := now.Sub(p.start)
+	var eta time.Duration
+	if p.done < p.total {
+		eta = elapsed / time.Duration(p.done) * time.Duration(p.total-p.done)
+	}
+	percent := 100 * float64(p.done) / float64(p.total)
+	if p.ndjson {
+		event := "progress"
+		if p.done >= p.total {
+			event = "done"
+		}
+		json.NewEncoder(p.w).Encode(progressEvent{Event: event, Task: p.task, Done: p.done, Total: p.total, Percent: percent, ElapsedSeconds: elapsed.Seconds(), ETASeconds: eta.Seconds()})
+		return
+	}
+	text := fmt.Sprintf("%s: %3.0f%% (%d/%d), %s left", p.task, percent, p.done, p.total, eta.Round(time.Second))
+	if p.done >= p.total {
+		text = fmt.Sprintf("%s: %3.0f%% (%d/%d) in %s", p.task, percent, p.done, p.total, elapsed.Round(time.Second))
+	}
+	switch {
+	case !p.overwrite:
+		fmt.Fprintln(p.w, text)
+	case p.done >= p.total:
+		// Clear the end of a longer previous line
+		fmt.Fprintf(p.w, "\r%s\x1b[K\n", text)
+	default:
+		fmt.Fprintf(p.w, "\r%s\x1b[K", text)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock advances by a step on each call
+func fakeClock(step time.Duration) func() time.Time {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func TestProgressReporterText(t *testing.T) {
+	var output strings.Builder
+	progress, err := newProgressReporter(&output, "text", "batch", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress.now = fakeClock(2 * time.Second)
+	progress.start = progress.now()
+	progress.reported = progress.start
+	for range 4 {
+		progress.step()
+	}
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	if len(lines) != 4 || lines[0] != "batch:  25% (1/4), 6s left" || lines[3] != "batch: 100% (4/4) in 8s" {
+		t.Errorf("Unexpected progress %q", output.String())
+	}
+}
+
+func TestProgressReporterNDJSON(t *testing.T) {
+	var output strings.Builder
+	progress, err := newProgressReporter(&output, "ndjson", "temperature sweep", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress.now = fakeClock(100 * time.Millisecond)
+	for range 10 {
+		progress.step()
+	}
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	var event progressEvent
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &event); err != nil {
+		t.Fatal(err)
+	}
+	// Reports are at most once a second, apart from the last one
+	if len(lines) != 1 || event.Event != "done" || event.Done != 10 || event.Percent != 100 {
+		t.Errorf("Unexpected progress %q", output.String())
+	}
+}
+
+func TestNewProgressReporter(t *testing.T) {
+	for _, format := range []string{"auto", "off"} {
+		if progress, err := newProgressReporter(&strings.Builder{}, format, "batch", 1); err != nil || progress != nil {
+			t.Errorf("Expected no progress with %s outside a terminal, got %+v (%v)", format, progress, err)
+		}
+	}
+	if _, err := newProgressReporter(&strings.Builder{}, "json", "batch", 1); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+	// A nil reporter ignores steps
+	var progress *progressReporter
+	progress.step()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Protocol buffer wire types used by proto/whip.proto
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// protoEncoder builds a protocol buffer message. Zero values are left out, as in proto3.
+type protoEncoder struct {
+	content []byte
+}
+
+func (e *protoEncoder) tag(field int, wireType int) {
+	e.content = binary.AppendUvarint(e.content, uint64(field)<<3|uint64(wireType))
+}
+
+func (e *protoEncoder) stringField(field int, value string) {
+	e.bytesField(field, []byte(value))
+}
+
+func (e *protoEncoder) bytesField(field int, value []byte) {
+	if len(value) == 0 {
+		return
+	}
+	e.tag(field, protoBytes)
+	e.content = binary.AppendUvarint(e.content, uint64(len(value)))
+	e.content = append(e.content, value...)
+}
+
+func (e *protoEncoder) doubleField(field int, value float64) {
+	if value == 0 {
+		return
+	}
+	e.tag(field, protoFixed64)
+	e.content = binary.LittleEndian.AppendUint64(e.content, math.Float64bits(value))
+}
+
+func (e *protoEncoder) intField(field int, value int) {
+	if value == 0 {
+		return
+	}
+	e.tag(field, protoVarint)
+	e.content = binary.AppendUvarint(e.content, uint64(value))
+}
+
+// protoField is a single decoded field; value holds varints and fixed size values, content length delimited ones
+type protoField struct {
+	number   int
+	wireType int
+	value    uint64
+	content  []byte
+}
+
+func (f protoField) double() float64 {
+	return math.Float64frombits(f.value)
+}
+
+// decodeProto calls handle for each field of a protocol buffer message
+func decodeProto(content []byte, handle func(field protoField) error) error {
+	for len(content) > 0 {
+		key, n := binary.Uvarint(content)
+		if n <= 0 {
+			return errors.New("invalid protocol buffer field")
+		}
+		content = content[n:]
+		field := protoField{number: int(key >> 3), wireType: int(key & 7)}
+		switch field.wireType {
+		case protoVarint:
+			if field.value, n = binary.Uvarint(content); n <= 0 {
+				return errors.New("invalid protocol buffer varint")
+			}
+			content = content[n:]
+		case protoFixed64:
+			if len(content) < 8 {
+				return errors.New("truncated protocol buffer message")
+			}
+			field.value, content = binary.LittleEndian.Uint64(content), content[8:]
+		case protoFixed32:
+			if len(content) < 4 {
+				return errors.New("truncated protocol buffer message")
+			}
+			field.value, content = uint64(binary.LittleEndian.Uint32(content)), content[4:]
+		case protoBytes:
+			length, n := binary.Uvarint(content)
+			if n <= 0 || uint64(len(content)-n) < length {
+				return errors.New("truncated protocol buffer message")
+			}
+			field.content, content = content[n:n+int(length)], content[n+int(length):]
+		default:
+			return fmt.Errorf("unsupported protocol buffer wire type %d", field.wireType)
+		}
+		if err := handle(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
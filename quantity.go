@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ojarva/scuba-whip-calculator-go/units"
+)
+
+// maxCylinderVolume is the largest accepted cylinder volume, in liters
+const maxCylinderVolume CylinderVolume = 1000
+
+// finite tells whether the value is neither NaN nor infinite
+func finite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+// NewPressureBar returns an absolute pressure in bar, which must not be negative
+func NewPressureBar(bar float64) (PressureBar, error) {
+	if !finite(bar) || bar < 0 {
+		return 0, fmt.Errorf("%w %g bar; must be >= 0", ErrInvalidPressure, bar)
+	}
+	return PressureBar(bar), nil
+}
+
+// NewCylinderVolume returns a cylinder volume in liters, which must be greater than 0 and at most 1000 liters
+func NewCylinderVolume(liters float64) (CylinderVolume, error) {
+	if !finite(liters) || liters <= 0 || CylinderVolume(liters) > maxCylinderVolume {
+		return 0, fmt.Errorf("%w %gl; must be greater than 0 and at most %.0fl", ErrInvalidVolume, liters, maxCylinderVolume)
+	}
+	return CylinderVolume(liters), nil
+}
+
+// NewGasVolume returns a gas volume in liters, which must not be negative
+func NewGasVolume(liters float64) (GasVolume, error) {
+	if !finite(liters) || liters < 0 {
+		return 0, fmt.Errorf("%w %gl of gas; must be >= 0", ErrInvalidVolume, liters)
+	}
+	return GasVolume(liters), nil
+}
+
+// NewTemperatureKelvin returns a temperature in kelvins, which must be above absolute zero
+func NewTemperatureKelvin(kelvins float64) (Temperature, error) {
+	if !finite(kelvins) || kelvins <= 0 {
+		return 0, fmt.Errorf("%w %gK; must be above absolute zero", ErrInvalidTemperature, kelvins)
+	}
+	return Temperature(kelvins), nil
+}
+
+// NewTemperatureCelsius returns a temperature from degrees Celsius, which must be above absolute zero
+func NewTemperatureCelsius(celsius float64) (Temperature, error) {
+	if !finite(celsius) || celsius <= -ZeroCelsius {
+		return 0, fmt.Errorf("%w %g°C; must be above absolute zero", ErrInvalidTemperature, celsius)
+	}
+	return Temperature(units.CelsiusToKelvin(celsius)), nil
+}
+
+// Sub returns the pressure less the other pressure, or an error when the result would be negative
+func (p PressureBar) Sub(other PressureBar) (PressureBar, error) {
+	if other > p {
+		return 0, fmt.Errorf("%w: %.1f bar less %.1f bar is negative", ErrInvalidPressure, p, other)
+	}
+	return p - other, nil
+}
+
+// Sub returns the gas volume less the other gas volume, or an error when the result would be negative
+func (v GasVolume) Sub(other GasVolume) (GasVolume, error) {
+	if other > v {
+		return 0, fmt.Errorf("%w: %.1fl less %.1fl of gas is negative", ErrInvalidVolume, v, other)
+	}
+	return v - other, nil
+}
+
+// Validate checks that the fractions of the gas composition are between 0 and 1 and add up to 1
+func (gc GasComposition) Validate() error {
+	var sum float64
+	for gas, fraction := range gc {
+		if !finite(fraction) || fraction < 0 || fraction > 1 {
+			return fmt.Errorf("%w: %s fraction %g must be between 0 and 1", ErrInvalidGasMix, SpeciesLookup[gas].Symbol, fraction)
+		}
+		sum += fraction
+	}
+	switch {
+	case sum > 1+1e-6:
+		return fmt.Errorf("%w %s: %w", ErrInvalidGasMix, gc, ErrCompositionExceeds100)
+	case sum < 1-1e-6:
+		return fmt.Errorf("%w %s: fractions add up to %.1f%%", ErrInvalidGasMix, gc, sum*100)
+	}
+	return nil
+}
+
+// NewCylinder returns a cylinder after checking its volume, pressure and gas composition
+func NewCylinder(description string, cylinderVolume CylinderVolume, pressure PressureBar, gasComposition GasComposition) (Cylinder, error) {
+	if _, err := NewCylinderVolume(float64(cylinderVolume)); err != nil {
+		return Cylinder{}, fmt.Errorf("cylinder %s: %w", description, err)
+	}
+	if _, err := NewPressureBar(float64(pressure)); err != nil {
+		return Cylinder{}, fmt.Errorf("cylinder %s: %w", description, err)
+	}
+	if err := gasComposition.Validate(); err != nil {
+		return Cylinder{}, fmt.Errorf("cylinder %s: %w", description, err)
+	}
+	return Cylinder{Description: description, CylinderVolume: cylinderVolume, Pressure: pressure, GasComposition: gasComposition.Clone()}, nil
+}
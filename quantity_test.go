@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestNewQuantities(t *testing.T) {
+	if pressure, err := NewPressureBar(232); err != nil || pressure != 232 {
+		t.Errorf("Invalid pressure %f: %v", pressure, err)
+	}
+	if _, err := NewPressureBar(-1); !errors.Is(err, ErrInvalidPressure) {
+		t.Errorf("Expected ErrInvalidPressure for a negative pressure, got %v", err)
+	}
+	if _, err := NewCylinderVolume(0); !errors.Is(err, ErrInvalidVolume) {
+		t.Errorf("Expected ErrInvalidVolume for an empty cylinder, got %v", err)
+	}
+	if _, err := NewCylinderVolume(math.NaN()); !errors.Is(err, ErrInvalidVolume) {
+		t.Errorf("Expected ErrInvalidVolume for NaN, got %v", err)
+	}
+	if _, err := NewGasVolume(math.Inf(1)); !errors.Is(err, ErrInvalidVolume) {
+		t.Errorf("Expected ErrInvalidVolume for an infinite gas volume, got %v", err)
+	}
+	if temperature, err := NewTemperatureCelsius(20); err != nil || !compareFloats(float64(temperature), 293.15) {
+		t.Errorf("Invalid temperature %f: %v", temperature, err)
+	}
+	if _, err := NewTemperatureCelsius(-300); !errors.Is(err, ErrInvalidTemperature) {
+		t.Errorf("Expected ErrInvalidTemperature below absolute zero, got %v", err)
+	}
+	if _, err := NewTemperatureKelvin(0); !errors.Is(err, ErrInvalidTemperature) {
+		t.Errorf("Expected ErrInvalidTemperature at absolute zero, got %v", err)
+	}
+}
+
+func TestParseValidatesQuantities(t *testing.T) {
+	if _, err := Metric.ParsePressure("-5bar"); !errors.Is(err, ErrInvalidPressure) {
+		t.Errorf("Expected ErrInvalidPressure below vacuum, got %v", err)
+	}
+	if _, err := Metric.ParseTemperature("-300C"); !errors.Is(err, ErrInvalidTemperature) {
+		t.Errorf("Expected ErrInvalidTemperature below absolute zero, got %v", err)
+	}
+	if _, err := Metric.ParseConsumption("-20l"); !errors.Is(err, ErrInvalidVolume) {
+		t.Errorf("Expected ErrInvalidVolume for a negative consumption, got %v", err)
+	}
+	if err := checkCylinders(CylinderList{{CylinderVolume: 2000, Pressure: 200}}, "source", false, Metric); !errors.Is(err, ErrInvalidVolume) {
+		t.Errorf("Expected ErrInvalidVolume for a 2000l cylinder, got %v", err)
+	}
+}
+
+func TestQuantityArithmetic(t *testing.T) {
+	if pressure, err := PressureBar(200).Sub(50); err != nil || pressure != 150 {
+		t.Errorf("Invalid pressure %f: %v", pressure, err)
+	}
+	if _, err := PressureBar(50).Sub(200); !errors.Is(err, ErrInvalidPressure) {
+		t.Errorf("Expected ErrInvalidPressure for a negative result, got %v", err)
+	}
+	if _, err := GasVolume(100).Sub(101); !errors.Is(err, ErrInvalidVolume) {
+		t.Errorf("Expected ErrInvalidVolume for a negative result, got %v", err)
+	}
+}
+
+func TestNewCylinder(t *testing.T) {
+	cylinder, err := NewCylinder("twinset", 24, 232, GasComposition{Oxygen: 0.32, Nitrogen: 0.68})
+	if err != nil || cylinder.CylinderVolume != 24 || cylinder.GasComposition.String() != "EAN32.0" {
+		t.Errorf("Invalid cylinder %+v: %v", cylinder, err)
+	}
+	if _, err := NewCylinder("twinset", 24, 232, GasComposition{Oxygen: 0.6, Helium: 0.6}); !errors.Is(err, ErrCompositionExceeds100) {
+		t.Errorf("Expected ErrCompositionExceeds100, got %v", err)
+	}
+	if _, err := NewCylinder("twinset", 24, 232, GasComposition{Oxygen: 0.21}); !errors.Is(err, ErrInvalidGasMix) {
+		t.Errorf("Expected ErrInvalidGasMix for fractions below 100%%, got %v", err)
+	}
+	if _, err := NewCylinder("twinset", -24, 232, GasComposition{Oxygen: 0.21, Nitrogen: 0.79}); !errors.Is(err, ErrInvalidVolume) {
+		t.Errorf("Expected ErrInvalidVolume, got %v", err)
+	}
+}
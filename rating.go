@@ -0,0 +1,92 @@
+package main
+
+import "fmt"
+
+// defaultMaxCylinderPressure is the highest gauge pressure accepted for cylinders without a test pressure
+const defaultMaxCylinderPressure PressureBar = 350
+
+// pressureLimit returns the highest pressure the cylinder may hold: its test pressure, or 350 bar gauge when the
+// test pressure is not known
+func (c1 Cylinder) pressureLimit(units UnitSystem) PressureBar {
+	if c1.TestPressure > 0 {
+		return c1.TestPressure
+	}
+	return defaultMaxCylinderPressure + units.AmbientPressure
+}
+
+// pressureLimit returns the lowest pressure limit of the cylinders
+func (cl CylinderList) pressureLimit(units UnitSystem) PressureBar {
+	limit := defaultMaxCylinderPressure + units.AmbientPressure
+	for i, cylinder := range cl {
+		if i == 0 || cylinder.pressureLimit(units) < limit {
+			limit = cylinder.pressureLimit(units)
+		}
+	}
+	return limit
+}
+
+// bankModeMaxCylinderPressure is the highest gauge pressure accepted with -bank-mode for storage cylinders without a
+// test pressure, such as industrial bottles filled by a high-pressure compressor
+const bankModeMaxCylinderPressure PressureBar = 700
+
+// checkBankCylinders checks storage cylinders like checkCylinders, but lets the cylinders without a test pressure hold
+// up to 700 bar gauge. It returns a warning for each of them above 350 bar, whose rating the calculator cannot check,
+// and for pressures beyond the compressibility table of -gas-system z-table.
+func checkBankCylinders(cylinders CylinderList, side string, gasSystem GasSystem, units UnitSystem) ([]string, error) {
+	var warnings []string
+	pressureUnit := units.PressureUnit()
+	for _, cylinder := range cylinders {
+		if cylinder.TestPressure == 0 {
+			if cylinder.Pressure > defaultMaxCylinderPressure+units.AmbientPressure {
+				warnings = append(warnings, fmt.Sprintf("Warning: %s at %.0f%s has no test pressure; bank mode takes it for a storage cylinder rated above %.0f%s, check its stamping", cylinder.Description, units.Pressure(cylinder.Pressure), pressureUnit, units.Pressure(defaultMaxCylinderPressure+units.AmbientPressure), pressureUnit))
+			}
+			cylinder.TestPressure = bankModeMaxCylinderPressure + units.AmbientPressure
+		}
+		if err := checkCylinders(CylinderList{cylinder}, side, false, units); err != nil {
+			return nil, err
+		}
+	}
+	if tableLimit := compressibilityTablePressures[len(compressibilityTablePressures)-1]; gasSystem == CompressibilityTableGas && cylinders.MaxPressure() > tableLimit {
+		warnings = append(warnings, fmt.Sprintf("Warning: z-table compressibility factors end at %.0f%s and higher pressures use the last factors; consider another -gas-system", units.Pressure(tableLimit), pressureUnit))
+	}
+	return warnings, nil
+}
+
+// ratedPressureWarnings returns warnings for destination cylinders going above their working or test pressure in
+// any transfer step or in the end result of a configuration, including boosting and compressor top-off. Steps to
+// the combined destination of an open manifold apply to every destination cylinder.
+func ratedPressureWarnings(destinationCylinders CylinderList, transferSteps []TransferStep, cylinderSummaries []CylinderSummary, units UnitSystem) []string {
+	peakPressures := make(map[string]map[string]PressureBar)
+	raise := func(configuration string, destination string, pressure PressureBar) {
+		if peakPressures[configuration] == nil {
+			peakPressures[configuration] = make(map[string]PressureBar)
+		}
+		for _, cylinder := range destinationCylinders {
+			if (destination == "" || destination == cylinder.Description || len(destinationCylinders) > 1 && destination == "destination") && pressure > peakPressures[configuration][cylinder.Description] {
+				peakPressures[configuration][cylinder.Description] = pressure
+			}
+		}
+	}
+	for _, step := range transferSteps {
+		raise(step.Configuration, step.Destination, step.DestinationPressureAfter)
+	}
+	for _, cylinderSummary := range cylinderSummaries {
+		raise(cylinderSummary.Description, "", cylinderSummary.DestinationCylinderPressure)
+	}
+
+	var warnings []string
+	pressureUnit := units.PressureUnit()
+	for _, cylinderSummary := range cylinderSummaries {
+		for _, cylinder := range destinationCylinders {
+			pressure := peakPressures[cylinderSummary.Description][cylinder.Description]
+			rating, limit := "test", cylinder.TestPressure
+			if limit == 0 || pressure <= limit {
+				rating, limit = "working", cylinder.WorkingPressure
+			}
+			if limit > 0 && pressure > limit {
+				warnings = append(warnings, fmt.Sprintf("Warning: %s reaches %.0f%s with %s, above its %s pressure %.0f%s", cylinder.Description, units.Pressure(pressure), pressureUnit, cylinderSummary.Description, rating, units.Pressure(limit), pressureUnit))
+			}
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCheckCylindersTestPressure(t *testing.T) {
+	tube := Cylinder{CylinderVolume: 50, Pressure: 400, TestPressure: 450}
+	if err := checkCylinders(CylinderList{tube}, "source", false, Metric); err != nil {
+		t.Errorf("Expected a 400 bar cylinder with a 450 bar test pressure to be valid, got %v", err)
+	}
+	tube.TestPressure = 0
+	if err := checkCylinders(CylinderList{tube}, "source", false, Metric); err == nil {
+		t.Error("Expected a 400 bar cylinder without a test pressure to be invalid")
+	}
+	tube.TestPressure, tube.Pressure = 300, 310
+	if err := checkCylinders(CylinderList{tube}, "source", false, Metric); err == nil {
+		t.Error("Expected a cylinder above its test pressure to be invalid")
+	}
+}
+
+func TestCheckBankCylinders(t *testing.T) {
+	banks := CylinderList{
+		{Description: "bank1", CylinderVolume: 50, Pressure: 450},
+		{Description: "bank2", CylinderVolume: 50, Pressure: 300},
+		{Description: "bank3", CylinderVolume: 50, Pressure: 500, TestPressure: 750},
+	}
+	warnings, err := checkBankCylinders(banks, "bank", VanDerWaals, Metric)
+	if err != nil {
+		t.Fatalf("Expected banks up to 700 bar to be valid in bank mode, got %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "bank1 at 450bar has no test pressure") {
+		t.Errorf("Expected a warning only for the unrated bank above 350 bar, got %q", warnings)
+	}
+	if banks[0].TestPressure != 0 {
+		t.Error("Expected checking the banks to leave their test pressures unchanged")
+	}
+	if warnings, _ = checkBankCylinders(banks, "bank", CompressibilityTableGas, Metric); len(warnings) != 2 || !strings.Contains(warnings[1], "z-table") {
+		t.Errorf("Expected a warning for pressures beyond the z-table, got %q", warnings)
+	}
+	banks[0].Pressure = 710
+	if _, err := checkBankCylinders(banks, "bank", VanDerWaals, Metric); err == nil {
+		t.Error("Expected an unrated bank above 700 bar to be invalid in bank mode")
+	}
+	banks[0].Pressure, banks[0].TestPressure = 450, 300
+	if _, err := checkBankCylinders(banks, "bank", VanDerWaals, Metric); err == nil {
+		t.Error("Expected a bank above its test pressure to be invalid in bank mode")
+	}
+}
+
+func TestRatedPressureWarnings(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders: CylinderList{{Description: "source", CylinderVolume: 15, Pressure: 300, GasComposition: air}},
+		DestinationCylinders: CylinderList{
+			{Description: "left", CylinderVolume: 6, Pressure: 50, GasComposition: air, WorkingPressure: 200},
+			{Description: "right", CylinderVolume: 6, Pressure: 50, GasComposition: air, WorkingPressure: 200},
+		},
+	}
+	var transferSteps []TransferStep
+	cylinderConfiguration.OnTransferStep = func(step TransferStep) {
+		transferSteps = append(transferSteps, step)
+	}
+	cylinderSummaries := equalizeAllConfigurations(io.Discard, cylinderConfiguration, IdealGas, 293.15, Metric, false, nil)
+	warnings := ratedPressureWarnings(cylinderConfiguration.DestinationCylinders, transferSteps, cylinderSummaries, Metric)
+	// With the destination manifold closed, left is filled first to (15*300+6*50)/21 bar and both end at 203 bar once
+	// the manifold is opened; all manifolds open stays at (15*300+12*50)/27 bar
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "left reaches 229bar with destination manifold closed") || !strings.Contains(warnings[0], "working pressure 200bar") || !strings.Contains(warnings[1], "right reaches 203bar") {
+		t.Errorf("Invalid warnings %q", warnings)
+	}
+
+	cylinderConfiguration.DestinationCylinders[1].TestPressure = 150
+	warnings = ratedPressureWarnings(cylinderConfiguration.DestinationCylinders, transferSteps, cylinderSummaries, Metric)
+	if len(warnings) != 3 || !strings.Contains(warnings[1], "test pressure 150bar") || !strings.Contains(warnings[2], "right reaches 189bar with all manifolds open, above its test pressure") {
+		t.Errorf("Invalid warnings %q", warnings)
+	}
+
+	// Real gases end above the gas volume over the cylinder volume once the destination manifold is opened
+	cylinderConfiguration.DestinationCylinders[1].TestPressure = 0
+	cylinderConfiguration.DestinationCylinders[1].WorkingPressure = 180
+	transferSteps = nil
+	cylinderSummaries = equalizeAllConfigurations(io.Discard, cylinderConfiguration, VanDerWaals, 293.15, Metric, false, nil)
+	warnings = ratedPressureWarnings(cylinderConfiguration.DestinationCylinders, transferSteps, cylinderSummaries, Metric)
+	expectedPressure := PressureFromGasVolume(12, cylinderSummaries[0].DestinationCylinderGasVolume, VanDerWaals, air, 293.15)
+	if expected := fmt.Sprintf("right reaches %.0fbar with destination manifold closed", expectedPressure); len(warnings) < 2 || !strings.Contains(warnings[1], expected) || expectedPressure <= 180 || PressureFromVolumes(cylinderSummaries[0].DestinationCylinderGasVolume, 12) >= 180 {
+		t.Errorf("Expected %q, got %q", expected, warnings)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// rebreatherPresets are the diluent and oxygen bottle volumes of common rebreathers
+var rebreatherPresets = map[string]CylinderVolume{
+	"2l": 2,
+	"3l": 3,
+}
+
+// TopOffStep is a connection of a bank to a rebreather bottle through the whip. The bottle heats up while filling
+// and settles to a lower pressure once it has cooled down.
+type TopOffStep struct {
+	Bank              Cylinder
+	BankPressureAfter PressureBar
+	Fill              FillResult
+	AddedGasVolume    GasVolume
+	WhipGasVolume     GasVolume
+}
+
+// TopOff is topping off a rebreather bottle from the banks holding its gas
+type TopOff struct {
+	Bottle          Cylinder
+	Steps           []TopOffStep
+	SettledPressure PressureBar
+	AddedGasVolume  GasVolume
+	WhipGasVolume   GasVolume
+}
+
+// PlanTopOff tops off the bottle from banks with its mix, lowest pressure bank first, connecting each bank cycles
+// times and letting the bottle cool down in between. The whip is vented after each connection; with bottles of a
+// couple of liters its dead volume is a noticeable part of the gas added.
+func PlanTopOff(bottle Cylinder, banks CylinderList, whipVolume CylinderVolume, cycles int, fillProcess FillProcess, ambientPressure PressureBar, gasSystem GasSystem, temperature Temperature) TopOff {
+	topOff := TopOff{Bottle: bottle}
+	var sourceBanks CylinderList
+	for _, bank := range banks {
+		if bank.GasComposition.Equal(bottle.GasComposition) {
+			sourceBanks = append(sourceBanks, bank)
+		}
+	}
+	sort.SliceStable(sourceBanks, func(i, j int) bool { return sourceBanks[i].Pressure < sourceBanks[j].Pressure })
+	whip := Whip{Volume: whipVolume, Connections: 1}
+	for _, bank := range sourceBanks {
+		for cycle := 0; cycle < cycles && bank.Pressure > bottle.Pressure; cycle++ {
+			step := TopOffStep{Bank: bank}
+			gasVolumeBefore := bottle.GasVolume(gasSystem, temperature)
+			step.Fill = bottle.Fill(&bank, fillProcess, gasSystem, temperature)
+			step.AddedGasVolume = bottle.GasVolume(gasSystem, temperature) - gasVolumeBefore
+			if whipVolume > 0 {
+				step.WhipGasVolume = whip.Vent(&bank, Cylinder{CylinderVolume: bottle.CylinderVolume, Pressure: step.Fill.HotPressure}, ambientPressure, gasSystem, temperature)
+			}
+			step.BankPressureAfter = bank.Pressure
+			topOff.AddedGasVolume += step.AddedGasVolume
+			topOff.WhipGasVolume += step.WhipGasVolume
+			topOff.Steps = append(topOff.Steps, step)
+		}
+	}
+	topOff.SettledPressure = bottle.Pressure
+	return topOff
+}
+
+// BankPressures returns the pressure of each bank after the top-off, by bank description
+func (t TopOff) BankPressures() map[string]PressureBar {
+	pressures := make(map[string]PressureBar)
+	for _, step := range t.Steps {
+		pressures[step.Bank.Description] = step.BankPressureAfter
+	}
+	return pressures
+}
+
+func printTopOff(w io.Writer, topOff TopOff, units UnitSystem) {
+	pressureUnit, volumeUnit := units.PressureUnit(), units.VolumeUnit()
+	fmt.Fprintf(w, "%s: %.1fl at %.0f%s, %s\n", topOff.Bottle.Description, topOff.Bottle.CylinderVolume, units.Pressure(topOff.Bottle.Pressure), pressureUnit, topOff.Bottle.GasComposition)
+	if len(topOff.Steps) == 0 {
+		fmt.Fprintf(w, "   no bank with %s above the bottle pressure\n", topOff.Bottle.GasComposition)
+		return
+	}
+	for _, step := range topOff.Steps {
+		fmt.Fprintf(w, "   from %s: %.0f%s to %.0f%s; %.0f%s at %.0f°%s, settles to %.0f%s", step.Bank.Description, units.Pressure(step.Bank.Pressure), pressureUnit, units.Pressure(step.BankPressureAfter), pressureUnit, units.Pressure(step.Fill.HotPressure), pressureUnit, units.Temperature(step.Fill.HotTemperature), units.TemperatureUnit(), units.Pressure(step.Fill.SettledPressure), pressureUnit)
+		if step.WhipGasVolume > 0 {
+			fmt.Fprintf(w, "; whip vents %.1f%s", units.Volume(step.WhipGasVolume), volumeUnit)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "   topped off to %.0f%s with %.0f%s of gas", units.Pressure(topOff.SettledPressure), pressureUnit, units.Volume(topOff.AddedGasVolume), volumeUnit)
+	if topOff.WhipGasVolume > 0 {
+		fmt.Fprintf(w, "; whip vented %.1f%s (%.1f%% of the gas added)", units.Volume(topOff.WhipGasVolume), volumeUnit, float64(topOff.WhipGasVolume/topOff.AddedGasVolume)*100)
+	}
+	fmt.Fprintln(w)
+}
+
+func ccrMain(args []string) error {
+	fs := flag.NewFlagSet("ccr", flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	var bankFlags stringListFlag
+	fs.Var(&bankFlags, "bank", "Storage bank as [name=]volume@pressure:mix, e.g. o2=50l@200bar:oxygen; repeat for each bank")
+	var presetFlag = fs.String("preset", "3l", "Rebreather bottle volumes: 2l or 3l")
+	var diluentVolumeFlag = fs.String("diluent-volume", "", "Diluent bottle volume, overriding -preset")
+	var diluentPressureFlag = fs.String("diluent-pressure", "50bar", "Diluent bottle pressure before the top-off")
+	var diluentMixFlag = fs.String("diluent-mix", "air", "Diluent mix, e.g. air or 21/35")
+	var oxygenVolumeFlag = fs.String("oxygen-volume", "", "Oxygen bottle volume, overriding -preset")
+	var oxygenPressureFlag = fs.String("oxygen-pressure", "50bar", "Oxygen bottle pressure before the top-off")
+	var whipVolumeFlag = fs.String("whip-volume", "0.02l", "Internal volume of the whip, vented after each connection")
+	var cyclesFlag = fs.Int("cycles", 1, "Connections per bank, letting the bottle cool down in between")
+	var fillProcessFlag = fs.String("fill-process", "adiabatic", "Gas temperature while filling: isothermal, adiabatic or polytropic")
+	var polytropicExponentFlag = fs.Float64("polytropic-exponent", 1.2, "Polytropic exponent for -fill-process polytropic")
+	bankStateFlags := registerBankStateFlags(fs)
+	fs.Parse(args)
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	w, err := flags.output(os.Stdout, units)
+	if err != nil {
+		return err
+	}
+	gasSystem, temperature, err := flags.gasSettings(units)
+	if err != nil {
+		return err
+	}
+	presetVolume, ok := rebreatherPresets[*presetFlag]
+	if !ok {
+		return errors.New("invalid preset; must be 2l or 3l")
+	}
+	fillProcess, err := ParseFillProcess(*fillProcessFlag, *polytropicExponentFlag)
+	if err != nil {
+		return fmt.Errorf("invalid fill process: %w", err)
+	}
+	if *cyclesFlag < 1 {
+		return errors.New("invalid cycles; must be >=1")
+	}
+	whipVolume, err := units.ParseCylinderVolume(*whipVolumeFlag)
+	if err != nil || whipVolume < 0 {
+		return fmt.Errorf("invalid whip volume: %s", *whipVolumeFlag)
+	}
+	diluentComposition, err := ParseGasComposition(*diluentMixFlag)
+	if err != nil {
+		return fmt.Errorf("invalid diluent mix: %w", err)
+	}
+	bottles := CylinderList{
+		{Description: "diluent", CylinderVolume: presetVolume, GasComposition: diluentComposition},
+		{Description: "oxygen", CylinderVolume: presetVolume, GasComposition: GasComposition{Oxygen: 1}},
+	}
+	for i, bottle := range []struct {
+		volume   string
+		pressure string
+	}{{*diluentVolumeFlag, *diluentPressureFlag}, {*oxygenVolumeFlag, *oxygenPressureFlag}} {
+		if bottle.volume != "" {
+			if bottles[i].CylinderVolume, err = units.ParseCylinderVolume(bottle.volume); err != nil {
+				return fmt.Errorf("invalid %s volume: %w", bottles[i].Description, err)
+			}
+		}
+		if bottles[i].Pressure, err = units.ParsePressure(bottle.pressure); err != nil {
+			return fmt.Errorf("invalid %s pressure: %w", bottles[i].Description, err)
+		}
+	}
+	if err := checkCylinders(bottles, "bottle", true, units); err != nil {
+		return err
+	}
+	banks, err := units.ParseCylinderSpecs(bankFlags, "bank")
+	if err != nil {
+		return fmt.Errorf("invalid bank: %w", err)
+	}
+	banks.SetDefaultGasComposition(GasComposition{Oxygen: 0.21, Nitrogen: 0.79})
+	if err := checkCylinders(banks, "bank", false, units); err != nil {
+		return err
+	}
+	savedBanks, err := bankStateFlags.banks(w, units)
+	if err != nil {
+		return err
+	}
+	banks = append(banks, savedBanks...)
+	if len(banks) == 0 {
+		return errors.New("at least one -bank or -use-bank is required")
+	}
+
+	pressures := make(map[string]PressureBar)
+	for _, bottle := range bottles {
+		topOff := PlanTopOff(bottle, banks, whipVolume, *cyclesFlag, fillProcess, units.AmbientPressure, gasSystem, temperature)
+		printTopOff(w, topOff, units)
+		for name, pressure := range topOff.BankPressures() {
+			pressures[name] = pressure
+			for i := range banks {
+				if banks[i].Description == name {
+					banks[i].Pressure = pressure
+				}
+			}
+		}
+	}
+	return bankStateFlags.update(w, savedBanks, pressures, units)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlanTopOff(t *testing.T) {
+	oxygen := GasComposition{Oxygen: 1}
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	bottle := Cylinder{Description: "oxygen", CylinderVolume: 2, Pressure: 50, GasComposition: oxygen}
+	banks := CylinderList{
+		{Description: "o2-high", CylinderVolume: 50, Pressure: 200, GasComposition: oxygen},
+		{Description: "o2-low", CylinderVolume: 50, Pressure: 120, GasComposition: oxygen},
+		{Description: "air", CylinderVolume: 50, Pressure: 300, GasComposition: air},
+	}
+	isothermal := PlanTopOff(bottle, banks, 0, 1, IsothermalFill, 0, IdealGas, 293.15)
+	if len(isothermal.Steps) != 2 || isothermal.Steps[0].Bank.Description != "o2-low" || isothermal.WhipGasVolume != 0 {
+		t.Fatalf("Expected the lower oxygen bank first and no air, got %+v", isothermal.Steps)
+	}
+	if isothermal.SettledPressure <= 190 || isothermal.SettledPressure >= 200 {
+		t.Errorf("Invalid isothermal top-off pressure %f", isothermal.SettledPressure)
+	}
+
+	hot := PlanTopOff(bottle, banks, 0, 1, AdiabaticFill, 0, IdealGas, 293.15)
+	if hot.SettledPressure >= isothermal.SettledPressure || hot.Steps[0].Fill.HotTemperature <= 293.15 {
+		t.Errorf("Expected a hot fill to settle lower, got %f and %f", hot.SettledPressure, isothermal.SettledPressure)
+	}
+	cycled := PlanTopOff(bottle, banks, 0, 3, AdiabaticFill, 0, IdealGas, 293.15)
+	if cycled.SettledPressure <= hot.SettledPressure || len(cycled.Steps) != 6 {
+		t.Errorf("Expected cooling down between cycles to fill higher, got %f with %d steps", cycled.SettledPressure, len(cycled.Steps))
+	}
+
+	whip := PlanTopOff(bottle, banks, 0.02, 1, IsothermalFill, 0, IdealGas, 293.15)
+	if !compareFloats(float64(whip.WhipGasVolume), 0.02*float64(isothermal.Steps[0].Fill.HotPressure+isothermal.Steps[1].Fill.HotPressure)) {
+		t.Errorf("Invalid whip gas %f", whip.WhipGasVolume)
+	}
+	if whip.BankPressures()["o2-high"] >= isothermal.BankPressures()["o2-high"] {
+		t.Errorf("Expected the whip to take gas from the banks")
+	}
+
+	var output strings.Builder
+	printTopOff(&output, whip, Metric)
+	for _, expected := range []string{"oxygen: 2.0l at 50bar, EAN100.0\n", "   from o2-low: 120bar to ", "; whip vents 2.", "% of the gas added)\n"} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("Expected %q in output %q", expected, output.String())
+		}
+	}
+}
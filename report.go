@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+)
+
+// OxygenCleanFraction is the oxygen fraction above which cylinders and whips must be oxygen clean
+const OxygenCleanFraction = 0.40
+
+// Report is an equalization scenario with its results, with values formatted in the unit system
+type Report struct {
+	GasSystem      string
+	Temperature    string
+	FillProcess    string
+	Source         []ReportCylinder
+	Destination    []ReportCylinder
+	Configurations []ReportConfiguration
+	Warnings       []string
+}
+
+// ReportCylinder is a cylinder before any transfers
+type ReportCylinder struct {
+	Description string
+	Volume      string
+	Pressure    string
+	Mix         string
+}
+
+// ReportConfiguration holds the transfers and the result of a manifold configuration
+type ReportConfiguration struct {
+	Description          string
+	Steps                []ReportStep
+	SourcePressure       string
+	SourceGasVolume      string
+	SourceMix            string
+	DestinationPressure  string
+	DestinationGasVolume string
+	DestinationMix       string
+	Improvement          string
+}
+
+// ReportStep is a single transfer between a source and a destination cylinder
+type ReportStep struct {
+	Step                 int
+	Cycle                int
+	Source               string
+	Destination          string
+	SourcePressures      string
+	DestinationPressures string
+	Transferred          string
+}
+
+// reportTemplate renders a report as a single HTML page without external resources
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Transfer whip report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #bbb; padding: 0.3em 0.7em; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+th { background: #eee; }
+.warning { background: #fff3cd; border: 1px solid #e0b000; padding: 0.5em 1em; margin-bottom: 0.5em; }
+</style>
+</head>
+<body>
+<h1>Transfer whip report</h1>
+<p>Gas system {{.GasSystem}}, temperature {{.Temperature}}, fill process {{.FillProcess}}.</p>
+{{range .Warnings}}<div class="warning">{{.}}</div>
+{{end}}
+<h2>Cylinders</h2>
+<table>
+<tr><th>Cylinder</th><th>Side</th><th>Volume</th><th>Pressure</th><th>Mix</th></tr>
+{{range .Source}}<tr><td>{{.Description}}</td><td>source</td><td>{{.Volume}}</td><td>{{.Pressure}}</td><td>{{.Mix}}</td></tr>
+{{end}}{{range .Destination}}<tr><td>{{.Description}}</td><td>destination</td><td>{{.Volume}}</td><td>{{.Pressure}}</td><td>{{.Mix}}</td></tr>
+{{end}}</table>
+<h2>Summary</h2>
+<table>
+<tr><th>Configuration</th><th>Source pressure</th><th>Source gas</th><th>Destination pressure</th><th>Destination gas</th><th>Destination mix</th><th>Improvement</th></tr>
+{{range .Configurations}}<tr><td>{{.Description}}</td><td>{{.SourcePressure}}</td><td>{{.SourceGasVolume}}</td><td>{{.DestinationPressure}}</td><td>{{.DestinationGasVolume}}</td><td>{{.DestinationMix}}</td><td>{{.Improvement}}</td></tr>
+{{end}}</table>
+{{range .Configurations}}<h2>Transfers with {{.Description}}</h2>
+<table>
+<tr><th>Step</th><th>Cycle</th><th>From</th><th>To</th><th>Source pressure</th><th>Destination pressure</th><th>Transferred</th></tr>
+{{range .Steps}}<tr><td>{{.Step}}</td><td>{{.Cycle}}</td><td>{{.Source}}</td><td>{{.Destination}}</td><td>{{.SourcePressures}}</td><td>{{.DestinationPressures}}</td><td>{{.Transferred}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+// newReport builds a report from the cylinders before any transfers, the transfer steps and the summaries
+func newReport(cylinderConfiguration CylinderConfiguration, gasSystemName string, temperature Temperature, units UnitSystem, steps []TransferStep, cylinderSummaries []CylinderSummary) Report {
+	pressureUnit, volumeUnit := units.PressureUnit(), units.VolumeUnit()
+	report := Report{
+		GasSystem:   gasSystemName,
+		Temperature: fmt.Sprintf("%.0f°%s", units.Temperature(temperature), units.TemperatureUnit()),
+		FillProcess: cylinderConfiguration.FillProcess.String(),
+	}
+	reportCylinders := func(cylinders CylinderList) []ReportCylinder {
+		var reportCylinders []ReportCylinder
+		for _, cylinder := range cylinders {
+			reportCylinders = append(reportCylinders, ReportCylinder{
+				Description: cylinder.Description,
+				Volume:      fmt.Sprintf("%.1fl", float64(cylinder.CylinderVolume)),
+				Pressure:    fmt.Sprintf("%.0f%s", units.Pressure(cylinder.Pressure), pressureUnit),
+				Mix:         cylinder.GasComposition.String(),
+			})
+		}
+		return reportCylinders
+	}
+	report.Source = reportCylinders(cylinderConfiguration.SourceCylinders)
+	report.Destination = reportCylinders(cylinderConfiguration.DestinationCylinders)
+
+	worstDestinationPressure := worstDestinationPressure(cylinderSummaries)
+	for _, cylinderSummary := range cylinderSummaries {
+		configuration := ReportConfiguration{
+			Description:          cylinderSummary.Description,
+			SourcePressure:       fmt.Sprintf("%.0f%s", units.Pressure(cylinderSummary.SourceCylinderPressure), pressureUnit),
+			SourceGasVolume:      fmt.Sprintf("%.0f%s", units.Volume(cylinderSummary.SourceCylinderGasVolume), volumeUnit),
+			SourceMix:            cylinderSummary.SourceGasComposition.String(),
+			DestinationPressure:  fmt.Sprintf("%.0f%s", units.Pressure(cylinderSummary.DestinationCylinderPressure), pressureUnit),
+			DestinationGasVolume: fmt.Sprintf("%.0f%s", units.Volume(cylinderSummary.DestinationCylinderGasVolume), volumeUnit),
+			DestinationMix:       cylinderSummary.DestinationGasComposition.String(),
+			Improvement:          fmt.Sprintf("%.2f%%", 100*(cylinderSummary.DestinationCylinderPressure-worstDestinationPressure)/worstDestinationPressure),
+		}
+		for _, step := range steps {
+			if step.Configuration != cylinderSummary.Description {
+				continue
+			}
+			configuration.Steps = append(configuration.Steps, ReportStep{
+				Step:                 step.Step,
+				Cycle:                step.Cycle,
+				Source:               step.Source,
+				Destination:          step.Destination,
+				SourcePressures:      fmt.Sprintf("%.0f → %.0f%s", units.Pressure(step.SourcePressureBefore), units.Pressure(step.SourcePressureAfter), pressureUnit),
+				DestinationPressures: fmt.Sprintf("%.0f → %.0f%s", units.Pressure(step.DestinationPressureBefore), units.Pressure(step.DestinationPressureAfter), pressureUnit),
+				Transferred:          fmt.Sprintf("%.0f%s", units.Volume(step.TransferredGasVolume), volumeUnit),
+			})
+		}
+		report.Configurations = append(report.Configurations, configuration)
+	}
+	report.Warnings = reportWarnings(cylinderConfiguration, cylinderSummaries)
+	return report
+}
+
+// reportWarnings returns warnings about mixes changing during the transfer and about oxygen cleaning
+func reportWarnings(cylinderConfiguration CylinderConfiguration, cylinderSummaries []CylinderSummary) []string {
+	var warnings []string
+	for _, cylinderSummary := range cylinderSummaries {
+		if !cylinderSummary.UniformGasComposition {
+			warnings = append(warnings, "Cylinders contain different mixes; analyze the destination mix after the transfer.")
+			break
+		}
+	}
+	allCylinders := append(append(CylinderList(nil), cylinderConfiguration.SourceCylinders...), cylinderConfiguration.DestinationCylinders...)
+	for _, cylinder := range allCylinders {
+		if cylinder.GasComposition[Oxygen] > OxygenCleanFraction {
+			warnings = append(warnings, fmt.Sprintf("%s contains %s with over %.0f%% oxygen; cylinders and the whip must be oxygen clean.", cylinder.Description, cylinder.GasComposition, OxygenCleanFraction*100))
+		}
+	}
+	if !cylinderConfiguration.FillProcess.Isothermal() {
+		warnings = append(warnings, "Pressures are settled pressures after cooling down; the destination is hotter and at a higher pressure right after filling.")
+	}
+	return warnings
+}
+
+// Write renders the report as HTML
+func (r Report) Write(w io.Writer) error {
+	return reportTemplate.Execute(w, r)
+}
+
+// writeReport writes the report to an HTML file
+func writeReport(path string, report Report) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = report.Write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
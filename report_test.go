@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "bank", CylinderVolume: 50, Pressure: 200, GasComposition: GasComposition{Oxygen: 0.5, Nitrogen: 0.5}}},
+		DestinationCylinders: CylinderList{{Description: "<stage>", CylinderVolume: 12, Pressure: 50, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+	}
+	var steps []TransferStep
+	cylinderConfiguration.OnTransferStep = func(step TransferStep) {
+		steps = append(steps, step)
+	}
+	cylinderSummaries := equalizeAllConfigurations(&bytes.Buffer{}, cylinderConfiguration, IdealGas, 293.15, Metric, false, nil)
+	report := newReport(cylinderConfiguration, "ideal", 293.15, Metric, steps, cylinderSummaries)
+	if len(report.Configurations) != 1 || len(report.Configurations[0].Steps) != 1 || report.Configurations[0].DestinationPressure != "171bar" {
+		t.Fatalf("Invalid report %+v", report)
+	}
+	if len(report.Warnings) != 2 || !strings.Contains(report.Warnings[1], "oxygen clean") {
+		t.Errorf("Expected mix and oxygen clean warnings, got %v", report.Warnings)
+	}
+	var output bytes.Buffer
+	if err := report.Write(&output); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output.String(), "&lt;stage&gt;") || !strings.Contains(output.String(), "200 → 171bar") {
+		t.Errorf("Invalid HTML:\n%s", output.String())
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseConsumption parses gas breathed per minute at the surface, such as 20l or 0.7cuft, optionally followed by
+// /min. Values without a unit use the unit system.
+func (u UnitSystem) ParseConsumption(s string) (GasVolume, error) {
+	liters, err := u.parseQuantity(strings.TrimSuffix(strings.TrimSpace(s), "/min"), u.VolumeUnit(), gasVolumeUnits, ErrInvalidVolume)
+	if err != nil {
+		return 0, err
+	}
+	return NewGasVolume(liters)
+}
+
+// ReservePolicy is how much of the gas in a cylinder may be breathed on a dive, leaving the rest in reserve
+type ReservePolicy struct {
+	Name string
+	// UsableFraction is the fraction of the gas that may be breathed, e.g. two thirds with the rule of thirds
+	UsableFraction float64
+	// ReservePressure is the pressure left in the cylinder; used instead of UsableFraction when set
+	ReservePressure PressureBar
+}
+
+// reservePolicies are the named reserve policies
+var reservePolicies = map[string]ReservePolicy{
+	// A third in, a third out and a third in reserve
+	"thirds": {Name: "thirds", UsableFraction: 2.0 / 3},
+	// A sixth in and a sixth out, for dives where the way out takes more gas than the way in
+	"sixths": {Name: "sixths", UsableFraction: 1.0 / 3},
+	"halves": {Name: "halves", UsableFraction: 1.0 / 2},
+	"all":    {Name: "all", UsableFraction: 1},
+}
+
+// ParseReservePolicy parses a named reserve policy (thirds, sixths, halves or all) or a reserve pressure such as
+// 50bar
+func (u UnitSystem) ParseReservePolicy(s string) (ReservePolicy, error) {
+	if policy, ok := reservePolicies[strings.ToLower(s)]; ok {
+		return policy, nil
+	}
+	pressure, err := u.ParsePressure(s)
+	if err != nil {
+		return ReservePolicy{}, fmt.Errorf("invalid reserve %q; must be thirds, sixths, halves, all or a pressure", s)
+	}
+	return ReservePolicy{Name: s + " reserve", ReservePressure: pressure}, nil
+}
+
+// UsableGasVolume returns the gas in the cylinder that may be breathed with the reserve policy
+func (r ReservePolicy) UsableGasVolume(cylinder Cylinder, gasSystem GasSystem, temperature Temperature) GasVolume {
+	if r.ReservePressure > 0 {
+		if cylinder.Pressure <= r.ReservePressure {
+			return 0
+		}
+		reserve := Cylinder{CylinderVolume: cylinder.CylinderVolume, Pressure: r.ReservePressure, GasComposition: cylinder.GasComposition}
+		return cylinder.GasVolume(gasSystem, temperature) - reserve.GasVolume(gasSystem, temperature)
+	}
+	return cylinder.GasVolume(gasSystem, temperature) * GasVolume(r.UsableFraction)
+}
+
+// FillGasVolume returns the gas a cylinder must hold for the gas volume to be usable with the reserve policy
+func (r ReservePolicy) FillGasVolume(gasVolume GasVolume, cylinderVolume CylinderVolume, gasComposition GasComposition, gasSystem GasSystem, temperature Temperature) GasVolume {
+	if r.ReservePressure > 0 {
+		reserve := Cylinder{CylinderVolume: cylinderVolume, Pressure: r.ReservePressure, GasComposition: gasComposition}
+		return gasVolume + reserve.GasVolume(gasSystem, temperature)
+	}
+	return gasVolume / GasVolume(r.UsableFraction)
+}
+
+// DiveRequirement is a dive at a constant depth breathing gas at a surface air consumption (SAC) rate
+type DiveRequirement struct {
+	Depth   Depth
+	Minutes float64
+	// SurfaceAirConsumption is the gas breathed per minute at the surface
+	SurfaceAirConsumption GasVolume
+}
+
+// GasVolume returns the gas the dive needs
+func (d DiveRequirement) GasVolume() GasVolume {
+	return d.SurfaceAirConsumption * GasVolume(d.Minutes*float64(d.Depth.AmbientPressure())/SurfacePressure)
+}
+
+func printDiveRequirement(w io.Writer, requirement DiveRequirement, policy ReservePolicy, cylinder Cylinder, gasSystem GasSystem, temperature Temperature, units UnitSystem) {
+	volumeUnit := units.VolumeUnit()
+	needed := requirement.GasVolume()
+	usable := policy.UsableGasVolume(cylinder, gasSystem, temperature)
+	fmt.Fprintf(w, "Dive of %.0f minutes at %.1f%s with SAC %.1f%s/min needs %.0f%s; %.0f%s usable with %s", requirement.Minutes, units.Depth(requirement.Depth), units.DepthUnit(), units.Volume(requirement.SurfaceAirConsumption), volumeUnit, units.Volume(needed), volumeUnit, units.Volume(usable), volumeUnit, policy.Name)
+	if usable < needed {
+		fmt.Fprintf(w, ", %.0f%s short\n", units.Volume(needed-usable), volumeUnit)
+		return
+	}
+	fmt.Fprintf(w, ", %.0f%s to spare\n", units.Volume(usable-needed), volumeUnit)
+}
+
+// diveRequirementFlags holds flags for comparing the resulting gas against a planned dive
+type diveRequirementFlags struct {
+	minutes *float64
+	sac     *string
+	reserve *string
+}
+
+func registerDiveRequirementFlags(fs *flag.FlagSet) *diveRequirementFlags {
+	return &diveRequirementFlags{
+		minutes: fs.Float64("dive-time", 0, "Minutes of a dive at -planned-depth; reports whether the resulting gas covers it"),
+		sac:     fs.String("sac", "20l", "Surface air consumption per minute for -dive-time, e.g. 20l or 0.7cuft"),
+		reserve: fs.String("reserve", "thirds", "Reserve for -dive-time: thirds, sixths, halves, all or a pressure left in the cylinders, e.g. 50bar"),
+	}
+}
+
+// requirement returns the dive requirement and reserve policy, or nil when no dive is planned
+func (f *diveRequirementFlags) requirement(plannedDepth Depth, units UnitSystem) (*DiveRequirement, ReservePolicy, error) {
+	if *f.minutes == 0 {
+		return nil, ReservePolicy{}, nil
+	}
+	if *f.minutes < 0 || plannedDepth <= 0 {
+		return nil, ReservePolicy{}, errors.New("invalid dive; -dive-time must be >0 and needs -planned-depth")
+	}
+	sac, err := units.ParseConsumption(*f.sac)
+	if err != nil || sac <= 0 {
+		return nil, ReservePolicy{}, fmt.Errorf("invalid SAC rate: %s", *f.sac)
+	}
+	policy, err := units.ParseReservePolicy(*f.reserve)
+	if err != nil {
+		return nil, ReservePolicy{}, err
+	}
+	return &DiveRequirement{Depth: plannedDepth, Minutes: *f.minutes, SurfaceAirConsumption: sac}, policy, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiveRequirementGasVolume(t *testing.T) {
+	requirement := DiveRequirement{Depth: 30, Minutes: 40, SurfaceAirConsumption: 20}
+	expected := 20 * 40 * (SurfacePressure + 3) / SurfacePressure
+	if volume := requirement.GasVolume(); !compareFloats(float64(volume), expected) {
+		t.Errorf("Invalid gas volume %f, expected %f", volume, expected)
+	}
+}
+
+func TestReservePolicies(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	cylinder := Cylinder{CylinderVolume: 24, Pressure: 200, GasComposition: air}
+	total := float64(cylinder.GasVolume(IdealGas, 293.15))
+	for _, test := range []struct {
+		reserve  string
+		expected float64
+	}{
+		{"thirds", total * 2 / 3},
+		{"sixths", total / 3},
+		{"halves", total / 2},
+		{"all", total},
+		{"50bar", total * 150 / 200},
+		{"250bar", 0},
+	} {
+		policy, err := Metric.ParseReservePolicy(test.reserve)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %s", test.reserve, err)
+			continue
+		}
+		if usable := policy.UsableGasVolume(cylinder, IdealGas, 293.15); !compareFloats(float64(usable), test.expected) {
+			t.Errorf("Invalid usable gas with %s: %f, expected %f", test.reserve, usable, test.expected)
+		}
+	}
+	if _, err := Metric.ParseReservePolicy("quarters"); err == nil {
+		t.Errorf("Expected an error for an unknown reserve policy")
+	}
+}
+
+func TestPrintDiveRequirement(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	cylinder := Cylinder{CylinderVolume: 24, Pressure: 200, GasComposition: air}
+	policy, _ := Metric.ParseReservePolicy("thirds")
+	var output strings.Builder
+	printDiveRequirement(&output, DiveRequirement{Depth: 30, Minutes: 40, SurfaceAirConsumption: 20}, policy, cylinder, IdealGas, 293.15, Metric)
+	if !strings.HasPrefix(output.String(), "Dive of 40 minutes at 30.0m with SAC 20.0l/min needs 3169l; 3200l usable with thirds, 31l to spare") {
+		t.Errorf("Invalid output %q", output.String())
+	}
+	output.Reset()
+	printDiveRequirement(&output, DiveRequirement{Depth: 30, Minutes: 60, SurfaceAirConsumption: 20}, policy, cylinder, IdealGas, 293.15, Metric)
+	if !strings.HasSuffix(output.String(), "short\n") {
+		t.Errorf("Expected the dive to be short of gas, got %q", output.String())
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Scenario describes source and destination cylinders, typically loaded from a JSON file
+type Scenario struct {
+	Source      []ScenarioCylinder `json:"source"`
+	Destination []ScenarioCylinder `json:"destination"`
+}
+
+// ScenarioCylinder is a single cylinder in a scenario. Volume is in liters and pressure in bar.
+type ScenarioCylinder struct {
+	Description string  `json:"description,omitempty"`
+	Volume      float64 `json:"volume"`
+	Pressure    float64 `json:"pressure"`
+	Mix         string  `json:"mix,omitempty"`
+	// WorkingPressure and TestPressure are the rated pressures in bar; zero when not known
+	WorkingPressure float64 `json:"working_pressure,omitempty"`
+	TestPressure    float64 `json:"test_pressure,omitempty"`
+	OxygenClean     bool    `json:"oxygen_clean,omitempty"`
+}
+
+// LoadScenario reads a JSON scenario file
+func LoadScenario(path string) (Scenario, error) {
+	var scenario Scenario
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return scenario, err
+	}
+	if err := json.Unmarshal(content, &scenario); err != nil {
+		return scenario, fmt.Errorf("invalid scenario file %s: %w", path, err)
+	}
+	return scenario, nil
+}
+
+// Cylinders returns source and destination cylinders described by the scenario. Pressures are in bar, relative to
+// the pressure reference of the unit system.
+func (s Scenario) Cylinders(units UnitSystem) (CylinderList, CylinderList, error) {
+	sourceCylinders, err := scenarioCylinderList(s.Source, "source", units)
+	if err != nil {
+		return nil, nil, err
+	}
+	destinationCylinders, err := scenarioCylinderList(s.Destination, "destination", units)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sourceCylinders, destinationCylinders, nil
+}
+
+// NewScenario describes source and destination cylinders as a scenario, with pressures in bar relative to the
+// pressure reference of the unit system
+func NewScenario(sourceCylinders CylinderList, destinationCylinders CylinderList, units UnitSystem) Scenario {
+	return Scenario{
+		Source:      newScenarioCylinders(sourceCylinders, units),
+		Destination: newScenarioCylinders(destinationCylinders, units),
+	}
+}
+
+func newScenarioCylinders(cylinders CylinderList, units UnitSystem) []ScenarioCylinder {
+	scenarioCylinders := make([]ScenarioCylinder, len(cylinders))
+	for i, cylinder := range cylinders {
+		scenarioCylinders[i] = ScenarioCylinder{
+			Description: cylinder.Description,
+			Volume:      float64(cylinder.CylinderVolume),
+			Pressure:    float64(cylinder.Pressure - units.AmbientPressure),
+			OxygenClean: cylinder.O2Clean,
+		}
+		if len(cylinder.GasComposition) > 0 {
+			scenarioCylinders[i].Mix = cylinder.GasComposition.String()
+		}
+		if cylinder.WorkingPressure > 0 {
+			scenarioCylinders[i].WorkingPressure = float64(cylinder.WorkingPressure - units.AmbientPressure)
+		}
+		if cylinder.TestPressure > 0 {
+			scenarioCylinders[i].TestPressure = float64(cylinder.TestPressure - units.AmbientPressure)
+		}
+	}
+	return scenarioCylinders
+}
+
+func scenarioCylinderList(scenarioCylinders []ScenarioCylinder, side string, units UnitSystem) (CylinderList, error) {
+	cylinders := make(CylinderList, len(scenarioCylinders))
+	for i, scenarioCylinder := range scenarioCylinders {
+		cylinders[i] = Cylinder{
+			Description:    scenarioCylinder.Description,
+			CylinderVolume: CylinderVolume(scenarioCylinder.Volume),
+			Pressure:       units.AbsolutePressure(PressureBar(scenarioCylinder.Pressure)),
+			O2Clean:        scenarioCylinder.OxygenClean,
+		}
+		if cylinders[i].Description == "" {
+			cylinders[i].Description = defaultCylinderDescription(side, i, len(scenarioCylinders))
+		}
+		if scenarioCylinder.WorkingPressure > 0 {
+			cylinders[i].WorkingPressure = units.AbsolutePressure(PressureBar(scenarioCylinder.WorkingPressure))
+		}
+		if scenarioCylinder.TestPressure > 0 {
+			cylinders[i].TestPressure = units.AbsolutePressure(PressureBar(scenarioCylinder.TestPressure))
+		}
+		if scenarioCylinder.Mix != "" {
+			gasComposition, err := ParseGasComposition(scenarioCylinder.Mix)
+			if err != nil {
+				return nil, err
+			}
+			cylinders[i].GasComposition = gasComposition
+		}
+	}
+	return cylinders, nil
+}
+
+func defaultCylinderDescription(side string, i int, count int) string {
+	if count == 1 {
+		return side
+	}
+	return fmt.Sprintf("%s %d", side, i+1)
+}
+
+// stringListFlag collects values of a repeated command line flag
+type stringListFlag []string
+
+func (s *stringListFlag) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringListFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// ParseCylinderSpec parses a cylinder definition such as "left=12l@232bar", "12l@50bar:21/35" or
+// "al80=77.4cuft@3000psi@2000psi" (rated capacity at service pressure, then pressure). Rated pressures follow as
+// options, e.g. "12l@50bar,wp=232bar,tp=348bar"; the service pressure of a rated capacity is the working pressure.
+// The o2clean option marks the cylinder oxygen clean. A bank preset such as "50l-300bar" stands for the volume and
+// the rated pressures, filled to the working pressure unless a pressure follows, e.g. "bank1=50l-300bar@250bar".
+// Cylinders without a mix have no gas composition set.
+func (u UnitSystem) ParseCylinderSpec(spec string, defaultDescription string) (Cylinder, error) {
+	cylinder := Cylinder{Description: defaultDescription}
+	spec, options, _ := strings.Cut(spec, ",")
+	if i := strings.Index(spec, "="); i != -1 {
+		cylinder.Description = strings.TrimSpace(spec[:i])
+		spec = spec[i+1:]
+	}
+	if i := strings.LastIndex(spec, ":"); i != -1 {
+		gasComposition, err := ParseGasComposition(spec[i+1:])
+		if err != nil {
+			return cylinder, err
+		}
+		cylinder.GasComposition = gasComposition
+		spec = spec[:i]
+	}
+	presetName, presetPressure, hasPressure := strings.Cut(spec, "@")
+	if preset, ok := lookupBankPreset(presetName); ok {
+		gasComposition := cylinder.GasComposition
+		cylinder = preset.cylinder(cylinder.Description, u)
+		cylinder.GasComposition = gasComposition
+		if hasPressure {
+			var err error
+			if cylinder.Pressure, err = u.ParsePressure(presetPressure); err != nil {
+				return cylinder, err
+			}
+		}
+		return u.parseCylinderOptions(cylinder, options)
+	}
+	parts := strings.Split(spec, "@")
+	if len(parts) == 3 {
+		// Rated capacity, e.g. "77.4cuft@3000psi@2000psi"
+		parts = []string{parts[0] + "@" + parts[1], parts[2]}
+	}
+	if len(parts) != 2 {
+		return cylinder, fmt.Errorf("invalid cylinder %q; expected volume@pressure, capacity@service-pressure@pressure or a bank preset (%s)", spec, strings.Join(bankPresetNames(), ", "))
+	}
+	var err error
+	if cylinder.CylinderVolume, err = u.ParseCylinderVolume(parts[0]); err != nil {
+		return cylinder, err
+	}
+	if cylinder.Pressure, err = u.ParsePressure(parts[1]); err != nil {
+		return cylinder, err
+	}
+	if _, servicePressure, rated := strings.Cut(parts[0], "@"); rated {
+		if cylinder.WorkingPressure, err = u.ParsePressure(servicePressure); err != nil {
+			return cylinder, err
+		}
+	}
+	return u.parseCylinderOptions(cylinder, options)
+}
+
+// parseCylinderOptions sets the options of a cylinder definition, separated by commas, on the cylinder
+func (u UnitSystem) parseCylinderOptions(cylinder Cylinder, options string) (Cylinder, error) {
+	if options == "" {
+		return cylinder, nil
+	}
+	var err error
+	for _, option := range strings.Split(options, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+		var pressure *PressureBar
+		switch name {
+		case "o2clean":
+			cylinder.O2Clean = true
+			continue
+		case "wp":
+			pressure = &cylinder.WorkingPressure
+		case "tp":
+			pressure = &cylinder.TestPressure
+		default:
+			return cylinder, fmt.Errorf("unknown cylinder option %q; must be wp, tp or o2clean", option)
+		}
+		if *pressure, err = u.ParsePressure(value); err != nil {
+			return cylinder, err
+		}
+	}
+	return cylinder, nil
+}
+
+// ParseCylinderSpecs parses a list of cylinder definitions
+func (u UnitSystem) ParseCylinderSpecs(specs []string, side string) (CylinderList, error) {
+	cylinders := make(CylinderList, len(specs))
+	for i, spec := range specs {
+		cylinder, err := u.ParseCylinderSpec(spec, defaultCylinderDescription(side, i, len(specs)))
+		if err != nil {
+			return nil, err
+		}
+		cylinders[i] = cylinder
+	}
+	return cylinders, nil
+}
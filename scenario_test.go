@@ -0,0 +1,82 @@
+package main
+
+import "testing"
+
+func TestParseCylinderSpec(t *testing.T) {
+	cylinder, err := Metric.ParseCylinderSpec("left=12l@232bar", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cylinder.Description != "left" || cylinder.CylinderVolume != 12 || cylinder.Pressure != 232 {
+		t.Errorf("Invalid cylinder %+v", cylinder)
+	}
+	cylinder, err = Metric.ParseCylinderSpec("50@200", "source 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cylinder.Description != "source 2" || cylinder.CylinderVolume != 50 || cylinder.Pressure != 200 {
+		t.Errorf("Invalid cylinder %+v", cylinder)
+	}
+	cylinder, err = Metric.ParseCylinderSpec("12l@50bar:21/35", "destination")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cylinder.GasComposition[Helium] != 0.35 || cylinder.Pressure != 50 {
+		t.Errorf("Invalid cylinder %+v", cylinder)
+	}
+	cylinder, err = Metric.ParseCylinderSpec("right=12l@50bar:32,wp=232bar,tp=348bar,o2clean", "destination")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cylinder.Description != "right" || cylinder.GasComposition[Oxygen] != 0.32 || cylinder.WorkingPressure != 232 || cylinder.TestPressure != 348 || !cylinder.O2Clean {
+		t.Errorf("Invalid cylinder %+v", cylinder)
+	}
+	if _, err := Metric.ParseCylinderSpec("50l", "source"); err == nil {
+		t.Error("Expected an error for a cylinder without pressure")
+	}
+	if _, err := Metric.ParseCylinderSpec("12l@50bar,xp=232bar", "destination"); err == nil {
+		t.Error("Expected an error for an unknown option")
+	}
+}
+
+func TestParseCylinderSpecBankPreset(t *testing.T) {
+	cylinder, err := Metric.ParseCylinderSpec("50L-300bar", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cylinder.Description != "source" || cylinder.CylinderVolume != 50 || cylinder.Pressure != 300 || cylinder.WorkingPressure != 300 || cylinder.TestPressure != 450 {
+		t.Errorf("Invalid cylinder %+v", cylinder)
+	}
+	cylinder, err = Metric.ParseCylinderSpec("quad=12x50l-200bar@150bar:32,o2clean", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cylinder.Description != "quad" || cylinder.CylinderVolume != 600 || cylinder.Pressure != 150 || cylinder.GasComposition[Oxygen] != 0.32 || !cylinder.O2Clean {
+		t.Errorf("Invalid cylinder %+v", cylinder)
+	}
+	// Gauge pressures of the preset are converted to absolute pressures
+	gauge := UnitSystem{AmbientPressure: 1}
+	if cylinder, err = gauge.ParseCylinderSpec("j", "source"); err != nil || cylinder.WorkingPressure != 138 || cylinder.Pressure != 138 {
+		t.Errorf("Invalid cylinder %+v: %v", cylinder, err)
+	}
+}
+
+func TestScenarioCylinders(t *testing.T) {
+	scenario := Scenario{
+		Source:      []ScenarioCylinder{{Volume: 50, Pressure: 200}, {Volume: 50, Pressure: 300}},
+		Destination: []ScenarioCylinder{{Description: "twin", Volume: 24, Pressure: 50}},
+	}
+	source, destination, err := scenario.Cylinders(Metric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(source) != 2 || source[1].Description != "source 2" || source[1].Pressure != 300 {
+		t.Errorf("Invalid source cylinders %+v", source)
+	}
+	if len(destination) != 1 || destination[0].Description != "twin" {
+		t.Errorf("Invalid destination cylinders %+v", destination)
+	}
+	if source.MaxPressure() != 300 {
+		t.Errorf("Invalid max pressure %f", source.MaxPressure())
+	}
+}
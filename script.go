@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TransferOperation is an operation of a transfer script: opening or closing a connection of the topology,
+// equalizing two cylinders through a whip, or taking and rolling back to snapshots of the simulation
+type TransferOperation struct {
+	// Operation is open, close, equalize, snapshot, rollback or undo
+	Operation string
+	// Names are the connection to open or close, the cylinders to equalize from and to, or the snapshot
+	Names []string
+	// Text is the operation as written in the script
+	Text string
+}
+
+// ParseTransferScript parses a transfer script such as "open isolator; equalize bank1 -> left; close isolator".
+// Operations are separated by semicolons or newlines, and # starts a comment.
+func ParseTransferScript(script string) ([]TransferOperation, error) {
+	operations, err := parseTransferOperations(script)
+	if err != nil {
+		return nil, err
+	}
+	if len(operations) == 0 {
+		return nil, errors.New("script has no operations")
+	}
+	return operations, nil
+}
+
+// parseTransferOperations parses the operations of a script, which may have none
+func parseTransferOperations(script string) ([]TransferOperation, error) {
+	var operations []TransferOperation
+	for _, line := range strings.Split(script, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		for _, text := range strings.Split(line, ";") {
+			text = strings.TrimSpace(text)
+			if text == "" {
+				continue
+			}
+			operation, rest, _ := strings.Cut(text, " ")
+			operation = strings.ToLower(operation)
+			rest = strings.TrimSpace(rest)
+			switch operation {
+			case "open", "close", "snapshot", "rollback":
+				if rest == "" {
+					if operation == "snapshot" || operation == "rollback" {
+						return nil, fmt.Errorf("%q needs a snapshot name", text)
+					}
+					return nil, fmt.Errorf("%q needs a connection to %s", text, operation)
+				}
+				operations = append(operations, TransferOperation{Operation: operation, Names: []string{rest}, Text: text})
+			case "undo":
+				if rest != "" {
+					return nil, fmt.Errorf("%q takes no arguments", text)
+				}
+				operations = append(operations, TransferOperation{Operation: operation, Text: text})
+			case "equalize":
+				from, to, ok := strings.Cut(rest, "->")
+				from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+				if !ok || from == "" || to == "" {
+					return nil, fmt.Errorf("%q must be equalize <cylinder> -> <cylinder>", text)
+				}
+				operations = append(operations, TransferOperation{Operation: operation, Names: []string{from, to}, Text: text})
+			default:
+				return nil, fmt.Errorf("unknown operation %q in %q; must be open, close, equalize, snapshot, rollback or undo", operation, text)
+			}
+		}
+	}
+	return operations, nil
+}
+
+// scriptRunner runs the operations of transfer scripts on a topology one at a time
+type scriptRunner struct {
+	state     *topologyState
+	cylinders map[string]bool
+	snapshots map[string]topologySnapshot
+	// history is the state before each operation that changed it, for undo
+	history []topologySnapshot
+}
+
+func (t Topology) newScriptRunner(cylinders CylinderList, gasSystem GasSystem, temperature Temperature) (*scriptRunner, error) {
+	state, err := t.newState(cylinders, gasSystem, temperature)
+	if err != nil {
+		return nil, err
+	}
+	runner := &scriptRunner{state: state, cylinders: make(map[string]bool), snapshots: make(map[string]topologySnapshot)}
+	// The whip attaches to a cylinder or to an outlet of its H or Y valve
+	for _, node := range t.nodes() {
+		runner.cylinders[node] = true
+	}
+	return runner, nil
+}
+
+// run runs the operation and returns the cylinders after it. Opening a connection equalizes the cylinders it joins,
+// and equalizing two cylinders connects them with a whip until they are equal, together with the cylinders open to
+// them. Rolling back restores the cylinders and connections of a named snapshot, and undo those before the last
+// operation that changed them.
+func (r *scriptRunner) run(operation TransferOperation) (TopologyStepResult, error) {
+	before := r.state.snapshot()
+	switch operation.Operation {
+	case "open", "close":
+		if _, ok := r.state.open[operation.Names[0]]; !ok {
+			return TopologyStepResult{}, fmt.Errorf("unknown connection %q in %q", operation.Names[0], operation.Text)
+		}
+		r.state.open[operation.Names[0]] = operation.Operation == "open"
+		r.history = append(r.history, before)
+		return r.state.equalize(operation.Text), nil
+	case "equalize":
+		for _, name := range operation.Names {
+			if !r.cylinders[name] {
+				return TopologyStepResult{}, fmt.Errorf("unknown cylinder or outlet %q in %q", name, operation.Text)
+			}
+		}
+		if operation.Names[0] == operation.Names[1] {
+			return TopologyStepResult{}, fmt.Errorf("%q must equalize two different cylinders", operation.Text)
+		}
+		whip := TopologyConnection{Name: "whip", Kind: "whip", From: operation.Names[0], To: operation.Names[1]}
+		r.history = append(r.history, before)
+		return r.state.equalize(operation.Text, whip), nil
+	case "snapshot":
+		r.snapshots[operation.Names[0]] = before
+	case "rollback":
+		snapshot, ok := r.snapshots[operation.Names[0]]
+		if !ok {
+			return TopologyStepResult{}, fmt.Errorf("unknown snapshot %q in %q", operation.Names[0], operation.Text)
+		}
+		r.state.restore(snapshot)
+		r.history = append(r.history, before)
+	case "undo":
+		if len(r.history) == 0 {
+			return TopologyStepResult{}, errors.New("nothing to undo")
+		}
+		r.state.restore(r.history[len(r.history)-1])
+		r.history = r.history[:len(r.history)-1]
+	}
+	return TopologyStepResult{Description: operation.Text, Cylinders: copyCylinders(r.state.cylinders)}, nil
+}
+
+// RunScript runs the operations on the topology starting from cylinders and returns the cylinders after each
+// operation
+func (t Topology) RunScript(operations []TransferOperation, cylinders CylinderList, gasSystem GasSystem, temperature Temperature) ([]TopologyStepResult, error) {
+	runner, err := t.newScriptRunner(cylinders, gasSystem, temperature)
+	if err != nil {
+		return nil, err
+	}
+	var results []TopologyStepResult
+	for _, operation := range operations {
+		result, err := runner.run(operation)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// scriptPressures returns the pressure of each cylinder, such as "bank 200bar, left 50bar"
+func scriptPressures(cylinders CylinderList, units UnitSystem) string {
+	pressures := make([]string, len(cylinders))
+	for i, cylinder := range cylinders {
+		pressures[i] = fmt.Sprintf("%s %.0f%s", cylinder.Description, units.Pressure(cylinder.Pressure), units.PressureUnit())
+	}
+	return strings.Join(pressures, ", ")
+}
+
+// interactive reads operations from in a line at a time and prints the pressures after each, to explore what
+// would have happened with another procedure by rolling back to snapshots or undoing operations
+func (r *scriptRunner) interactive(in io.Reader, out io.Writer, units UnitSystem) {
+	scanner := bufio.NewScanner(in)
+	fmt.Fprintf(out, "%s\n", scriptPressures(r.state.cylinders, units))
+	for {
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return
+		}
+		operations, err := parseTransferOperations(scanner.Text())
+		if err != nil {
+			fmt.Fprintln(out, err.Error())
+			continue
+		}
+		for _, operation := range operations {
+			result, err := r.run(operation)
+			if err != nil {
+				fmt.Fprintln(out, err.Error())
+				break
+			}
+			fmt.Fprintf(out, "%s\n", scriptPressures(result.Cylinders, units))
+		}
+	}
+}
+
+// printScriptResults prints the pressure of each cylinder after each operation of a script
+func printScriptResults(w io.Writer, cylinders CylinderList, results []TopologyStepResult, units UnitSystem, style tableStyle) {
+	var scriptTable table
+	scriptTable.addColumn("operation", 0, true)
+	for _, cylinder := range cylinders {
+		scriptTable.addColumn(cylinder.Description+" "+units.PressureUnit(), 0, false)
+	}
+	pressures := func(description string, cylinders CylinderList) {
+		cells := []string{description}
+		for _, cylinder := range cylinders {
+			cells = append(cells, fmt.Sprintf("%.0f", units.Pressure(cylinder.Pressure)))
+		}
+		scriptTable.addRow("", cells...)
+	}
+	pressures("start", cylinders)
+	for _, result := range results {
+		pressures(result.Description, result.Cylinders)
+	}
+	scriptTable.render(w, style)
+}
+
+// printScriptComparison prints the pressure and mix of each cylinder at the end of each named script
+func printScriptComparison(w io.Writer, names []string, cylinders CylinderList, results [][]TopologyStepResult, units UnitSystem, style tableStyle) {
+	var comparisonTable table
+	comparisonTable.addColumn("cylinder", 0, true)
+	comparisonTable.addColumn("start "+units.PressureUnit(), 0, false)
+	for _, name := range names {
+		comparisonTable.addColumn(name, 0, false)
+	}
+	for i, cylinder := range cylinders {
+		cells := []string{cylinder.Description, fmt.Sprintf("%.0f", units.Pressure(cylinder.Pressure))}
+		for _, scriptResults := range results {
+			end := scriptResults[len(scriptResults)-1].Cylinders[i]
+			cells = append(cells, fmt.Sprintf("%.0f %s", units.Pressure(end.Pressure), end.GasComposition))
+		}
+		comparisonTable.addRow("", cells...)
+	}
+	comparisonTable.render(w, style)
+}
+
+func scriptMain(args []string) error {
+	fs := flag.NewFlagSet("script", flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	gasFlags := registerGasCompositionFlags(fs)
+	var topologyFlag = fs.String("topology", "", "JSON topology file with the cylinders and connections the scripts operate on")
+	var scriptFlag = fs.String("e", "", "Run this script, e.g. \"open isolator; equalize bank1 -> left\", instead of script files")
+	var interactiveFlag = fs.Bool("interactive", false, "Read operations from standard input one line at a time, after any -e script, and print the pressures after each")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s script [flags] -topology <topology.json> <script file>...\n\nScripts run operations in order, separated by semicolons or newlines:\n  open <connection>, close <connection>, equalize <cylinder> -> <cylinder>\n  snapshot <name>, rollback <name>, undo\nWith several scripts, the cylinders at the end of each are compared.\n\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	gasSystem, temperature, err := flags.gasSettings(units)
+	if err != nil {
+		return err
+	}
+	gasComposition, err := gasFlags.gasComposition()
+	if err != nil {
+		return err
+	}
+	style, err := flags.parseTableStyle()
+	if err != nil {
+		return err
+	}
+	if *topologyFlag == "" || (*interactiveFlag && fs.NArg() > 0) || (!*interactiveFlag && (*scriptFlag == "") == (fs.NArg() == 0)) {
+		fs.Usage()
+		return errUsage
+	}
+	topology, err := LoadTopology(*topologyFlag)
+	if err != nil {
+		return err
+	}
+	cylinders, err := topology.CylinderList(units)
+	if err != nil {
+		return fmt.Errorf("invalid topology: %w", err)
+	}
+	if err := checkCylinders(cylinders, "cylinder", true, units); err != nil {
+		return err
+	}
+	cylinders.SetDefaultGasComposition(gasComposition)
+	names := []string{"-e"}
+	scripts := []string{*scriptFlag}
+	if *scriptFlag == "" {
+		names, scripts = nil, nil
+		for _, path := range fs.Args() {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			names = append(names, filepath.Base(path))
+			scripts = append(scripts, string(content))
+		}
+	}
+	w, err := flags.output(os.Stdout, units)
+	if err != nil {
+		return err
+	}
+	if *interactiveFlag {
+		runner, err := topology.newScriptRunner(cylinders, gasSystem, temperature)
+		if err != nil {
+			return fmt.Errorf("invalid topology: %w", err)
+		}
+		operations, err := parseTransferOperations(*scriptFlag)
+		if err != nil {
+			return fmt.Errorf("invalid script -e: %w", err)
+		}
+		for _, operation := range operations {
+			if _, err := runner.run(operation); err != nil {
+				return fmt.Errorf("invalid script -e: %w", err)
+			}
+		}
+		runner.interactive(os.Stdin, w, units)
+		return nil
+	}
+	var allResults [][]TopologyStepResult
+	for i, script := range scripts {
+		operations, err := ParseTransferScript(script)
+		if err != nil {
+			return fmt.Errorf("invalid script %s: %w", names[i], err)
+		}
+		results, err := topology.RunScript(operations, cylinders, gasSystem, temperature)
+		if err != nil {
+			return fmt.Errorf("invalid script %s: %w", names[i], err)
+		}
+		if len(scripts) > 1 {
+			fmt.Fprintf(w, "%s:\n", names[i])
+		}
+		printScriptResults(w, cylinders, results, units, style)
+		if len(scripts) > 1 {
+			fmt.Fprintln(w)
+		}
+		allResults = append(allResults, results)
+	}
+	if len(scripts) > 1 {
+		printScriptComparison(w, names, cylinders, allResults, units, style)
+	}
+	return nil
+}
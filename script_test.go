@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParseTransferScript(t *testing.T) {
+	operations, err := ParseTransferScript("open isolator; equalize bank 1 -> left\n# comment\nCLOSE isolator # done\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 3 || operations[1].Operation != "equalize" || operations[1].Names[0] != "bank 1" || operations[1].Names[1] != "left" || operations[2].Operation != "close" || operations[2].Text != "CLOSE isolator" {
+		t.Errorf("Unexpected operations %+v", operations)
+	}
+	for _, script := range []string{"", "# only a comment", "vent left", "open", "equalize left", "equalize -> left"} {
+		if _, err := ParseTransferScript(script); err == nil {
+			t.Errorf("Expected an error for %q", script)
+		}
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	var topology Topology
+	if err := json.Unmarshal([]byte(`{
+		"cylinders": [
+			{"name": "bank", "volume": 24, "pressure": 200},
+			{"name": "left", "volume": 12, "pressure": 50},
+			{"name": "right", "volume": 12, "pressure": 50}
+		],
+		"connections": [{"name": "isolator", "kind": "manifold", "from": "left", "to": "right", "open": true}]
+	}`), &topology); err != nil {
+		t.Fatal(err)
+	}
+	cylinders, err := topology.CylinderList(Metric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cylinders.SetDefaultGasComposition(GasComposition{Oxygen: 0.21, Nitrogen: 0.79})
+	operations, err := ParseTransferScript("close isolator; equalize bank -> left; open isolator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := topology.RunScript(operations, cylinders, IdealGas, 293.15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// With the isolator closed only the left cylinder fills, to 150bar, and opening the isolator shares it
+	pressures := func(result TopologyStepResult) []float64 {
+		return []float64{float64(result.Cylinders[0].Pressure), float64(result.Cylinders[1].Pressure), float64(result.Cylinders[2].Pressure)}
+	}
+	for i, expected := range [][]float64{{200, 50, 50}, {150, 150, 50}, {150, 100, 100}} {
+		for j, pressure := range pressures(results[i]) {
+			if math.Abs(pressure-expected[j]) > 1e-6 {
+				t.Errorf("Expected %.0fbar in %s after %q, got %.2fbar", expected[j], cylinders[j].Description, results[i].Description, pressure)
+			}
+		}
+	}
+	for _, script := range []string{"open valve", "equalize bank -> middle", "equalize bank -> bank"} {
+		operations, err := ParseTransferScript(script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := topology.RunScript(operations, cylinders, IdealGas, 293.15); err == nil {
+			t.Errorf("Expected an error for %q", script)
+		}
+	}
+}
+
+func TestRunScriptRollback(t *testing.T) {
+	var topology Topology
+	if err := json.Unmarshal([]byte(`{
+		"cylinders": [{"name": "bank", "volume": 12, "pressure": 200}, {"name": "left", "volume": 12, "pressure": 50}, {"name": "right", "volume": 12, "pressure": 50}],
+		"connections": [{"name": "isolator", "from": "left", "to": "right", "open": true}]
+	}`), &topology); err != nil {
+		t.Fatal(err)
+	}
+	cylinders, err := topology.CylinderList(Metric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cylinders.SetDefaultGasComposition(GasComposition{Oxygen: 0.21, Nitrogen: 0.79})
+	operations, err := ParseTransferScript("snapshot start; equalize bank -> left; rollback start; close isolator; equalize bank -> left; undo; undo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := topology.RunScript(operations, cylinders, IdealGas, 293.15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []float64{200, 100, 200, 200, 125, 200, 200} {
+		if pressure := float64(results[i].Cylinders[0].Pressure); math.Abs(pressure-expected) > 1e-6 {
+			t.Errorf("Expected the bank at %.0fbar after %q, got %.2fbar", expected, results[i].Description, pressure)
+		}
+	}
+	// The last undo reopens the isolator closed after rolling back
+	if operations, err = ParseTransferScript("close isolator; undo; equalize bank -> left"); err != nil {
+		t.Fatal(err)
+	}
+	if results, err = topology.RunScript(operations, cylinders, IdealGas, 293.15); err != nil || math.Abs(float64(results[2].Cylinders[2].Pressure)-100) > 1e-6 {
+		t.Errorf("Expected undo to reopen the isolator, got %+v (%v)", results, err)
+	}
+	for _, script := range []string{"undo", "rollback start"} {
+		operations, err := ParseTransferScript(script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := topology.RunScript(operations, cylinders, IdealGas, 293.15); err == nil {
+			t.Errorf("Expected an error for %q", script)
+		}
+	}
+}
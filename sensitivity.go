@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// sensitivityPressureStep and sensitivityTemperatureStep are the input changes used to estimate derivatives
+const (
+	sensitivityPressureStep    = PressureBar(0.5)
+	sensitivityTemperatureStep = Temperature(0.5)
+)
+
+// Sensitivity is the change of the final destination pressure of a manifold configuration per change of an input
+type Sensitivity struct {
+	Input string
+	// Temperature is set for the gas temperature; other inputs are cylinder pressures
+	Temperature bool
+	// Derivative is in bar per bar for pressures and bar per kelvin for the temperature
+	Derivative float64
+	// Error is the destination pressure error caused by the reading error of the input
+	Error PressureBar
+}
+
+// ConfigurationSensitivity holds sensitivities of the destination pressure of a manifold configuration
+type ConfigurationSensitivity struct {
+	Description   string
+	Sensitivities []Sensitivity
+}
+
+// CombinedError returns the root sum of squares of the errors of all inputs
+func (c ConfigurationSensitivity) CombinedError() PressureBar {
+	var sum float64
+	for _, sensitivity := range c.Sensitivities {
+		sum += float64(sensitivity.Error * sensitivity.Error)
+	}
+	return PressureBar(math.Sqrt(sum))
+}
+
+// Dominant returns the input causing the largest error
+func (c ConfigurationSensitivity) Dominant() Sensitivity {
+	var dominant Sensitivity
+	for _, sensitivity := range c.Sensitivities {
+		if sensitivity.Error > dominant.Error {
+			dominant = sensitivity
+		}
+	}
+	return dominant
+}
+
+// pressureSensitivities estimates with central differences how the destination pressure of each manifold
+// configuration depends on the pressure of each cylinder and on the temperature. gaugeError and temperatureError
+// are reading errors of the gauges and the thermometer.
+func pressureSensitivities(cylinderConfiguration CylinderConfiguration, gasSystem GasSystem, temperature Temperature, units UnitSystem, gaugeError PressureBar, temperatureError Temperature) []ConfigurationSensitivity {
+	destinationPressures := func(cylinderConfiguration CylinderConfiguration, temperature Temperature) []PressureBar {
+		var pressures []PressureBar
+		for _, cylinderSummary := range equalizeAllConfigurations(io.Discard, cylinderConfiguration, gasSystem, temperature, units, false, nil) {
+			pressures = append(pressures, cylinderSummary.DestinationCylinderPressure)
+		}
+		return pressures
+	}
+	cylinderSummaries := equalizeAllConfigurations(io.Discard, cylinderConfiguration, gasSystem, temperature, units, false, nil)
+	sensitivities := make([]ConfigurationSensitivity, len(cylinderSummaries))
+	for i, cylinderSummary := range cylinderSummaries {
+		sensitivities[i].Description = cylinderSummary.Description
+	}
+	addSensitivities := func(input string, isTemperature bool, lower []PressureBar, upper []PressureBar, step float64, readingError float64) {
+		for i := range sensitivities {
+			derivative := float64(upper[i]-lower[i]) / (2 * step)
+			sensitivities[i].Sensitivities = append(sensitivities[i].Sensitivities, Sensitivity{
+				Input:       input,
+				Temperature: isTemperature,
+				Derivative:  derivative,
+				Error:       PressureBar(math.Abs(derivative * readingError)),
+			})
+		}
+	}
+	for _, side := range []*CylinderList{&cylinderConfiguration.SourceCylinders, &cylinderConfiguration.DestinationCylinders} {
+		cylinders := *side
+		for i, cylinder := range cylinders {
+			changed := append(CylinderList(nil), cylinders...)
+			*side = changed
+			changed[i].Pressure = cylinder.Pressure - sensitivityPressureStep
+			lower := destinationPressures(cylinderConfiguration, temperature)
+			changed[i].Pressure = cylinder.Pressure + sensitivityPressureStep
+			upper := destinationPressures(cylinderConfiguration, temperature)
+			addSensitivities(cylinder.Description+" pressure", false, lower, upper, float64(sensitivityPressureStep), float64(gaugeError))
+		}
+		*side = cylinders
+	}
+	lower := destinationPressures(cylinderConfiguration, temperature-sensitivityTemperatureStep)
+	upper := destinationPressures(cylinderConfiguration, temperature+sensitivityTemperatureStep)
+	addSensitivities("temperature", true, lower, upper, float64(sensitivityTemperatureStep), float64(temperatureError))
+	return sensitivities
+}
+
+// printSensitivities writes the sensitivities of each configuration and the input dominating the error
+func printSensitivities(w io.Writer, sensitivities []ConfigurationSensitivity, units UnitSystem) {
+	pressureUnit := units.PressureUnit()
+	for _, configuration := range sensitivities {
+		fmt.Fprintf(w, "Sensitivity of destination pressure, %s:\n", configuration.Description)
+		for _, sensitivity := range configuration.Sensitivities {
+			derivative := fmt.Sprintf("%.3f %s/%s", sensitivity.Derivative, pressureUnit, pressureUnit)
+			if sensitivity.Temperature {
+				// Temperature derivatives are per kelvin; a fahrenheit degree is 5/9 kelvin
+				perDegree := units.PressureDifference(PressureBar(sensitivity.Derivative))
+				if units.Imperial {
+					perDegree *= 5.0 / 9
+				}
+				derivative = fmt.Sprintf("%.3f %s/°%s", perDegree, pressureUnit, units.TemperatureUnit())
+			}
+			fmt.Fprintf(w, "  %-30s %16s  ±%.1f%s\n", sensitivity.Input, derivative, units.PressureDifference(sensitivity.Error), pressureUnit)
+		}
+		dominant := configuration.Dominant()
+		fmt.Fprintf(w, "  Combined error ±%.1f%s; %s dominates\n", units.PressureDifference(configuration.CombinedError()), pressureUnit, dominant.Input)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestPressureSensitivities(t *testing.T) {
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "source", CylinderVolume: 12, Pressure: 232, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+		DestinationCylinders: CylinderList{{Description: "destination", CylinderVolume: 12, Pressure: 80, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+	}
+	sensitivities := pressureSensitivities(cylinderConfiguration, IdealGas, 293.15, Metric, 2, 2)
+	if len(sensitivities) != 1 || len(sensitivities[0].Sensitivities) != 3 {
+		t.Fatalf("Invalid sensitivities %+v", sensitivities)
+	}
+	// With equal volumes of ideal gas the destination ends at the average of the pressures
+	for i, expected := range []float64{0.5, 0.5, 0} {
+		if sensitivity := sensitivities[0].Sensitivities[i]; !compareFloats(sensitivity.Derivative, expected) {
+			t.Errorf("Expected %s derivative %f, got %f", sensitivity.Input, expected, sensitivity.Derivative)
+		}
+	}
+	if !compareFloats(float64(sensitivities[0].CombinedError()), math.Sqrt2) {
+		t.Errorf("Invalid combined error %f", sensitivities[0].CombinedError())
+	}
+	if cylinderConfiguration.SourceCylinders[0].Pressure != 232 || cylinderConfiguration.DestinationCylinders[0].Pressure != 80 {
+		t.Error("Expected cylinders to be unchanged")
+	}
+
+	cylinderConfiguration.SourceCylinders[0].CylinderVolume = 50
+	sensitivities = pressureSensitivities(cylinderConfiguration, VanDerWaals, 293.15, Metric, 2, 2)
+	if dominant := sensitivities[0].Dominant(); dominant.Input != "source pressure" {
+		t.Errorf("Expected the source gauge to dominate, got %+v", dominant)
+	}
+	if sensitivities[0].Sensitivities[2].Derivative <= 0 {
+		t.Errorf("Expected destination pressure to increase with temperature, got %+v", sensitivities[0].Sensitivities[2])
+	}
+	var output bytes.Buffer
+	printSensitivities(&output, sensitivities, Metric)
+	if !strings.Contains(output.String(), "source pressure dominates") {
+		t.Errorf("Invalid output:\n%s", output.String())
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+)
+
+// serialNumber matches a reading with an optional unit suffix
+const serialNumber = `[-+]?[0-9]*\.?[0-9]+(?:[eE][-+]?[0-9]+)?(?:\s*[a-zA-Z]+)?`
+
+// serialProtocols are line formats of common pressure transducers, as regular expressions with "channel" and
+// "value" groups. Lines without a channel are readings of channel 1.
+var serialProtocols = map[string]string{
+	"value": `^\s*(?P<value>` + serialNumber + `)\s*$`,
+	"csv":   `^\s*(?P<channel>[^,;]+?)\s*[,;]\s*(?P<value>` + serialNumber + `)\s*$`,
+}
+
+// serialReading is a single reading of a transducer channel
+type serialReading struct {
+	channel string
+	value   string
+}
+
+// parseSerialProtocol returns the pattern for a protocol name, or compiles a custom regular expression
+func parseSerialProtocol(protocol string) (*regexp.Regexp, error) {
+	if pattern, ok := serialProtocols[protocol]; ok {
+		protocol = pattern
+	}
+	pattern, err := regexp.Compile(protocol)
+	if err != nil {
+		return nil, fmt.Errorf("invalid protocol; must be value, csv or a regular expression: %w", err)
+	}
+	if pattern.SubexpIndex("value") == -1 {
+		return nil, fmt.Errorf("protocol %q has no (?P<value>...) group", protocol)
+	}
+	return pattern, nil
+}
+
+// parseSerialLine returns the reading on a line; ok is false for lines not matching the pattern
+func parseSerialLine(pattern *regexp.Regexp, line string) (serialReading, bool) {
+	match := pattern.FindStringSubmatch(line)
+	if match == nil {
+		return serialReading{}, false
+	}
+	reading := serialReading{channel: "1", value: match[pattern.SubexpIndex("value")]}
+	if i := pattern.SubexpIndex("channel"); i != -1 && match[i] != "" {
+		reading.channel = match[i]
+	}
+	return reading, true
+}
+
+// scanSerialLines splits input to lines ending with a carriage return, a newline or both
+func scanSerialLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, "\r\n"); i != -1 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// runSerialPanel updates the panel model from transducer lines, writing the prediction whenever it changes
+func runSerialPanel(in io.Reader, out io.Writer, model *panelModel, pattern *regexp.Regexp, temperatureChannel string) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Split(scanSerialLines)
+	var previous string
+	for scanner.Scan() {
+		reading, ok := parseSerialLine(pattern, scanner.Text())
+		if !ok {
+			continue
+		}
+		var err error
+		if reading.channel == temperatureChannel {
+			err = model.SetTemperature(reading.value)
+		} else {
+			_, err = model.SetPressure(reading.channel, reading.value)
+		}
+		if err != nil {
+			fmt.Fprintf(out, "Invalid reading %q from channel %s: %s\n", reading.value, reading.channel, err)
+			continue
+		}
+		if !model.Ready() {
+			continue
+		}
+		var current bytes.Buffer
+		if prediction, err := model.Predict(); err != nil {
+			fmt.Fprintln(&current, err.Error())
+		} else {
+			model.printPrediction(&current, prediction)
+		}
+		if current.String() != previous {
+			out.Write(current.Bytes())
+			previous = current.String()
+		}
+	}
+	return scanner.Err()
+}
+
+func serialMain(args []string) error {
+	fs := flag.NewFlagSet("serial", flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	gasFlags := registerGasCompositionFlags(fs)
+	var portFlag = fs.String("port", "/dev/ttyUSB0", "Serial port of the pressure transducer; - reads standard input")
+	var baudFlag = fs.Int("baud", 9600, "Serial port speed; 0 leaves port settings unchanged")
+	var protocolFlag = fs.String("protocol", "value", "Line format: value (a pressure per line), csv (channel,pressure) or a regular expression with (?P<channel>...) and (?P<value>...) groups")
+	var temperatureChannelFlag = fs.String("temperature-channel", "", "Channel reporting the gas temperature; -temperature is used without one")
+	var sourceFlags, destinationFlags stringListFlag
+	fs.Var(&sourceFlags, "source", "Source cylinder as [name=]volume@channel[:mix], e.g. bank=50l@1, or volume@pressure without a transducer; repeat for multiple cylinders")
+	fs.Var(&destinationFlags, "destination", "Destination cylinder as [name=]volume@channel[:mix], e.g. 12l@2, or volume@pressure without a transducer; repeat for multiple cylinders")
+	fs.Parse(args)
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	w, err := flags.output(os.Stdout, units)
+	if err != nil {
+		return err
+	}
+	gasSystem, temperature, err := flags.gasSettings(units)
+	if err != nil {
+		return err
+	}
+	gasComposition, err := gasFlags.gasComposition()
+	if err != nil {
+		return err
+	}
+	pattern, err := parseSerialProtocol(*protocolFlag)
+	if err != nil {
+		return err
+	}
+	if len(sourceFlags) == 0 || len(destinationFlags) == 0 {
+		return errors.New("at least one -source and -destination is required")
+	}
+	sourceCylinders, sourceChannels, err := parsePanelCylinderSpecs(units, sourceFlags, "source")
+	if err != nil {
+		return fmt.Errorf("invalid source cylinder: %w", err)
+	}
+	destinationCylinders, destinationChannels, err := parsePanelCylinderSpecs(units, destinationFlags, "destination")
+	if err != nil {
+		return fmt.Errorf("invalid destination cylinder: %w", err)
+	}
+	model := newPanelModel(units, gasSystem, temperature, sourceCylinders, sourceChannels, destinationCylinders, destinationChannels, gasComposition)
+
+	in := os.Stdin
+	if *portFlag != "-" {
+		if in, err = os.OpenFile(*portFlag, os.O_RDONLY|os.O_SYNC, 0); err != nil {
+			return fmt.Errorf("unable to open serial port: %w", err)
+		}
+		defer in.Close()
+		if *baudFlag != 0 {
+			if err := configureSerialPort(int(in.Fd()), *baudFlag); err != nil {
+				return fmt.Errorf("unable to configure serial port: %w", err)
+			}
+		}
+	}
+	return runSerialPanel(in, w, model, pattern, *temperatureChannelFlag)
+}
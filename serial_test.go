@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseSerialLine(t *testing.T) {
+	tests := []struct {
+		protocol string
+		line     string
+		channel  string
+		value    string
+		ok       bool
+	}{
+		{"value", " 231.5\r", "1", "231.5", true},
+		{"value", "3350psi", "1", "3350psi", true},
+		{"value", "ERR", "", "", false},
+		{"csv", "2, 80.0 bar", "2", "80.0 bar", true},
+		{"csv", "temp;18.5C", "temp", "18.5C", true},
+		{`^P(?P<channel>\d)=(?P<value>[0-9.]+)$`, "P3=199.9", "3", "199.9", true},
+	}
+	for _, test := range tests {
+		pattern, err := parseSerialProtocol(test.protocol)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reading, ok := parseSerialLine(pattern, test.line)
+		if ok != test.ok || reading.channel != test.channel || reading.value != test.value {
+			t.Errorf("%s %q: expected %q %q %t, got %q %q %t", test.protocol, test.line, test.channel, test.value, test.ok, reading.channel, reading.value, ok)
+		}
+	}
+	if _, err := parseSerialProtocol(`^(?P<channel>\d)$`); err == nil {
+		t.Error("Expected an error for a protocol without a value group")
+	}
+}
+
+func TestRunSerialPanel(t *testing.T) {
+	units := UnitSystem{AmbientPressure: SurfacePressure}
+	sourceCylinders, sourceChannels, _ := parsePanelCylinderSpecs(units, []string{"12l@1"}, "source")
+	destinationCylinders, destinationChannels, _ := parsePanelCylinderSpecs(units, []string{"12l@80bar"}, "destination")
+	model := newPanelModel(units, IdealGas, 293.15, sourceCylinders, sourceChannels, destinationCylinders, destinationChannels, GasComposition{Oxygen: 0.21, Nitrogen: 0.79})
+	pattern, _ := parseSerialProtocol("value")
+	var out bytes.Buffer
+	if err := runSerialPanel(strings.NewReader("232\r\n232\r\nnoise\r\n242\r\n"), &out, model, pattern, ""); err != nil {
+		t.Fatal(err)
+	}
+	expected := "If equalized now, destination ends at: all manifolds open 156bar\nIf equalized now, destination ends at: all manifolds open 161bar\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
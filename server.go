@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// maxRequestSize limits the size of request bodies accepted by the server
+const maxRequestSize = 1 << 20
+
+// EqualizeRequest is the body of POST /equalize: a scenario, with a default mix for cylinders without one.
+// Volumes are in liters and pressures in bar.
+type EqualizeRequest struct {
+	Scenario
+	Mix string `json:"mix,omitempty"`
+}
+
+// EqualizeResponse holds a summary for each manifold configuration
+type EqualizeResponse struct {
+	Summaries []SummaryResponse `json:"summaries"`
+}
+
+// SummaryResponse is the result of a single manifold configuration. Gas volumes are in liters and pressures in bar.
+type SummaryResponse struct {
+	Description          string  `json:"description"`
+	SourcePressure       float64 `json:"source_pressure"`
+	SourceGasVolume      float64 `json:"source_gas_volume"`
+	SourceMix            string  `json:"source_mix"`
+	DestinationPressure  float64 `json:"destination_pressure"`
+	DestinationGasVolume float64 `json:"destination_gas_volume"`
+	DestinationMix       string  `json:"destination_mix"`
+	ImprovementPercent   float64 `json:"improvement_percent"`
+}
+
+// BlendRequest is the body of POST /blend. Volumes are in liters and pressures in bar.
+type BlendRequest struct {
+	Target         string  `json:"target"`
+	TargetPressure float64 `json:"target_pressure"`
+	CylinderVolume float64 `json:"cylinder_volume"`
+	TopUp          string  `json:"top_up,omitempty"`
+	StartPressure  float64 `json:"start_pressure,omitempty"`
+	StartMix       string  `json:"start_mix,omitempty"`
+}
+
+// BlendResponse is a blend plan. Gas volumes are in liters and pressures in bar.
+type BlendResponse struct {
+	Target          string              `json:"target"`
+	TargetPressure  float64             `json:"target_pressure"`
+	DrainRequired   bool                `json:"drain_required"`
+	DrainToPressure float64             `json:"drain_to_pressure,omitempty"`
+	Steps           []BlendStepResponse `json:"steps"`
+}
+
+// BlendStepResponse is a single gas addition of a blend plan
+type BlendStepResponse struct {
+	Description    string  `json:"description"`
+	Mix            string  `json:"mix"`
+	AddedPressure  float64 `json:"added_pressure"`
+	FillToPressure float64 `json:"fill_to_pressure"`
+	AddedGasVolume float64 `json:"added_gas_volume"`
+}
+
+// ErrorResponse is returned with all failed requests
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// server handles calculator HTTP requests. Request and response pressures use the pressure reference of units,
+// always in bar.
+type server struct {
+	gasSystem   GasSystem
+	temperature Temperature
+	units       UnitSystem
+	// observer, if set, is notified of the transfers of equalizeCylinders
+	observer TransferObserver
+}
+
+// defaultServer uses the command line defaults: Van der Waals at 20°C, gauge pressures at sea level
+var defaultServer = server{gasSystem: VanDerWaals, temperature: ZeroCelsius + 20, units: UnitSystem{AmbientPressure: SurfacePressure}}
+
+func newServer(gasSystem GasSystem, temperature Temperature, units UnitSystem) http.Handler {
+	s := server{gasSystem: gasSystem, temperature: temperature, units: UnitSystem{AmbientPressure: units.AmbientPressure}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/equalize", postOnly(s.handleEqualize))
+	mux.HandleFunc("/blend", postOnly(s.handleBlend))
+	mux.HandleFunc("/openapi.json", handleOpenAPI)
+	mux.HandleFunc("/whip.Whip/Equalize", postOnly(s.handleGRPCEqualize))
+	return mux
+}
+
+// postOnly rejects requests other than POST
+func postOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSON(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// decodeRequest reads a JSON request body, writing an error response on failure
+func decodeRequest(w http.ResponseWriter, r *http.Request, value interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid request: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+// Equalize returns equalization summaries for the request
+func (s server) Equalize(request EqualizeRequest) (EqualizeResponse, error) {
+	return s.equalizeRequest(request, nil)
+}
+
+// equalizeRequest returns equalization summaries for the request, calling onTransferStep after each transfer when
+// set
+func (s server) equalizeRequest(request EqualizeRequest, onTransferStep func(TransferStep)) (EqualizeResponse, error) {
+	gasComposition := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	if request.Mix != "" {
+		var err error
+		if gasComposition, err = ParseGasComposition(request.Mix); err != nil {
+			return EqualizeResponse{}, err
+		}
+	}
+	sourceCylinders, destinationCylinders, err := request.Cylinders(s.units)
+	if err != nil {
+		return EqualizeResponse{}, err
+	}
+	cylinderSummaries, err := s.equalizeCylinders(sourceCylinders, destinationCylinders, gasComposition, onTransferStep)
+	if err != nil {
+		return EqualizeResponse{}, err
+	}
+	return s.equalizeResponse(cylinderSummaries), nil
+}
+
+// equalizeResponse converts summaries to a response
+func (s server) equalizeResponse(cylinderSummaries []CylinderSummary) EqualizeResponse {
+	worstDestinationPressure := worstDestinationPressure(cylinderSummaries)
+	response := EqualizeResponse{Summaries: []SummaryResponse{}}
+	for _, cylinderSummary := range cylinderSummaries {
+		response.Summaries = append(response.Summaries, SummaryResponse{
+			Description:          cylinderSummary.Description,
+			SourcePressure:       s.units.Pressure(cylinderSummary.SourceCylinderPressure),
+			SourceGasVolume:      float64(cylinderSummary.SourceCylinderGasVolume),
+			SourceMix:            cylinderSummary.SourceGasComposition.String(),
+			DestinationPressure:  s.units.Pressure(cylinderSummary.DestinationCylinderPressure),
+			DestinationGasVolume: float64(cylinderSummary.DestinationCylinderGasVolume),
+			DestinationMix:       cylinderSummary.DestinationGasComposition.String(),
+			ImprovementPercent:   float64(100 * (cylinderSummary.DestinationCylinderPressure - worstDestinationPressure) / worstDestinationPressure),
+		})
+	}
+	return response
+}
+
+// equalizeCylinders checks the cylinders and equalizes them with each manifold configuration. Cylinders without a
+// mix get gasComposition.
+func (s server) equalizeCylinders(sourceCylinders CylinderList, destinationCylinders CylinderList, gasComposition GasComposition, onTransferStep func(TransferStep)) ([]CylinderSummary, error) {
+	if len(sourceCylinders) == 0 || len(destinationCylinders) == 0 {
+		return nil, errors.New("at least one source and destination cylinder is required")
+	}
+	if err := checkCylinders(sourceCylinders, "source", false, s.units); err != nil {
+		return nil, err
+	}
+	if err := checkCylinders(destinationCylinders, "destination", true, s.units); err != nil {
+		return nil, err
+	}
+	sourceCylinders.SetDefaultGasComposition(gasComposition)
+	destinationCylinders.SetDefaultGasComposition(gasComposition)
+	if sourceCylinders.MaxPressure() < destinationCylinders.MaxPressure() {
+		return nil, errors.New("source pressure must be higher than destination pressure")
+	}
+	cylinderConfiguration := CylinderConfiguration{SourceCylinders: sourceCylinders, DestinationCylinders: destinationCylinders, OnTransferStep: onTransferStep, Observer: s.observer}
+	return equalizeAllConfigurations(io.Discard, cylinderConfiguration, s.gasSystem, s.temperature, s.units, false, nil), nil
+}
+
+func (s server) handleEqualize(w http.ResponseWriter, r *http.Request) {
+	var request EqualizeRequest
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+	response, err := s.Equalize(request)
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Blend returns a partial pressure blend plan for the request
+func (s server) Blend(request BlendRequest) (BlendResponse, error) {
+	targetComposition, err := ParseGasComposition(request.Target)
+	if err != nil {
+		return BlendResponse{}, fmt.Errorf("invalid target mix: %w", err)
+	}
+	topUpComposition := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	if request.TopUp != "" {
+		if topUpComposition, err = ParseGasComposition(request.TopUp); err != nil {
+			return BlendResponse{}, fmt.Errorf("invalid top-up mix: %w", err)
+		}
+	}
+	startComposition := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	if request.StartMix != "" {
+		if startComposition, err = ParseGasComposition(request.StartMix); err != nil {
+			return BlendResponse{}, fmt.Errorf("invalid start mix: %w", err)
+		}
+	}
+	start := Cylinder{CylinderVolume: CylinderVolume(request.CylinderVolume), Pressure: s.units.AbsolutePressure(PressureBar(request.StartPressure)), GasComposition: startComposition}
+	plan, err := s.planBlend(start, targetComposition, s.units.AbsolutePressure(PressureBar(request.TargetPressure)), topUpComposition)
+	if err != nil {
+		return BlendResponse{}, err
+	}
+	response := BlendResponse{
+		Target:         plan.TargetComposition.String(),
+		TargetPressure: s.units.Pressure(plan.TargetPressure),
+		DrainRequired:  plan.DrainRequired,
+		Steps:          []BlendStepResponse{},
+	}
+	if plan.DrainRequired {
+		response.DrainToPressure = s.units.Pressure(plan.DrainToPressure)
+	}
+	for _, step := range plan.Steps {
+		response.Steps = append(response.Steps, BlendStepResponse{
+			Description:    step.Description,
+			Mix:            step.GasComposition.String(),
+			AddedPressure:  s.units.PressureDifference(step.AddedPressure),
+			FillToPressure: s.units.Pressure(step.FillToPressure),
+			AddedGasVolume: float64(step.AddedGasVolume),
+		})
+	}
+	return response, nil
+}
+
+// planBlend checks the cylinder and pressures and plans a partial pressure blend
+func (s server) planBlend(start Cylinder, targetComposition GasComposition, targetPressure PressureBar, topUpComposition GasComposition) (BlendPlan, error) {
+	if err := checkCylinders(CylinderList{{CylinderVolume: start.CylinderVolume, Pressure: targetPressure}}, "target", false, s.units); err != nil {
+		return BlendPlan{}, err
+	}
+	if err := checkCylinders(CylinderList{start}, "start", true, s.units); err != nil {
+		return BlendPlan{}, err
+	}
+	if start.Pressure > targetPressure {
+		return BlendPlan{}, errors.New("start pressure must not exceed target pressure")
+	}
+	return PlanBlend(start, targetComposition, targetPressure, topUpComposition, s.units.AmbientPressure, s.gasSystem, s.temperature)
+}
+
+func (s server) handleBlend(w http.ResponseWriter, r *http.Request) {
+	var request BlendRequest
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+	response, err := s.Blend(request)
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// EqualizeJSON runs Equalize for a JSON request in the HTTP API format and returns a JSON response. Errors are
+// returned as ErrorResponse.
+func (s server) EqualizeJSON(content []byte) []byte {
+	var request EqualizeRequest
+	if err := json.Unmarshal(content, &request); err != nil {
+		return marshalResponse(nil, err)
+	}
+	return marshalResponse(s.Equalize(request))
+}
+
+// BlendJSON runs Blend for a JSON request in the HTTP API format and returns a JSON response. Errors are returned
+// as ErrorResponse.
+func (s server) BlendJSON(content []byte) []byte {
+	var request BlendRequest
+	if err := json.Unmarshal(content, &request); err != nil {
+		return marshalResponse(nil, err)
+	}
+	return marshalResponse(s.Blend(request))
+}
+
+func marshalResponse(value interface{}, err error) []byte {
+	if err != nil {
+		value = ErrorResponse{Error: err.Error()}
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		content, _ = json.Marshal(ErrorResponse{Error: err.Error()})
+	}
+	return content
+}
+
+func serveMain(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	var listenFlag = fs.String("listen", "localhost:8080", "Address to listen on")
+	fs.Parse(args)
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	gasSystem, temperature, err := flags.gasSettings(units)
+	if err != nil {
+		return err
+	}
+	// gRPC clients connect with HTTP/2 without TLS
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	httpServer := &http.Server{Addr: *listenFlag, Handler: logRequests(newServer(gasSystem, temperature, units)), Protocols: &protocols}
+	slog.Info("listening", "address", *listenFlag)
+	return httpServer.ListenAndServe()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServerEqualize(t *testing.T) {
+	handler := newServer(IdealGas, 293.15, Metric)
+	body := `{"source": [{"volume": 12, "pressure": 232}], "destination": [{"volume": 12, "pressure": 80}], "mix": "32"}`
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/equalize", strings.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Unexpected status %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response EqualizeResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Summaries) != 1 || !compareFloats(response.Summaries[0].DestinationPressure, 156) || response.Summaries[0].DestinationMix != "EAN32.0" {
+		t.Errorf("Invalid response %+v", response)
+	}
+}
+
+func TestServerBlend(t *testing.T) {
+	handler := newServer(IdealGas, 293.15, Metric)
+	body := `{"target": "32", "target_pressure": 200, "cylinder_volume": 12}`
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/blend", strings.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Unexpected status %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response BlendResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Steps) == 0 || response.Steps[0].Description != "oxygen" || !compareFloats(response.Steps[1].FillToPressure, 200) {
+		t.Errorf("Invalid response %+v", response)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	handler := newServer(IdealGas, 293.15, Metric)
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{http.MethodGet, "/equalize", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/equalize", "{", http.StatusBadRequest},
+		{http.MethodPost, "/equalize", `{"source": [{"volume": 12, "pressure": 50}], "destination": [{"volume": 12, "pressure": 80}]}`, http.StatusUnprocessableEntity},
+		{http.MethodPost, "/blend", `{"target": "nonsense", "target_pressure": 200, "cylinder_volume": 12}`, http.StatusUnprocessableEntity},
+	}
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(test.method, test.path, strings.NewReader(test.body)))
+		if recorder.Code != test.status {
+			t.Errorf("%s %s %s: expected status %d, got %d", test.method, test.path, test.body, test.status, recorder.Code)
+		}
+	}
+}
+
+func TestServerJSON(t *testing.T) {
+	s := server{gasSystem: IdealGas, temperature: 293.15}
+	var response BlendResponse
+	if err := json.Unmarshal(s.BlendJSON([]byte(`{"target": "32", "target_pressure": 200, "cylinder_volume": 12}`)), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Steps) != 2 {
+		t.Errorf("Invalid response %+v", response)
+	}
+	var errorResponse ErrorResponse
+	if err := json.Unmarshal(s.EqualizeJSON([]byte(`{"source": []}`)), &errorResponse); err != nil {
+		t.Fatal(err)
+	}
+	if errorResponse.Error != "at least one source and destination cylinder is required" {
+		t.Errorf("Unexpected error %q", errorResponse.Error)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// slowFillSteps is the number of time steps used when integrating the temperature of a throttled fill
+const slowFillSteps = 1000
+
+// maxFillRate is the highest fill rate in bar per minute searched for by RecommendedFillRate
+const maxFillRate PressureBar = 1000
+
+// SlowFill is a fill throttled to a fixed rate, with heat flowing from the gas through the cylinder wall
+type SlowFill struct {
+	// Rate is the pressure rise per minute
+	Rate PressureBar
+	// HeatTransfer is the heat transfer coefficient between the gas and the surroundings in W/K
+	HeatTransfer float64
+}
+
+// SlowFillResult describes the temperature of the destination during a throttled fill
+type SlowFillResult struct {
+	Minutes         float64
+	PeakTemperature Temperature
+	// HotPressure is the destination pressure at the end of the fill, before cooling down
+	HotPressure PressureBar
+}
+
+// Simulate fills the destination to targetPressure with gas of the composition at the fill rate. The gas enters at
+// ambient temperature; its enthalpy heats the destination, which loses heat in proportion to the temperature
+// difference. The target is the pressure after cooling down to ambient temperature.
+func (f SlowFill) Simulate(destination Cylinder, targetPressure PressureBar, gasComposition GasComposition, gasSystem GasSystem, temperature Temperature) SlowFillResult {
+	result := SlowFillResult{PeakTemperature: temperature, HotPressure: destination.Pressure}
+	if targetPressure <= destination.Pressure || f.Rate <= 0 {
+		return result
+	}
+	result.Minutes = float64((targetPressure - destination.Pressure) / f.Rate)
+	startMoles := gasSystem.Moles(destination.CylinderVolume, destination.Pressure, temperature, destination.GasComposition)
+	endMoles := gasSystem.Moles(destination.CylinderVolume, targetPressure, temperature, destination.GasComposition)
+	seconds := result.Minutes * 60 / slowFillSteps
+	molesPerSecond := float64(endMoles-startMoles) / (result.Minutes * 60)
+	inletHeatCapacity := gasComposition.HeatCapacity()
+
+	moles := float64(startMoles)
+	gasTemperature := float64(temperature)
+	mix := destination.GasComposition
+	for step := 0; step < slowFillSteps; step++ {
+		mix = make(GasComposition)
+		for gasType, fraction := range destination.GasComposition {
+			mix[gasType] += fraction * float64(startMoles) / moles
+		}
+		for gasType, fraction := range gasComposition {
+			mix[gasType] += fraction * (moles - float64(startMoles)) / moles
+		}
+		heatCapacity := mix.HeatCapacity() - MolarGasConstant
+		// n cv dT/dt = dn/dt (cp,in T0 - cv T) - hA (T - T0), stepped implicitly to stay stable at slow rates
+		gasTemperature = (moles*heatCapacity*gasTemperature + seconds*(molesPerSecond*inletHeatCapacity+f.HeatTransfer)*float64(temperature)) / (moles*heatCapacity + seconds*(molesPerSecond*heatCapacity+f.HeatTransfer))
+		moles += molesPerSecond * seconds
+		if Temperature(gasTemperature) > result.PeakTemperature {
+			result.PeakTemperature = Temperature(gasTemperature)
+		}
+	}
+	result.HotPressure = gasSystem.Pressure(destination.CylinderVolume, MoleCount(moles), Temperature(gasTemperature), mix)
+	return result
+}
+
+// RecommendedFillRate returns the highest fill rate in bar per minute keeping the destination below the temperature
+// limit, or maxFillRate when even that stays below it
+func (f SlowFill) RecommendedFillRate(destination Cylinder, targetPressure PressureBar, gasComposition GasComposition, temperatureLimit Temperature, gasSystem GasSystem, temperature Temperature) PressureBar {
+	peakTemperature := func(rate PressureBar) Temperature {
+		f.Rate = rate
+		return f.Simulate(destination, targetPressure, gasComposition, gasSystem, temperature).PeakTemperature
+	}
+	if peakTemperature(maxFillRate) <= temperatureLimit {
+		return maxFillRate
+	}
+	low, high := PressureBar(0), maxFillRate
+	for high-low > solverTolerance {
+		middle := (low + high) / 2
+		if peakTemperature(middle) <= temperatureLimit {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+	return low
+}
+
+func printSlowFill(w io.Writer, slowFill SlowFill, result SlowFillResult, recommendedRate PressureBar, temperatureLimit Temperature, units UnitSystem) {
+	pressureUnit, temperatureUnit := units.PressureUnit(), units.TemperatureUnit()
+	fmt.Fprintf(w, "Fill at %.0f%s/min: %.1f minutes, destination peaks at %.0f°%s (%.0f%s hot)\n", units.PressureDifference(slowFill.Rate), pressureUnit, result.Minutes, units.Temperature(result.PeakTemperature), temperatureUnit, units.Pressure(result.HotPressure), pressureUnit)
+	if recommendedRate >= maxFillRate {
+		fmt.Fprintf(w, "Any fill rate stays below %.0f°%s\n", units.Temperature(temperatureLimit), temperatureUnit)
+		return
+	}
+	fmt.Fprintf(w, "Fill at most %.0f%s/min to stay below %.0f°%s\n", units.PressureDifference(recommendedRate), pressureUnit, units.Temperature(temperatureLimit), temperatureUnit)
+}
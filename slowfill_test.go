@@ -0,0 +1,41 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSlowFillAdiabatic(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	destination := Cylinder{CylinderVolume: 12, Pressure: 50, GasComposition: air}
+	result := SlowFill{Rate: 20}.Simulate(destination, 200, air, IdealGas, 293.15)
+	if !compareFloats(result.Minutes, 7.5) {
+		t.Errorf("Invalid fill time %f, expected 7.5", result.Minutes)
+	}
+	// Without heat transfer the destination ends where the enthalpy of the added gas takes it
+	cp := air.HeatCapacity()
+	cv := cp - MolarGasConstant
+	startMoles, endMoles := 12*50.0, 12*200.0
+	expected := 293.15 * (startMoles*cv + (endMoles-startMoles)*cp) / (endMoles * cv)
+	if math.Abs(float64(result.PeakTemperature)-expected) > 0.5 {
+		t.Errorf("Invalid peak temperature %f, expected %f", result.PeakTemperature, expected)
+	}
+}
+
+func TestSlowFillRecommendedRate(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	destination := Cylinder{CylinderVolume: 12, Pressure: 30, GasComposition: air}
+	slowFill := SlowFill{Rate: 100, HeatTransfer: 20}
+	fast := slowFill.Simulate(destination, 230, air, VanDerWaals, 293.15)
+	slowFill.Rate = 10
+	slow := slowFill.Simulate(destination, 230, air, VanDerWaals, 293.15)
+	if slow.PeakTemperature >= fast.PeakTemperature || fast.HotPressure <= slow.HotPressure {
+		t.Errorf("Expected a slower fill to stay cooler, got %+v and %+v", slow, fast)
+	}
+	limit := Temperature(ZeroCelsius + 50)
+	rate := slowFill.RecommendedFillRate(destination, 230, air, limit, VanDerWaals, 293.15)
+	slowFill.Rate = rate
+	if peak := slowFill.Simulate(destination, 230, air, VanDerWaals, 293.15).PeakTemperature; rate <= 0 || rate >= maxFillRate || math.Abs(float64(peak-limit)) > 0.1 {
+		t.Errorf("Expected to peak at the limit at %f bar/min, got %f", rate, peak)
+	}
+}
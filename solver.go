@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxSolvedSourcePressure is the highest source pressure searched for by SolveSourcePressure
+const maxSolvedSourcePressure PressureBar = 1000
+
+// solverTolerance is the precision of solved pressures in bar
+const solverTolerance PressureBar = 0.01
+
+// maxSolvedSourceVolume is the largest total source volume searched for by SolveSourceVolume
+const maxSolvedSourceVolume CylinderVolume = 100000
+
+// volumeSolverTolerance is the precision of solved volumes in liters
+const volumeSolverTolerance CylinderVolume = 0.1
+
+// ErrTargetNotSolvable is returned when no source reaches the target pressure
+var ErrTargetNotSolvable = errors.New("target pressure is not reachable")
+
+// SourceSolution is a solved source with the best manifold configuration and the destination pressure it reaches
+type SourceSolution struct {
+	SourceCylinders     CylinderList
+	Configuration       string
+	DestinationPressure PressureBar
+}
+
+// withSourcePressure returns a copy of the configuration with every source cylinder at the pressure
+func withSourcePressure(cylinderConfiguration CylinderConfiguration, pressure PressureBar) CylinderConfiguration {
+	sourceCylinders := append(CylinderList(nil), cylinderConfiguration.SourceCylinders...)
+	for i := range sourceCylinders {
+		sourceCylinders[i].Pressure = pressure
+	}
+	cylinderConfiguration.SourceCylinders = sourceCylinders
+	return cylinderConfiguration
+}
+
+// SolveSourcePressure returns the lowest pressure of the source cylinders, all at the same pressure, at which
+// equalizing fills the destination to the target pressure with the best manifold configuration. The transfers are
+// simulated with the gas system, so the solution holds for real gases as well.
+func SolveSourcePressure(cylinderConfiguration CylinderConfiguration, targetPressure PressureBar, gasSystem GasSystem, temperature Temperature, units UnitSystem) (SourceSolution, error) {
+	if cylinderConfiguration.DestinationCylinders.MinPressure() >= targetPressure {
+		// Any source above the destination will do
+		pressure := cylinderConfiguration.DestinationCylinders.MaxPressure()
+		return sourceSolution(withSourcePressure(cylinderConfiguration, pressure), gasSystem, temperature, units), nil
+	}
+	// The destination never ends above the source, so the source pressure is at least the target
+	low, high := targetPressure, targetPressure
+	for {
+		if bestTransfer(withSourcePressure(cylinderConfiguration, high), gasSystem, temperature, units).DestinationCylinderPressure >= targetPressure {
+			break
+		}
+		if high >= maxSolvedSourcePressure {
+			return SourceSolution{}, fmt.Errorf("%w with source pressures up to %.0f%s", ErrTargetNotSolvable, units.Pressure(maxSolvedSourcePressure), units.PressureUnit())
+		}
+		low, high = high, high*2
+		if high > maxSolvedSourcePressure {
+			high = maxSolvedSourcePressure
+		}
+	}
+	for high-low > solverTolerance {
+		middle := (low + high) / 2
+		if bestTransfer(withSourcePressure(cylinderConfiguration, middle), gasSystem, temperature, units).DestinationCylinderPressure >= targetPressure {
+			high = middle
+		} else {
+			low = middle
+		}
+	}
+	return sourceSolution(withSourcePressure(cylinderConfiguration, high), gasSystem, temperature, units), nil
+}
+
+// withSourceVolume returns a copy of the configuration with source cylinders scaled to the total volume, keeping
+// their relative sizes
+func withSourceVolume(cylinderConfiguration CylinderConfiguration, totalVolume CylinderVolume) CylinderConfiguration {
+	scale := totalVolume / cylinderConfiguration.SourceCylinders.TotalVolume()
+	sourceCylinders := append(CylinderList(nil), cylinderConfiguration.SourceCylinders...)
+	for i := range sourceCylinders {
+		sourceCylinders[i].CylinderVolume *= scale
+	}
+	cylinderConfiguration.SourceCylinders = sourceCylinders
+	return cylinderConfiguration
+}
+
+// SolveSourceVolume returns the smallest total volume of the source cylinders, at their pressures and relative sizes,
+// with which equalizing fills the destination to the target pressure with the best manifold configuration
+func SolveSourceVolume(cylinderConfiguration CylinderConfiguration, targetPressure PressureBar, gasSystem GasSystem, temperature Temperature, units UnitSystem) (SourceSolution, error) {
+	if cylinderConfiguration.SourceCylinders.MaxPressure() <= targetPressure {
+		return SourceSolution{}, fmt.Errorf("%w; sources must be above %.0f%s", ErrTargetNotSolvable, units.Pressure(targetPressure), units.PressureUnit())
+	}
+	low, high := CylinderVolume(0), cylinderConfiguration.DestinationCylinders.TotalVolume()
+	for {
+		if bestTransfer(withSourceVolume(cylinderConfiguration, high), gasSystem, temperature, units).DestinationCylinderPressure >= targetPressure {
+			break
+		}
+		if high >= maxSolvedSourceVolume {
+			return SourceSolution{}, fmt.Errorf("%w with source volumes up to %.0f%s", ErrTargetNotSolvable, units.Volume(GasVolume(maxSolvedSourceVolume)), units.VolumeUnit())
+		}
+		low, high = high, high*2
+		if high > maxSolvedSourceVolume {
+			high = maxSolvedSourceVolume
+		}
+	}
+	for high-low > volumeSolverTolerance {
+		middle := (low + high) / 2
+		if bestTransfer(withSourceVolume(cylinderConfiguration, middle), gasSystem, temperature, units).DestinationCylinderPressure >= targetPressure {
+			high = middle
+		} else {
+			low = middle
+		}
+	}
+	return sourceSolution(withSourceVolume(cylinderConfiguration, high), gasSystem, temperature, units), nil
+}
+
+func sourceSolution(cylinderConfiguration CylinderConfiguration, gasSystem GasSystem, temperature Temperature, units UnitSystem) SourceSolution {
+	best := bestTransfer(cylinderConfiguration, gasSystem, temperature, units)
+	return SourceSolution{
+		SourceCylinders:     cylinderConfiguration.SourceCylinders,
+		Configuration:       best.Description,
+		DestinationPressure: best.DestinationCylinderPressure,
+	}
+}
+
+func printSourcePressureSolution(w io.Writer, solution SourceSolution, targetPressure PressureBar, units UnitSystem) {
+	pressureUnit := units.PressureUnit()
+	fmt.Fprintf(w, "Source pressure needed for %.0f%s: %.1f%s with %s (destination ends at %.1f%s)\n", units.Pressure(targetPressure), pressureUnit, units.Pressure(units.roundPressureUp(solution.SourceCylinders.MaxPressure())), pressureUnit, solution.Configuration, units.Pressure(solution.DestinationPressure), pressureUnit)
+}
+
+func printSourceVolumeSolution(w io.Writer, solution SourceSolution, targetPressure PressureBar, units UnitSystem) {
+	pressureUnit := units.PressureUnit()
+	fmt.Fprintf(w, "Source volume needed for %.0f%s: %.1fl with %s (destination ends at %.1f%s)\n", units.Pressure(targetPressure), pressureUnit, solution.SourceCylinders.TotalVolume(), solution.Configuration, units.Pressure(solution.DestinationPressure), pressureUnit)
+	if len(solution.SourceCylinders) > 1 {
+		for _, cylinder := range solution.SourceCylinders {
+			fmt.Fprintf(w, "  %s: %.1fl at %.0f%s\n", cylinder.Description, cylinder.CylinderVolume, units.Pressure(cylinder.Pressure), pressureUnit)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestSolveSourcePressure(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "source", CylinderVolume: 50, Pressure: 100, GasComposition: air}},
+		DestinationCylinders: CylinderList{{Description: "destination", CylinderVolume: 12, Pressure: 50, GasComposition: air}},
+	}
+	solution, err := SolveSourcePressure(cylinderConfiguration, 200, IdealGas, 293.15, Metric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 50l at p and 12l at 50 bar equalize to 200 bar
+	expected := (200*62 - 12*50) / 50.0
+	if pressure := float64(solution.SourceCylinders[0].Pressure); pressure < expected || pressure > expected+float64(solverTolerance) {
+		t.Errorf("Invalid source pressure %f, expected %f", pressure, expected)
+	}
+	if cylinderConfiguration.SourceCylinders[0].Pressure != 100 {
+		t.Errorf("Source cylinders were modified: %+v", cylinderConfiguration.SourceCylinders)
+	}
+
+	// Real gases differ from ideal gas at high pressures; the solution still fills to the target
+	solution, err = SolveSourcePressure(cylinderConfiguration, 200, VanDerWaals, 293.15, Metric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if solution.DestinationPressure < 200 || solution.DestinationPressure > 200+solverTolerance {
+		t.Errorf("Expected the solution to fill to 200 bar, got %+v", solution)
+	}
+	gasVolume := solution.SourceCylinders.TotalGasVolume(VanDerWaals, 293.15) + cylinderConfiguration.DestinationCylinders.TotalGasVolume(VanDerWaals, 293.15)
+	if pressure := PressureFromGasVolume(62, gasVolume, VanDerWaals, air, 293.15); math.Abs(float64(pressure-200)) > float64(solverTolerance) {
+		t.Errorf("Expected the solved source to equalize to 200 bar, got %f", pressure)
+	}
+
+	cylinderConfiguration.SourceCylinders[0].CylinderVolume = 1
+	if _, err := SolveSourcePressure(cylinderConfiguration, 200, IdealGas, 293.15, Metric); !errors.Is(err, ErrTargetNotSolvable) {
+		t.Errorf("Expected a tiny source not to reach the target, got %v", err)
+	}
+}
+
+func TestSolveSourceVolume(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "bank", CylinderVolume: 10, Pressure: 300, GasComposition: air}},
+		DestinationCylinders: CylinderList{{Description: "destination", CylinderVolume: 24, Pressure: 50, GasComposition: air}},
+	}
+	solution, err := SolveSourceVolume(cylinderConfiguration, 200, IdealGas, 293.15, Metric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// V at 300 bar and 24l at 50 bar equalize to 200 bar
+	expected := 24 * (200 - 50) / (300 - 200.0)
+	if volume := float64(solution.SourceCylinders.TotalVolume()); volume < expected || volume > expected+float64(volumeSolverTolerance) {
+		t.Errorf("Invalid source volume %f, expected %f", volume, expected)
+	}
+	if cylinderConfiguration.SourceCylinders[0].CylinderVolume != 10 {
+		t.Errorf("Source cylinders were modified: %+v", cylinderConfiguration.SourceCylinders)
+	}
+
+	// With real gases the solved source equalizes with the destination to the target as read from a gauge
+	solution, err = SolveSourceVolume(cylinderConfiguration, 200, VanDerWaals, 293.15, Metric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	volume := solution.SourceCylinders.TotalVolume()
+	gasVolume := solution.SourceCylinders.TotalGasVolume(VanDerWaals, 293.15) + cylinderConfiguration.DestinationCylinders.TotalGasVolume(VanDerWaals, 293.15)
+	if pressure := PressureFromGasVolume(volume+24, gasVolume, VanDerWaals, air, 293.15); pressure < 200 || pressure > 200.5 {
+		t.Errorf("Expected the solved source of %fl to equalize to 200 bar, got %f", volume, pressure)
+	}
+
+	if _, err := SolveSourceVolume(cylinderConfiguration, 300, IdealGas, 293.15, Metric); !errors.Is(err, ErrTargetNotSolvable) {
+		t.Errorf("Expected a source at the target pressure not to be solvable, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Species describes a gas: names used for printing and parsing, molar mass and constants for the gas systems.
+type Species struct {
+	Name        string
+	Symbol      string
+	MolarMass   MolarMass
+	VanDerWaals VanDerWaalsConstant
+	Critical    CriticalProperties
+	// HeatCapacity is the ideal gas molar heat capacity at constant pressure, J/(mol K)
+	HeatCapacity float64
+}
+
+// SpeciesLookup holds all known gases. Use RegisterSpecies to add more.
+var SpeciesLookup = map[Gas]Species{
+	Argon: {
+		Name: "argon", Symbol: "Ar", MolarMass: 39.948, HeatCapacity: 20.786,
+		VanDerWaals: VanDerWaalsConstant{A: 1.355, B: 0.03201},
+		Critical:    CriticalProperties{Temperature: 150.69, Pressure: 48.63, AcentricFactor: -0.002},
+	},
+	CarbonDioxide: {
+		Name: "carbon dioxide", Symbol: "CO2", MolarMass: 44.0095, HeatCapacity: 37.135,
+		VanDerWaals: VanDerWaalsConstant{A: 3.640, B: 0.04267},
+		Critical:    CriticalProperties{Temperature: 304.13, Pressure: 73.77, AcentricFactor: 0.225},
+	},
+	CarbonMonoxide: {
+		Name: "carbon monoxide", Symbol: "CO", MolarMass: 28.0101, HeatCapacity: 29.142,
+		VanDerWaals: VanDerWaalsConstant{A: 1.505, B: 0.03985},
+		Critical:    CriticalProperties{Temperature: 132.86, Pressure: 34.94, AcentricFactor: 0.045},
+	},
+	Helium: {
+		Name: "helium", Symbol: "He", MolarMass: 4.002602, HeatCapacity: 20.786,
+		VanDerWaals: VanDerWaalsConstant{A: 0.0346, B: 0.0238},
+		Critical:    CriticalProperties{Temperature: 5.19, Pressure: 2.27, AcentricFactor: -0.390},
+	},
+	Hydrogen: {
+		Name: "hydrogen", Symbol: "H2", MolarMass: 2.01588, HeatCapacity: 28.836,
+		VanDerWaals: VanDerWaalsConstant{A: 0.2476, B: 0.02661},
+		Critical:    CriticalProperties{Temperature: 33.18, Pressure: 13.13, AcentricFactor: -0.219},
+	},
+	Neon: {
+		Name: "neon", Symbol: "Ne", MolarMass: 20.1797, HeatCapacity: 20.786,
+		VanDerWaals: VanDerWaalsConstant{A: 0.2135, B: 0.01709},
+		Critical:    CriticalProperties{Temperature: 44.49, Pressure: 26.79, AcentricFactor: -0.029},
+	},
+	Nitrogen: {
+		Name: "nitrogen", Symbol: "N2", MolarMass: 28.0134, HeatCapacity: 29.124,
+		VanDerWaals: VanDerWaalsConstant{A: 1.370, B: 0.0387},
+		Critical:    CriticalProperties{Temperature: 126.19, Pressure: 33.96, AcentricFactor: 0.037},
+	},
+	Oxygen: {
+		Name: "oxygen", Symbol: "O2", MolarMass: 31.9988, HeatCapacity: 29.378,
+		VanDerWaals: VanDerWaalsConstant{A: 1.382, B: 0.03186},
+		Critical:    CriticalProperties{Temperature: 154.58, Pressure: 50.43, AcentricFactor: 0.022},
+	},
+}
+
+// RegisterSpecies adds a new gas to SpeciesLookup and returns its identifier
+func RegisterSpecies(species Species) Gas {
+	var gasType Gas
+	for existing := range SpeciesLookup {
+		if existing >= gasType {
+			gasType = existing + 1
+		}
+	}
+	SpeciesLookup[gasType] = species
+	return gasType
+}
+
+// LookupSpecies returns the gas matching a symbol or a name, ignoring case
+func LookupSpecies(name string) (Gas, bool) {
+	for gasType, species := range SpeciesLookup {
+		if strings.EqualFold(species.Symbol, name) || strings.EqualFold(species.Name, name) {
+			return gasType, true
+		}
+	}
+	return 0, false
+}
+
+// CustomSpecies describes a user defined gas. Either Van der Waals constants or critical properties (temperature in
+// kelvin, pressure in bar) are required; the missing ones are derived from the others. HeatCapacity defaults to
+// DefaultHeatCapacity.
+type CustomSpecies struct {
+	Name                string  `json:"name"`
+	Symbol              string  `json:"symbol"`
+	MolarMass           float64 `json:"molar_mass"`
+	VanDerWaalsA        float64 `json:"vdw_a,omitempty"`
+	VanDerWaalsB        float64 `json:"vdw_b,omitempty"`
+	CriticalTemperature float64 `json:"critical_temperature,omitempty"`
+	CriticalPressure    float64 `json:"critical_pressure,omitempty"`
+	AcentricFactor      float64 `json:"acentric_factor,omitempty"`
+	HeatCapacity        float64 `json:"heat_capacity,omitempty"`
+}
+
+// DefaultHeatCapacity is used for custom gases without a heat capacity (typical for diatomic gases), J/(mol K)
+const DefaultHeatCapacity = 29.1
+
+// Species returns the species with missing constants derived from the critical point or Van der Waals constants
+func (c CustomSpecies) Species() (Species, error) {
+	if c.Symbol == "" {
+		return Species{}, fmt.Errorf("custom gas %q has no symbol", c.Name)
+	}
+	if c.MolarMass <= 0 {
+		return Species{}, fmt.Errorf("custom gas %s must have a positive molar mass", c.Symbol)
+	}
+	species := Species{
+		Name:         c.Name,
+		Symbol:       c.Symbol,
+		MolarMass:    MolarMass(c.MolarMass),
+		VanDerWaals:  VanDerWaalsConstant{A: c.VanDerWaalsA, B: c.VanDerWaalsB},
+		Critical:     CriticalProperties{Temperature: Temperature(c.CriticalTemperature), Pressure: PressureBar(c.CriticalPressure), AcentricFactor: c.AcentricFactor},
+		HeatCapacity: c.HeatCapacity,
+	}
+	if species.HeatCapacity == 0 {
+		species.HeatCapacity = DefaultHeatCapacity
+	}
+	if species.Name == "" {
+		species.Name = c.Symbol
+	}
+	hasVanDerWaals := c.VanDerWaalsA > 0 && c.VanDerWaalsB > 0
+	hasCritical := c.CriticalTemperature > 0 && c.CriticalPressure > 0
+	switch {
+	case hasVanDerWaals && hasCritical:
+	case hasCritical:
+		species.VanDerWaals.A = 27 * R * R * c.CriticalTemperature * c.CriticalTemperature / (64 * c.CriticalPressure)
+		species.VanDerWaals.B = R * c.CriticalTemperature / (8 * c.CriticalPressure)
+	case hasVanDerWaals:
+		species.Critical.Temperature = Temperature(8 * c.VanDerWaalsA / (27 * R * c.VanDerWaalsB))
+		species.Critical.Pressure = PressureBar(c.VanDerWaalsA / (27 * c.VanDerWaalsB * c.VanDerWaalsB))
+	default:
+		return Species{}, fmt.Errorf("custom gas %s needs Van der Waals constants or critical temperature and pressure", c.Symbol)
+	}
+	return species, nil
+}
+
+// ParseCustomSpecies parses a custom gas given as comma separated key=value pairs, e.g.
+// "symbol=SF6,name=sulfur hexafluoride,mass=146.06,tc=318.7,pc=37.6,omega=0.21". Keys a and b set Van der Waals
+// constants and cp the heat capacity.
+func ParseCustomSpecies(spec string) (CustomSpecies, error) {
+	var custom CustomSpecies
+	for _, field := range strings.Split(spec, ",") {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			return custom, fmt.Errorf("invalid custom gas field %q; must be key=value", field)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "symbol":
+			custom.Symbol = value
+			continue
+		case "name":
+			custom.Name = value
+			continue
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return custom, fmt.Errorf("invalid value %q for %s", value, key)
+		}
+		switch key {
+		case "mass":
+			custom.MolarMass = number
+		case "a":
+			custom.VanDerWaalsA = number
+		case "b":
+			custom.VanDerWaalsB = number
+		case "tc":
+			custom.CriticalTemperature = number
+		case "pc":
+			custom.CriticalPressure = number
+		case "omega":
+			custom.AcentricFactor = number
+		case "cp":
+			custom.HeatCapacity = number
+		default:
+			return custom, fmt.Errorf("unknown custom gas field %q", key)
+		}
+	}
+	return custom, nil
+}
+
+// LoadCustomSpecies reads a JSON file containing a list of custom gases
+func LoadCustomSpecies(path string) ([]CustomSpecies, error) {
+	var customSpecies []CustomSpecies
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &customSpecies); err != nil {
+		return nil, fmt.Errorf("invalid gas file %s: %w", path, err)
+	}
+	return customSpecies, nil
+}
+
+// RegisterCustomSpecies registers user defined gases, refusing symbols or names that are already in use
+func RegisterCustomSpecies(customSpecies []CustomSpecies) error {
+	for _, custom := range customSpecies {
+		species, err := custom.Species()
+		if err != nil {
+			return err
+		}
+		if _, exists := LookupSpecies(species.Symbol); exists {
+			return fmt.Errorf("gas %s is already defined", species.Symbol)
+		}
+		if _, exists := LookupSpecies(species.Name); exists {
+			return fmt.Errorf("gas %s is already defined", species.Name)
+		}
+		RegisterSpecies(species)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestRegisterSpecies(t *testing.T) {
+	krypton := RegisterSpecies(Species{Name: "krypton", Symbol: "Kr", MolarMass: 83.798})
+	defer delete(SpeciesLookup, krypton)
+	if _, ok := SpeciesLookup[krypton]; !ok || krypton <= Hydrogen {
+		t.Errorf("Invalid gas identifier %d for registered species", krypton)
+	}
+	if SpeciesLookup[krypton].Symbol != "Kr" {
+		t.Errorf("Invalid symbol %s for registered species", SpeciesLookup[krypton].Symbol)
+	}
+}
+
+func TestAirMolarMass(t *testing.T) {
+	var molarMass MolarMass
+	for gasType, fraction := range (GasComposition{Oxygen: 0.21, Nitrogen: 0.79}) {
+		molarMass += MolarMass(fraction) * SpeciesLookup[gasType].MolarMass
+	}
+	if molarMass < 28.8 || molarMass > 29.0 {
+		t.Errorf("Invalid molar mass %f for air", molarMass)
+	}
+}
+
+func TestCustomSpeciesFromCriticalPoint(t *testing.T) {
+	custom, err := ParseCustomSpecies("symbol=SF6,name=sulfur hexafluoride,mass=146.06,tc=318.7,pc=37.6,omega=0.21")
+	if err != nil {
+		t.Fatal(err)
+	}
+	species, err := custom.Species()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedB := R * 318.7 / (8 * 37.6)
+	if !compareFloats(species.VanDerWaals.B, expectedB) || species.VanDerWaals.A <= 0 {
+		t.Errorf("Invalid derived Van der Waals constants %+v", species.VanDerWaals)
+	}
+	if _, err := (CustomSpecies{Symbol: "X", MolarMass: 10}).Species(); err == nil {
+		t.Error("Expected an error for a custom gas without constants")
+	}
+	if err := RegisterCustomSpecies([]CustomSpecies{{Symbol: "He", MolarMass: 4, VanDerWaalsA: 1, VanDerWaalsB: 0.1}}); err == nil {
+		t.Error("Expected an error for a duplicate gas")
+	}
+}
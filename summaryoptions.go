@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// summarySorts lists the orders of the summary table rows; each sorts the best row first
+var summarySorts = map[string]func(a, b CylinderSummary) bool{
+	"configuration": nil,
+	"dst-pressure": func(a, b CylinderSummary) bool {
+		return a.DestinationCylinderPressure > b.DestinationCylinderPressure
+	},
+	"improvement": func(a, b CylinderSummary) bool {
+		return a.DestinationCylinderPressure > b.DestinationCylinderPressure
+	},
+	"dst-volume": func(a, b CylinderSummary) bool {
+		return a.DestinationCylinderGasVolume > b.DestinationCylinderGasVolume
+	},
+	"src-pressure": func(a, b CylinderSummary) bool {
+		return a.SourceCylinderPressure > b.SourceCylinderPressure
+	},
+	"cost": func(a, b CylinderSummary) bool {
+		return a.GasCost != nil && b.GasCost != nil && a.GasCost.Total < b.GasCost.Total
+	},
+}
+
+// baselineConfigurations lists the configurations selectable as the baseline of improvements
+var baselineConfigurations = []string{"both manifolds closed", "destination manifold closed", "source manifold closed", "all manifolds open"}
+
+// summaryOptions sorts and filters the rows of the summary table and selects the baseline of improvements. The zero
+// value prints all configurations in the order they were run, with improvements on the worst configuration.
+type summaryOptions struct {
+	// sortBy is a key of summarySorts
+	sortBy string
+	// minImprovement hides configurations improving the destination pressure by less than this many percent on the
+	// baseline
+	minImprovement float64
+	// baseline is the description of the configuration improvements are relative to; empty is the worst
+	// configuration
+	baseline string
+	// style is the table style of -table-style
+	style tableStyle
+}
+
+// newSummaryOptions returns the options of the -sort, -min-improvement and -baseline flags
+func newSummaryOptions(sortBy string, minImprovement float64, baseline string) (summaryOptions, error) {
+	if _, ok := summarySorts[sortBy]; !ok && sortBy != "" {
+		return summaryOptions{}, fmt.Errorf("invalid sort %q; must be configuration, dst-pressure, improvement, dst-volume, src-pressure or cost", sortBy)
+	}
+	if minImprovement < 0 {
+		return summaryOptions{}, fmt.Errorf("invalid minimum improvement %g%%; must be >=0", minImprovement)
+	}
+	if baseline == "worst" {
+		baseline = ""
+	}
+	if baseline != "" && !slices.Contains(baselineConfigurations, baseline) {
+		return summaryOptions{}, fmt.Errorf("invalid baseline %q; must be worst or one of %s", baseline, strings.Join(baselineConfigurations, ", "))
+	}
+	return summaryOptions{sortBy: sortBy, minImprovement: minImprovement, baseline: baseline}, nil
+}
+
+// baselineSummary returns the summary improvements are relative to: the baseline configuration, or the one with the
+// lowest destination pressure. A baseline configuration that was not run, such as a closed manifold without
+// twinsets, falls back to the worst configuration.
+func (o summaryOptions) baselineSummary(cylinderSummaries []CylinderSummary) CylinderSummary {
+	var worst CylinderSummary
+	for _, cylinderSummary := range cylinderSummaries {
+		if o.baseline != "" && cylinderSummary.Description == o.baseline {
+			return cylinderSummary
+		}
+		if cylinderSummary.Description != "" && (worst.Description == "" || cylinderSummary.DestinationCylinderPressure < worst.DestinationCylinderPressure) {
+			worst = cylinderSummary
+		}
+	}
+	return worst
+}
+
+// improvement returns how many percent the destination pressure of the summary improves on the baseline
+func improvement(cylinderSummary CylinderSummary, baseline CylinderSummary) float64 {
+	return float64(100 * (cylinderSummary.DestinationCylinderPressure - baseline.DestinationCylinderPressure) / baseline.DestinationCylinderPressure)
+}
+
+// rows returns the summaries to print in order. Improvements are relative to the baseline of all summaries,
+// including the ones filtered out.
+func (o summaryOptions) rows(cylinderSummaries []CylinderSummary) []CylinderSummary {
+	baseline := o.baselineSummary(cylinderSummaries)
+	var rows []CylinderSummary
+	for _, cylinderSummary := range cylinderSummaries {
+		if cylinderSummary.Description == "" || o.minImprovement > 0 && improvement(cylinderSummary, baseline) < o.minImprovement {
+			continue
+		}
+		rows = append(rows, cylinderSummary)
+	}
+	if less := summarySorts[o.sortBy]; less != nil {
+		sort.SliceStable(rows, func(i, j int) bool { return less(rows[i], rows[j]) })
+	}
+	return rows
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSummaryFilter(t *testing.T) {
+	cylinderSummaries := []CylinderSummary{
+		{Description: "both manifolds closed", DestinationCylinderPressure: 101},
+		{Description: "destination manifold closed", DestinationCylinderPressure: 110},
+		{Description: "all manifolds open", DestinationCylinderPressure: 100},
+	}
+	filter, err := newSummaryOptions("dst-pressure", 0.5, "worst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := filter.rows(cylinderSummaries)
+	if len(rows) != 2 || rows[0].Description != "destination manifold closed" || rows[1].Description != "both manifolds closed" {
+		t.Errorf("Unexpected rows %+v", rows)
+	}
+	var output strings.Builder
+	printSummaries(&output, cylinderSummaries, filter, Metric, false)
+	if strings.Contains(output.String(), "all manifolds open") || !strings.Contains(output.String(), "10.00%") {
+		t.Errorf("Expected improvements on the filtered out worst configuration, got %q", output.String())
+	}
+	if rows := (summaryOptions{}).rows(cylinderSummaries); len(rows) != 3 || rows[0].Description != "both manifolds closed" {
+		t.Errorf("Expected the order of configurations, got %+v", rows)
+	}
+	if _, err := newSummaryOptions("", 0, "manifolds open"); err == nil {
+		t.Error("Expected an error for an unknown baseline")
+	}
+	if _, err := newSummaryOptions("pressure", 0, ""); err == nil {
+		t.Error("Expected an error for an unknown sort")
+	}
+	if _, err := newSummaryOptions("", -1, ""); err == nil {
+		t.Error("Expected an error for a negative minimum improvement")
+	}
+}
+
+func TestSummaryBaseline(t *testing.T) {
+	cylinderSummaries := []CylinderSummary{
+		{Description: "both manifolds closed", DestinationCylinderPressure: 100, DestinationCylinderGasVolume: 1000},
+		{Description: "all manifolds open", DestinationCylinderPressure: 125, DestinationCylinderGasVolume: 1250},
+	}
+	options, err := newSummaryOptions("", 0, "all manifolds open")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if baseline := options.baselineSummary(cylinderSummaries); baseline.Description != "all manifolds open" {
+		t.Errorf("Expected the baseline all manifolds open, got %q", baseline.Description)
+	}
+	var output strings.Builder
+	printSummaries(&output, cylinderSummaries, options, Metric, false)
+	lines := strings.Split(output.String(), "\n")
+	if fields := strings.Fields(lines[1]); strings.Join(fields[len(fields)-3:], " ") != "-25 -250 -20.00%" {
+		t.Errorf("Unexpected improvements on all manifolds open %q", output.String())
+	}
+	// Without twinsets only the closed manifolds run, and the baseline falls back to the worst configuration
+	options.baseline = "destination manifold closed"
+	if baseline := options.baselineSummary(cylinderSummaries); baseline.Description != "both manifolds closed" {
+		t.Errorf("Expected the worst configuration as the baseline, got %q", baseline.Description)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxSweepTemperatures limits the number of temperatures in a sweep
+const maxSweepTemperatures = 1000
+
+// ParseTemperatureSweep parses a temperature range such as "0:40:5" to the temperatures from start to end. Start
+// and end are parsed like -temperature; the step is in degrees of the unit system unless it has a C, F or K suffix.
+func (u UnitSystem) ParseTemperatureSweep(s string) ([]Temperature, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid temperature sweep %q; expected start:end:step", s)
+	}
+	start, err := u.ParseTemperature(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	end, err := u.ParseTemperature(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	step, err := u.ParseTemperatureDifference(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	if step <= 0 || end < start {
+		return nil, fmt.Errorf("invalid temperature sweep %q; step must be >0 and end at least start", s)
+	}
+	if (end-start)/step >= maxSweepTemperatures {
+		return nil, fmt.Errorf("invalid temperature sweep %q; more than %d temperatures", s, maxSweepTemperatures)
+	}
+	var temperatures []Temperature
+	// Allow for rounding errors so that the end is included when it is a multiple of the step
+	for i := 0; start+Temperature(i)*step <= end+step/1e6; i++ {
+		temperatures = append(temperatures, start+Temperature(i)*step)
+	}
+	return temperatures, nil
+}
+
+// temperatureSweep equalizes each manifold configuration at each temperature, with the same starting pressures,
+// reporting each temperature to the progress reporter
+func temperatureSweep(cylinderConfiguration CylinderConfiguration, gasSystem GasSystem, temperatures []Temperature, units UnitSystem, progress *progressReporter) [][]CylinderSummary {
+	sweep := make([][]CylinderSummary, len(temperatures))
+	for i, temperature := range temperatures {
+		sweep[i] = equalizeAllConfigurations(io.Discard, cylinderConfiguration, gasSystem, temperature, units, false, nil)
+		progress.step()
+	}
+	return sweep
+}
+
+// printTemperatureSweep writes the destination pressure of each configuration at each temperature, followed by the
+// change over the whole range
+func printTemperatureSweep(w io.Writer, temperatures []Temperature, sweep [][]CylinderSummary, units UnitSystem) {
+	if len(sweep) == 0 {
+		return
+	}
+	pressureUnit := units.PressureUnit()
+	fmt.Fprintf(w, "%8s", "temp")
+	for _, cylinderSummary := range sweep[0] {
+		fmt.Fprintf(w, " %28s", cylinderSummary.Description)
+	}
+	fmt.Fprintln(w)
+	for i, temperature := range temperatures {
+		fmt.Fprintf(w, "%6.1f°%s", units.Temperature(temperature), units.TemperatureUnit())
+		for _, cylinderSummary := range sweep[i] {
+			fmt.Fprintf(w, " %25.1f%s", units.Pressure(cylinderSummary.DestinationCylinderPressure), pressureUnit)
+		}
+		fmt.Fprintln(w)
+	}
+	first, last := sweep[0], sweep[len(sweep)-1]
+	for i, cylinderSummary := range first {
+		fmt.Fprintf(w, "%s: destination %+.1f%s from %.0f°%s to %.0f°%s\n", cylinderSummary.Description, units.PressureDifference(last[i].DestinationCylinderPressure-cylinderSummary.DestinationCylinderPressure), pressureUnit, units.Temperature(temperatures[0]), units.TemperatureUnit(), units.Temperature(temperatures[len(temperatures)-1]), units.TemperatureUnit())
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseTemperatureSweep(t *testing.T) {
+	temperatures, err := Metric.ParseTemperatureSweep("0:40:5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(temperatures) != 9 || temperatures[0] != ZeroCelsius || !compareFloats(float64(temperatures[8]), ZeroCelsius+40) {
+		t.Errorf("Invalid temperatures %v", temperatures)
+	}
+	temperatures, err = Imperial.ParseTemperatureSweep("32:50:9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(temperatures) != 3 || !compareFloats(float64(temperatures[2]), ZeroCelsius+10) {
+		t.Errorf("Invalid temperatures %v", temperatures)
+	}
+	for _, sweep := range []string{"0:40", "40:0:5", "0:40:0", "0:40:5x", "0:1000:0.001"} {
+		if _, err := Metric.ParseTemperatureSweep(sweep); err == nil {
+			t.Errorf("Expected an error for %q", sweep)
+		}
+	}
+}
+
+func TestTemperatureSweep(t *testing.T) {
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "source", CylinderVolume: 50, Pressure: 232, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+		DestinationCylinders: CylinderList{{Description: "destination", CylinderVolume: 12, Pressure: 50, GasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}}},
+	}
+	temperatures := []Temperature{ZeroCelsius, ZeroCelsius + 40}
+	sweep := temperatureSweep(cylinderConfiguration, IdealGas, temperatures, Metric, nil)
+	if len(sweep) != 2 || !compareFloats(float64(sweep[0][0].DestinationCylinderPressure), float64(sweep[1][0].DestinationCylinderPressure)) {
+		t.Errorf("Expected ideal gas to be independent of temperature, got %+v", sweep)
+	}
+	sweep = temperatureSweep(cylinderConfiguration, VanDerWaals, temperatures, Metric, nil)
+	if sweep[0][0].DestinationCylinderPressure == sweep[1][0].DestinationCylinderPressure {
+		t.Errorf("Expected Van der Waals to depend on temperature, got %+v", sweep)
+	}
+	var output bytes.Buffer
+	printTemperatureSweep(&output, temperatures, sweep, Metric)
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[3], "all manifolds open: destination ") {
+		t.Fatalf("Invalid output:\n%s", output.String())
+	}
+	// Each row shows the pressure of the equalized gas at the temperature of the row
+	chart := temperatureSweepChart(temperatures, sweep, Metric)
+	for i, temperature := range temperatures {
+		gasVolume := cylinderConfiguration.SourceCylinders.TotalGasVolume(VanDerWaals, temperature) + cylinderConfiguration.DestinationCylinders.TotalGasVolume(VanDerWaals, temperature)
+		expectedPressure := PressureFromGasVolume(62, gasVolume, VanDerWaals, GasComposition{Oxygen: 0.21, Nitrogen: 0.79}, temperature)
+		if !strings.HasSuffix(lines[i+1], fmt.Sprintf("%.1fbar", expectedPressure)) {
+			t.Errorf("Expected %.1fbar at %.0fK, got %q", expectedPressure, temperature, lines[i+1])
+		}
+		if math.Abs(chart.Series[0].Y[i]-float64(expectedPressure)) > 0.01 {
+			t.Errorf("Expected %.1fbar charted at %.0fK, got %f", expectedPressure, temperature, chart.Series[0].Y[i])
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// tableStyle selects how tables are drawn
+type tableStyle string
+
+const (
+	// tablePlain aligns columns with spaces
+	tablePlain tableStyle = "plain"
+	// tableMarkdown draws a Markdown table for pasting into wikis
+	tableMarkdown tableStyle = "markdown"
+	// tableBox draws borders with box-drawing characters
+	tableBox tableStyle = "box"
+)
+
+// ParseTableStyle returns the table style of a name; an empty name is plain
+func ParseTableStyle(name string) (tableStyle, error) {
+	switch style := tableStyle(strings.ToLower(name)); style {
+	case "":
+		return tablePlain, nil
+	case tablePlain, tableMarkdown, tableBox:
+		return style, nil
+	}
+	return "", fmt.Errorf("unknown table style %q; must be plain, markdown or box", name)
+}
+
+// tableColumn is a column of a table, padded to at least width characters
+type tableColumn struct {
+	header    string
+	width     int
+	alignLeft bool
+}
+
+// tableRow is a row of cells, or a note line under the previous row when note is set
+type tableRow struct {
+	cells []string
+	color ansiColor
+	note  string
+}
+
+// table collects the columns and rows of a table and renders them in a style
+type table struct {
+	columns []tableColumn
+	rows    []tableRow
+}
+
+// addColumn adds a column, right-aligned unless alignLeft is set
+func (t *table) addColumn(header string, width int, alignLeft bool) {
+	t.columns = append(t.columns, tableColumn{header: header, width: width, alignLeft: alignLeft})
+}
+
+// addRow adds a row of cells, one per column, printed in the color unless it is empty
+func (t *table) addRow(color ansiColor, cells ...string) {
+	t.rows = append(t.rows, tableRow{cells: cells, color: color})
+}
+
+// addNote adds a line of text under the last row, such as the gas weights of -verbose. Plain tables print the note
+// as is; other styles print it as a row of its own.
+func (t *table) addNote(note string) {
+	t.rows = append(t.rows, tableRow{note: note})
+}
+
+// widths returns the widths of the columns fitting their headers and cells once formatted with the locale
+func (t *table) widths(locale Locale) []int {
+	widths := make([]int, len(t.columns))
+	for i, column := range t.columns {
+		widths[i] = max(column.width, locale.width(column.header))
+	}
+	for _, row := range t.rows {
+		for i, cell := range row.cells {
+			if i < len(widths) {
+				widths[i] = max(widths[i], locale.width(cell))
+			}
+		}
+	}
+	return widths
+}
+
+// pad returns the cell padded to the width of the column once formatted with the locale
+func (c tableColumn) pad(cell string, width int, locale Locale) string {
+	padding := strings.Repeat(" ", max(width-locale.width(cell), 0))
+	if c.alignLeft {
+		return cell + padding
+	}
+	return padding + cell
+}
+
+// padCells returns the values padded to the widths of the columns; missing values are empty
+func (t *table) padCells(values []string, widths []int, locale Locale) []string {
+	padded := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		var value string
+		if i < len(values) {
+			value = values[i]
+		}
+		padded[i] = column.pad(value, widths[i], locale)
+	}
+	return padded
+}
+
+// render writes the table in the style. Columns are padded to the width of the cells as formatted by the locale
+// of w, so grouped numbers stay aligned.
+func (t *table) render(w io.Writer, style tableStyle) {
+	locale := writerLocale(w)
+	widths := t.widths(locale)
+	headers := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		headers[i] = column.header
+	}
+	line := func(row tableRow, text string) {
+		if row.color != "" {
+			text = colorize(w, row.color, text)
+		}
+		fmt.Fprintln(w, text)
+	}
+	switch style {
+	case tableMarkdown:
+		// Escaped pipes widen cells, and the separator row needs three characters per column
+		escape := strings.NewReplacer("|", `\|`)
+		for i := range widths {
+			widths[i] = max(widths[i], 3)
+		}
+		for _, row := range t.rows {
+			for i, cell := range row.cells {
+				if i < len(widths) {
+					widths[i] = max(widths[i], locale.width(escape.Replace(cell)))
+				}
+			}
+		}
+		cells := func(values []string) string {
+			escaped := make([]string, len(values))
+			for i, value := range values {
+				escaped[i] = escape.Replace(value)
+			}
+			return "| " + strings.Join(t.padCells(escaped, widths, locale), " | ") + " |"
+		}
+		fmt.Fprintln(w, cells(headers))
+		separators := make([]string, len(t.columns))
+		for i, column := range t.columns {
+			if column.alignLeft {
+				separators[i] = strings.Repeat("-", widths[i])
+			} else {
+				separators[i] = strings.Repeat("-", widths[i]-1) + ":"
+			}
+		}
+		fmt.Fprintln(w, "| "+strings.Join(separators, " | ")+" |")
+		for _, row := range t.rows {
+			if row.note != "" {
+				line(row, cells([]string{strings.TrimSpace(row.note)}))
+				continue
+			}
+			line(row, cells(row.cells))
+		}
+	case tableBox:
+		inner := 3 * (len(widths) - 1)
+		for _, width := range widths {
+			inner += width
+		}
+		// Notes wider than the table widen its last column
+		for _, row := range t.rows {
+			if note := locale.width(strings.TrimSpace(row.note)); note > inner {
+				widths[len(widths)-1] += note - inner
+				inner = note
+			}
+		}
+		border := func(left, middle, right string) string {
+			segments := make([]string, len(widths))
+			for i, width := range widths {
+				segments[i] = strings.Repeat("─", width+2)
+			}
+			return left + strings.Join(segments, middle) + right
+		}
+		cells := func(values []string) string {
+			return "│ " + strings.Join(t.padCells(values, widths, locale), " │ ") + " │"
+		}
+		fmt.Fprintln(w, border("┌", "┬", "┐"))
+		fmt.Fprintln(w, cells(headers))
+		fmt.Fprintln(w, border("├", "┼", "┤"))
+		for _, row := range t.rows {
+			if row.note != "" {
+				line(row, "│ "+tableColumn{alignLeft: true}.pad(strings.TrimSpace(row.note), inner, locale)+" │")
+				continue
+			}
+			line(row, cells(row.cells))
+		}
+		fmt.Fprintln(w, border("└", "┴", "┘"))
+	default:
+		cells := func(values []string) string {
+			return strings.TrimRight(strings.Join(t.padCells(values, widths, locale), " "), " ")
+		}
+		fmt.Fprintln(w, cells(headers))
+		for _, row := range t.rows {
+			if row.note != "" {
+				line(row, row.note)
+				continue
+			}
+			line(row, cells(row.cells))
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTableStyle(t *testing.T) {
+	for name, expected := range map[string]tableStyle{"": tablePlain, "plain": tablePlain, "Markdown": tableMarkdown, "box": tableBox} {
+		if style, err := ParseTableStyle(name); err != nil || style != expected {
+			t.Errorf("Expected %q for %q, got %q (%v)", expected, name, style, err)
+		}
+	}
+	if _, err := ParseTableStyle("html"); err == nil {
+		t.Error("Expected an error for an unknown table style")
+	}
+}
+
+func TestTableRender(t *testing.T) {
+	var testTable table
+	testTable.addColumn("name", 0, true)
+	testTable.addColumn("bar", 5, false)
+	testTable.addRow("", "läh|de", "232")
+	testTable.addNote("  failed")
+	tests := map[tableStyle]string{
+		tablePlain: "name     bar\n" +
+			"läh|de   232\n" +
+			"  failed\n",
+		tableMarkdown: "| name    |   bar |\n" +
+			"| ------- | ----: |\n" +
+			"| läh\\|de |   232 |\n" +
+			"| failed  |       |\n",
+		tableBox: "┌────────┬───────┐\n" +
+			"│ name   │   bar │\n" +
+			"├────────┼───────┤\n" +
+			"│ läh|de │   232 │\n" +
+			"│ failed         │\n" +
+			"└────────┴───────┘\n",
+	}
+	for style, expected := range tests {
+		var output strings.Builder
+		testTable.render(&output, style)
+		if output.String() != expected {
+			t.Errorf("Unexpected %s table:\n%s\nexpected:\n%s", style, output.String(), expected)
+		}
+	}
+}
+
+func TestPrintSummariesMarkdown(t *testing.T) {
+	cylinderSummaries := []CylinderSummary{{Description: "all manifolds open", DestinationCylinderPressure: 100, SourceCylinderGasWeight: 2000, DestinationCylinderGasWeight: 1000}}
+	var output strings.Builder
+	printSummaries(&output, cylinderSummaries, summaryOptions{style: tableMarkdown}, Metric, true)
+	lines := strings.Split(output.String(), "\n")
+	if len(lines) != 4 || !strings.HasSuffix(lines[0], " src g |  dst g |") || !strings.HasSuffix(lines[2], " 2000 |   1000 |") {
+		t.Errorf("Expected gas weights in columns, got %q", output.String())
+	}
+}
+
+func TestTableRenderLocale(t *testing.T) {
+	var testTable table
+	testTable.addColumn("src bar", 7, false)
+	testTable.addColumn("src l", 6, false)
+	testTable.addRow("", "207", "104012")
+	testTable.addRow("", "156.5", "2496")
+	locale, _ := ParseLocale("fi")
+	var output strings.Builder
+	testTable.render(newLocaleWriter(&output, locale), tablePlain)
+	expected := "src bar   src l\n" +
+		"    207 104 012\n" +
+		"  156,5    2496\n"
+	if output.String() != expected {
+		t.Errorf("Unexpected output:\n%s", output.String())
+	}
+}
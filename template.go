@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// TemplateSummary is a manifold configuration result for -template. Pressures, volumes and weights are in the units
+// and pressure reference of the unit system.
+type TemplateSummary struct {
+	Description                  string
+	SourceCylinderPressure       float64
+	SourceCylinderGasVolume      float64
+	SourceCylinderGasWeight      float64
+	DestinationCylinderPressure  float64
+	DestinationCylinderGasVolume float64
+	DestinationCylinderGasWeight float64
+	SourceGasComposition         string
+	DestinationGasComposition    string
+	WhipGasVolume                float64
+	// GasCost is zero when no prices are set
+	GasCost float64
+}
+
+// TemplateData is passed to -template: the fields of the configuration reaching the highest destination pressure,
+// all configurations in Summaries, and the unit names
+type TemplateData struct {
+	TemplateSummary
+	Summaries    []TemplateSummary
+	PressureUnit string
+	VolumeUnit   string
+	WeightUnit   string
+}
+
+func newTemplateSummary(summary CylinderSummary, units UnitSystem) TemplateSummary {
+	templateSummary := TemplateSummary{
+		Description:                  summary.Description,
+		SourceCylinderPressure:       units.Pressure(summary.SourceCylinderPressure),
+		SourceCylinderGasVolume:      units.Volume(summary.SourceCylinderGasVolume),
+		SourceCylinderGasWeight:      units.Weight(summary.SourceCylinderGasWeight),
+		DestinationCylinderPressure:  units.Pressure(summary.DestinationCylinderPressure),
+		DestinationCylinderGasVolume: units.Volume(summary.DestinationCylinderGasVolume),
+		DestinationCylinderGasWeight: units.Weight(summary.DestinationCylinderGasWeight),
+		SourceGasComposition:         summary.SourceGasComposition.String(),
+		DestinationGasComposition:    summary.DestinationGasComposition.String(),
+		WhipGasVolume:                units.Volume(summary.WhipGasVolume),
+	}
+	if summary.GasCost != nil {
+		templateSummary.GasCost = summary.GasCost.Total
+	}
+	return templateSummary
+}
+
+// newTemplateData returns the template data of the summaries, skipping configurations that were not run
+func newTemplateData(cylinderSummaries []CylinderSummary, units UnitSystem) TemplateData {
+	data := TemplateData{PressureUnit: units.PressureUnit(), VolumeUnit: units.VolumeUnit(), WeightUnit: units.WeightUnit()}
+	for _, cylinderSummary := range cylinderSummaries {
+		if cylinderSummary.Description == "" {
+			continue
+		}
+		data.Summaries = append(data.Summaries, newTemplateSummary(cylinderSummary, units))
+	}
+	data.TemplateSummary = newTemplateSummary(bestSummary(cylinderSummaries), units)
+	return data
+}
+
+// parseOutputTemplate parses a -template; values are rounded with printf, e.g. {{printf "%.0f" .DestinationCylinderPressure}}
+func parseOutputTemplate(text string) (*template.Template, error) {
+	outputTemplate, err := template.New("output").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return outputTemplate, nil
+}
+
+// quietOutputs maps the -output names of -quiet to templates printing a single value
+var quietOutputs = map[string]string{
+	"dest-pressure": `{{printf "%.1f" .DestinationCylinderPressure}}`,
+	"dest-volume":   `{{printf "%.1f" .DestinationCylinderGasVolume}}`,
+	"dest-weight":   `{{printf "%.1f" .DestinationCylinderGasWeight}}`,
+	"dest-mix":      `{{.DestinationGasComposition}}`,
+	"src-pressure":  `{{printf "%.1f" .SourceCylinderPressure}}`,
+	"src-volume":    `{{printf "%.1f" .SourceCylinderGasVolume}}`,
+	"src-weight":    `{{printf "%.1f" .SourceCylinderGasWeight}}`,
+	"src-mix":       `{{.SourceGasComposition}}`,
+	"whip-volume":   `{{printf "%.2f" .WhipGasVolume}}`,
+	"cost":          `{{printf "%.2f" .GasCost}}`,
+	"configuration": `{{.Description}}`,
+}
+
+// quietOutputTemplate returns the template printing the -output value
+func quietOutputTemplate(name string) (*template.Template, error) {
+	text, ok := quietOutputs[name]
+	if !ok {
+		names := make([]string, 0, len(quietOutputs))
+		for name := range quietOutputs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown output %q; must be one of %s", name, strings.Join(names, ", "))
+	}
+	return parseOutputTemplate(text)
+}
+
+// executeOutputTemplate writes the template applied to the summaries, ending with a newline
+func executeOutputTemplate(w io.Writer, outputTemplate *template.Template, cylinderSummaries []CylinderSummary, units UnitSystem) error {
+	var output strings.Builder
+	if err := outputTemplate.Execute(&output, newTemplateData(cylinderSummaries, units)); err != nil {
+		return fmt.Errorf("unable to apply template: %w", err)
+	}
+	if !strings.HasSuffix(output.String(), "\n") {
+		output.WriteString("\n")
+	}
+	_, err := io.WriteString(w, output.String())
+	return err
+}
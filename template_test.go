@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExecuteOutputTemplate(t *testing.T) {
+	cylinderSummaries := []CylinderSummary{
+		{Description: "Equalizing with all manifolds open", DestinationCylinderPressure: 150, DestinationGasComposition: GasComposition{Oxygen: 0.32, Nitrogen: 0.68}},
+		{Description: "Equalizing one cylinder at a time", DestinationCylinderPressure: 160, DestinationGasComposition: GasComposition{Oxygen: 0.32, Nitrogen: 0.68}},
+	}
+	outputTemplate, err := parseOutputTemplate(`{{printf "%.0f" .DestinationCylinderPressure}} {{.PressureUnit}} {{.DestinationGasComposition}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output strings.Builder
+	if err := executeOutputTemplate(&output, outputTemplate, cylinderSummaries, Metric); err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "160 bar EAN32.0\n" {
+		t.Errorf("Unexpected output %q", output.String())
+	}
+	outputTemplate, _ = parseOutputTemplate("{{range .Summaries}}{{printf \"%.0f\" .DestinationCylinderPressure}}\n{{end}}")
+	output.Reset()
+	if err := executeOutputTemplate(&output, outputTemplate, cylinderSummaries, Metric); err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "150\n160\n" {
+		t.Errorf("Unexpected output %q", output.String())
+	}
+	if _, err := parseOutputTemplate("{{.DestinationCylinderPressure"); err == nil {
+		t.Error("Expected an error for an unterminated template")
+	}
+	outputTemplate, _ = parseOutputTemplate("{{.Unknown}}")
+	if err := executeOutputTemplate(&output, outputTemplate, cylinderSummaries, Metric); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+}
+
+func TestQuietOutputTemplate(t *testing.T) {
+	cylinderSummaries := []CylinderSummary{
+		{Description: "all manifolds open", DestinationCylinderPressure: 156.25, DestinationGasComposition: GasComposition{Oxygen: 0.21, Nitrogen: 0.79}},
+	}
+	for name, expected := range map[string]string{"dest-pressure": "156.2\n", "dest-mix": "air\n", "configuration": "all manifolds open\n", "cost": "0.00\n"} {
+		outputTemplate, err := quietOutputTemplate(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var output strings.Builder
+		if err := executeOutputTemplate(&output, outputTemplate, cylinderSummaries, Metric); err != nil || output.String() != expected {
+			t.Errorf("Invalid %s output %q, expected %q: %v", name, output.String(), expected, err)
+		}
+	}
+	if _, err := quietOutputTemplate("pressure"); err == nil {
+		t.Error("Expected an error for an unknown output")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// makeRawTerminal switches the terminal to unbuffered input without echo and returns a function restoring it.
+// An error is returned when fd is not a terminal.
+func makeRawTerminal(fd int) (func(), error) {
+	var original syscall.Termios
+	if err := ioctlTermios(fd, syscall.TCGETS, &original); err != nil {
+		return nil, err
+	}
+	raw := original
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctlTermios(fd, syscall.TCSETS, &raw); err != nil {
+		return nil, err
+	}
+	return func() { ioctlTermios(fd, syscall.TCSETS, &original) }, nil
+}
+
+// termiosBaudMask is CBAUD, which syscall does not define on all architectures
+const termiosBaudMask = 0x100f
+
+// serialBaudRates maps supported serial port speeds to termios constants
+var serialBaudRates = map[int]uint32{
+	1200: syscall.B1200, 2400: syscall.B2400, 4800: syscall.B4800, 9600: syscall.B9600, 19200: syscall.B19200,
+	38400: syscall.B38400, 57600: syscall.B57600, 115200: syscall.B115200, 230400: syscall.B230400,
+}
+
+// configureSerialPort sets a serial port to raw 8N1 at the given speed
+func configureSerialPort(fd int, baudRate int) error {
+	speed, ok := serialBaudRates[baudRate]
+	if !ok {
+		return fmt.Errorf("unsupported baud rate %d", baudRate)
+	}
+	var termios syscall.Termios
+	if err := ioctlTermios(fd, syscall.TCGETS, &termios); err != nil {
+		return err
+	}
+	termios.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	termios.Oflag &^= syscall.OPOST
+	termios.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	termios.Cflag &^= syscall.CSIZE | syscall.PARENB | syscall.CSTOPB | termiosBaudMask
+	termios.Cflag |= syscall.CS8 | syscall.CREAD | syscall.CLOCAL | speed
+	termios.Ispeed = speed
+	termios.Ospeed = speed
+	termios.Cc[syscall.VMIN] = 1
+	termios.Cc[syscall.VTIME] = 0
+	return ioctlTermios(fd, syscall.TCSETS, &termios)
+}
+
+func ioctlTermios(fd int, request uintptr, termios *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(unsafe.Pointer(termios))); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// makeRawTerminal is only supported on Linux; other platforms use the line based wizard.
+func makeRawTerminal(fd int) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
+
+// configureSerialPort is only supported on Linux; configure the port with other tools and use -baud 0.
+func configureSerialPort(fd int, baudRate int) error {
+	return errors.New("serial port settings are not supported on this platform; use -baud 0")
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MolarGasConstant is the gas constant in J/(mol K), used with heat capacities
+const MolarGasConstant = 8.314462618
+
+// HeatCapacity returns mole fraction weighted ideal gas heat capacity at constant pressure, J/(mol K)
+func (gc GasComposition) HeatCapacity() float64 {
+	var heatCapacity, fractionSum float64
+	for gasType, fraction := range gc {
+		heatCapacity += fraction * SpeciesLookup[gasType].HeatCapacity
+		fractionSum += fraction
+	}
+	if fractionSum == 0 {
+		return 0
+	}
+	return heatCapacity / fractionSum
+}
+
+// HeatCapacityRatio returns the ratio of heat capacities (cp/cv) of the mix
+func (gc GasComposition) HeatCapacityRatio() float64 {
+	heatCapacity := gc.HeatCapacity()
+	return heatCapacity / (heatCapacity - MolarGasConstant)
+}
+
+// FillProcess describes how gas temperature behaves during a transfer
+type FillProcess struct {
+	// Adiabatic transfers have no heat exchange; the heat capacity ratio of each gas is used as the exponent
+	Adiabatic bool
+	// Exponent is the polytropic exponent when not adiabatic; 1 (or unset) is isothermal
+	Exponent float64
+}
+
+// Isothermal reports whether gas stays at ambient temperature; the zero value is isothermal
+func (p FillProcess) Isothermal() bool {
+	return !p.Adiabatic && (p.Exponent == 0 || p.Exponent == 1)
+}
+
+// IsothermalFill keeps gas at ambient temperature during transfers
+var IsothermalFill = FillProcess{Exponent: 1}
+
+// AdiabaticFill transfers gas without heat exchange
+var AdiabaticFill = FillProcess{Adiabatic: true}
+
+// ParseFillProcess returns fill process matching the name (isothermal, adiabatic or polytropic). Exponent is used
+// for polytropic processes and must be at least 1.
+func ParseFillProcess(name string, exponent float64) (FillProcess, error) {
+	switch strings.ToLower(name) {
+	case "isothermal":
+		return IsothermalFill, nil
+	case "adiabatic":
+		return AdiabaticFill, nil
+	case "polytropic":
+		if exponent < 1 {
+			return FillProcess{}, fmt.Errorf("invalid polytropic exponent %g; must be at least 1", exponent)
+		}
+		return FillProcess{Exponent: exponent}, nil
+	}
+	return FillProcess{}, fmt.Errorf("unknown fill process %q; must be isothermal, adiabatic or polytropic", name)
+}
+
+// String returns the name of the fill process
+func (p FillProcess) String() string {
+	if p.Adiabatic {
+		return "adiabatic"
+	}
+	if p.Isothermal() {
+		return "isothermal"
+	}
+	return fmt.Sprintf("polytropic n=%g", p.Exponent)
+}
+
+// heatingFraction returns how much of the adiabatic temperature change happens with the given heat capacity ratio
+func (p FillProcess) heatingFraction(heatCapacityRatio float64) float64 {
+	if p.Adiabatic {
+		return 1
+	}
+	return (p.Exponent - 1) / (heatCapacityRatio - 1)
+}
+
+// expansionExponent returns the exponent used for gas expanding in the source
+func (p FillProcess) expansionExponent(heatCapacityRatio float64) float64 {
+	if p.Adiabatic {
+		return heatCapacityRatio
+	}
+	return p.Exponent
+}
+
+// FillResult describes a transfer where gas temperature changes while filling
+type FillResult struct {
+	DestinationPressureBefore PressureBar
+	// HotPressure and HotTemperature are destination pressure and temperature right after the transfer
+	HotPressure    PressureBar
+	HotTemperature Temperature
+	// SettledPressure is the destination pressure after cooling down to ambient temperature
+	SettledPressure PressureBar
+	// SourceTemperature is the source temperature right after the transfer; expanding gas cools down
+	SourceTemperature   Temperature
+	SourcePressureAfter PressureBar
+	TransferredMoles    MoleCount
+}
+
+// Fill transfers gas from source to c1 until the pressures equalize. Gas entering the destination is compressed
+// and heats up, so the transfer stops earlier than with a slow fill; the source cools down as it expands.
+// Adiabatic temperatures use ideal gas heat capacities and gas entering at ambient temperature; polytropic
+// processes scale the adiabatic temperature rise by (n-1)/(k-1). Pressures use the gas system. Both cylinders are
+// left at the pressures they settle to at ambient temperature.
+func (c1 *Cylinder) Fill(source *Cylinder, fillProcess FillProcess, gasSystem GasSystem, temperature Temperature) FillResult {
+	result := FillResult{
+		DestinationPressureBefore: c1.Pressure,
+		HotPressure:               c1.Pressure,
+		HotTemperature:            temperature,
+		SettledPressure:           c1.Pressure,
+		SourceTemperature:         temperature,
+		SourcePressureAfter:       source.Pressure,
+	}
+	if source.Pressure <= c1.Pressure {
+		return result
+	}
+	destinationMoles := gasSystem.Moles(c1.CylinderVolume, c1.Pressure, temperature, c1.GasComposition)
+	sourceMoles := gasSystem.Moles(source.CylinderVolume, source.Pressure, temperature, source.GasComposition)
+	destinationHeatCapacity := c1.GasComposition.HeatCapacity() - MolarGasConstant
+	sourceHeatCapacity := source.GasComposition.HeatCapacity()
+	sourceExponent := fillProcess.expansionExponent(source.GasComposition.HeatCapacityRatio())
+
+	var gasComposition GasComposition
+	var hotTemperature, sourceTemperature Temperature
+	var hotPressure, sourcePressure PressureBar
+	transfer := func(moles MoleCount) {
+		totalMoles := destinationMoles + moles
+		gasComposition = make(GasComposition)
+		for gasType, fraction := range c1.GasComposition {
+			gasComposition[gasType] += fraction * float64(destinationMoles/totalMoles)
+		}
+		for gasType, fraction := range source.GasComposition {
+			gasComposition[gasType] += fraction * float64(moles/totalMoles)
+		}
+		// Internal energy of the destination grows by the enthalpy of the gas pushed in
+		energy := float64(destinationMoles)*destinationHeatCapacity*float64(temperature) + float64(moles)*sourceHeatCapacity*float64(temperature)
+		adiabaticTemperature := energy / (float64(totalMoles) * (gasComposition.HeatCapacity() - MolarGasConstant))
+		heatingFraction := fillProcess.heatingFraction(gasComposition.HeatCapacityRatio())
+		hotTemperature = Temperature(float64(temperature) + heatingFraction*(adiabaticTemperature-float64(temperature)))
+		hotPressure = gasSystem.Pressure(c1.CylinderVolume, totalMoles, hotTemperature, gasComposition)
+		remainingMoles := sourceMoles - moles
+		sourceTemperature = Temperature(float64(temperature) * math.Pow(float64(remainingMoles/sourceMoles), sourceExponent-1))
+		sourcePressure = gasSystem.Pressure(source.CylinderVolume, remainingMoles, sourceTemperature, source.GasComposition)
+	}
+	low, high := MoleCount(0), sourceMoles
+	for i := 0; i < 60; i++ {
+		moles := (low + high) / 2
+		transfer(moles)
+		// Real gas equations give no meaningful pressure when the destination would hold more gas than fits in it
+		if hotPressure > sourcePressure || !(hotPressure > 0) {
+			high = moles
+		} else {
+			low = moles
+		}
+	}
+	transfer(low)
+	result.TransferredMoles = low
+	result.HotPressure = hotPressure
+	result.HotTemperature = hotTemperature
+	result.SourceTemperature = sourceTemperature
+	c1.GasComposition = gasComposition
+	c1.Pressure = gasSystem.Pressure(c1.CylinderVolume, destinationMoles+low, temperature, gasComposition)
+	source.Pressure = gasSystem.Pressure(source.CylinderVolume, sourceMoles-low, temperature, source.GasComposition)
+	result.SettledPressure = c1.Pressure
+	result.SourcePressureAfter = source.Pressure
+	return result
+}
+
+// SettledPressure returns the pressure of the cylinder, read at the hot temperature right after filling, once it has
+// cooled down to the temperature. The amount of gas and the mix stay the same.
+func SettledPressure(cylinder Cylinder, hotTemperature Temperature, gasSystem GasSystem, temperature Temperature) PressureBar {
+	moles := gasSystem.Moles(cylinder.CylinderVolume, cylinder.Pressure, hotTemperature, cylinder.GasComposition)
+	return gasSystem.Pressure(cylinder.CylinderVolume, moles, temperature, cylinder.GasComposition)
+}
@@ -0,0 +1,99 @@
+package main
+
+import "testing"
+
+func TestHeatCapacityRatio(t *testing.T) {
+	if ratio := (GasComposition{Helium: 1}).HeatCapacityRatio(); ratio < 1.66 || ratio > 1.67 {
+		t.Errorf("Invalid heat capacity ratio %f for helium", ratio)
+	}
+	if ratio := (GasComposition{Oxygen: 0.21, Nitrogen: 0.79}).HeatCapacityRatio(); ratio < 1.39 || ratio > 1.41 {
+		t.Errorf("Invalid heat capacity ratio %f for air", ratio)
+	}
+}
+
+func TestAdiabaticFill(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	source := Cylinder{CylinderVolume: 50, Pressure: 232, GasComposition: air}
+	destination := Cylinder{CylinderVolume: 12, Pressure: 50, GasComposition: air.Clone()}
+	slowSource, slowDestination := source, destination
+	slowDestination.Equalize(&slowSource, IdealGas, 293.15, nil)
+
+	result := destination.Fill(&source, AdiabaticFill, IdealGas, 293.15)
+	if result.HotTemperature <= 293.15 || result.SourceTemperature >= 293.15 {
+		t.Errorf("Invalid temperatures %f (destination) and %f (source)", result.HotTemperature, result.SourceTemperature)
+	}
+	if result.HotPressure <= result.SettledPressure {
+		t.Errorf("Hot pressure %f must be above settled pressure %f", result.HotPressure, result.SettledPressure)
+	}
+	if result.SettledPressure >= slowDestination.Pressure {
+		t.Errorf("Settled pressure %f must be below slow fill pressure %f", result.SettledPressure, slowDestination.Pressure)
+	}
+	if result.SettledPressure <= 50 {
+		t.Errorf("Settled pressure %f must be above the starting pressure", result.SettledPressure)
+	}
+}
+
+func TestAdiabaticFillFromLargeSource(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	source := Cylinder{CylinderVolume: 100, Pressure: 206, GasComposition: air}
+	destination := Cylinder{CylinderVolume: 12, Pressure: 50, GasComposition: air.Clone()}
+	result := destination.Fill(&source, AdiabaticFill, VanDerWaals, 293.15)
+	if result.SettledPressure <= 50 || result.HotPressure > 206 || source.Pressure <= 150 {
+		t.Errorf("Invalid fill %+v; source ends at %f", result, source.Pressure)
+	}
+}
+
+func TestPolytropicFill(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	var settledPressures []PressureBar
+	for _, fillProcess := range []FillProcess{IsothermalFill, {Exponent: 1.2}, AdiabaticFill} {
+		source := Cylinder{CylinderVolume: 50, Pressure: 232, GasComposition: air}
+		destination := Cylinder{CylinderVolume: 12, Pressure: 50, GasComposition: air.Clone()}
+		result := destination.Fill(&source, fillProcess, IdealGas, 293.15)
+		settledPressures = append(settledPressures, result.SettledPressure)
+	}
+	isothermal := settledPressures[0]
+	expected := (50*232 + 12*50) / 62.0
+	if !compareFloats(float64(isothermal), expected) {
+		t.Errorf("Invalid isothermal pressure %f, expected %f", isothermal, expected)
+	}
+	if !(settledPressures[0] > settledPressures[1] && settledPressures[1] > settledPressures[2]) {
+		t.Errorf("Settled pressures %v must decrease with the exponent", settledPressures)
+	}
+	if _, err := ParseFillProcess("polytropic", 0.9); err == nil {
+		t.Error("Expected an error for exponent below 1")
+	}
+}
+
+func TestCoolDownAndRetop(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	source := Cylinder{CylinderVolume: 50, Pressure: 232, GasComposition: air}
+	destination := Cylinder{CylinderVolume: 12, Pressure: 50, GasComposition: air.Clone()}
+	previous := destination.Pressure
+	for cycle := 0; cycle < 5; cycle++ {
+		destination.Fill(&source, AdiabaticFill, IdealGas, 293.15)
+		if destination.Pressure < previous {
+			t.Fatalf("Cycle %d: pressure %f dropped below %f", cycle, destination.Pressure, previous)
+		}
+		previous = destination.Pressure
+	}
+	expected := (50*232 + 12*50) / 62.0
+	if float64(destination.Pressure) < expected-1 || float64(destination.Pressure) > expected+1e-6 {
+		t.Errorf("Pressure %f after re-tops must approach the equalized pressure %f", destination.Pressure, expected)
+	}
+}
+
+func TestSettledPressure(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	cylinder := Cylinder{CylinderVolume: 12, Pressure: 210, GasComposition: air}
+	if pressure := SettledPressure(cylinder, ZeroCelsius+45, IdealGas, ZeroCelsius+20); !compareFloats(float64(pressure), 210*293.15/318.15) {
+		t.Errorf("Invalid settled pressure %f, expected %f", pressure, 210*293.15/318.15)
+	}
+	// Pressure of real gases near 200 bar changes more with temperature than ideal gas pressure
+	if pressure := SettledPressure(cylinder, ZeroCelsius+45, VanDerWaals, ZeroCelsius+20); pressure >= 210*293.15/318.15 || pressure < 180 {
+		t.Errorf("Invalid settled pressure with Van der Waals %f", pressure)
+	}
+	if pressure := SettledPressure(cylinder, ZeroCelsius+20, VanDerWaals, ZeroCelsius+20); !compareFloats(float64(pressure), 210) {
+		t.Errorf("Expected the pressure to stay the same at the same temperature, got %f", pressure)
+	}
+}
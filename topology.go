@@ -0,0 +1,433 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Topology is plumbing of cylinders connected by valves, whips and manifolds, typically loaded from a JSON file.
+// Cylinders are the nodes and connections the edges of the graph; cylinders connected through open connections
+// equalize together, which expresses fill panels, quads and sidemount rigs.
+type Topology struct {
+	Cylinders   []TopologyCylinder   `json:"cylinders"`
+	Connections []TopologyConnection `json:"connections"`
+	// Steps open and close connections in order; without steps, the connections equalize once as they are
+	Steps []TopologyStep `json:"steps,omitempty"`
+}
+
+// TopologyCylinder is a cylinder of a topology, named for connections. Volume is in liters and pressure in bar.
+type TopologyCylinder struct {
+	Name string `json:"name"`
+	// Valve is single (the default), h or y. An H or Y valve has a left and a right outlet with a knob each, for
+	// separate regulators; connections attach to them as "<name>/left" and "<name>/right", and the knobs are
+	// connections of the same names between the cylinder and its outlets.
+	Valve string `json:"valve,omitempty"`
+	// ClosedOutlets are the outlets of an H or Y valve with the knob closed initially
+	ClosedOutlets []string `json:"closed_outlets,omitempty"`
+	ScenarioCylinder
+}
+
+// topologyOutlets are the outlets of an H or Y valve
+var topologyOutlets = []string{"left", "right"}
+
+// outletName returns the name of an outlet of a cylinder with an H or Y valve, such as "stage/left"
+func outletName(cylinder string, outlet string) string {
+	return cylinder + "/" + outlet
+}
+
+// TopologyConnection is a valve, whip or manifold between two cylinders
+type TopologyConnection struct {
+	Name string `json:"name"`
+	// Kind is valve, whip or manifold; it only describes the connection
+	Kind string `json:"kind,omitempty"`
+	From string `json:"from"`
+	To   string `json:"to"`
+	Open bool   `json:"open,omitempty"`
+}
+
+// TopologyStep opens and closes connections before equalizing the cylinders connected through open connections
+type TopologyStep struct {
+	Description string   `json:"description,omitempty"`
+	Open        []string `json:"open,omitempty"`
+	Close       []string `json:"close,omitempty"`
+}
+
+// TopologyStepResult is the cylinders of a topology after a step
+type TopologyStepResult struct {
+	Description string
+	// Groups are the names of the cylinders equalized together, in the order of the cylinders
+	Groups    [][]string
+	Cylinders CylinderList
+}
+
+// LoadTopology reads a JSON topology file
+func LoadTopology(path string) (Topology, error) {
+	var topology Topology
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return topology, err
+	}
+	if err := json.Unmarshal(content, &topology); err != nil {
+		return topology, fmt.Errorf("invalid topology file %s: %w", path, err)
+	}
+	return topology, nil
+}
+
+// CylinderList returns the cylinders of the topology, described by their names. Pressures are in bar, relative to
+// the pressure reference of the unit system.
+func (t Topology) CylinderList(units UnitSystem) (CylinderList, error) {
+	scenarioCylinders := make([]ScenarioCylinder, len(t.Cylinders))
+	names := make(map[string]bool)
+	for i, cylinder := range t.Cylinders {
+		if cylinder.Name == "" {
+			return nil, fmt.Errorf("cylinder %d has no name", i+1)
+		}
+		if names[cylinder.Name] {
+			return nil, fmt.Errorf("duplicate cylinder name %q", cylinder.Name)
+		}
+		if strings.Contains(cylinder.Name, "/") {
+			return nil, fmt.Errorf("cylinder name %q must not contain /, which names valve outlets", cylinder.Name)
+		}
+		names[cylinder.Name] = true
+		scenarioCylinders[i] = cylinder.ScenarioCylinder
+		scenarioCylinders[i].Description = cylinder.Name
+	}
+	return scenarioCylinderList(scenarioCylinders, "cylinder", units)
+}
+
+// nodes returns the names of the cylinders followed by the outlets of their H and Y valves, which connections join
+func (t Topology) nodes() []string {
+	var nodes, outlets []string
+	for _, cylinder := range t.Cylinders {
+		nodes = append(nodes, cylinder.Name)
+		if cylinder.Valve == "h" || cylinder.Valve == "y" {
+			for _, outlet := range topologyOutlets {
+				outlets = append(outlets, outletName(cylinder.Name, outlet))
+			}
+		}
+	}
+	return append(nodes, outlets...)
+}
+
+// connections returns the connections of the topology followed by the knobs joining the outlets of H and Y valves
+// to their cylinders
+func (t Topology) connections() []TopologyConnection {
+	connections := append([]TopologyConnection(nil), t.Connections...)
+	for _, cylinder := range t.Cylinders {
+		if cylinder.Valve != "h" && cylinder.Valve != "y" {
+			continue
+		}
+		for _, outlet := range topologyOutlets {
+			name := outletName(cylinder.Name, outlet)
+			connections = append(connections, TopologyConnection{Name: name, Kind: "valve", From: cylinder.Name, To: name, Open: !slices.Contains(cylinder.ClosedOutlets, outlet)})
+		}
+	}
+	return connections
+}
+
+// connectionStates returns whether each connection is open initially, by name, after checking that connections
+// join two different cylinders or valve outlets of the topology and steps refer to connections of the topology
+func (t Topology) connectionStates() (map[string]bool, error) {
+	for _, cylinder := range t.Cylinders {
+		switch cylinder.Valve {
+		case "", "single", "h", "y":
+		default:
+			return nil, fmt.Errorf("unknown valve %q of cylinder %s; must be single, h or y", cylinder.Valve, cylinder.Name)
+		}
+		for _, outlet := range cylinder.ClosedOutlets {
+			if (cylinder.Valve != "h" && cylinder.Valve != "y") || !slices.Contains(topologyOutlets, outlet) {
+				return nil, fmt.Errorf("unknown outlet %q of cylinder %s; H and Y valves have outlets %s", outlet, cylinder.Name, strings.Join(topologyOutlets, " and "))
+			}
+		}
+	}
+	nodes := make(map[string]bool)
+	for _, node := range t.nodes() {
+		nodes[node] = true
+	}
+	open := make(map[string]bool)
+	for i, connection := range t.connections() {
+		if connection.Name == "" {
+			return nil, fmt.Errorf("connection %d has no name", i+1)
+		}
+		if _, ok := open[connection.Name]; ok {
+			return nil, fmt.Errorf("duplicate connection name %q", connection.Name)
+		}
+		switch connection.Kind {
+		case "", "valve", "whip", "manifold":
+		default:
+			return nil, fmt.Errorf("unknown kind %q of connection %s; must be valve, whip or manifold", connection.Kind, connection.Name)
+		}
+		for _, end := range []string{connection.From, connection.To} {
+			if !nodes[end] {
+				return nil, fmt.Errorf("unknown cylinder or outlet %q in connection %s", end, connection.Name)
+			}
+		}
+		if connection.From == connection.To {
+			return nil, fmt.Errorf("connection %s must join two different cylinders", connection.Name)
+		}
+		open[connection.Name] = connection.Open
+	}
+	for i, step := range t.Steps {
+		for _, name := range append(append([]string(nil), step.Open...), step.Close...) {
+			if _, ok := open[name]; !ok {
+				return nil, fmt.Errorf("unknown connection %q in step %d", name, i+1)
+			}
+		}
+	}
+	return open, nil
+}
+
+// groups returns the indexes of the cylinders connected to each other through open connections or the extra
+// connections, for groups of more than one cylinder in the order of their first cylinder. Cylinders connected to the
+// same valve outlet are connected even with its knob closed.
+func (t Topology) groups(open map[string]bool, extra ...TopologyConnection) [][]int {
+	nodes := t.nodes()
+	index := make(map[string]int)
+	for i, node := range nodes {
+		index[node] = i
+	}
+	// Union-find over the cylinders and valve outlets with each group represented by its first cylinder
+	parent := make([]int, len(nodes))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	connections := append([]TopologyConnection(nil), extra...)
+	for _, connection := range t.connections() {
+		if open[connection.Name] {
+			connections = append(connections, connection)
+		}
+	}
+	for _, connection := range connections {
+		from, to := find(index[connection.From]), find(index[connection.To])
+		parent[max(from, to)] = min(from, to)
+	}
+	members := make(map[int][]int)
+	var roots []int
+	for i := range t.Cylinders {
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], i)
+	}
+	var groups [][]int
+	for _, root := range roots {
+		if len(members[root]) > 1 {
+			groups = append(groups, members[root])
+		}
+	}
+	return groups
+}
+
+// topologyState is the cylinders of a topology and its open connections while simulating it
+type topologyState struct {
+	topology    Topology
+	cylinders   CylinderList
+	open        map[string]bool
+	gasSystem   GasSystem
+	temperature Temperature
+}
+
+// newState starts simulating the topology from cylinders, which are not changed
+func (t Topology) newState(cylinders CylinderList, gasSystem GasSystem, temperature Temperature) (*topologyState, error) {
+	open, err := t.connectionStates()
+	if err != nil {
+		return nil, err
+	}
+	return &topologyState{topology: t, cylinders: append(CylinderList(nil), cylinders...), open: open, gasSystem: gasSystem, temperature: temperature}, nil
+}
+
+// topologySnapshot is the full state of a topology simulation: the cylinders with their mixes, the open
+// connections and the temperature
+type topologySnapshot struct {
+	cylinders   CylinderList
+	open        map[string]bool
+	temperature Temperature
+}
+
+// copyCylinders returns copies of the cylinders with copies of their mixes
+func copyCylinders(cylinders CylinderList) CylinderList {
+	copied := append(CylinderList(nil), cylinders...)
+	for i := range copied {
+		copied[i].GasComposition = copied[i].GasComposition.Clone()
+	}
+	return copied
+}
+
+// snapshot returns the current state of the simulation to roll back to
+func (s *topologyState) snapshot() topologySnapshot {
+	return topologySnapshot{cylinders: copyCylinders(s.cylinders), open: maps.Clone(s.open), temperature: s.temperature}
+}
+
+// restore rolls the simulation back to the snapshot, which can be restored again
+func (s *topologyState) restore(snapshot topologySnapshot) {
+	s.cylinders = copyCylinders(snapshot.cylinders)
+	s.open = maps.Clone(snapshot.open)
+	s.temperature = snapshot.temperature
+}
+
+// equalize equalizes each group of cylinders connected through open connections or the extra connections and
+// returns the cylinders after it
+func (s *topologyState) equalize(description string, extra ...TopologyConnection) TopologyStepResult {
+	result := TopologyStepResult{Description: description}
+	for _, group := range s.topology.groups(s.open, extra...) {
+		groupCylinders := make([]*Cylinder, len(group))
+		names := make([]string, len(group))
+		for i, cylinderIndex := range group {
+			groupCylinders[i] = &s.cylinders[cylinderIndex]
+			names[i] = s.cylinders[cylinderIndex].Description
+		}
+		Equalize(groupCylinders, s.gasSystem, s.temperature, nil)
+		result.Groups = append(result.Groups, names)
+	}
+	result.Cylinders = append(CylinderList(nil), s.cylinders...)
+	return result
+}
+
+// Simulate equalizes the cylinders of the topology connected through open connections after each step, starting
+// from cylinders, and returns the cylinders after each step
+func (t Topology) Simulate(cylinders CylinderList, gasSystem GasSystem, temperature Temperature) ([]TopologyStepResult, error) {
+	state, err := t.newState(cylinders, gasSystem, temperature)
+	if err != nil {
+		return nil, err
+	}
+	steps := t.Steps
+	if len(steps) == 0 {
+		steps = []TopologyStep{{Description: "connections as described"}}
+	}
+	var results []TopologyStepResult
+	for _, step := range steps {
+		for _, name := range step.Open {
+			state.open[name] = true
+		}
+		for _, name := range step.Close {
+			state.open[name] = false
+		}
+		description := step.Description
+		if description == "" {
+			description = topologyStepDescription(step)
+		}
+		results = append(results, state.equalize(description))
+	}
+	return results, nil
+}
+
+// topologyStepDescription describes the connections a step opens and closes, such as "open whip; close left valve"
+func topologyStepDescription(step TopologyStep) string {
+	var parts []string
+	if len(step.Open) > 0 {
+		parts = append(parts, "open "+strings.Join(step.Open, ", "))
+	}
+	if len(step.Close) > 0 {
+		parts = append(parts, "close "+strings.Join(step.Close, ", "))
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// printTopologyResults prints the groups equalized at each step and a table of the cylinders before and after
+// all steps
+func printTopologyResults(w io.Writer, cylinders CylinderList, results []TopologyStepResult, units UnitSystem, style tableStyle) {
+	pressureUnit := units.PressureUnit()
+	for i, result := range results {
+		fmt.Fprintf(w, "Step %d: %s\n", i+1, result.Description)
+		if len(result.Groups) == 0 {
+			fmt.Fprintln(w, "  no cylinders connected")
+		}
+		for _, group := range result.Groups {
+			cylinder := result.Cylinders[slices.IndexFunc(result.Cylinders, func(c Cylinder) bool { return c.Description == group[0] })]
+			fmt.Fprintf(w, "  %s: %.0f%s, %s\n", strings.Join(group, ", "), units.Pressure(cylinder.Pressure), pressureUnit, cylinder.GasComposition)
+		}
+	}
+	if len(results) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	var topologyTable table
+	topologyTable.addColumn("cylinder", 0, true)
+	topologyTable.addColumn("start "+pressureUnit, 0, false)
+	topologyTable.addColumn("end "+pressureUnit, 0, false)
+	topologyTable.addColumn("change", 0, false)
+	topologyTable.addColumn("end mix", 0, true)
+	for i, cylinder := range results[len(results)-1].Cylinders {
+		start, end := units.Pressure(cylinders[i].Pressure), units.Pressure(cylinder.Pressure)
+		topologyTable.addRow("", cylinder.Description, fmt.Sprintf("%.0f", start), fmt.Sprintf("%.0f", end), fmt.Sprintf("%+.0f", end-start), cylinder.GasComposition.String())
+	}
+	topologyTable.render(w, style)
+}
+
+func topologyMain(args []string) error {
+	fs := flag.NewFlagSet("topology", flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	gasFlags := registerGasCompositionFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s topology [flags] <topology.json>\n\nThe file lists cylinders, the valves, whips and manifolds connecting them, and steps opening and closing them.\n\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	gasSystem, temperature, err := flags.gasSettings(units)
+	if err != nil {
+		return err
+	}
+	gasComposition, err := gasFlags.gasComposition()
+	if err != nil {
+		return err
+	}
+	style, err := flags.parseTableStyle()
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	topology, err := LoadTopology(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	cylinders, err := topology.CylinderList(units)
+	if err != nil {
+		return fmt.Errorf("invalid topology: %w", err)
+	}
+	if err := checkCylinders(cylinders, "cylinder", true, units); err != nil {
+		return err
+	}
+	cylinders.SetDefaultGasComposition(gasComposition)
+	results, err := topology.Simulate(cylinders, gasSystem, temperature)
+	if err != nil {
+		return fmt.Errorf("invalid topology: %w", err)
+	}
+	w, err := flags.output(os.Stdout, units)
+	if err != nil {
+		return err
+	}
+	printTopologyResults(w, cylinders, results, units, style)
+	return nil
+}
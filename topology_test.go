@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+const testQuadTopology = `{
+	"cylinders": [
+		{"name": "bank", "volume": 50, "pressure": 230},
+		{"name": "q1", "volume": 12, "pressure": 50},
+		{"name": "q2", "volume": 12, "pressure": 70},
+		{"name": "spare", "volume": 12, "pressure": 100}
+	],
+	"connections": [
+		{"name": "whip", "kind": "whip", "from": "bank", "to": "q1"},
+		{"name": "bar", "kind": "manifold", "from": "q1", "to": "q2", "open": true}
+	],
+	"steps": [{}, {"open": ["whip"]}]
+}`
+
+func TestTopologySimulate(t *testing.T) {
+	var topology Topology
+	if err := json.Unmarshal([]byte(testQuadTopology), &topology); err != nil {
+		t.Fatal(err)
+	}
+	cylinders, err := topology.CylinderList(Metric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cylinders.SetDefaultGasComposition(GasComposition{Oxygen: 0.21, Nitrogen: 0.79})
+	results, err := topology.Simulate(cylinders, IdealGas, 293.15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[1].Description != "open whip" {
+		t.Fatalf("Unexpected results %+v", results)
+	}
+	// The manifold equalizes the quad first, then the whip joins the bank
+	if len(results[0].Groups) != 1 || strings.Join(results[0].Groups[0], ",") != "q1,q2" || math.Abs(float64(results[0].Cylinders[1].Pressure)-60) > 1e-6 {
+		t.Errorf("Unexpected first step %+v", results[0])
+	}
+	expected := (CylinderList{cylinders[0], results[0].Cylinders[1], results[0].Cylinders[2]}).CombinedPressure(IdealGas, 293.15)
+	for i, cylinder := range results[1].Cylinders[:3] {
+		if math.Abs(float64(cylinder.Pressure-expected)) > 1e-6 {
+			t.Errorf("Expected %s at %.2fbar, got %.2fbar", cylinders[i].Description, expected, cylinder.Pressure)
+		}
+	}
+	if results[1].Cylinders[3].Pressure != cylinders[3].Pressure {
+		t.Errorf("Expected the unconnected cylinder to keep its pressure, got %.2fbar", results[1].Cylinders[3].Pressure)
+	}
+	// Cylinders passed in are not changed
+	if cylinders[0].Pressure != 230 {
+		t.Errorf("Expected the starting cylinders unchanged, got %.2fbar", cylinders[0].Pressure)
+	}
+}
+
+func TestTopologyErrors(t *testing.T) {
+	for _, topology := range []string{
+		`{"cylinders": [{"name": "a", "volume": 12}, {"name": "a", "volume": 12}]}`,
+		`{"cylinders": [{"volume": 12}]}`,
+		`{"cylinders": [{"name": "a", "volume": 12}], "connections": [{"name": "whip", "from": "a", "to": "b"}]}`,
+		`{"cylinders": [{"name": "a", "volume": 12}], "connections": [{"name": "whip", "from": "a", "to": "a"}]}`,
+		`{"cylinders": [{"name": "a", "volume": 12}, {"name": "b", "volume": 12}], "connections": [{"name": "whip", "kind": "hose", "from": "a", "to": "b"}]}`,
+		`{"cylinders": [{"name": "a", "volume": 12}, {"name": "b", "volume": 12}], "connections": [{"name": "whip", "from": "a", "to": "b"}], "steps": [{"open": ["valve"]}]}`,
+		`{"cylinders": [{"name": "a/b", "volume": 12}]}`,
+		`{"cylinders": [{"name": "a", "volume": 12, "valve": "k"}]}`,
+		`{"cylinders": [{"name": "a", "volume": 12, "valve": "h", "closed_outlets": ["center"]}]}`,
+		`{"cylinders": [{"name": "a", "volume": 12, "closed_outlets": ["left"]}]}`,
+		`{"cylinders": [{"name": "a", "volume": 12}, {"name": "b", "volume": 12}], "connections": [{"name": "whip", "from": "a/left", "to": "b"}]}`,
+	} {
+		var parsed Topology
+		if err := json.Unmarshal([]byte(topology), &parsed); err != nil {
+			t.Fatal(err)
+		}
+		cylinders, err := parsed.CylinderList(Metric)
+		if err == nil {
+			_, err = parsed.Simulate(cylinders, IdealGas, 293.15)
+		}
+		if err == nil {
+			t.Errorf("Expected an error for %s", topology)
+		}
+	}
+}
+
+func TestTopologyHValve(t *testing.T) {
+	var topology Topology
+	err := json.Unmarshal([]byte(`{
+		"cylinders": [
+			{"name": "bank", "volume": 50, "pressure": 200},
+			{"name": "stage", "volume": 10, "pressure": 50, "valve": "h", "closed_outlets": ["left"]},
+			{"name": "spare", "volume": 10, "pressure": 80}
+		],
+		"connections": [
+			{"name": "whip", "kind": "whip", "from": "bank", "to": "stage/left"},
+			{"name": "gauge", "kind": "whip", "from": "spare", "to": "stage/left", "open": true}
+		],
+		"steps": [{"open": ["whip"]}, {"close": ["whip"]}, {"open": ["stage/left"]}]
+	}`), &topology)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cylinders, err := topology.CylinderList(Metric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cylinders.SetDefaultGasComposition(GasComposition{Oxygen: 0.21, Nitrogen: 0.79})
+	results, err := topology.Simulate(cylinders, IdealGas, 293.15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// With the knob closed the whip only reaches the gauge hose on the same outlet
+	if len(results[0].Groups) != 1 || strings.Join(results[0].Groups[0], ",") != "bank,spare" || results[0].Cylinders[1].Pressure != 50 {
+		t.Errorf("Unexpected first step %+v", results[0])
+	}
+	// 10l at 50bar and 10l at the 180bar of bank and spare
+	if strings.Join(results[2].Groups[0], ",") != "stage,spare" || math.Abs(float64(results[2].Cylinders[1].Pressure)-115) > 1e-6 {
+		t.Errorf("Unexpected last step %+v", results[2])
+	}
+}
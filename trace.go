@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// TraceGasExposure describes a trace gas (such as CO or CO2 contamination) at cylinder pressure and when breathed
+// at depth
+type TraceGasExposure struct {
+	Gas                     Gas
+	Fraction                float64
+	CylinderPartialPressure PressureBar
+	DepthPartialPressure    PressureBar
+	// SurfaceEquivalentFraction is the fraction giving the same partial pressure when breathed at the surface
+	SurfaceEquivalentFraction float64
+}
+
+// TraceGasExposures returns exposures for all gases below TraceGasFraction, and for CO and CO2 at any fraction
+func TraceGasExposures(gasComposition GasComposition, cylinderPressure PressureBar, depth Depth) []TraceGasExposure {
+	var exposures []TraceGasExposure
+	for gasType, fraction := range gasComposition {
+		if fraction <= 0 || (fraction > TraceGasFraction && gasType != CarbonDioxide && gasType != CarbonMonoxide) {
+			continue
+		}
+		exposures = append(exposures, TraceGasExposure{
+			Gas:                       gasType,
+			Fraction:                  fraction,
+			CylinderPartialPressure:   cylinderPressure.PartialPressure(fraction),
+			DepthPartialPressure:      depth.AmbientPressure().PartialPressure(fraction),
+			SurfaceEquivalentFraction: fraction * float64(depth.AmbientPressure()) / SurfacePressure,
+		})
+	}
+	sort.Slice(exposures, func(i, j int) bool { return exposures[i].Gas < exposures[j].Gas })
+	return exposures
+}
+
+func traceMain(args []string) error {
+	fs := flag.NewFlagSet("trace", flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	var mixFlag = fs.String("mix", "air+10ppmCO", "Gas mix with trace gases, e.g. air+10ppmCO+500ppmCO2")
+	var pressureFlag = fs.String("pressure", "232bar", "Cylinder pressure")
+	var depthFlag = fs.String("depth", "30m", "Depth the gas is breathed at (m or ft)")
+	fs.Parse(args)
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	w, err := flags.output(os.Stdout, units)
+	if err != nil {
+		return err
+	}
+	gasComposition, err := ParseGasComposition(*mixFlag)
+	if err != nil {
+		return fmt.Errorf("invalid mix: %w", err)
+	}
+	pressure, err := units.ParsePressure(*pressureFlag)
+	if err != nil {
+		return fmt.Errorf("invalid pressure: %w", err)
+	}
+	depth, err := units.ParseDepth(*depthFlag)
+	if err != nil || depth < 0 {
+		return fmt.Errorf("invalid depth: %s", *depthFlag)
+	}
+	exposures := TraceGasExposures(gasComposition, pressure, depth)
+	if len(exposures) == 0 {
+		fmt.Fprintln(w, "No trace gases in", gasComposition)
+		return nil
+	}
+	fmt.Fprintf(w, "%8s %10s %14s %14s %16s\n", "gas", "ppm", "cylinder mbar", fmt.Sprintf("%.0f%s mbar", units.Depth(depth), units.DepthUnit()), "surface eqv ppm")
+	for _, exposure := range exposures {
+		fmt.Fprintf(w, "%8s %10.1f %14.2f %14.3f %16.1f\n", SpeciesLookup[exposure.Gas].Symbol, exposure.Fraction*1e6, float64(exposure.CylinderPartialPressure)*1000, float64(exposure.DepthPartialPressure)*1000, exposure.SurfaceEquivalentFraction*1e6)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestParseTraceGases(t *testing.T) {
+	gasComposition, err := ParseGasComposition("air+10ppmCO+0.5%CO2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !compareFloats(gasComposition[CarbonMonoxide], 10e-6) || !compareFloats(gasComposition[CarbonDioxide], 0.005) {
+		t.Errorf("Invalid trace gases %v", gasComposition)
+	}
+	if !compareFloats(gasComposition[Oxygen], 0.21*(1-0.00501)) {
+		t.Errorf("Invalid oxygen fraction %f", gasComposition[Oxygen])
+	}
+	if gasComposition.String() != "20.9/0.0 + CO2 0.5% + CO 10ppm" {
+		t.Errorf("Invalid gas composition %s", gasComposition)
+	}
+	if _, err := ParseGasComposition("air+10ppmXe"); err == nil {
+		t.Error("Expected an error for unknown gas")
+	}
+}
+
+func TestTraceGasExposures(t *testing.T) {
+	exposures := TraceGasExposures(GasComposition{Oxygen: 0.21, Nitrogen: 0.79, CarbonMonoxide: 10e-6}, 232, 30)
+	if len(exposures) != 1 || exposures[0].Gas != CarbonMonoxide {
+		t.Fatalf("Invalid exposures %+v", exposures)
+	}
+	if !compareFloats(float64(exposures[0].CylinderPartialPressure), 232*10e-6) {
+		t.Errorf("Invalid cylinder partial pressure %f", exposures[0].CylinderPartialPressure)
+	}
+	expected := 10e-6 * (SurfacePressure + 3) / SurfacePressure
+	if !compareFloats(exposures[0].SurfaceEquivalentFraction, expected) {
+		t.Errorf("Invalid surface equivalent fraction %f, expected %f", exposures[0].SurfaceEquivalentFraction, expected)
+	}
+}
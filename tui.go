@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+)
+
+// tuiFieldKind selects how a TUI field is shown and edited
+type tuiFieldKind int
+
+const (
+	tuiVolume tuiFieldKind = iota
+	tuiPressure
+	tuiPercent
+	tuiToggle
+)
+
+// tuiField is an adjustable value in the interactive mode. Values are liters, gauge bar, percent, or 0/1.
+type tuiField struct {
+	label string
+	kind  tuiFieldKind
+	value float64
+	step  float64
+	min   float64
+	max   float64
+}
+
+// adjust changes the value by the number of steps, keeping it within limits
+func (f *tuiField) adjust(steps int) {
+	if f.kind == tuiToggle {
+		f.value = 1 - f.value
+		return
+	}
+	f.value = math.Max(f.min, math.Min(f.max, f.value+float64(steps)*f.step))
+}
+
+func (f tuiField) format(units UnitSystem) string {
+	switch f.kind {
+	case tuiVolume:
+		return fmt.Sprintf("%.1fl", f.value)
+	case tuiPressure:
+		return fmt.Sprintf("%.0f%s", units.PressureDifference(PressureBar(f.value)), units.PressureUnit())
+	case tuiPercent:
+		return fmt.Sprintf("%.0f%%", f.value)
+	}
+	if f.value == 1 {
+		return "yes"
+	}
+	return "no"
+}
+
+// parse sets the value from user input, using the same units as flags
+func (f *tuiField) parse(units UnitSystem, s string) error {
+	var value float64
+	switch f.kind {
+	case tuiVolume:
+		volume, err := units.ParseCylinderVolume(s)
+		if err != nil {
+			return err
+		}
+		value = float64(volume)
+	case tuiPressure:
+		pressure, err := units.ParsePressureDifference(s)
+		if err != nil {
+			return err
+		}
+		value = float64(pressure)
+	case tuiPercent:
+		if _, err := fmt.Sscanf(strings.TrimSuffix(strings.TrimSpace(s), "%"), "%g", &value); err != nil {
+			return fmt.Errorf("invalid percentage %q", s)
+		}
+	case tuiToggle:
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "y", "yes", "1", "true":
+			value = 1
+		case "n", "no", "0", "false":
+		default:
+			return fmt.Errorf("expected yes or no, got %q", s)
+		}
+	}
+	if value < f.min || value > f.max {
+		return fmt.Errorf("%s must be between %g and %g", f.label, f.min, f.max)
+	}
+	f.value = value
+	return nil
+}
+
+// tuiModel holds the values edited in the interactive mode
+type tuiModel struct {
+	fields   []tuiField
+	selected int
+	// snapshot holds the values saved to roll back to, to explore changes; nil when none are saved
+	snapshot    []tuiField
+	units       UnitSystem
+	gasSystem   GasSystem
+	temperature Temperature
+}
+
+const (
+	tuiSourceVolume = iota
+	tuiSourcePressure
+	tuiSourceTwinset
+	tuiDestinationVolume
+	tuiDestinationPressure
+	tuiDestinationTwinset
+	tuiOxygen
+	tuiHelium
+)
+
+func newTUIModel(units UnitSystem, gasSystem GasSystem, temperature Temperature) *tuiModel {
+	return &tuiModel{
+		fields: []tuiField{
+			{label: "Source volume", kind: tuiVolume, value: 12, step: 0.5, min: 0.5, max: 1000},
+			{label: "Source pressure", kind: tuiPressure, value: 232, step: 5, min: 5, max: 350},
+			{label: "Source twinset", kind: tuiToggle, max: 1},
+			{label: "Destination volume", kind: tuiVolume, value: 12, step: 0.5, min: 0.5, max: 1000},
+			{label: "Destination pressure", kind: tuiPressure, value: 100, step: 5, min: 0, max: 350},
+			{label: "Destination twinset", kind: tuiToggle, max: 1},
+			{label: "Oxygen", kind: tuiPercent, value: 21, step: 1, min: 1, max: 100},
+			{label: "Helium", kind: tuiPercent, value: 0, step: 1, min: 0, max: 99},
+		},
+		units:       units,
+		gasSystem:   gasSystem,
+		temperature: temperature,
+	}
+}
+
+// cylinders returns the cylinders for a side described by the volume, pressure and twinset fields
+func (m *tuiModel) cylinders(volumeField int, description string) CylinderList {
+	volume := CylinderVolume(m.fields[volumeField].value)
+	pressure := m.units.AbsolutePressure(PressureBar(m.fields[volumeField+1].value))
+	if m.fields[volumeField+2].value == 1 {
+		return NewTwinset(volume, pressure)
+	}
+	return CylinderList{{Description: description, CylinderVolume: volume, Pressure: pressure}}
+}
+
+// summaries equalizes the cylinders described by the fields
+func (m *tuiModel) summaries() ([]CylinderSummary, error) {
+	oxygen := m.fields[tuiOxygen].value / 100
+	helium := m.fields[tuiHelium].value / 100
+	if oxygen+helium > 1 {
+		return nil, fmt.Errorf("oxygen and helium add up to more than 100%%")
+	}
+	if m.fields[tuiSourcePressure].value < m.fields[tuiDestinationPressure].value {
+		return nil, fmt.Errorf("source pressure must be higher than destination pressure")
+	}
+	gasComposition := GasComposition{Oxygen: oxygen, Helium: helium, Nitrogen: 1 - oxygen - helium}
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      m.cylinders(tuiSourceVolume, "source"),
+		DestinationCylinders: m.cylinders(tuiDestinationVolume, "destination"),
+	}
+	cylinderConfiguration.SourceCylinders.SetDefaultGasComposition(gasComposition)
+	cylinderConfiguration.DestinationCylinders.SetDefaultGasComposition(gasComposition)
+	return equalizeAllConfigurations(io.Discard, cylinderConfiguration, m.gasSystem, m.temperature, m.units, false, nil), nil
+}
+
+// render writes the fields and results; the selected field is marked when marker is set
+func (m *tuiModel) render(w io.Writer, marker bool) {
+	for i, field := range m.fields {
+		prefix := fmt.Sprintf("%d.", i+1)
+		if marker {
+			prefix = " "
+			if i == m.selected {
+				prefix = ">"
+			}
+		}
+		fmt.Fprintf(w, "%s %-22s %s\n", prefix, field.label, field.format(m.units))
+	}
+	if m.snapshot != nil {
+		var changes []string
+		for i, field := range m.fields {
+			if field.value != m.snapshot[i].value {
+				changes = append(changes, fmt.Sprintf("%s %s → %s", field.label, m.snapshot[i].format(m.units), field.format(m.units)))
+			}
+		}
+		if len(changes) == 0 {
+			changes = []string{"none"}
+		}
+		fmt.Fprintf(w, "\nChanges since snapshot: %s\n", strings.Join(changes, ", "))
+	}
+	fmt.Fprintln(w)
+	cylinderSummaries, err := m.summaries()
+	if err != nil {
+		fmt.Fprintln(w, err.Error())
+		return
+	}
+	printSummaries(w, cylinderSummaries, summaryOptions{}, m.units, false)
+}
+
+// keypress handles a key in the live panel and reports whether to quit
+func (m *tuiModel) keypress(key string) bool {
+	switch key {
+	case "q", "\x03", "\x04", "\x1b":
+		return true
+	case "\x1b[A", "k":
+		m.selected = (m.selected + len(m.fields) - 1) % len(m.fields)
+	case "\x1b[B", "j", "\t":
+		m.selected = (m.selected + 1) % len(m.fields)
+	case "\x1b[C", "l", "+":
+		m.fields[m.selected].adjust(1)
+	case "\x1b[D", "h", "-":
+		m.fields[m.selected].adjust(-1)
+	case "s":
+		m.takeSnapshot()
+	case "r":
+		m.rollback()
+	}
+	return false
+}
+
+// takeSnapshot saves the values to roll back to
+func (m *tuiModel) takeSnapshot() {
+	m.snapshot = slices.Clone(m.fields)
+}
+
+// rollback restores the values of the snapshot, if any
+func (m *tuiModel) rollback() {
+	if m.snapshot != nil {
+		m.fields = slices.Clone(m.snapshot)
+	}
+}
+
+// runPanel shows a live panel on a raw terminal, recalculating after each key
+func (m *tuiModel) runPanel(in io.Reader, out io.Writer) {
+	buf := make([]byte, 16)
+	for {
+		var screen bytes.Buffer
+		screen.WriteString("\x1b[H\x1b[2J")
+		fmt.Fprint(&screen, "Up/down: select, left/right: adjust, s: snapshot, r: roll back, q: quit\n\n")
+		m.render(&screen, true)
+		out.Write(screen.Bytes())
+		n, err := in.Read(buf)
+		if err != nil || m.keypress(string(buf[:n])) {
+			return
+		}
+	}
+}
+
+// runWizard asks for changes one line at a time, for input that is not a terminal
+func (m *tuiModel) runWizard(in io.Reader, out io.Writer) {
+	scanner := bufio.NewScanner(in)
+	for {
+		m.render(out, false)
+		fmt.Fprint(out, "\nField to change, s to snapshot or r to roll back (empty to quit): ")
+		if !scanner.Scan() || strings.TrimSpace(scanner.Text()) == "" {
+			return
+		}
+		switch strings.TrimSpace(scanner.Text()) {
+		case "s":
+			m.takeSnapshot()
+			fmt.Fprintln(out)
+			continue
+		case "r":
+			m.rollback()
+			fmt.Fprintln(out)
+			continue
+		}
+		var fieldI int
+		if _, err := fmt.Sscanf(scanner.Text(), "%d", &fieldI); err != nil || fieldI < 1 || fieldI > len(m.fields) {
+			fmt.Fprintln(out, "Invalid field:", scanner.Text())
+			continue
+		}
+		field := &m.fields[fieldI-1]
+		fmt.Fprintf(out, "%s [%s]: ", field.label, field.format(m.units))
+		if !scanner.Scan() {
+			return
+		}
+		if err := field.parse(m.units, scanner.Text()); err != nil {
+			fmt.Fprintln(out, err.Error())
+		}
+		fmt.Fprintln(out)
+	}
+}
+
+func tuiMain(args []string) error {
+	fs := flag.NewFlagSet("tui", flag.ExitOnError)
+	flags := registerCommonFlags(fs)
+	var wizardFlag = fs.Bool("wizard", false, "Ask for values line by line instead of the live panel")
+	fs.Parse(args)
+
+	if err := flags.configureLogging(); err != nil {
+		return err
+	}
+	if err := flags.registerCustomGases(); err != nil {
+		return err
+	}
+	units, err := flags.unitSystem()
+	if err != nil {
+		return err
+	}
+	w, err := flags.output(os.Stdout, units)
+	if err != nil {
+		return err
+	}
+	gasSystem, temperature, err := flags.gasSettings(units)
+	if err != nil {
+		return err
+	}
+	model := newTUIModel(units, gasSystem, temperature)
+	if !*wizardFlag {
+		if restore, err := makeRawTerminal(int(os.Stdin.Fd())); err == nil {
+			defer restore()
+			model.runPanel(os.Stdin, w)
+			fmt.Fprintln(w)
+			return nil
+		}
+	}
+	model.runWizard(os.Stdin, w)
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTUIKeypress(t *testing.T) {
+	model := newTUIModel(Metric, IdealGas, 293.15)
+	model.keypress("\x1b[B")
+	model.keypress("\x1b[C")
+	if model.fields[tuiSourcePressure].value != 237 {
+		t.Errorf("Right arrow should add a step to source pressure, got %f", model.fields[tuiSourcePressure].value)
+	}
+	model.keypress("\x1b[B")
+	model.keypress("\x1b[C")
+	if model.fields[tuiSourceTwinset].value != 1 {
+		t.Error("Right arrow should toggle source twinset")
+	}
+	if !model.keypress("q") {
+		t.Error("q should quit")
+	}
+}
+
+func TestTUIWizard(t *testing.T) {
+	model := newTUIModel(Metric, IdealGas, 293.15)
+	var out bytes.Buffer
+	model.runWizard(strings.NewReader("5\n80bar\n\n"), &out)
+	if model.fields[tuiDestinationPressure].value != 80 {
+		t.Errorf("Wizard should set destination pressure, got %f", model.fields[tuiDestinationPressure].value)
+	}
+	// Ideal gas, 12l at 232 bar and 12l at 80 bar
+	if !strings.Contains(out.String(), "all manifolds open     156") {
+		t.Errorf("Results missing from output:\n%s", out.String())
+	}
+}
+
+func TestTUISnapshot(t *testing.T) {
+	model := newTUIModel(Metric, IdealGas, 293.15)
+	model.keypress("r")
+	model.keypress("s")
+	model.selected = tuiDestinationTwinset
+	model.keypress("+")
+	var out bytes.Buffer
+	model.render(&out, false)
+	if !strings.Contains(out.String(), "Changes since snapshot: Destination twinset no → yes\n") {
+		t.Errorf("Expected the change since the snapshot in:\n%s", out.String())
+	}
+	model.keypress("r")
+	if model.fields[tuiDestinationTwinset].value != 0 {
+		t.Error("Rolling back should restore the destination twinset")
+	}
+	// Rolled back values are copies, so the snapshot can be restored again
+	model.keypress("+")
+	model.keypress("r")
+	if model.fields[tuiDestinationTwinset].value != 0 {
+		t.Error("Rolling back twice should restore the snapshot again")
+	}
+}
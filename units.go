@@ -0,0 +1,393 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ojarva/scuba-whip-calculator-go/units"
+)
+
+// PSIPerBar is the number of pounds per square inch in a single bar
+const PSIPerBar = units.PSIPerBar
+
+// LitersPerCubicFoot is the number of liters in a single cubic foot
+const LitersPerCubicFoot = units.LitersPerCubicFoot
+
+// FeetPerMeter is the number of feet in a single meter
+const FeetPerMeter = units.FeetPerMeter
+
+// SurfacePressure is the ambient pressure at sea level (in bar)
+const SurfacePressure = units.BarPerAtm
+
+// Depth is depth in seawater (in meters)
+type Depth float64
+
+// AmbientPressure returns absolute pressure at the depth, assuming 10 meters of seawater per bar
+func (d Depth) AmbientPressure() PressureBar {
+	return PressureBar(SurfacePressure + float64(d)/10)
+}
+
+// ZeroCelsius is 0°C in kelvins
+const ZeroCelsius = units.ZeroCelsius
+
+// GramsPerPound is the number of grams in a single pound
+const GramsPerPound = units.GramsPerPound
+
+// UnitSystem selects units used for unsuffixed input values and for output, and the reference for pressures.
+type UnitSystem struct {
+	// Imperial selects psi, cubic feet and pounds instead of bar, liters and grams
+	Imperial bool
+	// AmbientPressure is the reference for gauge pressures: it is added to parsed pressures and subtracted from
+	// printed ones. Zero means pressures are absolute.
+	AmbientPressure PressureBar
+	// Locale selects the decimal separator accepted in parsed values and the separators of printed numbers
+	Locale Locale
+	// Decimals of printed pressures and volumes; nil uses the decimals of each report
+	Decimals *int
+	// RoundTo rounds printed pressures and volumes to a multiple of it, in the units of the unit system. Zero does
+	// not round.
+	RoundTo float64
+	// GaugeIncrement is the smallest step an operator can read or set on a gauge; recommended fill pressures are
+	// rounded to it. Zero does not round.
+	GaugeIncrement PressureBar
+	// DualUnits prints the summary in both metric and imperial units
+	DualUnits bool
+}
+
+// Metric uses bar, liters and grams with absolute pressures
+var Metric = UnitSystem{}
+
+// Imperial uses psi, cubic feet and pounds with absolute pressures
+var Imperial = UnitSystem{Imperial: true}
+
+// AmbientPressureAtAltitude returns standard atmosphere pressure at the altitude (in meters)
+func AmbientPressureAtAltitude(altitude float64) PressureBar {
+	return PressureBar(SurfacePressure * math.Pow(1-2.25577e-5*altitude, 5.25588))
+}
+
+// Errors returned for invalid quantities; parse errors wrap them with the invalid input
+var (
+	ErrInvalidPressure    = errors.New("invalid pressure")
+	ErrInvalidVolume      = errors.New("invalid volume")
+	ErrInvalidLength      = errors.New("invalid length")
+	ErrInvalidTemperature = errors.New("invalid temperature")
+)
+
+// ParseUnitSystem returns unit system matching the name ("metric" or "imperial").
+func ParseUnitSystem(name string) (UnitSystem, error) {
+	switch strings.ToLower(name) {
+	case "metric":
+		return Metric, nil
+	case "imperial":
+		return Imperial, nil
+	}
+	return Metric, fmt.Errorf("unknown unit system %q; must be metric or imperial", name)
+}
+
+// splitQuantity splits a value such as "3000psi" to a number and a lowercase unit suffix. The number may use the
+// decimal comma of the locale.
+func (u UnitSystem) splitQuantity(s string) (float64, string, error) {
+	s = strings.TrimSpace(s)
+	unitStart := strings.IndexFunc(s, unicode.IsLetter)
+	if unitStart == -1 {
+		unitStart = len(s)
+	}
+	value, err := strconv.ParseFloat(u.Locale.normalizeNumber(strings.TrimSpace(s[:unitStart])), 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid quantity %q", s)
+	}
+	return value, strings.ToLower(strings.TrimSpace(s[unitStart:])), nil
+}
+
+// pressureUnits maps pressure unit suffixes to bar
+var pressureUnits = map[string]float64{
+	"bar": 1,
+	"psi": units.PSIToBar(1),
+	"mpa": units.MPaToBar(1),
+	"kpa": units.KPaToBar(1),
+	"atm": units.AtmToBar(1),
+}
+
+// cylinderVolumeUnits maps cylinder volume unit suffixes to liters
+var cylinderVolumeUnits = map[string]float64{
+	"l":    1,
+	"cuft": units.CubicFeetToLiters(1),
+	"ft3":  units.CubicFeetToLiters(1),
+}
+
+// lengthUnits maps length unit suffixes to meters
+var lengthUnits = map[string]float64{
+	"m":  1,
+	"ft": units.FeetToMeters(1),
+}
+
+// parseQuantity parses a value with an optional unit suffix and converts it with the unit table. Values without a
+// suffix use defaultUnit. Errors wrap invalid, such as ErrInvalidPressure.
+func (u UnitSystem) parseQuantity(s string, defaultUnit string, units map[string]float64, invalid error) (float64, error) {
+	value, unit, err := u.splitQuantity(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w %q", invalid, s)
+	}
+	if unit == "" {
+		unit = defaultUnit
+	}
+	factor, ok := units[unit]
+	if !ok {
+		return 0, fmt.Errorf("%w %q: unknown unit %q", invalid, s, unit)
+	}
+	return value * factor, nil
+}
+
+// ParsePressure parses a gauge pressure with an optional bar, psi, MPa, kPa or atm suffix and returns the absolute pressure. Values
+// without a suffix use the unit system.
+func (u UnitSystem) ParsePressure(s string) (PressureBar, error) {
+	pressure, err := u.ParsePressureDifference(s)
+	if err != nil {
+		return 0, err
+	}
+	return NewPressureBar(float64(u.AbsolutePressure(pressure)))
+}
+
+// AbsolutePressure converts a gauge pressure (in bar) to absolute pressure
+func (u UnitSystem) AbsolutePressure(gaugePressure PressureBar) PressureBar {
+	return gaugePressure + u.AmbientPressure
+}
+
+// ParsePressureDifference parses a pressure difference, or a pressure not related to ambient pressure, with an
+// optional bar, psi, MPa, kPa or atm suffix.
+func (u UnitSystem) ParsePressureDifference(s string) (PressureBar, error) {
+	value, err := u.parseQuantity(s, u.PressureUnit(), pressureUnits, ErrInvalidPressure)
+	return PressureBar(value), err
+}
+
+// RatingTemperature is the temperature (70°F) for rated cylinder capacities
+const RatingTemperature = Temperature(ZeroCelsius + 21.11)
+
+// ParseCylinderVolume parses a cylinder volume with an optional l or cuft suffix. Values without a suffix use the unit system.
+// A rated capacity such as "77.4cuft@3000psi" (free air at 1 atm when filled to the gauge service pressure) is
+// converted to water volume.
+func (u UnitSystem) ParseCylinderVolume(s string) (CylinderVolume, error) {
+	if i := strings.Index(s, "@"); i != -1 {
+		capacity, err := u.parseQuantity(s[:i], u.VolumeUnit(), cylinderVolumeUnits, ErrInvalidVolume)
+		if err != nil {
+			return 0, err
+		}
+		servicePressure, err := u.ParsePressureDifference(s[i+1:])
+		if err != nil {
+			return 0, err
+		}
+		if capacity <= 0 || servicePressure <= 0 {
+			return 0, fmt.Errorf("%w %q: invalid rated capacity", ErrInvalidVolume, s)
+		}
+		return RatedCylinderVolume(GasVolume(capacity), servicePressure), nil
+	}
+	value, err := u.parseQuantity(s, u.VolumeUnit(), cylinderVolumeUnits, ErrInvalidVolume)
+	return CylinderVolume(value), err
+}
+
+// RatedCylinderVolume returns the water volume of a cylinder holding capacity liters of free air at 1 atm when
+// filled to the gauge service pressure at 70°F. Compressibility of air is taken from the compressibility table.
+func RatedCylinderVolume(capacity GasVolume, servicePressure PressureBar) CylinderVolume {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	// 1 bar l is 100 J
+	moles := float64(capacity) * SurfacePressure * 100 / (MolarGasConstant * float64(RatingTemperature))
+	molesPerLiter := CompressibilityTableGas.Moles(1, servicePressure+SurfacePressure, RatingTemperature, air)
+	return CylinderVolume(moles / float64(molesPerLiter))
+}
+
+// ParseDepth parses a depth with an optional m or ft suffix. Values without a suffix use the unit system.
+func (u UnitSystem) ParseDepth(s string) (Depth, error) {
+	meters, err := u.ParseLength(s)
+	return Depth(meters), err
+}
+
+// ParseLength parses a length such as altitude with an optional m or ft suffix and returns meters. Values without a
+// suffix use the unit system.
+func (u UnitSystem) ParseLength(s string) (float64, error) {
+	return u.parseQuantity(s, u.DepthUnit(), lengthUnits, ErrInvalidLength)
+}
+
+// ParseTemperature parses a temperature with an optional C, F or K suffix. Values without a suffix use the unit
+// system (celsius or fahrenheit).
+func (u UnitSystem) ParseTemperature(s string) (Temperature, error) {
+	value, unit, err := u.splitQuantity(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w %q", ErrInvalidTemperature, s)
+	}
+	if unit == "" {
+		unit = strings.ToLower(u.TemperatureUnit())
+	}
+	switch unit {
+	case "c":
+		return NewTemperatureCelsius(value)
+	case "f":
+		return NewTemperatureKelvin(units.FahrenheitToKelvin(value))
+	case "k":
+		return NewTemperatureKelvin(value)
+	}
+	return 0, fmt.Errorf("%w %q: unknown unit %q", ErrInvalidTemperature, s, unit)
+}
+
+// ParseTemperatureDifference parses a temperature difference with an optional C, F or K suffix. Values without a
+// suffix are in degrees of the unit system.
+func (u UnitSystem) ParseTemperatureDifference(s string) (Temperature, error) {
+	value, unit, err := u.splitQuantity(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w %q", ErrInvalidTemperature, s)
+	}
+	if unit == "" {
+		unit = strings.ToLower(u.TemperatureUnit())
+	}
+	switch unit {
+	case "c", "k":
+		return Temperature(value), nil
+	case "f":
+		return Temperature(value * 5 / 9), nil
+	}
+	return 0, fmt.Errorf("%w %q: unknown unit %q", ErrInvalidTemperature, s, unit)
+}
+
+// Pressure converts absolute pressure to the unit system and pressure reference
+func (u UnitSystem) Pressure(p PressureBar) float64 {
+	return u.PressureDifference(p - u.AmbientPressure)
+}
+
+// PressureDifference converts a pressure difference to the unit system
+func (u UnitSystem) PressureDifference(p PressureBar) float64 {
+	if u.Imperial {
+		return units.BarToPSI(float64(p))
+	}
+	return float64(p)
+}
+
+// otherUnits returns the unit system with imperial units swapped for metric or the other way around, keeping the
+// pressure reference. Rounding is in the units of the unit system and does not carry over.
+func (u UnitSystem) otherUnits() UnitSystem {
+	u.Imperial = !u.Imperial
+	u.RoundTo = 0
+	u.GaugeIncrement = 0
+	return u
+}
+
+// decimals returns the decimals of a printed pressure or volume, defaultDecimals unless set in the unit system
+func (u UnitSystem) decimals(defaultDecimals int) int {
+	if u.Decimals != nil {
+		return *u.Decimals
+	}
+	return defaultDecimals
+}
+
+// round rounds a pressure or volume converted to the unit system to a multiple of RoundTo
+func (u UnitSystem) round(value float64) float64 {
+	if u.RoundTo <= 0 {
+		return value
+	}
+	return math.Round(value/u.RoundTo) * u.RoundTo
+}
+
+// roundPressureDown rounds a pressure down to the gauge increment, for pressures that must not be exceeded such as
+// pressures to fill to
+func (u UnitSystem) roundPressureDown(p PressureBar) PressureBar {
+	return u.roundPressure(p, math.Floor)
+}
+
+// roundPressureUp rounds a pressure up to the gauge increment, for pressures that must at least be reached
+func (u UnitSystem) roundPressureUp(p PressureBar) PressureBar {
+	return u.roundPressure(p, math.Ceil)
+}
+
+// roundPressure rounds a pressure, as read on a gauge in the unit system, to a multiple of the gauge increment
+func (u UnitSystem) roundPressure(p PressureBar, round func(float64) float64) PressureBar {
+	if u.GaugeIncrement <= 0 {
+		return p
+	}
+	increment := u.PressureDifference(u.GaugeIncrement)
+	steps := u.Pressure(p) / increment
+	// A pressure already at an increment must not move a step because of conversion errors
+	if math.Abs(steps-math.Round(steps)) < 1e-9 {
+		steps = math.Round(steps)
+	} else {
+		steps = round(steps)
+	}
+	difference := steps*increment - u.Pressure(p)
+	if u.Imperial {
+		difference = units.PSIToBar(difference)
+	}
+	return p + PressureBar(difference)
+}
+
+// PressureUnit returns the pressure unit name
+func (u UnitSystem) PressureUnit() string {
+	if u.Imperial {
+		return "psi"
+	}
+	return "bar"
+}
+
+// Volume converts gas volume to the unit system
+func (u UnitSystem) Volume(v GasVolume) float64 {
+	if u.Imperial {
+		return units.LitersToCubicFeet(float64(v))
+	}
+	return float64(v)
+}
+
+// VolumeUnit returns the volume unit name
+func (u UnitSystem) VolumeUnit() string {
+	if u.Imperial {
+		return "cuft"
+	}
+	return "l"
+}
+
+// Weight converts gas weight (in grams) to the unit system
+func (u UnitSystem) Weight(w GasWeight) float64 {
+	if u.Imperial {
+		return units.GramsToPounds(float64(w))
+	}
+	return float64(w)
+}
+
+// WeightUnit returns the weight unit name
+func (u UnitSystem) WeightUnit() string {
+	if u.Imperial {
+		return "lb"
+	}
+	return "g"
+}
+
+// Depth converts depth to the unit system
+func (u UnitSystem) Depth(d Depth) float64 {
+	if u.Imperial {
+		return units.MetersToFeet(float64(d))
+	}
+	return float64(d)
+}
+
+// DepthUnit returns the depth unit name
+func (u UnitSystem) DepthUnit() string {
+	if u.Imperial {
+		return "ft"
+	}
+	return "m"
+}
+
+// Temperature converts temperature to the unit system
+func (u UnitSystem) Temperature(t Temperature) float64 {
+	if u.Imperial {
+		return units.KelvinToFahrenheit(float64(t))
+	}
+	return units.KelvinToCelsius(float64(t))
+}
+
+// TemperatureUnit returns the temperature unit name
+func (u UnitSystem) TemperatureUnit() string {
+	if u.Imperial {
+		return "F"
+	}
+	return "C"
+}
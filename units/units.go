@@ -0,0 +1,132 @@
+// Package units converts pressures, volumes, temperatures, weights and lengths between the metric and imperial units
+// used by the scuba transfer whip calculator. All functions take and return plain float64 values.
+package units
+
+// PSIPerBar is the number of pounds per square inch in a single bar
+const PSIPerBar = 14.5037738
+
+// BarPerMPa is the number of bar in a single megapascal
+const BarPerMPa = 10
+
+// KPaPerBar is the number of kilopascals in a single bar
+const KPaPerBar = 100
+
+// BarPerAtm is the number of bar in a single standard atmosphere
+const BarPerAtm = 1.01325
+
+// LitersPerCubicFoot is the number of liters in a single cubic foot
+const LitersPerCubicFoot = 28.3168466
+
+// FeetPerMeter is the number of feet in a single meter
+const FeetPerMeter = 3.2808399
+
+// ZeroCelsius is 0°C in kelvins
+const ZeroCelsius = 273.15
+
+// GramsPerPound is the number of grams in a single pound
+const GramsPerPound = 453.59237
+
+// BarToPSI converts bar to pounds per square inch
+func BarToPSI(bar float64) float64 {
+	return bar * PSIPerBar
+}
+
+// PSIToBar converts pounds per square inch to bar
+func PSIToBar(psi float64) float64 {
+	return psi / PSIPerBar
+}
+
+// BarToMPa converts bar to megapascals
+func BarToMPa(bar float64) float64 {
+	return bar / BarPerMPa
+}
+
+// MPaToBar converts megapascals to bar
+func MPaToBar(mpa float64) float64 {
+	return mpa * BarPerMPa
+}
+
+// BarToKPa converts bar to kilopascals
+func BarToKPa(bar float64) float64 {
+	return bar * KPaPerBar
+}
+
+// KPaToBar converts kilopascals to bar
+func KPaToBar(kpa float64) float64 {
+	return kpa / KPaPerBar
+}
+
+// BarToAtm converts bar to standard atmospheres
+func BarToAtm(bar float64) float64 {
+	return bar / BarPerAtm
+}
+
+// AtmToBar converts standard atmospheres to bar
+func AtmToBar(atm float64) float64 {
+	return atm * BarPerAtm
+}
+
+// LitersToCubicFeet converts liters to cubic feet
+func LitersToCubicFeet(liters float64) float64 {
+	return liters / LitersPerCubicFoot
+}
+
+// CubicFeetToLiters converts cubic feet to liters
+func CubicFeetToLiters(cubicFeet float64) float64 {
+	return cubicFeet * LitersPerCubicFoot
+}
+
+// CelsiusToKelvin converts degrees Celsius to kelvins
+func CelsiusToKelvin(celsius float64) float64 {
+	return celsius + ZeroCelsius
+}
+
+// KelvinToCelsius converts kelvins to degrees Celsius
+func KelvinToCelsius(kelvins float64) float64 {
+	return kelvins - ZeroCelsius
+}
+
+// CelsiusToFahrenheit converts degrees Celsius to degrees Fahrenheit
+func CelsiusToFahrenheit(celsius float64) float64 {
+	return celsius*9/5 + 32
+}
+
+// FahrenheitToCelsius converts degrees Fahrenheit to degrees Celsius
+func FahrenheitToCelsius(fahrenheit float64) float64 {
+	return (fahrenheit - 32) * 5 / 9
+}
+
+// KelvinToFahrenheit converts kelvins to degrees Fahrenheit
+func KelvinToFahrenheit(kelvins float64) float64 {
+	return CelsiusToFahrenheit(KelvinToCelsius(kelvins))
+}
+
+// FahrenheitToKelvin converts degrees Fahrenheit to kelvins
+func FahrenheitToKelvin(fahrenheit float64) float64 {
+	return CelsiusToKelvin(FahrenheitToCelsius(fahrenheit))
+}
+
+// KilogramsToPounds converts kilograms to pounds
+func KilogramsToPounds(kilograms float64) float64 {
+	return kilograms * 1000 / GramsPerPound
+}
+
+// PoundsToKilograms converts pounds to kilograms
+func PoundsToKilograms(pounds float64) float64 {
+	return pounds * GramsPerPound / 1000
+}
+
+// GramsToPounds converts grams to pounds
+func GramsToPounds(grams float64) float64 {
+	return grams / GramsPerPound
+}
+
+// MetersToFeet converts meters to feet
+func MetersToFeet(meters float64) float64 {
+	return meters * FeetPerMeter
+}
+
+// FeetToMeters converts feet to meters
+func FeetToMeters(feet float64) float64 {
+	return feet / FeetPerMeter
+}
@@ -0,0 +1,52 @@
+package units
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    float64
+		expected float64
+	}{
+		{"bar to psi", BarToPSI(200), 2900.75476},
+		{"psi to bar", PSIToBar(3000), 206.84271},
+		{"bar to MPa", BarToMPa(232), 23.2},
+		{"kPa to bar", KPaToBar(500), 5},
+		{"atm to bar", AtmToBar(2), 2.0265},
+		{"liters to cubic feet", LitersToCubicFeet(11.1), 0.39200},
+		{"celsius to kelvin", CelsiusToKelvin(20), 293.15},
+		{"celsius to fahrenheit", CelsiusToFahrenheit(-40), -40},
+		{"fahrenheit to kelvin", FahrenheitToKelvin(68), 293.15},
+		{"kilograms to pounds", KilogramsToPounds(1), 2.20462},
+		{"meters to feet", MetersToFeet(30), 98.42520},
+	}
+	for _, test := range tests {
+		if math.Abs(test.value-test.expected) > 1e-4 {
+			t.Errorf("Invalid %s: expected %f, got %f", test.name, test.expected, test.value)
+		}
+	}
+}
+
+func TestRoundTrips(t *testing.T) {
+	for _, value := range []float64{0, 1, 232, 3000} {
+		for name, roundTrip := range map[string]float64{
+			"psi":        PSIToBar(BarToPSI(value)),
+			"MPa":        MPaToBar(BarToMPa(value)),
+			"kPa":        KPaToBar(BarToKPa(value)),
+			"atm":        AtmToBar(BarToAtm(value)),
+			"cubic feet": CubicFeetToLiters(LitersToCubicFeet(value)),
+			"celsius":    KelvinToCelsius(CelsiusToKelvin(value)),
+			"fahrenheit": FahrenheitToCelsius(CelsiusToFahrenheit(value)),
+			"kelvin":     FahrenheitToKelvin(KelvinToFahrenheit(value)),
+			"pounds":     PoundsToKilograms(KilogramsToPounds(value)),
+			"feet":       FeetToMeters(MetersToFeet(value)),
+		} {
+			if math.Abs(roundTrip-value) > 1e-9 {
+				t.Errorf("%s round trip of %f returned %f", name, value, roundTrip)
+			}
+		}
+	}
+}
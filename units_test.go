@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParsePressure(t *testing.T) {
+	pressure, err := Metric.ParsePressure("3000psi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !compareFloats(float64(pressure), 3000/PSIPerBar) {
+		t.Errorf("Invalid pressure, expected %f, got %f", 3000/PSIPerBar, pressure)
+	}
+	pressure, err = Imperial.ParsePressure("200bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pressure != 200 {
+		t.Errorf("Invalid pressure, expected 200, got %f", pressure)
+	}
+	pressure, err = Imperial.ParsePressure("14.5037738")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !compareFloats(float64(pressure), 1.0) {
+		t.Errorf("Unsuffixed imperial pressure should be psi, got %f bar", pressure)
+	}
+	for value, expected := range map[string]float64{"23.2MPa": 232, "500kPa": 5, "2atm": 2 * SurfacePressure} {
+		pressure, err = Metric.ParsePressure(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !compareFloats(float64(pressure), expected) {
+			t.Errorf("Invalid pressure for %s, expected %f, got %f", value, expected, pressure)
+		}
+	}
+	if _, err := Metric.ParsePressure("200furlongs"); !errors.Is(err, ErrInvalidPressure) {
+		t.Errorf("Expected ErrInvalidPressure for unknown unit, got %v", err)
+	}
+	if _, err := Metric.ParseCylinderVolume("twelve"); !errors.Is(err, ErrInvalidVolume) {
+		t.Errorf("Expected ErrInvalidVolume, got %v", err)
+	}
+	if _, err := Metric.ParseTemperature("20X"); !errors.Is(err, ErrInvalidTemperature) {
+		t.Errorf("Expected ErrInvalidTemperature, got %v", err)
+	}
+}
+
+func TestParseCylinderVolume(t *testing.T) {
+	volume, err := Metric.ParseCylinderVolume("1cuft")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !compareFloats(float64(volume), LitersPerCubicFoot) {
+		t.Errorf("Invalid volume, expected %f, got %f", LitersPerCubicFoot, volume)
+	}
+	volume, err = Metric.ParseCylinderVolume("12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if volume != 12 {
+		t.Errorf("Invalid volume, expected 12, got %f", volume)
+	}
+}
+
+func TestGaugePressure(t *testing.T) {
+	units := UnitSystem{AmbientPressure: SurfacePressure}
+	pressure, err := units.ParsePressure("200bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !compareFloats(float64(pressure), 201.01325) {
+		t.Errorf("Gauge pressure should be converted to absolute, got %f", pressure)
+	}
+	if !compareFloats(units.Pressure(pressure), 200) {
+		t.Errorf("Absolute pressure should be printed as gauge, got %f", units.Pressure(pressure))
+	}
+	difference, err := units.ParsePressureDifference("10bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if difference != 10 {
+		t.Errorf("Pressure difference should not include ambient pressure, got %f", difference)
+	}
+}
+
+func TestAmbientPressureAtAltitude(t *testing.T) {
+	if !compareFloats(float64(AmbientPressureAtAltitude(0)), SurfacePressure) {
+		t.Errorf("Expected sea level pressure, got %f", AmbientPressureAtAltitude(0))
+	}
+	if pressure := AmbientPressureAtAltitude(2000); pressure < 0.79 || pressure > 0.80 {
+		t.Errorf("Expected about 0.795 bar at 2000m, got %f", pressure)
+	}
+}
+
+func TestParseTemperature(t *testing.T) {
+	tests := []struct {
+		units UnitSystem
+		value string
+	}{
+		{Metric, "20"},
+		{Metric, "20C"},
+		{Metric, "68F"},
+		{Metric, "293.15K"},
+		{Imperial, "68"},
+	}
+	for _, test := range tests {
+		temperature, err := test.units.ParseTemperature(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !compareFloats(float64(temperature), 293.15) {
+			t.Errorf("Invalid temperature for %s, expected 293.15K, got %f", test.value, temperature)
+		}
+	}
+	if !compareFloats(Imperial.Temperature(293.15), 68) {
+		t.Errorf("Expected 68F, got %f", Imperial.Temperature(293.15))
+	}
+	if _, err := Metric.ParseTemperature("20R"); err == nil {
+		t.Error("Expected an error for unknown unit")
+	}
+}
+
+func TestParseRatedCylinderVolume(t *testing.T) {
+	volume, err := Metric.ParseCylinderVolume("77.4cuft@3000psi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// AL80 is about 11 liters; compressibility of air adds about 4% over the ideal gas volume
+	if volume < 10.8 || volume > 11.1 {
+		t.Errorf("Invalid AL80 water volume %f", volume)
+	}
+	cylinder, err := Metric.ParseCylinderSpec("al80=77.4cuft@3000psi@2000psi", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cylinder.CylinderVolume != volume || !compareFloats(float64(cylinder.Pressure), 2000/PSIPerBar) || !compareFloats(float64(cylinder.WorkingPressure), 3000/PSIPerBar) {
+		t.Errorf("Invalid cylinder %+v", cylinder)
+	}
+}
+
+func TestOutputPrecision(t *testing.T) {
+	decimals := 2
+	units := UnitSystem{Decimals: &decimals, RoundTo: 0.5}
+	if units.decimals(0) != 2 || Metric.decimals(1) != 1 {
+		t.Errorf("Invalid decimals %d and %d", units.decimals(0), Metric.decimals(1))
+	}
+	if rounded := units.round(156.3); rounded != 156.5 {
+		t.Errorf("Invalid rounding of 156.3 to 0.5: %f", rounded)
+	}
+	if rounded := Metric.round(156.3); rounded != 156.3 {
+		t.Errorf("Expected no rounding without RoundTo, got %f", rounded)
+	}
+	var output strings.Builder
+	printSummaries(&output, []CylinderSummary{{Description: "all manifolds open", SourceCylinderPressure: 190.26, DestinationCylinderPressure: 190.26}}, summaryOptions{}, UnitSystem{Decimals: &decimals}, false)
+	if !strings.Contains(output.String(), " 190.26 ") {
+		t.Errorf("Expected pressures with two decimals, got %q", output.String())
+	}
+}
+
+func TestGaugeIncrementRounding(t *testing.T) {
+	metric := UnitSystem{AmbientPressure: SurfacePressure, GaugeIncrement: 5}
+	if pressure := metric.Pressure(metric.roundPressureDown(SurfacePressure + 203.7)); !compareFloats(pressure, 200) {
+		t.Errorf("Expected 203.7 bar to round down to 200 bar, got %f", pressure)
+	}
+	if pressure := metric.Pressure(metric.roundPressureUp(SurfacePressure + 201.2)); !compareFloats(pressure, 205) {
+		t.Errorf("Expected 201.2 bar to round up to 205 bar, got %f", pressure)
+	}
+	if pressure := metric.Pressure(metric.roundPressureUp(SurfacePressure + 200)); !compareFloats(pressure, 200) {
+		t.Errorf("Expected 200 bar to stay, got %f", pressure)
+	}
+	imperial := UnitSystem{Imperial: true, GaugeIncrement: 100 / PSIPerBar}
+	if pressure := imperial.Pressure(imperial.roundPressureDown(PressureBar(3050 / PSIPerBar))); math.Abs(pressure-3000) > 1e-6 {
+		t.Errorf("Expected 3050 psi to round down to 3000 psi, got %f", pressure)
+	}
+	if pressure := Metric.roundPressureDown(203.7); pressure != 203.7 {
+		t.Errorf("Expected no rounding without a gauge increment, got %f", pressure)
+	}
+}
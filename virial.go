@@ -0,0 +1,81 @@
+package main
+
+import "math"
+
+// VirialSystem uses virial equation of state truncated after the third coefficient, Z = 1 + B/Vm + C/Vm^2.
+// Coefficients are temperature dependent and combined for mixes from mole fractions.
+type VirialSystem struct{}
+
+// Virial is the virial equation of state gas system
+var Virial = VirialSystem{}
+
+// quantumVirialCoefficients holds virial coefficients for light gases which corresponding states correlations do
+// not describe well. B (l/mol) is linear in temperature around 300 K; C (l^2/mol^2) is constant.
+type quantumVirialCoefficients struct {
+	B300   float64
+	BSlope float64
+	C      float64
+}
+
+var quantumVirialCoefficientsLookup = map[Gas]quantumVirialCoefficients{
+	Helium:   {B300: 0.0118, BSlope: -0.0000074, C: 0.000121},
+	Hydrogen: {B300: 0.0149, BSlope: 0.000044, C: 0.00035},
+	Neon:     {B300: 0.0113, BSlope: 0.000022, C: 0.00022},
+}
+
+// VirialCoefficients returns second (l/mol) and third (l^2/mol^2) virial coefficients of a gas. Gases other than
+// helium, hydrogen and neon use Tsonopoulos (B) and Orbey-Vera (C) correlations from critical properties.
+func VirialCoefficients(gasType Gas, temperature Temperature) (float64, float64) {
+	if coefficients, ok := quantumVirialCoefficientsLookup[gasType]; ok {
+		return coefficients.B300 + coefficients.BSlope*(float64(temperature)-300), coefficients.C
+	}
+	criticalProperties := SpeciesLookup[gasType].Critical
+	omega := criticalProperties.AcentricFactor
+	Tr := float64(temperature) / float64(criticalProperties.Temperature)
+	f0 := 0.1445 - 0.330/Tr - 0.1385/math.Pow(Tr, 2) - 0.0121/math.Pow(Tr, 3) - 0.000607/math.Pow(Tr, 8)
+	f1 := 0.0637 + 0.331/math.Pow(Tr, 2) - 0.423/math.Pow(Tr, 3) - 0.008/math.Pow(Tr, 8)
+	g0 := 0.01407 + 0.02432/math.Pow(Tr, 2.8) - 0.00313/math.Pow(Tr, 10.5)
+	g1 := -0.02676 + 0.01770/math.Pow(Tr, 2.8) + 0.040/math.Pow(Tr, 3) - 0.003/math.Pow(Tr, 6) - 0.00228/math.Pow(Tr, 10.5)
+	scale := R * float64(criticalProperties.Temperature) / float64(criticalProperties.Pressure)
+	return (f0 + omega*f1) * scale, (g0 + omega*g1) * scale * scale
+}
+
+// mixVirialCoefficients returns virial coefficients of a mix. B is the mole fraction weighted mean and C combines
+// geometric means of the pure gas values.
+func mixVirialCoefficients(temperature Temperature, gasComposition GasComposition) (float64, float64) {
+	var B, cubeRootC float64
+	for gasType, fraction := range gasComposition {
+		if fraction == 0 {
+			continue
+		}
+		gasB, gasC := VirialCoefficients(gasType, temperature)
+		B += fraction * gasB
+		cubeRootC += fraction * math.Cbrt(gasC)
+	}
+	return B, math.Pow(cubeRootC, 3)
+}
+
+func virialPressure(molarVolume float64, temperature Temperature, B float64, C float64) float64 {
+	return R * float64(temperature) / molarVolume * (1 + B/molarVolume + C/(molarVolume*molarVolume))
+}
+
+// Moles returns number of moles of the gas mix
+func (VirialSystem) Moles(cylinderVolume CylinderVolume, pressure PressureBar, temperature Temperature, gasComposition GasComposition) MoleCount {
+	if pressure <= 0 {
+		return 0
+	}
+	B, C := mixVirialCoefficients(temperature, gasComposition)
+	molarVolume := solveMolarVolume(pressure, temperature, func(molarVolume float64) float64 {
+		return virialPressure(molarVolume, temperature, B, C)
+	})
+	return MoleCount(float64(cylinderVolume) / molarVolume)
+}
+
+// Pressure returns pressure of the gas mix
+func (VirialSystem) Pressure(cylinderVolume CylinderVolume, moleCount MoleCount, temperature Temperature, gasComposition GasComposition) PressureBar {
+	if moleCount <= 0 {
+		return 0
+	}
+	B, C := mixVirialCoefficients(temperature, gasComposition)
+	return PressureBar(virialPressure(float64(cylinderVolume)/float64(moleCount), temperature, B, C))
+}
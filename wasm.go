@@ -0,0 +1,25 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// wasmFunction wraps a calculation taking and returning JSON for JavaScript
+func wasmFunction(calculate func(request []byte) []byte) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) != 1 || args[0].Type() != js.TypeString {
+			return string(marshalResponse(ErrorResponse{Error: "expected a JSON request string"}, nil))
+		}
+		return string(calculate([]byte(args[0].String())))
+	})
+}
+
+// main exports whipEqualize and whipBlend to JavaScript. Both take a JSON request string, in the same format as
+// the HTTP API, and return a JSON response string. Errors are returned as {"error": "..."}.
+func main() {
+	js.Global().Set("whipEqualize", wasmFunction(defaultServer.EqualizeJSON))
+	js.Global().Set("whipBlend", wasmFunction(defaultServer.BlendJSON))
+	select {}
+}
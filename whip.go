@@ -0,0 +1,27 @@
+package main
+
+// Whip is the transfer whip between a source and a destination cylinder. Gas left in the hose is vented every time
+// it is disconnected.
+type Whip struct {
+	// Volume is the internal volume of the hose and fittings
+	Volume CylinderVolume
+	// Connections is the number of connect and disconnect cycles per transfer, e.g. when topping up in several goes
+	Connections int
+}
+
+// Vent returns the gas lost disconnecting the whip after a transfer and takes it from the source, which fills the
+// whip to the destination pressure on every connection. The whip is vented down to the ambient pressure.
+func (w Whip) Vent(source *Cylinder, destination Cylinder, ambientPressure PressureBar, gasSystem GasSystem, temperature Temperature) GasVolume {
+	if destination.Pressure <= ambientPressure {
+		return 0
+	}
+	full := Cylinder{CylinderVolume: w.Volume, Pressure: destination.Pressure, GasComposition: source.GasComposition}
+	vented := Cylinder{CylinderVolume: w.Volume, Pressure: ambientPressure, GasComposition: source.GasComposition}
+	lostGasVolume := GasVolume(w.Connections) * (full.GasVolume(gasSystem, temperature) - vented.GasVolume(gasSystem, temperature))
+	sourceGasVolume := source.GasVolume(gasSystem, temperature)
+	if lostGasVolume > sourceGasVolume {
+		lostGasVolume = sourceGasVolume
+	}
+	source.Pressure = PressureFromGasVolume(source.CylinderVolume, sourceGasVolume-lostGasVolume, gasSystem, source.GasComposition, temperature)
+	return lostGasVolume
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+func TestWhipVent(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	source := Cylinder{CylinderVolume: 50, Pressure: 180, GasComposition: air}
+	destination := Cylinder{CylinderVolume: 12, Pressure: 180, GasComposition: air}
+	whip := Whip{Volume: 0.05, Connections: 3}
+	lost := whip.Vent(&source, destination, 0, IdealGas, 293.15)
+	if !compareFloats(float64(lost), 3*0.05*180) {
+		t.Errorf("Invalid vented gas %f, expected %f", lost, 3*0.05*180)
+	}
+	if !compareFloats(float64(source.Pressure), 180-3*0.05*180/50) {
+		t.Errorf("Invalid source pressure %f", source.Pressure)
+	}
+}
+
+func TestWhipLossInTransfers(t *testing.T) {
+	air := GasComposition{Oxygen: 0.21, Nitrogen: 0.79}
+	cylinderConfiguration := CylinderConfiguration{
+		SourceCylinders:      CylinderList{{Description: "source", CylinderVolume: 50, Pressure: 200, GasComposition: air}},
+		DestinationCylinders: NewTwinset(24, 50),
+		Whip:                 &Whip{Volume: 0.1, Connections: 1},
+	}
+	cylinderConfiguration.DestinationCylinders.SetDefaultGasComposition(air)
+	withWhip := equalizeAllConfigurations(io.Discard, cylinderConfiguration, IdealGas, 293.15, Metric, false, nil)
+	cylinderConfiguration.Whip = nil
+	withoutWhip := equalizeAllConfigurations(io.Discard, cylinderConfiguration, IdealGas, 293.15, Metric, false, nil)
+	for i := range withWhip {
+		if withWhip[i].WhipGasVolume <= 0 || withWhip[i].SourceCylinderPressure >= withoutWhip[i].SourceCylinderPressure {
+			t.Errorf("Expected the whip to lose gas with %s, got %+v", withWhip[i].Description, withWhip[i])
+		}
+	}
+	// Open manifolds connect the whip once
+	last := withWhip[len(withWhip)-1]
+	if !compareFloats(float64(last.WhipGasVolume), 0.1*float64(withoutWhip[len(withoutWhip)-1].DestinationCylinderPressure)) {
+		t.Errorf("Invalid whip loss %f with open manifolds", last.WhipGasVolume)
+	}
+}
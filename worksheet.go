@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Worksheet page layout in PDF points; pages are A4
+const (
+	worksheetPageWidth  = 595
+	worksheetPageHeight = 842
+	worksheetMargin     = 56
+)
+
+// Worksheet is a printable fill record: details of the fill, steps to tick off and fields to fill in by hand
+type Worksheet struct {
+	Title   string
+	Details []string
+	Steps   []string
+	Fields  []string
+}
+
+// worksheetRecordFields are filled in by the blender on every worksheet
+var worksheetRecordFields = []string{"Cylinder serial / owner", "Date", "Final pressure", "Blender signature"}
+
+// blendWorksheet returns a worksheet for a partial pressure blend with fields for the analyzed mix
+func blendWorksheet(plan BlendPlan, units UnitSystem) Worksheet {
+	pressureUnit := units.PressureUnit()
+	worksheet := Worksheet{
+		Title: "Blending worksheet",
+		Details: []string{
+			fmt.Sprintf("Target: %s to %.0f%s", plan.TargetComposition, units.Pressure(plan.TargetPressure), pressureUnit),
+			fmt.Sprintf("Cylinder volume: %.1fl", float64(plan.CylinderVolume)),
+		},
+	}
+	if plan.StartPressure > units.AmbientPressure {
+		worksheet.Details = append(worksheet.Details, fmt.Sprintf("Starting from %.1f%s of %s", units.Pressure(plan.StartPressure), pressureUnit, plan.StartComposition))
+	}
+	if plan.DrainRequired {
+		worksheet.Steps = append(worksheet.Steps, fmt.Sprintf("Drain the cylinder to %.1f%s", units.Pressure(plan.DrainToPressure), pressureUnit))
+	}
+	for _, step := range plan.Steps {
+		worksheet.Steps = append(worksheet.Steps, fmt.Sprintf("Add %.1f%s of %s, fill to %.1f%s", units.PressureDifference(step.AddedPressure), pressureUnit, step.Description, units.Pressure(step.FillToPressure), pressureUnit))
+	}
+	worksheet.Fields = append([]string{"Analyzed O2 %", "Analyzed He %"}, worksheetRecordFields...)
+	return worksheet
+}
+
+// continuousBlendWorksheet returns a worksheet for a continuous nitrox blend
+func continuousBlendWorksheet(plan ContinuousBlendPlan, units UnitSystem) Worksheet {
+	pressureUnit := units.PressureUnit()
+	return Worksheet{
+		Title: "Continuous blending worksheet",
+		Details: []string{
+			fmt.Sprintf("Target: %s to %.0f%s", plan.TargetComposition, units.Pressure(plan.TargetPressure), pressureUnit),
+			fmt.Sprintf("Compressor throughput: %.0f%s", units.Volume(plan.CompressorThroughput), units.VolumeUnit()),
+		},
+		Steps: []string{
+			fmt.Sprintf("Set intake oxygen to %.1f%%", plan.IntakeOxygenFraction*100),
+			fmt.Sprintf("Fill to %.0f%s", units.Pressure(plan.TargetPressure), pressureUnit),
+		},
+		Fields: append([]string{"Analyzed intake O2 %", "Analyzed O2 %"}, worksheetRecordFields...),
+	}
+}
+
+// transferWorksheet returns a worksheet for the transfers of a manifold configuration
+func transferWorksheet(steps []TransferStep, cylinderSummary CylinderSummary, units UnitSystem) Worksheet {
+	pressureUnit := units.PressureUnit()
+	worksheet := Worksheet{
+		Title: "Transfer worksheet",
+		Details: []string{
+			"Configuration: " + cylinderSummary.Description,
+			fmt.Sprintf("Expected result: destination %.0f%s of %s, source %.0f%s", units.Pressure(cylinderSummary.DestinationCylinderPressure), pressureUnit, cylinderSummary.DestinationGasComposition, units.Pressure(cylinderSummary.SourceCylinderPressure), pressureUnit),
+		},
+	}
+	for _, step := range steps {
+		if step.Configuration != cylinderSummary.Description {
+			continue
+		}
+		worksheet.Steps = append(worksheet.Steps, fmt.Sprintf("Connect %s to %s, equalize to %.0f%s", step.Source, step.Destination, units.Pressure(step.DestinationPressureAfter), pressureUnit))
+	}
+	worksheet.Fields = append([]string{"Analyzed O2 %", "Analyzed He %"}, worksheetRecordFields...)
+	return worksheet
+}
+
+// pdfString encodes text as a PDF string in WinAnsiEncoding. Characters outside Latin-1 are replaced.
+func pdfString(s string) string {
+	var encoded strings.Builder
+	encoded.WriteByte('(')
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			encoded.WriteByte('\\')
+			encoded.WriteRune(r)
+		case r < 0x20 || (r >= 0x7f && r < 0xa0) || r > 0xff:
+			encoded.WriteByte('?')
+		default:
+			encoded.WriteByte(byte(r))
+		}
+	}
+	encoded.WriteByte(')')
+	return encoded.String()
+}
+
+// worksheetPage draws the content stream of a worksheet page
+type worksheetPage struct {
+	content bytes.Buffer
+	y       float64
+}
+
+func (p *worksheetPage) text(x float64, size int, font string, s string) {
+	fmt.Fprintf(&p.content, "BT /%s %d Tf %.1f %.1f Td %s Tj ET\n", font, size, x, p.y, pdfString(s))
+}
+
+func (p *worksheetPage) line(x1 float64, y1 float64, x2 float64, y2 float64) {
+	fmt.Fprintf(&p.content, "%.1f %.1f m %.1f %.1f l S\n", x1, y1, x2, y2)
+}
+
+// WritePDF writes the worksheet as a PDF document, continuing on new pages when needed
+func (ws Worksheet) WritePDF(w io.Writer, created time.Time) error {
+	var pages []*worksheetPage
+	page := &worksheetPage{}
+	newPage := func() {
+		page = &worksheetPage{y: worksheetPageHeight - worksheetMargin}
+		fmt.Fprintln(&page.content, "0.5 w")
+		pages = append(pages, page)
+	}
+	// advance moves down by height, starting a new page when the content would not fit
+	advance := func(height float64) {
+		if page.y-height < worksheetMargin {
+			newPage()
+		}
+		page.y -= height
+	}
+	newPage()
+	page.y -= 20
+	page.text(worksheetMargin, 20, "F2", ws.Title)
+	advance(16)
+	page.text(worksheetMargin, 9, "F1", "Generated "+created.Format("2006-01-02 15:04"))
+	advance(10)
+	for _, detail := range ws.Details {
+		advance(18)
+		page.text(worksheetMargin, 12, "F1", detail)
+	}
+	advance(30)
+	page.text(worksheetMargin, 14, "F2", "Steps")
+	for i, step := range ws.Steps {
+		advance(26)
+		fmt.Fprintf(&page.content, "%d %.1f 10 10 re S\n", worksheetMargin, page.y-1)
+		page.text(worksheetMargin+18, 11, "F1", fmt.Sprintf("%d. %s", i+1, step))
+		page.text(worksheetPageWidth-worksheetMargin-115, 10, "F1", "Actual:")
+		page.line(worksheetPageWidth-worksheetMargin-80, page.y-2, worksheetPageWidth-worksheetMargin, page.y-2)
+	}
+	advance(36)
+	page.text(worksheetMargin, 14, "F2", "Record")
+	for _, field := range ws.Fields {
+		advance(30)
+		page.text(worksheetMargin, 12, "F1", field+":")
+		page.line(worksheetMargin+170, page.y-2, worksheetPageWidth-worksheetMargin, page.y-2)
+	}
+
+	// Objects: 1 catalog, 2 page tree, 3 and 4 fonts, then a page and its content stream for each page
+	var objects []string
+	var kids []string
+	for i := range pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+2*i))
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	)
+	for i, page := range pages {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", worksheetPageWidth, worksheetPageHeight, 6+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.content.Len(), page.content.String()),
+		)
+	}
+	var document bytes.Buffer
+	document.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = document.Len()
+		fmt.Fprintf(&document, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := document.Len()
+	fmt.Fprintf(&document, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&document, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&document, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	_, err := w.Write(document.Bytes())
+	return err
+}
+
+// writeWorksheet writes the worksheet to a PDF file
+func writeWorksheet(path string, worksheet Worksheet) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = worksheet.WritePDF(file, time.Now())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
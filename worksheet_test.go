@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPDFString(t *testing.T) {
+	if encoded := pdfString(`a (b) \ 20°C → x`); encoded != `(a \(b\) \\ 20`+"\xb0"+`C ? x)` {
+		t.Errorf("Invalid PDF string %q", encoded)
+	}
+}
+
+func TestBlendWorksheet(t *testing.T) {
+	plan := BlendPlan{
+		CylinderVolume:    12,
+		StartPressure:     50,
+		StartComposition:  GasComposition{Oxygen: 0.21, Nitrogen: 0.79},
+		DrainRequired:     true,
+		DrainToPressure:   20,
+		TargetComposition: GasComposition{Oxygen: 0.32, Nitrogen: 0.68},
+		TargetPressure:    200,
+		Steps: []BlendStep{
+			{Description: "oxygen", AddedPressure: 25, FillToPressure: 45},
+			{Description: "air", AddedPressure: 155, FillToPressure: 200},
+		},
+	}
+	worksheet := blendWorksheet(plan, Metric)
+	if len(worksheet.Steps) != 3 || worksheet.Steps[0] != "Drain the cylinder to 20.0bar" || worksheet.Steps[2] != "Add 155.0bar of air, fill to 200.0bar" {
+		t.Errorf("Invalid steps %v", worksheet.Steps)
+	}
+	if len(worksheet.Details) != 3 || worksheet.Fields[0] != "Analyzed O2 %" {
+		t.Errorf("Invalid worksheet %+v", worksheet)
+	}
+}
+
+func TestWorksheetPDF(t *testing.T) {
+	worksheet := Worksheet{Title: "Transfer worksheet", Details: []string{"Configuration: all manifolds open"}, Fields: worksheetRecordFields}
+	for i := 0; i < 40; i++ {
+		worksheet.Steps = append(worksheet.Steps, fmt.Sprintf("Connect source to destination %d", i+1))
+	}
+	var output bytes.Buffer
+	if err := worksheet.WritePDF(&output, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	document := output.String()
+	if !strings.HasPrefix(document, "%PDF-1.4\n") || !strings.HasSuffix(document, "%%EOF\n") {
+		t.Fatalf("Invalid PDF:\n%s", document)
+	}
+	if pages := strings.Count(document, "/Type /Page "); pages != 2 {
+		t.Errorf("Expected 40 steps to take 2 pages, got %d", pages)
+	}
+	if !strings.Contains(document, "(40. Connect source to destination 40) Tj") || !strings.Contains(document, "(Generated 2024-05-01 12:00) Tj") {
+		t.Errorf("Missing content:\n%s", document)
+	}
+	xref := strings.LastIndex(document, "startxref\n")
+	var offset int
+	fmt.Sscanf(document[xref+len("startxref\n"):], "%d", &offset)
+	if !strings.HasPrefix(document[offset:], "xref\n") {
+		t.Errorf("Invalid xref offset %d", offset)
+	}
+}
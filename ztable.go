@@ -0,0 +1,142 @@
+package main
+
+import "math"
+
+// CompressibilityTable holds compressibility factors (Z) over a temperature and pressure grid
+type CompressibilityTable struct {
+	Temperatures []Temperature
+	Pressures    []PressureBar
+	// Factors is indexed by temperature, then pressure
+	Factors [][]float64
+}
+
+var compressibilityTableTemperatures = []Temperature{ZeroCelsius, ZeroCelsius + 20, ZeroCelsius + 40}
+var compressibilityTablePressures = []PressureBar{1, 50, 100, 150, 200, 250, 300, 350}
+
+// AirCompressibilityTable holds compressibility factors for air
+var AirCompressibilityTable = CompressibilityTable{
+	Temperatures: compressibilityTableTemperatures,
+	Pressures:    compressibilityTablePressures,
+	Factors: [][]float64{
+		{0.9994, 0.9768, 0.9680, 0.9738, 0.9921, 1.0197, 1.0539, 1.0922},
+		{0.9996, 0.9871, 0.9859, 0.9959, 1.0154, 1.0422, 1.0741, 1.1096},
+		{0.9998, 0.9942, 0.9981, 1.0109, 1.0312, 1.0573, 1.0876, 1.1210},
+	},
+}
+
+// CompressibilityTables holds compressibility factors for pure gases. Other gases in a mix fall back to
+// Peng-Robinson equation of state.
+var CompressibilityTables = map[Gas]CompressibilityTable{
+	Helium: {
+		Temperatures: compressibilityTableTemperatures,
+		Pressures:    compressibilityTablePressures,
+		Factors: [][]float64{
+			{1.0005, 1.0260, 1.0518, 1.0771, 1.1021, 1.1268, 1.1511, 1.1750},
+			{1.0005, 1.0241, 1.0478, 1.0713, 1.0944, 1.1172, 1.1397, 1.1619},
+			{1.0004, 1.0223, 1.0444, 1.0661, 1.0876, 1.1088, 1.1297, 1.1504},
+		},
+	},
+	Nitrogen: {
+		Temperatures: compressibilityTableTemperatures,
+		Pressures:    compressibilityTablePressures,
+		Factors: [][]float64{
+			{0.9995, 0.9845, 0.9839, 0.9976, 1.0226, 1.0557, 1.0943, 1.1363},
+			{0.9998, 0.9935, 0.9989, 1.0152, 1.0402, 1.0717, 1.1076, 1.1464},
+			{0.9999, 0.9993, 1.0083, 1.0260, 1.0508, 1.0807, 1.1144, 1.1506},
+		},
+	},
+	Oxygen: {
+		Temperatures: compressibilityTableTemperatures,
+		Pressures:    compressibilityTablePressures,
+		Factors: [][]float64{
+			{0.9990, 0.9557, 0.9223, 0.9033, 0.8996, 0.9095, 0.9297, 0.9569},
+			{0.9993, 0.9680, 0.9453, 0.9337, 0.9335, 0.9433, 0.9612, 0.9850},
+			{0.9995, 0.9768, 0.9617, 0.9555, 0.9580, 0.9684, 0.9852, 1.0069},
+		},
+	},
+}
+
+// interpolationIndex returns the index of the grid cell containing value and the position inside the cell.
+// Values outside the grid are clamped to the nearest cell edge.
+func interpolationIndex(grid []float64, value float64) (int, float64) {
+	if value <= grid[0] {
+		return 0, 0
+	}
+	for i := 1; i < len(grid); i++ {
+		if value <= grid[i] {
+			return i - 1, (value - grid[i-1]) / (grid[i] - grid[i-1])
+		}
+	}
+	return len(grid) - 2, 1
+}
+
+// CompressibilityFactor returns bilinearly interpolated compressibility factor
+func (ct CompressibilityTable) CompressibilityFactor(pressure PressureBar, temperature Temperature) float64 {
+	temperatures := make([]float64, len(ct.Temperatures))
+	for i, t := range ct.Temperatures {
+		temperatures[i] = float64(t)
+	}
+	pressures := make([]float64, len(ct.Pressures))
+	for i, p := range ct.Pressures {
+		pressures[i] = float64(p)
+	}
+	ti, tf := interpolationIndex(temperatures, float64(temperature))
+	pi, pf := interpolationIndex(pressures, float64(pressure))
+	low := ct.Factors[ti][pi]*(1-pf) + ct.Factors[ti][pi+1]*pf
+	high := ct.Factors[ti+1][pi]*(1-pf) + ct.Factors[ti+1][pi+1]*pf
+	return low*(1-tf) + high*tf
+}
+
+// mixCompressibilityFactor returns compressibility factor of a gas mix at the total pressure. Air uses its own table;
+// other mixes are combined from pure gas tables weighted by mole fraction (Amagat's law).
+func mixCompressibilityFactor(pressure PressureBar, temperature Temperature, gasComposition GasComposition) float64 {
+	if gasComposition.Equal(GasComposition{Oxygen: 0.21, Nitrogen: 0.79}) {
+		return AirCompressibilityTable.CompressibilityFactor(pressure, temperature)
+	}
+	var z, fractionSum float64
+	for gasType, fraction := range gasComposition {
+		if fraction == 0 {
+			continue
+		}
+		fractionSum += fraction
+		if table, ok := CompressibilityTables[gasType]; ok {
+			z += fraction * table.CompressibilityFactor(pressure, temperature)
+			continue
+		}
+		moles := PengRobinson.gasMoles(1, pressure, gasType, temperature)
+		z += fraction * float64(pressure) / (float64(moles) * R * float64(temperature))
+	}
+	if fractionSum == 0 {
+		return 1
+	}
+	return z / fractionSum
+}
+
+// CompressibilityTableSystem uses tabulated compressibility factors for the whole mix.
+type CompressibilityTableSystem struct{}
+
+// CompressibilityTableGas is the compressibility factor table gas system
+var CompressibilityTableGas = CompressibilityTableSystem{}
+
+// Moles returns total moles of the mix in the cylinder
+func (CompressibilityTableSystem) Moles(cylinderVolume CylinderVolume, pressure PressureBar, temperature Temperature, gasComposition GasComposition) MoleCount {
+	if pressure <= 0 {
+		return 0
+	}
+	z := mixCompressibilityFactor(pressure, temperature, gasComposition)
+	return MoleCount(float64(pressure) * float64(cylinderVolume) / (z * R * float64(temperature)))
+}
+
+// Pressure returns pressure of the mix in the cylinder using fixed point iteration
+func (CompressibilityTableSystem) Pressure(cylinderVolume CylinderVolume, moleCount MoleCount, temperature Temperature, gasComposition GasComposition) PressureBar {
+	idealPressure := float64(moleCount) * R * float64(temperature) / float64(cylinderVolume)
+	pressure := idealPressure
+	for i := 0; i < 100; i++ {
+		next := idealPressure * mixCompressibilityFactor(PressureBar(pressure), temperature, gasComposition)
+		if math.Abs(next-pressure) < 1e-9 {
+			break
+		}
+		pressure = next
+	}
+	return PressureBar(pressure)
+}
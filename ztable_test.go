@@ -0,0 +1,28 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCompressibilityFactorInterpolation(t *testing.T) {
+	z := AirCompressibilityTable.CompressibilityFactor(225, 293.15)
+	expected := (1.0154 + 1.0422) / 2
+	if !compareFloats(z, expected) {
+		t.Errorf("Invalid compressibility factor %f, expected %f", z, expected)
+	}
+	z = AirCompressibilityTable.CompressibilityFactor(200, 283.15)
+	expected = (0.9921 + 1.0154) / 2
+	if !compareFloats(z, expected) {
+		t.Errorf("Invalid compressibility factor %f, expected %f", z, expected)
+	}
+}
+
+func TestCompressibilityTableRoundTrip(t *testing.T) {
+	trimix := GasComposition{Oxygen: 0.18, Helium: 0.45, Nitrogen: 0.37}
+	moles := CompressibilityTableGas.Moles(12, 232, 293.15, trimix)
+	pressure := CompressibilityTableGas.Pressure(12, moles, 293.15, trimix)
+	if math.Abs(float64(pressure)-232) > 1e-6 {
+		t.Errorf("Invalid pressure %f, expected 232", pressure)
+	}
+}